@@ -0,0 +1,60 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:subresource:status
+
+// ClusterDbServer is a DbServer that can be used from every namespace
+// The Secret named in the spec is read from the namespace of the operator
+type ClusterDbServer struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DbServerSpec   `json:"spec,omitempty"`
+	Status DbServerStatus `json:"status,omitempty"`
+}
+
+// Returns the ClusterDbServer as a DbServer in the operator namespace so it can be used where a DbServer is expected
+func (s *ClusterDbServer) AsDbServer(operatorNamespace string) *DbServer {
+	dbServer := &DbServer{
+		TypeMeta: metav1.TypeMeta{Kind: CLUSTER_DB_SERVER_KIND, APIVersion: GroupVersion.String()},
+		Spec:     *s.Spec.DeepCopy(),
+		Status:   *s.Status.DeepCopy(),
+	}
+	s.ObjectMeta.DeepCopyInto(&dbServer.ObjectMeta)
+	dbServer.Namespace = operatorNamespace
+	return dbServer
+}
+
+//+kubebuilder:object:root=true
+
+// ClusterDbServerList contains a list of ClusterDbServer
+type ClusterDbServerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterDbServer `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterDbServer{}, &ClusterDbServerList{})
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:subresource:status
+
+// ClusterS3Storage is a S3Storage that can be used from every namespace
+// Jobs run in the namespace of the app and can't read Secrets from the operator namespace, so there is no access key Secret
+// The Jobs get access through assume_role_arn and the service account of the Job, for instance with IRSA
+// +kubebuilder:validation:XValidation:rule="!has(self.spec.secret_access_key_k8s_secret)",message="a ClusterS3Storage can't use an access key Secret, use assume_role_arn"
+type ClusterS3Storage struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   S3StorageSpec   `json:"spec,omitempty"`
+	Status S3StorageStatus `json:"status,omitempty"`
+}
+
+// Returns the ClusterS3Storage as a S3Storage in the operator namespace so it can be used where a S3Storage is expected
+func (s *ClusterS3Storage) AsS3Storage(operatorNamespace string) *S3Storage {
+	s3Storage := &S3Storage{
+		TypeMeta: metav1.TypeMeta{Kind: "ClusterS3Storage", APIVersion: GroupVersion.String()},
+		Spec:     *s.Spec.DeepCopy(),
+		Status:   *s.Status.DeepCopy(),
+	}
+	s.ObjectMeta.DeepCopyInto(&s3Storage.ObjectMeta)
+	s3Storage.Namespace = operatorNamespace
+	return s3Storage
+}
+
+//+kubebuilder:object:root=true
+
+// ClusterS3StorageList contains a list of ClusterS3Storage
+type ClusterS3StorageList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterS3Storage `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterS3Storage{}, &ClusterS3StorageList{})
+}
@@ -21,20 +21,87 @@ import (
 )
 
 // DbSpec defines the desired state of Db
+// +kubebuilder:validation:XValidation:rule="!(has(self.server) && size(self.server) > 0 && has(self.server_ref))",message="server and server_ref are mutually exclusive"
 type DbSpec struct {
-	Server         string `json:"server"`
-	DbName         string `json:"db_name"`
-	DropOnDeletion bool   `json:"drop_on_deletion"`
-	CascadeOnDrop  bool   `json:"cascade_on_drop,omitempty"`
-	AfterCreateSQL string `json:"after_create_sql,omitempty"`
+	// Name of the DbServer, use server_ref to point at a DbServer in a specific namespace
+	Server         string       `json:"server,omitempty"`
+	ServerRef      *DbServerRef `json:"server_ref,omitempty"`
+	DbName         string       `json:"db_name"`
+	DropOnDeletion bool         `json:"drop_on_deletion"`
+	CascadeOnDrop  bool         `json:"cascade_on_drop,omitempty"`
+	AfterCreateSQL string       `json:"after_create_sql,omitempty"`
+	// What to do when the database already exists on the server and wasn't created by this resource, defaults to ifExists.
+	// An adopted database is only dropped with drop_adopted
+	// +kubebuilder:validation:Enum=never;ifExists;require
+	Adopt string `json:"adopt,omitempty"`
+	// With drop_on_deletion also drop the database when it was adopted instead of created by this resource
+	DropAdopted bool `json:"drop_adopted,omitempty"`
+	// Name of a User in the namespace of the Db that owns the database, on MySQL the User gets ALL on the database
+	// When not set the owner is not managed
+	Owner string `json:"owner,omitempty"`
+
+	// Postgres and CockroachDB, can't be changed after creation
+	Encoding string `json:"encoding,omitempty"`
+	// Postgres only, can't be changed after creation
+	LcCollate string `json:"lc_collate,omitempty"`
+	// Postgres only, can't be changed after creation
+	LcCtype string `json:"lc_ctype,omitempty"`
+	// Postgres only, only used when creating the database
+	Template string `json:"template,omitempty"`
+	// Postgres only, -1 means no limit
+	// +kubebuilder:validation:Minimum=-1
+	ConnectionLimit *int `json:"connection_limit,omitempty"`
+	// Postgres only
+	Tablespace string `json:"tablespace,omitempty"`
+
+	// MySQL only
+	CharacterSet string `json:"character_set,omitempty"`
+	// MySQL only
+	Collate string `json:"collate,omitempty"`
+
+	// CockroachDB only, required when regions is set
+	PrimaryRegion string `json:"primary_region,omitempty"`
+	// CockroachDB only, the regions besides the primary region
+	Regions []string `json:"regions,omitempty"`
+	// CockroachDB only, the failure the database should survive
+	// +kubebuilder:validation:Enum=zone;region
+	Survive string `json:"survive,omitempty"`
+	// Generate a Service Binding Secret with the credentials of the owner, see https://servicebinding.io
+	ServiceBinding bool `json:"service_binding,omitempty"`
+}
+
+const (
+	ADOPT_NEVER     = "never"
+	ADOPT_IF_EXISTS = "ifExists"
+	ADOPT_REQUIRE   = "require"
+)
+
+const (
+	SURVIVE_ZONE   = "zone"
+	SURVIVE_REGION = "region"
+)
+
+func (s DbSpec) GetServerRef() DbServerRef {
+	if s.ServerRef != nil {
+		return *s.ServerRef
+	}
+	return DbServerRef{Name: s.Server}
+}
+
+// The Secret a Service Binding workload projection reads, status.binding of a Provisioned Service
+type BindingReference struct {
+	Name string `json:"name"`
 }
 
 // DbStatus defines the observed state of Db
 type DbStatus struct {
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	Binding    *BindingReference  `json:"binding,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:storageversion
 
 // Db is the Schema for the dbs API
 type Db struct {
@@ -0,0 +1,66 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DbMigrationSpec defines the desired state of DbMigration
+type DbMigrationSpec struct {
+	// Name of the Db in the namespace of the DbMigration
+	Db string `json:"db"`
+	// Name of the User in the namespace of the DbMigration to run the migrations as, the DbServer user is used when not set
+	User string `json:"user,omitempty"`
+	// ConfigMap with one .sql key per migration, they are applied in the order of the keys like 0001_create_users.sql
+	ConfigMapName string `json:"config_map_name"`
+	// Table in the database that records the applied migrations and their checksums, defaults to db_operator_migrations
+	HistoryTable string `json:"history_table,omitempty"`
+}
+
+// DbMigrationStatus defines the observed state of DbMigration
+type DbMigrationStatus struct {
+	// Key of the last migration that was applied
+	AppliedVersion    string             `json:"applied_version,omitempty"`
+	PendingMigrations []string           `json:"pending_migrations,omitempty"`
+	Conditions        []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// DbMigration is the Schema for the dbmigrations API
+type DbMigration struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DbMigrationSpec   `json:"spec,omitempty"`
+	Status DbMigrationStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// DbMigrationList contains a list of DbMigration
+type DbMigrationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DbMigration `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DbMigration{}, &DbMigrationList{})
+}
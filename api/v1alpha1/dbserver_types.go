@@ -37,8 +37,53 @@ type DbServerSpec struct {
 	Version     string            `json:"version,omitempty"`
 	ServerType  string            `json:"server_type"`
 	Options     map[string]string `json:"options,omitempty"`
+	// Maximum number of open connections per user and database, 0 means unlimited
+	// +kubebuilder:validation:Minimum:=0
+	MaxOpenConnections int `json:"max_open_connections,omitempty"`
+	// Namespaces other than the one of the DbServer that may use it, when not set every namespace can use it
+	AllowedNamespaces *AllowedNamespaces `json:"allowed_namespaces,omitempty"`
+	// Server privileges Users may request through server_privs, when not set every server privilege is allowed
+	AllowedServerPrivs []string `json:"allowed_server_privs,omitempty"`
+	// priv_types Users may request through db_privs, when not set every priv_type is allowed
+	AllowedPrivTypes []string `json:"allowed_priv_types,omitempty"`
+	// Puts every User of this server in dry run mode, see dry_run on User
+	DryRun bool `json:"dry_run,omitempty"`
+	// How often the privileges of the Users of this server are compared with the server, when not set only changes to a User are reconciled
+	ResyncInterval *metav1.Duration `json:"resync_interval,omitempty"`
+	// What to do with privileges that were changed on the server, defaults to enforce
+	// Grants on scopes that are not in db_privs count as extra privileges, on CockroachDB only grants on tables are found
+	// +kubebuilder:validation:Enum=enforce;report;ignore
+	DriftPolicy string `json:"drift_policy,omitempty"`
 }
 
+// A namespace is allowed when it's listed in Names or when its labels match the Selector
+type AllowedNamespaces struct {
+	Names    []string              `json:"names,omitempty"`
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// Reference to a DbServer
+// Without a namespace the DbServer is looked up in the namespace of the referencing object first and then in the other namespaces
+// Without a kind a ClusterDbServer with the name is used when no DbServer is found
+type DbServerRef struct {
+	// +kubebuilder:validation:MinLength=1
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	// +kubebuilder:validation:Enum=DbServer;ClusterDbServer
+	Kind string `json:"kind,omitempty"`
+}
+
+const (
+	DRIFT_POLICY_ENFORCE = "enforce"
+	DRIFT_POLICY_REPORT  = "report"
+	DRIFT_POLICY_IGNORE  = "ignore"
+)
+
+const (
+	DB_SERVER_KIND         = "DbServer"
+	CLUSTER_DB_SERVER_KIND = "ClusterDbServer"
+)
+
 // DbServerStatus defines the observed state of DbServer
 type DbServerStatus struct {
 	ConnectionAvailable bool     `json:"connection_available"`
@@ -49,6 +94,7 @@ type DbServerStatus struct {
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:storageversion
 
 // DbServer is the Schema for the dbservers API
 type DbServer struct {
@@ -0,0 +1,87 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DbServerInventorySpec defines the desired state of DbServerInventory
+type DbServerInventorySpec struct {
+	// The server to take the inventory of. An inventory in the namespace of the DbServer, or in the operator namespace for a
+	// ClusterDbServer, covers the Db, User and Schema CRs of every namespace and lists orphan databases and users.
+	// In other namespaces only the CRs of that namespace and the orphan schemas in their databases are listed
+	DbServerRef DbServerRef `json:"db_server_ref"`
+	// How often the inventory is taken, defaults to 1h
+	Interval *metav1.Duration `json:"interval,omitempty"`
+	// Users that are not reported as orphans, next to the DbServer user and built in users, * and ? can be used as wildcards
+	IgnoreUsers []string `json:"ignore_users,omitempty"`
+	// Databases that are not reported as orphans, next to the built in databases, * and ? can be used as wildcards
+	IgnoreDatabases []string `json:"ignore_databases,omitempty"`
+	// Schemas that are not reported as orphans, next to public and the built in schemas, * and ? can be used as wildcards
+	IgnoreSchemas []string `json:"ignore_schemas,omitempty"`
+}
+
+// A CR whose object doesn't exist on the server
+type MissingObject struct {
+	// Db, User or Schema
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// Name of the object on the server, schemas are named database.schema
+	Object string `json:"object"`
+}
+
+// DbServerInventoryStatus defines the observed state of DbServerInventory
+type DbServerInventoryStatus struct {
+	// Databases on the server without a Db CR
+	OrphanDatabases []string `json:"orphan_databases,omitempty"`
+	// Users on the server without a User CR
+	OrphanUsers []string `json:"orphan_users,omitempty"`
+	// Schemas named database.schema without a Schema CR, only the schemas of databases with a Db CR are listed
+	OrphanSchemas []string `json:"orphan_schemas,omitempty"`
+	// CRs that point to objects that don't exist on the server
+	MissingObjects []MissingObject `json:"missing_objects,omitempty"`
+	TakenAt        *metav1.Time    `json:"taken_at,omitempty"`
+	Message        string          `json:"message,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// DbServerInventory lists the objects on a DbServer that aren't managed by a CR and the CRs whose objects are missing
+// It's kept apart from the DbServer so the inventory of a large server doesn't bloat the DbServer status
+type DbServerInventory struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DbServerInventorySpec   `json:"spec,omitempty"`
+	Status DbServerInventoryStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// DbServerInventoryList contains a list of DbServerInventory
+type DbServerInventoryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DbServerInventory `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DbServerInventory{}, &DbServerInventoryList{})
+}
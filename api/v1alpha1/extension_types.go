@@ -0,0 +1,72 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ExtensionSpec defines the desired state of Extension
+type ExtensionSpec struct {
+	// Name of the Db in the namespace of the Extension, the extension is installed in its database
+	Db string `json:"db"`
+	// Name of the extension, like pgcrypto or postgis
+	Name string `json:"name"`
+	// Version to install and update to, when not set the default version is installed and never updated
+	Version string `json:"version,omitempty"`
+	// Schema to install the objects of the extension in, relocatable extensions are moved when it changes
+	Schema string `json:"schema,omitempty"`
+	// Also install the extensions this extension depends on
+	Cascade        bool `json:"cascade,omitempty"`
+	DropOnDeletion bool `json:"drop_on_deletion"`
+	CascadeOnDrop  bool `json:"cascade_on_drop,omitempty"`
+}
+
+// ExtensionStatus defines the observed state of Extension
+type ExtensionStatus struct {
+	// Set when this resource installed the extension, only those extensions are dropped on deletion
+	Created bool `json:"created,omitempty"`
+	// Version in pg_extension
+	InstalledVersion string             `json:"installed_version,omitempty"`
+	InstalledSchema  string             `json:"installed_schema,omitempty"`
+	Conditions       []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// Extension is the Schema for the extensions API
+type Extension struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ExtensionSpec   `json:"spec,omitempty"`
+	Status ExtensionStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ExtensionList contains a list of Extension
+type ExtensionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Extension `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Extension{}, &ExtensionList{})
+}
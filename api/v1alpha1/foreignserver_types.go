@@ -0,0 +1,97 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ForeignUserMapping lets a user of the local db query the remote db as another user
+type ForeignUserMapping struct {
+	// Name of the User in the namespace of the ForeignServer that gets the user mapping
+	User string `json:"user"`
+	// Name of the User whose user name and password are used on the remote db, defaults to user
+	RemoteUser string `json:"remote_user,omitempty"`
+}
+
+// ForeignSchemaImport creates foreign tables for the tables in a remote schema
+type ForeignSchemaImport struct {
+	// Schema in the remote db
+	RemoteSchema string `json:"remote_schema"`
+	// Schema in the local db the foreign tables are created in, it has to exist
+	Schema string `json:"schema"`
+	// Only import these tables, when not set all tables of the remote schema are imported
+	Tables []string `json:"tables,omitempty"`
+	// Name of the User in user_mappings that runs the import and owns the foreign tables, defaults to the first one
+	User string `json:"user,omitempty"`
+}
+
+// ForeignServerSpec defines the desired state of ForeignServer
+type ForeignServerSpec struct {
+	// Name of the Db in the namespace of the ForeignServer, the server is created in its database
+	Db string `json:"db"`
+	// Name of the Db in the namespace of the ForeignServer to connect to, it can be on another DbServer
+	RemoteDb string `json:"remote_db"`
+	// Name of the foreign server in the database
+	Name                string               `json:"name"`
+	UserMappings        []ForeignUserMapping `json:"user_mappings,omitempty"`
+	ImportForeignSchema *ForeignSchemaImport `json:"import_foreign_schema,omitempty"`
+	// Only a server that was created by this resource is dropped, together with the user mappings it created
+	DropOnDeletion bool `json:"drop_on_deletion"`
+	// Also drop the other user mappings and the foreign tables of the server, without it the drop fails while they exist
+	CascadeOnDrop bool `json:"cascade_on_drop,omitempty"`
+}
+
+// ForeignServerStatus defines the observed state of ForeignServer
+type ForeignServerStatus struct {
+	// Set when this resource created the server, only those servers are dropped on deletion
+	Created bool `json:"created,omitempty"`
+	// Users that have a user mapping for the server
+	UserMappings []string `json:"user_mappings,omitempty"`
+	// Users whose user mapping was created by the ForeignServer, only those are dropped when they're removed from user_mappings
+	CreatedUserMappings []string `json:"created_user_mappings,omitempty"`
+	// Hash of the options last set on each created user mapping, they can only be read back by a superuser
+	UserMappingHashes map[string]string `json:"user_mapping_hashes,omitempty"`
+	// Foreign tables of the server in the schema of import_foreign_schema
+	ImportedTables []string           `json:"imported_tables,omitempty"`
+	Conditions     []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// ForeignServer is the Schema for the foreignservers API
+type ForeignServer struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ForeignServerSpec   `json:"spec,omitempty"`
+	Status ForeignServerStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ForeignServerList contains a list of ForeignServer
+type ForeignServerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ForeignServer `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ForeignServer{}, &ForeignServerList{})
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PublicationSpec defines the desired state of Publication
+type PublicationSpec struct {
+	// Name of the Db in the namespace of the Publication, the publication is created in its database
+	Db string `json:"db"`
+	// Name of the publication in the database
+	Name string `json:"name"`
+	// Publish all tables in the database, including tables created later, can't be combined with tables
+	AllTables bool `json:"all_tables,omitempty"`
+	// Tables to publish as table or schema.table, tables without a schema are in public
+	Tables []string `json:"tables,omitempty"`
+	// Operations to publish out of insert, update, delete and truncate, all of them when not set
+	Operations     []string `json:"operations,omitempty"`
+	DropOnDeletion bool     `json:"drop_on_deletion"`
+}
+
+// PublicationStatus defines the observed state of Publication
+type PublicationStatus struct {
+	// Tables in pg_publication_tables
+	Tables     []string           `json:"tables,omitempty"`
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// Publication is the Schema for the publications API
+type Publication struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PublicationSpec   `json:"spec,omitempty"`
+	Status PublicationStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// PublicationList contains a list of Publication
+type PublicationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Publication `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Publication{}, &PublicationList{})
+}
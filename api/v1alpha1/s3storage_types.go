@@ -29,6 +29,9 @@ type S3StorageSpec struct {
 	Prefix                string `json:"prefix,omitempty"`
 	AccesKeyId            string `json:"access_key_id,omitempty"`
 	Endpoint              string `json:"endpoint,omitempty"`
+	// Namespaces other than the one of the storage that may use it, when not set every namespace can use it
+	// Only checked for a ClusterS3Storage, a S3Storage can only be used from its own namespace
+	AllowedNamespaces *AllowedNamespaces `json:"allowed_namespaces,omitempty"`
 }
 
 // S3StorageStatus defines the observed state of S3Storage
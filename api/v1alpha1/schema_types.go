@@ -24,22 +24,40 @@ import (
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
 // SchemaSpec defines the desired state of Schema
+// +kubebuilder:validation:XValidation:rule="!(has(self.db_server_name) && size(self.db_server_name) > 0 && has(self.db_server_ref))",message="db_server_name and db_server_ref are mutually exclusive"
 type SchemaSpec struct {
-	Server         string  `json:"db_server_name"`
-	DbName         string  `json:"db_name"`
-	Name           string  `json:"name"`
-	DropOnDeletion bool    `json:"drop_on_deletion"`
-	CascadeOnDrop  bool    `json:"cascade_on_drop,omitempty"`
-	Creator        *string `json:"creator,omitempty"`
+	// Name of the DbServer, use db_server_ref to point at a DbServer in a specific namespace
+	Server         string       `json:"db_server_name,omitempty"`
+	ServerRef      *DbServerRef `json:"db_server_ref,omitempty"`
+	DbName         string       `json:"db_name"`
+	Name           string       `json:"name"`
+	DropOnDeletion bool         `json:"drop_on_deletion"`
+	CascadeOnDrop  bool         `json:"cascade_on_drop,omitempty"`
+	Creator        *string      `json:"creator,omitempty"`
+	// What to do when the schema already exists on the server and wasn't created by this resource, defaults to ifExists.
+	// An adopted schema is only dropped with drop_adopted
+	// +kubebuilder:validation:Enum=never;ifExists;require
+	Adopt string `json:"adopt,omitempty"`
+	// With drop_on_deletion also drop the schema when it was adopted instead of created by this resource
+	DropAdopted bool `json:"drop_adopted,omitempty"`
+}
+
+func (s SchemaSpec) GetServerRef() DbServerRef {
+	if s.ServerRef != nil {
+		return *s.ServerRef
+	}
+	return DbServerRef{Name: s.Server}
 }
 
 // SchemaStatus defines the observed state of Schema
 type SchemaStatus struct {
-	Created bool `json:"created,omitempty"`
+	Created    bool               `json:"created,omitempty"`
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:storageversion
 
 // Schema is the Schema for the schemas API
 type Schema struct {
@@ -0,0 +1,84 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type SqlCronJobSpec struct {
+	// Name of the Db in the namespace of the SqlCronJob
+	Db string `json:"db"`
+	// Name of the User in the namespace of the SqlCronJob to run the statements as, the password is taken from its Secret
+	// The statements never run as the DbServer user, it can usually do anything on the server
+	// +kubebuilder:validation:MinLength=1
+	User string `json:"user"`
+	// +kubebuilder:validation:MinItems=1
+	Statements []string `json:"statements"`
+	Interval   string   `json:"interval"`
+	// Runs that take longer are stopped and marked as failed
+	Timeout        *metav1.Duration `json:"timeout,omitempty"`
+	Suspend        bool             `json:"suspend"`
+	ServiceAccount string           `json:"service_account,omitempty"`
+}
+
+const (
+	SQL_RUN_RUNNING   = "Running"
+	SQL_RUN_SUCCEEDED = "Succeeded"
+	SQL_RUN_FAILED    = "Failed"
+)
+
+type SqlCronJobRun struct {
+	JobName   string       `json:"job_name"`
+	StartTime *metav1.Time `json:"start_time,omitempty"`
+	// Running, Succeeded or Failed
+	Result   string           `json:"result"`
+	Duration *metav1.Duration `json:"duration,omitempty"`
+	Message  string           `json:"message,omitempty"`
+}
+
+// SqlCronJobStatus defines the observed state of SqlCronJob
+type SqlCronJobStatus struct {
+	Exists      bool           `json:"exists"`
+	CronJobName string         `json:"cronjob_name"`
+	LastRun     *SqlCronJobRun `json:"last_run,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// SqlCronJob is the Schema for the sqlcronjobs API
+type SqlCronJob struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SqlCronJobSpec   `json:"spec,omitempty"`
+	Status SqlCronJobStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// SqlCronJobList contains a list of SqlCronJob
+type SqlCronJobList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SqlCronJob `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SqlCronJob{}, &SqlCronJobList{})
+}
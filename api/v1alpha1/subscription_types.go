@@ -0,0 +1,83 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SubscriptionSpec defines the desired state of Subscription
+type SubscriptionSpec struct {
+	// Name of the Db in the namespace of the Subscription that receives the changes
+	Db string `json:"db"`
+	// Name of the Db in the namespace of the Subscription that publishes the changes
+	// The subscription connects to it with the credentials of its DbServer
+	SourceDb string `json:"source_db"`
+	// Name of the subscription in the database
+	Name string `json:"name"`
+	// Names of the publications in the source db
+	// +kubebuilder:validation:MinItems=1
+	Publications []string `json:"publications"`
+	// Name of the replication slot on the source, defaults to the name of the subscription
+	SlotName string `json:"slot_name,omitempty"`
+	// Create the replication slot on the source, set to false when the slot already exists
+	// or when both databases are on the same DbServer
+	CreateSlot *bool `json:"create_slot,omitempty"`
+	// Copy the existing data of the published tables when the subscription starts
+	CopyData       *bool `json:"copy_data,omitempty"`
+	DropOnDeletion bool  `json:"drop_on_deletion"`
+}
+
+// SubscriptionStatus defines the observed state of Subscription
+type SubscriptionStatus struct {
+	// Tables in pg_subscription_rel
+	Tables []string `json:"tables,omitempty"`
+	// Last WAL location received from the source, from pg_stat_subscription
+	ReceivedLsn string `json:"received_lsn,omitempty"`
+	// Last WAL location reported back to the source
+	LatestEndLsn string `json:"latest_end_lsn,omitempty"`
+	// Time since the last WAL location was reported back to the source
+	Lag *metav1.Duration `json:"lag,omitempty"`
+	// Hash of the connection string last set on the subscription, only superusers can read it back from the server
+	ConnInfoHash string             `json:"conn_info_hash,omitempty"`
+	Conditions   []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// Subscription is the Schema for the subscriptions API
+type Subscription struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SubscriptionSpec   `json:"spec,omitempty"`
+	Status SubscriptionStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// SubscriptionList contains a list of Subscription
+type SubscriptionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Subscription `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Subscription{}, &SubscriptionList{})
+}
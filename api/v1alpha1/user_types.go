@@ -34,28 +34,106 @@ type DropUserOptions struct {
 	ReassingOwnedTo  string `json:"reassign_owned_to,omitempty"`
 }
 
+// MySQL only, the transport requirements for the account (REQUIRE clause)
+// SSL and X509 are mutually exclusive with each other and with Subject, Issuer and Cipher
+// Subject, Issuer and Cipher can be combined
+// An empty TlsRequires results in REQUIRE NONE
+type TlsRequires struct {
+	SSL     bool   `json:"ssl,omitempty"`
+	X509    bool   `json:"x509,omitempty"`
+	Subject string `json:"subject,omitempty"`
+	Issuer  string `json:"issuer,omitempty"`
+	Cipher  string `json:"cipher,omitempty"`
+}
+
+// A Secret with what an application needs to connect as the user, kept up to date by the operator
+type ConnectionSecret struct {
+	// Name of the Secret, it's owned by the User and can't be the secret with the password of the user
+	SecretName string `json:"secret_name"`
+	// Name of the Db in the namespace of the User to connect to, defaults to the database of the DbServer
+	Db string `json:"db,omitempty"`
+	// Keys of the Secret with Go templates for their values, the templates can use
+	// .Uri, .JdbcUrl, .Libpq, .Host, .Port, .Database, .UserName, .Password and .SslMode
+	// Defaults to the keys uri, jdbc_url, libpq (Postgres only), host, port, database, username and password
+	Templates map[string]string `json:"templates,omitempty"`
+}
+
 // UserSpec defines the desired state of User
+// +kubebuilder:validation:XValidation:rule="!(has(self.db_server_name) && size(self.db_server_name) > 0 && has(self.db_server_ref))",message="db_server_name and db_server_ref are mutually exclusive"
 type UserSpec struct {
-	UserName        string           `json:"user_name"`
-	SecretName      string           `json:"secret_name"`
-	GenerateSecret  bool             `json:"generate_secret,omitempty"`
-	PasswordKey     string           `json:"password_key,omitempty"` // defaults to password
-	CaCertKey       string           `json:"ca_cert_key,omitempty"`
-	TlsCrtKey       string           `json:"tls_cert_key,omitempty"`
-	TlsKeyKey       string           `json:"tls_key_key,omitempty"`
-	DbServerName    string           `json:"db_server_name"`
+	UserName       string `json:"user_name"`
+	Host           string `json:"host,omitempty"` // MySQL only, defaults to %
+	SecretName     string `json:"secret_name"`
+	GenerateSecret bool   `json:"generate_secret,omitempty"`
+	PasswordKey    string `json:"password_key,omitempty"` // defaults to password
+	CaCertKey      string `json:"ca_cert_key,omitempty"`
+	TlsCrtKey      string `json:"tls_cert_key,omitempty"`
+	TlsKeyKey      string `json:"tls_key_key,omitempty"`
+	// Name of the DbServer, use db_server_ref to point at a DbServer in a specific namespace
+	DbServerName    string           `json:"db_server_name,omitempty"`
+	DbServerRef     *DbServerRef     `json:"db_server_ref,omitempty"`
 	DbPrivs         []DbPriv         `json:"db_privs"`
 	ServerPrivs     string           `json:"server_privs"`
 	DropOnDeletion  bool             `json:"drop_on_deletion,omitempty"`
 	DropUserOptions *DropUserOptions `json:"drop_user_options,omitempty"`
+	// MySQL only, when not set the TLS requirements of the account are not managed
+	TlsRequires *TlsRequires `json:"tls_requires,omitempty"`
+	// MySQL only, 0 means no limit, when not set the limit is not managed
+	MaxQueriesPerHour  *int `json:"max_queries_per_hour,omitempty"`
+	MaxUserConnections *int `json:"max_user_connections,omitempty"`
+	// Postgres only, -1 means no limit, when not set the limit is not managed
+	ConnectionLimit *int `json:"connection_limit,omitempty"`
+	// Postgres only, a timestamp or infinity, when empty the expiry is not managed
+	ValidUntil string `json:"valid_until,omitempty"`
+	// Postgres only, per role settings like statement_timeout, search_path or work_mem
+	// Settings that are not listed are reset, when not set the settings are not managed
+	RoleSettings map[string]string `json:"role_settings,omitempty"`
+	// Only plan the privilege changes, they are applied once the approve-privs-plan annotation matches the hash of the plan
+	DryRun bool `json:"dry_run,omitempty"`
+	// What to do when the user already exists on the server and wasn't created by this resource, defaults to ifExists.
+	// An adopted user is only dropped with drop_adopted
+	// +kubebuilder:validation:Enum=never;ifExists;require
+	Adopt string `json:"adopt,omitempty"`
+	// With drop_on_deletion also drop the user when it was adopted instead of created by this resource
+	DropAdopted      bool              `json:"drop_adopted,omitempty"`
+	ConnectionSecret *ConnectionSecret `json:"connection_secret,omitempty"`
+	// Generate a Service Binding Secret for the user, see https://servicebinding.io
+	ServiceBinding bool `json:"service_binding,omitempty"`
+}
+
+func (s UserSpec) GetDbServerRef() DbServerRef {
+	if s.DbServerRef != nil {
+		return *s.DbServerRef
+	}
+	return DbServerRef{Name: s.DbServerName}
+}
+
+// A statement of a privileges plan, Database and RunAs are empty when it runs on the default
+// database of the DbServer as the DbServer user
+type PlannedStatement struct {
+	Database  string `json:"database,omitempty"`
+	RunAs     string `json:"run_as,omitempty"`
+	Statement string `json:"statement"`
+}
+
+// The GRANT and REVOKE statements that would bring the privileges of a User in line with its spec
+type PrivsPlan struct {
+	Hash       string             `json:"hash"`
+	Statements []PlannedStatement `json:"statements"`
+	PlannedAt  metav1.Time        `json:"planned_at"`
 }
 
 // UserStatus defines the observed state of User
 type UserStatus struct {
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// Pending privilege changes of a User in dry run mode
+	PrivsPlan *PrivsPlan        `json:"privs_plan,omitempty"`
+	Binding   *BindingReference `json:"binding,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:storageversion
 
 // User is the Schema for the users API
 type User struct {
@@ -0,0 +1,135 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"encoding/json"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Not everything in a v1alpha1 spec can be expressed in v1beta1, like db_privs without a priv_type or
+// the original spelling of a server_type. When a v1alpha1 object is converted to v1beta1 and this would
+// lose information, the v1alpha1 spec is kept in this annotation. Converting back restores it as long
+// as the v1beta1 spec wasn't changed in the meantime, so objects survive being read and written through v1beta1
+const V1ALPHA1_SPEC_ANNOTATION = "db-operator.kubemaster.com/v1alpha1-spec"
+
+// Copies the metadata without sharing the annotations map and drops the annotation with the saved hub spec
+func copyObjectMeta(src metav1.ObjectMeta) (metav1.ObjectMeta, string) {
+	dst := *src.DeepCopy()
+	saved, found := dst.Annotations[V1ALPHA1_SPEC_ANNOTATION]
+	if found {
+		delete(dst.Annotations, V1ALPHA1_SPEC_ANNOTATION)
+		if len(dst.Annotations) == 0 {
+			dst.Annotations = nil
+		}
+	}
+	return dst, saved
+}
+
+func saveHubSpec(objectMeta *metav1.ObjectMeta, hubSpec interface{}) error {
+	data, err := json.Marshal(hubSpec)
+	if err != nil {
+		return err
+	}
+	if objectMeta.Annotations == nil {
+		objectMeta.Annotations = map[string]string{}
+	}
+	objectMeta.Annotations[V1ALPHA1_SPEC_ANNOTATION] = string(data)
+	return nil
+}
+
+// Returns false when there was nothing saved
+func restoreHubSpec(saved string, hubSpec interface{}) (bool, error) {
+	if saved == "" {
+		return false, nil
+	}
+	err := json.Unmarshal([]byte(saved), hubSpec)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func serverRefFromHub(ref dboperatorv1alpha1.DbServerRef) DbServerRef {
+	return DbServerRef{
+		Name:      ref.Name,
+		Namespace: ref.Namespace,
+		Kind:      DbServerKind(ref.Kind),
+	}
+}
+
+// A plain name goes into the name field of v1alpha1, anything else into the reference
+func serverRefToHub(ref DbServerRef) (string, *dboperatorv1alpha1.DbServerRef) {
+	if ref.Namespace == "" && ref.Kind == "" {
+		return ref.Name, nil
+	}
+	return "", &dboperatorv1alpha1.DbServerRef{
+		Name:      ref.Name,
+		Namespace: ref.Namespace,
+		Kind:      string(ref.Kind),
+	}
+}
+
+func copyConditions(conditions []metav1.Condition) []metav1.Condition {
+	if conditions == nil {
+		return nil
+	}
+	return append([]metav1.Condition{}, conditions...)
+}
+
+func copyIntPtr(value *int) *int {
+	if value == nil {
+		return nil
+	}
+	copied := *value
+	return &copied
+}
+
+func copyDuration(value *metav1.Duration) *metav1.Duration {
+	if value == nil {
+		return nil
+	}
+	copied := *value
+	return &copied
+}
+
+func bindingFromHub(value *dboperatorv1alpha1.BindingReference) *BindingReference {
+	if value == nil {
+		return nil
+	}
+	return &BindingReference{Name: value.Name}
+}
+
+func bindingToHub(value *BindingReference) *dboperatorv1alpha1.BindingReference {
+	if value == nil {
+		return nil
+	}
+	return &dboperatorv1alpha1.BindingReference{Name: value.Name}
+}
+
+func copyStringMap(value map[string]string) map[string]string {
+	if value == nil {
+		return nil
+	}
+	copied := make(map[string]string, len(value))
+	for key, item := range value {
+		copied[key] = item
+	}
+	return copied
+}
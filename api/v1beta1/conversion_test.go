@@ -0,0 +1,284 @@
+package v1beta1
+
+import (
+	"testing"
+	"time"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func intPtr(value int) *int {
+	return &value
+}
+
+func stringPtr(value string) *string {
+	return &value
+}
+
+func objectMeta() metav1.ObjectMeta {
+	return metav1.ObjectMeta{Name: "shop", Namespace: "shop", Labels: map[string]string{"team": "payments"}, Annotations: map[string]string{"owner": "payments"}}
+}
+
+func conditions() []metav1.Condition {
+	return []metav1.Condition{{Type: "Authorized", Status: metav1.ConditionTrue, Reason: "Allowed", LastTransitionTime: metav1.NewTime(time.Unix(1700000000, 0))}}
+}
+
+func TestDbRoundTrip(t *testing.T) {
+	hubs := []dboperatorv1alpha1.Db{
+		{
+			ObjectMeta: objectMeta(),
+			Spec: dboperatorv1alpha1.DbSpec{
+				Server: "postgres", DbName: "shop", DropOnDeletion: true, CascadeOnDrop: true, AfterCreateSQL: "CREATE EXTENSION citext;",
+				Adopt: "never", DropAdopted: true, Owner: "shop-owner", Encoding: "UTF8", LcCollate: "C", LcCtype: "C", Template: "template0",
+				ConnectionLimit: intPtr(10), Tablespace: "fast", ServiceBinding: true,
+			},
+			Status: dboperatorv1alpha1.DbStatus{Conditions: conditions(), Binding: &dboperatorv1alpha1.BindingReference{Name: "shop-db-binding"}},
+		},
+		{
+			ObjectMeta: objectMeta(),
+			Spec: dboperatorv1alpha1.DbSpec{
+				ServerRef: &dboperatorv1alpha1.DbServerRef{Name: "cockroachdb", Namespace: "databases", Kind: "ClusterDbServer"},
+				DbName:    "shop", PrimaryRegion: "eu-west-1", Regions: []string{"eu-west-1", "eu-central-1"}, Survive: "region",
+			},
+		},
+		{
+			ObjectMeta: objectMeta(),
+			Spec:       dboperatorv1alpha1.DbSpec{Server: "mysql", DbName: "shop", CharacterSet: "utf8mb4", Collate: "utf8mb4_bin"},
+		},
+	}
+	for _, hub := range hubs {
+		spoke := &Db{}
+		if err := spoke.ConvertFrom(hub.DeepCopy()); err != nil {
+			t.Fatalf("unexpected error %s", err)
+		}
+		if _, found := spoke.Annotations[V1ALPHA1_SPEC_ANNOTATION]; found {
+			t.Errorf("expected %+v to convert to v1beta1 without saving the v1alpha1 spec", hub.Spec)
+		}
+		back := &dboperatorv1alpha1.Db{}
+		if err := spoke.ConvertTo(back); err != nil {
+			t.Fatalf("unexpected error %s", err)
+		}
+		if !equality.Semantic.DeepEqual(hub, *back) {
+			t.Errorf("expected v1alpha1 -> v1beta1 -> v1alpha1 to keep %+v got %+v", hub, *back)
+		}
+
+		again := &Db{}
+		if err := again.ConvertFrom(back); err != nil {
+			t.Fatalf("unexpected error %s", err)
+		}
+		if !equality.Semantic.DeepEqual(*spoke, *again) {
+			t.Errorf("expected v1beta1 -> v1alpha1 -> v1beta1 to keep %+v got %+v", *spoke, *again)
+		}
+	}
+}
+
+func TestDbServerRoundTrip(t *testing.T) {
+	spoke := DbServer{
+		ObjectMeta: objectMeta(),
+		Spec: DbServerSpec{
+			Address: "postgres.databases", Port: 5432, UserName: "postgres", SecretName: "postgres-credentials", PasswordKey: "pw",
+			CaCertKey: "ca.crt", TlsCrtKey: "tls.crt", TlsKeyKey: "tls.key", Version: "15", ServerType: ServerTypePostgres,
+			Options: map[string]string{"sslmode": "verify-full"}, MaxOpenConnections: 5,
+			AllowedNamespaces:       &AllowedNamespaces{Names: []string{"shop"}, Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "payments"}}},
+			AllowedServerPrivileges: []string{"LOGIN"}, AllowedPrivilegeTypes: []PrivilegeType{PrivilegeTypeDatabase, PrivilegeTypeTable},
+			DryRun: true, ResyncInterval: &metav1.Duration{Duration: time.Hour}, DriftPolicy: "report",
+		},
+		Status: DbServerStatus{ConnectionAvailable: true, Databases: []string{"shop"}, Users: []string{"shop"}, Message: "connected"},
+	}
+	hub := &dboperatorv1alpha1.DbServer{}
+	if err := spoke.DeepCopy().ConvertTo(hub); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	back := &DbServer{}
+	if err := back.ConvertFrom(hub); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if !equality.Semantic.DeepEqual(spoke, *back) {
+		t.Errorf("expected v1beta1 -> v1alpha1 -> v1beta1 to keep %+v got %+v", spoke, *back)
+	}
+
+	// The spelling of server_type can't be expressed in v1beta1, it's restored from the annotation
+	hub.Spec.ServerType = "Postgres"
+	original := hub.DeepCopy()
+	if err := back.ConvertFrom(hub); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	roundTripped := &dboperatorv1alpha1.DbServer{}
+	if err := back.ConvertTo(roundTripped); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if !equality.Semantic.DeepEqual(*original, *roundTripped) {
+		t.Errorf("expected v1alpha1 -> v1beta1 -> v1alpha1 to keep %+v got %+v", *original, *roundTripped)
+	}
+}
+
+func TestSchemaRoundTrip(t *testing.T) {
+	hubs := []dboperatorv1alpha1.Schema{
+		{
+			ObjectMeta: objectMeta(),
+			Spec:       dboperatorv1alpha1.SchemaSpec{Server: "postgres", DbName: "shop", Name: "orders", DropOnDeletion: true, CascadeOnDrop: true, Creator: stringPtr("shop-owner"), Adopt: "require", DropAdopted: true},
+			Status:     dboperatorv1alpha1.SchemaStatus{Created: true, Conditions: conditions()},
+		},
+		{
+			ObjectMeta: objectMeta(),
+			Spec:       dboperatorv1alpha1.SchemaSpec{ServerRef: &dboperatorv1alpha1.DbServerRef{Name: "postgres", Namespace: "databases"}, DbName: "shop", Name: "orders"},
+		},
+	}
+	for _, hub := range hubs {
+		spoke := &Schema{}
+		if err := spoke.ConvertFrom(hub.DeepCopy()); err != nil {
+			t.Fatalf("unexpected error %s", err)
+		}
+		if _, found := spoke.Annotations[V1ALPHA1_SPEC_ANNOTATION]; found {
+			t.Errorf("expected %+v to convert to v1beta1 without saving the v1alpha1 spec", hub.Spec)
+		}
+		back := &dboperatorv1alpha1.Schema{}
+		if err := spoke.ConvertTo(back); err != nil {
+			t.Fatalf("unexpected error %s", err)
+		}
+		if !equality.Semantic.DeepEqual(hub, *back) {
+			t.Errorf("expected v1alpha1 -> v1beta1 -> v1alpha1 to keep %+v got %+v", hub, *back)
+		}
+
+		again := &Schema{}
+		if err := again.ConvertFrom(back); err != nil {
+			t.Fatalf("unexpected error %s", err)
+		}
+		if !equality.Semantic.DeepEqual(*spoke, *again) {
+			t.Errorf("expected v1beta1 -> v1alpha1 -> v1beta1 to keep %+v got %+v", *spoke, *again)
+		}
+	}
+}
+
+func TestUserRoundTrip(t *testing.T) {
+	spoke := User{
+		ObjectMeta: objectMeta(),
+		Spec: UserSpec{
+			UserName: "shop", Host: "%", SecretName: "shop-credentials", GenerateSecret: true, PasswordKey: "pw",
+			CaCertKey: "ca.crt", TlsCrtKey: "tls.crt", TlsKeyKey: "tls.key",
+			ServerRef: DbServerRef{Name: "mysql", Namespace: "databases"},
+			Privileges: []Privilege{
+				{Type: PrivilegeTypeDatabase, Scope: "shop", Privileges: []string{"SELECT", "INSERT"}},
+				{Type: PrivilegeTypeColumn, Scope: "shop.customers", Privileges: []string{"SELECT"}, Columns: []string{"id", "name"}},
+				{Type: PrivilegeTypeColumn, Scope: "shop.customers", Privileges: []string{"UPDATE"}, Columns: []string{"name"}},
+				{Type: PrivilegeTypeTable, Scope: "shop.orders", Privileges: []string{"SELECT"}, Grantor: "shop-owner"},
+			},
+			ServerPrivileges: []string{"PROCESS", "RELOAD"}, DropOnDeletion: true,
+			DropOptions:       &DropUserOptions{RevokePrivileges: true, ReassignOwnedTo: "shop-owner"},
+			TlsRequires:       &TlsRequires{X509: true, Subject: "/CN=shop"},
+			MaxQueriesPerHour: intPtr(1000), MaxUserConnections: intPtr(10),
+			DryRun: true, Adopt: "never", DropAdopted: true, ServiceBinding: true,
+			ConnectionSecret: &ConnectionSecret{SecretName: "shop-connection", Db: "shop", Templates: map[string]string{"url": "{{ .Uri }}"}},
+		},
+		Status: UserStatus{
+			Conditions: conditions(),
+			PrivsPlan:  &PrivsPlan{Hash: "abc", Statements: []PlannedStatement{{Database: "shop", Statement: "GRANT SELECT ON shop.* TO 'shop'@'%';"}}, PlannedAt: metav1.NewTime(time.Unix(1700000000, 0))},
+			Binding:    &BindingReference{Name: "shop-user-binding"},
+		},
+	}
+	hub := &dboperatorv1alpha1.User{}
+	if err := spoke.DeepCopy().ConvertTo(hub); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	back := &User{}
+	if err := back.ConvertFrom(hub); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if !equality.Semantic.DeepEqual(spoke, *back) {
+		t.Errorf("expected v1beta1 -> v1alpha1 -> v1beta1 to keep %+v got %+v", spoke, *back)
+	}
+
+	postgresHub := dboperatorv1alpha1.User{
+		ObjectMeta: objectMeta(),
+		Spec: dboperatorv1alpha1.UserSpec{
+			UserName: "shop", SecretName: "shop-credentials", DbServerName: "postgres",
+			DbPrivs: []dboperatorv1alpha1.DbPriv{
+				{Scope: "shop", Privs: "CONNECT", PrivType: "database"},
+				{Scope: "shop.customers", Privs: "SELECT(id,name),UPDATE(name)", PrivType: "column", Grantor: stringPtr("shop-owner")},
+			},
+			ServerPrivs: "LOGIN,CREATEDB", ConnectionLimit: intPtr(-1), ValidUntil: "infinity",
+			RoleSettings: map[string]string{"statement_timeout": "30s"},
+		},
+	}
+	postgresSpoke := &User{}
+	if err := postgresSpoke.ConvertFrom(postgresHub.DeepCopy()); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if _, found := postgresSpoke.Annotations[V1ALPHA1_SPEC_ANNOTATION]; found {
+		t.Errorf("expected %+v to convert to v1beta1 without saving the v1alpha1 spec", postgresHub.Spec)
+	}
+	postgresBack := &dboperatorv1alpha1.User{}
+	if err := postgresSpoke.ConvertTo(postgresBack); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if !equality.Semantic.DeepEqual(postgresHub, *postgresBack) {
+		t.Errorf("expected v1alpha1 -> v1beta1 -> v1alpha1 to keep %+v got %+v", postgresHub, *postgresBack)
+	}
+}
+
+func TestUserLegacyDbPrivs(t *testing.T) {
+	hub := dboperatorv1alpha1.User{
+		ObjectMeta: objectMeta(),
+		Spec: dboperatorv1alpha1.UserSpec{
+			UserName: "shop", SecretName: "shop-credentials", DbServerName: "postgres",
+			DbPrivs: []dboperatorv1alpha1.DbPriv{
+				{Scope: "shop", Privs: "CONNECT", PrivType: "database"},
+				{Scope: "shop", Privs: "public.orders:SELECT"},
+				{Scope: "shop.public", DefaultPrivs: "TABLES:SELECT"},
+			},
+			ServerPrivs: "LOGIN",
+		},
+	}
+	spoke := &User{}
+	if err := spoke.ConvertFrom(hub.DeepCopy()); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if len(spoke.Spec.Privileges) != 1 {
+		t.Errorf("expected the db_privs without a v1beta1 equivalent to be hidden got %+v", spoke.Spec.Privileges)
+	}
+	if _, found := spoke.Annotations[V1ALPHA1_SPEC_ANNOTATION]; !found {
+		t.Fatalf("expected the v1alpha1 spec to be saved")
+	}
+
+	unchanged := &dboperatorv1alpha1.User{}
+	if err := spoke.DeepCopy().ConvertTo(unchanged); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if !equality.Semantic.DeepEqual(hub, *unchanged) {
+		t.Errorf("expected an unchanged v1beta1 spec to restore the v1alpha1 spec got %+v", unchanged.Spec)
+	}
+
+	// An edit of another field through v1beta1 keeps the legacy db_privs
+	connectionLimit := 20
+	spoke.Spec.ConnectionLimit = &connectionLimit
+	spoke.Spec.Privileges[0].Privileges = append(spoke.Spec.Privileges[0].Privileges, "TEMP")
+	changed := &dboperatorv1alpha1.User{}
+	if err := spoke.ConvertTo(changed); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	expected := []dboperatorv1alpha1.DbPriv{
+		{Scope: "shop", Privs: "CONNECT,TEMP", PrivType: "database"},
+		{Scope: "shop", Privs: "public.orders:SELECT"},
+		{Scope: "shop.public", DefaultPrivs: "TABLES:SELECT"},
+	}
+	if !equality.Semantic.DeepEqual(expected, changed.Spec.DbPrivs) {
+		t.Errorf("expected the legacy db_privs to be kept after a v1beta1 change got %+v", changed.Spec.DbPrivs)
+	}
+	if changed.Spec.ConnectionLimit == nil || *changed.Spec.ConnectionLimit != 20 {
+		t.Errorf("expected the connection limit of the v1beta1 change got %v", changed.Spec.ConnectionLimit)
+	}
+	if _, found := changed.Annotations[V1ALPHA1_SPEC_ANNOTATION]; found {
+		t.Errorf("expected the saved v1alpha1 spec not to end up in the hub")
+	}
+
+	// Reading the changed User through v1beta1 saves the spec again
+	again := &User{}
+	if err := again.ConvertFrom(changed.DeepCopy()); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if _, found := again.Annotations[V1ALPHA1_SPEC_ANNOTATION]; !found {
+		t.Errorf("expected the v1alpha1 spec with the legacy db_privs to be saved again")
+	}
+}
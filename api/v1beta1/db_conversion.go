@@ -0,0 +1,109 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+func dbSpecFromHub(src dboperatorv1alpha1.DbSpec) DbSpec {
+	return DbSpec{
+		ServerRef:       serverRefFromHub(src.GetServerRef()),
+		DbName:          src.DbName,
+		DropOnDeletion:  src.DropOnDeletion,
+		Adopt:           src.Adopt,
+		DropAdopted:     src.DropAdopted,
+		Owner:           src.Owner,
+		CascadeOnDrop:   src.CascadeOnDrop,
+		AfterCreateSQL:  src.AfterCreateSQL,
+		Encoding:        src.Encoding,
+		LcCollate:       src.LcCollate,
+		LcCtype:         src.LcCtype,
+		Template:        src.Template,
+		ConnectionLimit: copyIntPtr(src.ConnectionLimit),
+		Tablespace:      src.Tablespace,
+		CharacterSet:    src.CharacterSet,
+		Collate:         src.Collate,
+		PrimaryRegion:   src.PrimaryRegion,
+		Regions:         append([]string(nil), src.Regions...),
+		Survive:         src.Survive,
+		ServiceBinding:  src.ServiceBinding,
+	}
+}
+
+func dbSpecToHub(src DbSpec) dboperatorv1alpha1.DbSpec {
+	server, serverRef := serverRefToHub(src.ServerRef)
+	return dboperatorv1alpha1.DbSpec{
+		Server:          server,
+		ServerRef:       serverRef,
+		DbName:          src.DbName,
+		DropOnDeletion:  src.DropOnDeletion,
+		Adopt:           src.Adopt,
+		DropAdopted:     src.DropAdopted,
+		Owner:           src.Owner,
+		CascadeOnDrop:   src.CascadeOnDrop,
+		AfterCreateSQL:  src.AfterCreateSQL,
+		Encoding:        src.Encoding,
+		LcCollate:       src.LcCollate,
+		LcCtype:         src.LcCtype,
+		Template:        src.Template,
+		ConnectionLimit: copyIntPtr(src.ConnectionLimit),
+		Tablespace:      src.Tablespace,
+		CharacterSet:    src.CharacterSet,
+		Collate:         src.Collate,
+		PrimaryRegion:   src.PrimaryRegion,
+		Regions:         append([]string(nil), src.Regions...),
+		Survive:         src.Survive,
+		ServiceBinding:  src.ServiceBinding,
+	}
+}
+
+// ConvertTo converts this Db to the Hub version (v1alpha1)
+func (src *Db) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*dboperatorv1alpha1.Db)
+	var saved string
+	dst.ObjectMeta, saved = copyObjectMeta(src.ObjectMeta)
+	dst.Spec = dbSpecToHub(src.Spec)
+	dst.Status.Conditions = copyConditions(src.Status.Conditions)
+	dst.Status.Binding = bindingToHub(src.Status.Binding)
+
+	savedSpec := dboperatorv1alpha1.DbSpec{}
+	found, err := restoreHubSpec(saved, &savedSpec)
+	if err != nil {
+		return err
+	}
+	if found && equality.Semantic.DeepEqual(dbSpecFromHub(savedSpec), src.Spec) {
+		dst.Spec = savedSpec
+	}
+	return nil
+}
+
+// ConvertFrom converts from the Hub version (v1alpha1) to this version
+func (dst *Db) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*dboperatorv1alpha1.Db)
+	dst.ObjectMeta, _ = copyObjectMeta(src.ObjectMeta)
+	dst.Spec = dbSpecFromHub(src.Spec)
+	dst.Status.Conditions = copyConditions(src.Status.Conditions)
+	dst.Status.Binding = bindingFromHub(src.Status.Binding)
+
+	if !equality.Semantic.DeepEqual(dbSpecToHub(dst.Spec), src.Spec) {
+		return saveHubSpec(&dst.ObjectMeta, src.Spec)
+	}
+	return nil
+}
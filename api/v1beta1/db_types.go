@@ -0,0 +1,104 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type DbSpec struct {
+	ServerRef DbServerRef `json:"serverRef"`
+	// +kubebuilder:validation:MinLength=1
+	DbName         string `json:"dbName"`
+	DropOnDeletion bool   `json:"dropOnDeletion,omitempty"`
+	CascadeOnDrop  bool   `json:"cascadeOnDrop,omitempty"`
+	AfterCreateSQL string `json:"afterCreateSQL,omitempty"`
+	// What to do when the database already exists on the server and wasn't created by this resource, defaults to ifExists.
+	// An adopted database is only dropped with dropAdopted
+	// +kubebuilder:validation:Enum=never;ifExists;require
+	Adopt string `json:"adopt,omitempty"`
+	// With dropOnDeletion also drop the database when it was adopted instead of created by this resource
+	DropAdopted bool `json:"dropAdopted,omitempty"`
+	// Name of a User in the namespace of the Db that owns the database, on MySQL the User gets ALL on the database
+	// When not set the owner is not managed
+	Owner string `json:"owner,omitempty"`
+
+	// Postgres and CockroachDB, can't be changed after creation
+	Encoding string `json:"encoding,omitempty"`
+	// Postgres only, can't be changed after creation
+	LcCollate string `json:"lcCollate,omitempty"`
+	// Postgres only, can't be changed after creation
+	LcCtype string `json:"lcCtype,omitempty"`
+	// Postgres only, only used when creating the database
+	Template string `json:"template,omitempty"`
+	// Postgres only, -1 means no limit
+	// +kubebuilder:validation:Minimum=-1
+	ConnectionLimit *int `json:"connectionLimit,omitempty"`
+	// Postgres only
+	Tablespace string `json:"tablespace,omitempty"`
+
+	// MySQL only
+	CharacterSet string `json:"characterSet,omitempty"`
+	// MySQL only
+	Collate string `json:"collate,omitempty"`
+
+	// CockroachDB only, required when regions is set
+	PrimaryRegion string `json:"primaryRegion,omitempty"`
+	// CockroachDB only, the regions besides the primary region
+	Regions []string `json:"regions,omitempty"`
+	// CockroachDB only, the failure the database should survive
+	// +kubebuilder:validation:Enum=zone;region
+	Survive string `json:"survive,omitempty"`
+	// Generate a Service Binding Secret with the credentials of the owner, see https://servicebinding.io
+	ServiceBinding bool `json:"serviceBinding,omitempty"`
+}
+
+// The Secret a Service Binding workload projection reads, status.binding of a Provisioned Service
+type BindingReference struct {
+	Name string `json:"name"`
+}
+
+type DbStatus struct {
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	Binding    *BindingReference  `json:"binding,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:unservedversion
+
+// Db is the Schema for the dbs API
+type Db struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DbSpec   `json:"spec,omitempty"`
+	Status DbStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// DbList contains a list of Db
+type DbList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Db `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Db{}, &DbList{})
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"strings"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+func dbServerSpecFromHub(src dboperatorv1alpha1.DbServerSpec) DbServerSpec {
+	dst := DbServerSpec{
+		Address:                 src.Address,
+		Port:                    src.Port,
+		UserName:                src.UserName,
+		SecretName:              src.SecretName,
+		PasswordKey:             src.PasswordKey,
+		CaCertKey:               src.CaCertKey,
+		TlsCrtKey:               src.TlsCrtKey,
+		TlsKeyKey:               src.TlsKeyKey,
+		Version:                 src.Version,
+		ServerType:              ServerType(strings.ToLower(src.ServerType)),
+		MaxOpenConnections:      src.MaxOpenConnections,
+		AllowedServerPrivileges: append([]string(nil), src.AllowedServerPrivs...),
+		DryRun:                  src.DryRun,
+		ResyncInterval:          copyDuration(src.ResyncInterval),
+		DriftPolicy:             src.DriftPolicy,
+	}
+	if src.Options != nil {
+		dst.Options = map[string]string{}
+		for key, value := range src.Options {
+			dst.Options[key] = value
+		}
+	}
+	if src.AllowedNamespaces != nil {
+		dst.AllowedNamespaces = &AllowedNamespaces{
+			Names:    append([]string(nil), src.AllowedNamespaces.Names...),
+			Selector: src.AllowedNamespaces.Selector.DeepCopy(),
+		}
+	}
+	for _, privType := range src.AllowedPrivTypes {
+		dst.AllowedPrivilegeTypes = append(dst.AllowedPrivilegeTypes, PrivilegeType(privType))
+	}
+	return dst
+}
+
+func dbServerSpecToHub(src DbServerSpec) dboperatorv1alpha1.DbServerSpec {
+	dst := dboperatorv1alpha1.DbServerSpec{
+		Address:            src.Address,
+		Port:               src.Port,
+		UserName:           src.UserName,
+		SecretName:         src.SecretName,
+		PasswordKey:        src.PasswordKey,
+		CaCertKey:          src.CaCertKey,
+		TlsCrtKey:          src.TlsCrtKey,
+		TlsKeyKey:          src.TlsKeyKey,
+		Version:            src.Version,
+		ServerType:         string(src.ServerType),
+		MaxOpenConnections: src.MaxOpenConnections,
+		AllowedServerPrivs: append([]string(nil), src.AllowedServerPrivileges...),
+		DryRun:             src.DryRun,
+		ResyncInterval:     copyDuration(src.ResyncInterval),
+		DriftPolicy:        src.DriftPolicy,
+	}
+	if src.Options != nil {
+		dst.Options = map[string]string{}
+		for key, value := range src.Options {
+			dst.Options[key] = value
+		}
+	}
+	if src.AllowedNamespaces != nil {
+		dst.AllowedNamespaces = &dboperatorv1alpha1.AllowedNamespaces{
+			Names:    append([]string(nil), src.AllowedNamespaces.Names...),
+			Selector: src.AllowedNamespaces.Selector.DeepCopy(),
+		}
+	}
+	for _, privType := range src.AllowedPrivilegeTypes {
+		dst.AllowedPrivTypes = append(dst.AllowedPrivTypes, string(privType))
+	}
+	return dst
+}
+
+func dbServerStatusFromHub(src dboperatorv1alpha1.DbServerStatus) DbServerStatus {
+	return DbServerStatus{
+		ConnectionAvailable: src.ConnectionAvailable,
+		Databases:           append([]string(nil), src.Databases...),
+		Users:               append([]string(nil), src.Users...),
+		Message:             src.Message,
+	}
+}
+
+func dbServerStatusToHub(src DbServerStatus) dboperatorv1alpha1.DbServerStatus {
+	return dboperatorv1alpha1.DbServerStatus{
+		ConnectionAvailable: src.ConnectionAvailable,
+		Databases:           append([]string(nil), src.Databases...),
+		Users:               append([]string(nil), src.Users...),
+		Message:             src.Message,
+	}
+}
+
+// ConvertTo converts this DbServer to the Hub version (v1alpha1)
+func (src *DbServer) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*dboperatorv1alpha1.DbServer)
+	var saved string
+	dst.ObjectMeta, saved = copyObjectMeta(src.ObjectMeta)
+	dst.Spec = dbServerSpecToHub(src.Spec)
+	dst.Status = dbServerStatusToHub(src.Status)
+
+	savedSpec := dboperatorv1alpha1.DbServerSpec{}
+	found, err := restoreHubSpec(saved, &savedSpec)
+	if err != nil {
+		return err
+	}
+	if found && equality.Semantic.DeepEqual(dbServerSpecFromHub(savedSpec), src.Spec) {
+		dst.Spec = savedSpec
+	}
+	return nil
+}
+
+// ConvertFrom converts from the Hub version (v1alpha1) to this version
+func (dst *DbServer) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*dboperatorv1alpha1.DbServer)
+	dst.ObjectMeta, _ = copyObjectMeta(src.ObjectMeta)
+	dst.Spec = dbServerSpecFromHub(src.Spec)
+	dst.Status = dbServerStatusFromHub(src.Status)
+
+	if !equality.Semantic.DeepEqual(dbServerSpecToHub(dst.Spec), src.Spec) {
+		return saveHubSpec(&dst.ObjectMeta, src.Spec)
+	}
+	return nil
+}
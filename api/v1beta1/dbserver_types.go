@@ -0,0 +1,126 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:validation:Enum=postgres;cockroachdb;mysql
+type ServerType string
+
+const (
+	ServerTypePostgres    ServerType = "postgres"
+	ServerTypeCockroachDB ServerType = "cockroachdb"
+	ServerTypeMySQL       ServerType = "mysql"
+)
+
+type DbServerSpec struct {
+	// Server address
+	// +kubebuilder:validation:MinLength=1
+	Address string `json:"address"`
+	// Server port
+	// +kubebuilder:validation:Minimum:=1
+	Port int `json:"port"`
+	// +kubebuilder:validation:MinLength=1
+	UserName string `json:"userName"`
+	// +kubebuilder:validation:MinLength=1
+	SecretName string `json:"secretName"`
+	// Defaults to password
+	PasswordKey string            `json:"passwordKey,omitempty"`
+	CaCertKey   string            `json:"caCertKey,omitempty"`
+	TlsCrtKey   string            `json:"tlsCertKey,omitempty"`
+	TlsKeyKey   string            `json:"tlsKeyKey,omitempty"`
+	Version     string            `json:"version,omitempty"`
+	ServerType  ServerType        `json:"serverType"`
+	Options     map[string]string `json:"options,omitempty"`
+	// Maximum number of open connections per user and database, 0 means unlimited
+	// +kubebuilder:validation:Minimum:=0
+	MaxOpenConnections int `json:"maxOpenConnections,omitempty"`
+	// Namespaces other than the one of the DbServer that may use it, when not set every namespace can use it
+	AllowedNamespaces *AllowedNamespaces `json:"allowedNamespaces,omitempty"`
+	// Server privileges Users may request, when not set every server privilege is allowed
+	AllowedServerPrivileges []string `json:"allowedServerPrivileges,omitempty"`
+	// Privilege types Users may request, when not set every privilege type is allowed
+	AllowedPrivilegeTypes []PrivilegeType `json:"allowedPrivilegeTypes,omitempty"`
+	// Puts every User of this server in dry run mode, see dryRun on User
+	DryRun bool `json:"dryRun,omitempty"`
+	// How often the privileges of the Users of this server are compared with the server, when not set only changes to a User are reconciled
+	ResyncInterval *metav1.Duration `json:"resyncInterval,omitempty"`
+	// What to do with privileges that were changed on the server, defaults to enforce
+	// Grants on scopes that are not in dbPrivs count as extra privileges, on CockroachDB only grants on tables are found
+	// +kubebuilder:validation:Enum=enforce;report;ignore
+	DriftPolicy string `json:"driftPolicy,omitempty"`
+}
+
+// A namespace is allowed when it's listed in Names or when its labels match the Selector
+type AllowedNamespaces struct {
+	Names    []string              `json:"names,omitempty"`
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=DbServer;ClusterDbServer
+type DbServerKind string
+
+const (
+	DbServerKindDbServer        DbServerKind = "DbServer"
+	DbServerKindClusterDbServer DbServerKind = "ClusterDbServer"
+)
+
+// Reference to a DbServer, used by every resource that needs one
+// Without a namespace the DbServer is looked up in the namespace of the referencing object first and then in the other namespaces
+// Without a kind a ClusterDbServer with the name is used when no DbServer is found
+type DbServerRef struct {
+	// +kubebuilder:validation:MinLength=1
+	Name      string       `json:"name"`
+	Namespace string       `json:"namespace,omitempty"`
+	Kind      DbServerKind `json:"kind,omitempty"`
+}
+
+// DbServerStatus defines the observed state of DbServer
+type DbServerStatus struct {
+	ConnectionAvailable bool     `json:"connectionAvailable"`
+	Databases           []string `json:"databases,omitempty"`
+	Users               []string `json:"users,omitempty"`
+	Message             string   `json:"message,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:unservedversion
+
+// DbServer is the Schema for the dbservers API
+type DbServer struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DbServerSpec   `json:"spec,omitempty"`
+	Status DbServerStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// DbServerList contains a list of DbServer
+type DbServerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DbServer `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DbServer{}, &DbServerList{})
+}
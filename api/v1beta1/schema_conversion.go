@@ -0,0 +1,92 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+func schemaSpecFromHub(src dboperatorv1alpha1.SchemaSpec) SchemaSpec {
+	dst := SchemaSpec{
+		ServerRef:      serverRefFromHub(src.GetServerRef()),
+		DbName:         src.DbName,
+		Name:           src.Name,
+		DropOnDeletion: src.DropOnDeletion,
+		Adopt:          src.Adopt,
+		DropAdopted:    src.DropAdopted,
+		CascadeOnDrop:  src.CascadeOnDrop,
+	}
+	if src.Creator != nil {
+		dst.Creator = *src.Creator
+	}
+	return dst
+}
+
+func schemaSpecToHub(src SchemaSpec) dboperatorv1alpha1.SchemaSpec {
+	server, serverRef := serverRefToHub(src.ServerRef)
+	dst := dboperatorv1alpha1.SchemaSpec{
+		Server:         server,
+		ServerRef:      serverRef,
+		DbName:         src.DbName,
+		Name:           src.Name,
+		DropOnDeletion: src.DropOnDeletion,
+		Adopt:          src.Adopt,
+		DropAdopted:    src.DropAdopted,
+		CascadeOnDrop:  src.CascadeOnDrop,
+	}
+	if src.Creator != "" {
+		creator := src.Creator
+		dst.Creator = &creator
+	}
+	return dst
+}
+
+// ConvertTo converts this Schema to the Hub version (v1alpha1)
+func (src *Schema) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*dboperatorv1alpha1.Schema)
+	var saved string
+	dst.ObjectMeta, saved = copyObjectMeta(src.ObjectMeta)
+	dst.Spec = schemaSpecToHub(src.Spec)
+	dst.Status.Created = src.Status.Created
+	dst.Status.Conditions = copyConditions(src.Status.Conditions)
+
+	savedSpec := dboperatorv1alpha1.SchemaSpec{}
+	found, err := restoreHubSpec(saved, &savedSpec)
+	if err != nil {
+		return err
+	}
+	if found && equality.Semantic.DeepEqual(schemaSpecFromHub(savedSpec), src.Spec) {
+		dst.Spec = savedSpec
+	}
+	return nil
+}
+
+// ConvertFrom converts from the Hub version (v1alpha1) to this version
+func (dst *Schema) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*dboperatorv1alpha1.Schema)
+	dst.ObjectMeta, _ = copyObjectMeta(src.ObjectMeta)
+	dst.Spec = schemaSpecFromHub(src.Spec)
+	dst.Status.Created = src.Status.Created
+	dst.Status.Conditions = copyConditions(src.Status.Conditions)
+
+	if !equality.Semantic.DeepEqual(schemaSpecToHub(dst.Spec), src.Spec) {
+		return saveHubSpec(&dst.ObjectMeta, src.Spec)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type SchemaSpec struct {
+	ServerRef DbServerRef `json:"serverRef"`
+	// +kubebuilder:validation:MinLength=1
+	DbName string `json:"dbName"`
+	// +kubebuilder:validation:MinLength=1
+	Name           string `json:"name"`
+	DropOnDeletion bool   `json:"dropOnDeletion,omitempty"`
+	CascadeOnDrop  bool   `json:"cascadeOnDrop,omitempty"`
+	// User that creates the schema, defaults to the user of the DbServer
+	Creator string `json:"creator,omitempty"`
+	// What to do when the schema already exists on the server and wasn't created by this resource, defaults to ifExists.
+	// An adopted schema is only dropped with dropAdopted
+	// +kubebuilder:validation:Enum=never;ifExists;require
+	Adopt string `json:"adopt,omitempty"`
+	// With dropOnDeletion also drop the schema when it was adopted instead of created by this resource
+	DropAdopted bool `json:"dropAdopted,omitempty"`
+}
+
+type SchemaStatus struct {
+	Created    bool               `json:"created,omitempty"`
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:unservedversion
+
+// Schema is the Schema for the schemas API
+type Schema struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SchemaSpec   `json:"spec,omitempty"`
+	Status SchemaStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// SchemaList contains a list of Schema
+type SchemaList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Schema `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Schema{}, &SchemaList{})
+}
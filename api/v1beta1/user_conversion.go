@@ -0,0 +1,370 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"fmt"
+	"strings"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+var PRIVILEGE_TYPES = []PrivilegeType{
+	PrivilegeTypeGlobal,
+	PrivilegeTypeDatabase,
+	PrivilegeTypeSchema,
+	PrivilegeTypeTable,
+	PrivilegeTypeColumn,
+	PrivilegeTypeRoutine,
+	PrivilegeTypeDefaultTable,
+}
+
+// Splits a v1alpha1 privs string on the commas that are not between parentheses, so
+// "SELECT(col1,col2), UPDATE" becomes "SELECT(col1,col2)" and "UPDATE"
+func splitPrivs(privs string) []string {
+	pieces := []string{}
+	depth := 0
+	current := ""
+	for _, char := range privs {
+		switch {
+		case char == '(':
+			depth++
+		case char == ')':
+			depth--
+		case char == ',' && depth == 0:
+			pieces = append(pieces, current)
+			current = ""
+			continue
+		}
+		current += string(char)
+	}
+	pieces = append(pieces, current)
+
+	trimmed := []string{}
+	for _, piece := range pieces {
+		piece = strings.TrimSpace(piece)
+		if piece != "" {
+			trimmed = append(trimmed, piece)
+		}
+	}
+	return trimmed
+}
+
+// Turns the v1alpha1 column privs "SELECT(id,name),UPDATE(name)" into one Privilege per set of columns
+func columnPrivilegesFromHub(dbPriv dboperatorv1alpha1.DbPriv, grantor string) ([]Privilege, error) {
+	privileges := []Privilege{}
+	indexByColumns := map[string]int{}
+	for _, piece := range splitPrivs(dbPriv.Privs) {
+		name, columnList, found := strings.Cut(piece, "(")
+		if !found || !strings.HasSuffix(columnList, ")") {
+			return nil, fmt.Errorf("expected PRIV(column, ...) in column privileges, got '%s'", piece)
+		}
+		columns := []string{}
+		for _, column := range strings.Split(strings.TrimSuffix(columnList, ")"), ",") {
+			column = strings.TrimSpace(column)
+			if column == "" {
+				return nil, fmt.Errorf("empty column in column privileges '%s'", piece)
+			}
+			columns = append(columns, column)
+		}
+
+		key := strings.Join(columns, ",")
+		index, exists := indexByColumns[key]
+		if !exists {
+			index = len(privileges)
+			indexByColumns[key] = index
+			privileges = append(privileges, Privilege{
+				Type:    PrivilegeTypeColumn,
+				Scope:   dbPriv.Scope,
+				Columns: columns,
+				Grantor: grantor,
+			})
+		}
+		privileges[index].Privileges = append(privileges[index].Privileges, strings.TrimSpace(name))
+	}
+	return privileges, nil
+}
+
+// Returns false for db_privs that have no v1beta1 equivalent, those without a known priv_type and those using default_privs
+func privilegesFromHub(dbPriv dboperatorv1alpha1.DbPriv) ([]Privilege, bool) {
+	privType := PrivilegeType(dbPriv.PrivType)
+	known := false
+	for _, candidate := range PRIVILEGE_TYPES {
+		if privType == candidate {
+			known = true
+			break
+		}
+	}
+	if !known || dbPriv.DefaultPrivs != "" {
+		return nil, false
+	}
+
+	grantor := ""
+	if dbPriv.Grantor != nil {
+		grantor = *dbPriv.Grantor
+	}
+	if privType == PrivilegeTypeColumn {
+		privileges, err := columnPrivilegesFromHub(dbPriv, grantor)
+		if err != nil || len(privileges) == 0 {
+			return nil, false
+		}
+		return privileges, true
+	}
+
+	privs := splitPrivs(dbPriv.Privs)
+	if len(privs) == 0 {
+		return nil, false
+	}
+	return []Privilege{{
+		Type:       privType,
+		Scope:      dbPriv.Scope,
+		Privileges: privs,
+		Grantor:    grantor,
+	}}, true
+}
+
+// The db_privs that are hidden in v1beta1
+func hiddenDbPrivs(dbPrivs []dboperatorv1alpha1.DbPriv) []dboperatorv1alpha1.DbPriv {
+	var hidden []dboperatorv1alpha1.DbPriv
+	for _, dbPriv := range dbPrivs {
+		if _, ok := privilegesFromHub(dbPriv); !ok {
+			hidden = append(hidden, dbPriv)
+		}
+	}
+	return hidden
+}
+
+// Column privileges on the same table end up in a single db_priv, otherwise they would revoke each other
+func privilegesToHub(privileges []Privilege) []dboperatorv1alpha1.DbPriv {
+	var dbPrivs []dboperatorv1alpha1.DbPriv
+	columnPrivIndex := map[string]int{}
+	for _, privilege := range privileges {
+		var grantor *string
+		if privilege.Grantor != "" {
+			grantorName := privilege.Grantor
+			grantor = &grantorName
+		}
+
+		if privilege.Type != PrivilegeTypeColumn {
+			dbPrivs = append(dbPrivs, dboperatorv1alpha1.DbPriv{
+				Scope:    privilege.Scope,
+				Privs:    strings.Join(privilege.Privileges, ","),
+				PrivType: string(privilege.Type),
+				Grantor:  grantor,
+			})
+			continue
+		}
+
+		columnPrivs := []string{}
+		for _, priv := range privilege.Privileges {
+			columnPrivs = append(columnPrivs, fmt.Sprintf("%s(%s)", priv, strings.Join(privilege.Columns, ",")))
+		}
+		key := privilege.Scope + "/" + privilege.Grantor
+		index, exists := columnPrivIndex[key]
+		if exists {
+			dbPrivs[index].Privs += "," + strings.Join(columnPrivs, ",")
+			continue
+		}
+		columnPrivIndex[key] = len(dbPrivs)
+		dbPrivs = append(dbPrivs, dboperatorv1alpha1.DbPriv{
+			Scope:    privilege.Scope,
+			Privs:    strings.Join(columnPrivs, ","),
+			PrivType: string(privilege.Type),
+			Grantor:  grantor,
+		})
+	}
+	return dbPrivs
+}
+
+func userSpecFromHub(src dboperatorv1alpha1.UserSpec) UserSpec {
+	dst := UserSpec{
+		UserName:           src.UserName,
+		Host:               src.Host,
+		SecretName:         src.SecretName,
+		GenerateSecret:     src.GenerateSecret,
+		PasswordKey:        src.PasswordKey,
+		CaCertKey:          src.CaCertKey,
+		TlsCrtKey:          src.TlsCrtKey,
+		TlsKeyKey:          src.TlsKeyKey,
+		ServerRef:          serverRefFromHub(src.GetDbServerRef()),
+		DropOnDeletion:     src.DropOnDeletion,
+		Adopt:              src.Adopt,
+		DropAdopted:        src.DropAdopted,
+		MaxQueriesPerHour:  copyIntPtr(src.MaxQueriesPerHour),
+		MaxUserConnections: copyIntPtr(src.MaxUserConnections),
+		ConnectionLimit:    copyIntPtr(src.ConnectionLimit),
+		ValidUntil:         src.ValidUntil,
+		DryRun:             src.DryRun,
+		ServiceBinding:     src.ServiceBinding,
+	}
+	for _, dbPriv := range src.DbPrivs {
+		privileges, ok := privilegesFromHub(dbPriv)
+		if ok {
+			dst.Privileges = append(dst.Privileges, privileges...)
+		}
+	}
+	for _, serverPriv := range strings.Split(src.ServerPrivs, ",") {
+		serverPriv = strings.TrimSpace(serverPriv)
+		if serverPriv != "" {
+			dst.ServerPrivileges = append(dst.ServerPrivileges, serverPriv)
+		}
+	}
+	if src.DropUserOptions != nil {
+		dst.DropOptions = &DropUserOptions{
+			RevokePrivileges: src.DropUserOptions.RevokePrivileges,
+			DropOwned:        src.DropUserOptions.DropOwned,
+			ReassignOwnedTo:  src.DropUserOptions.ReassingOwnedTo,
+		}
+	}
+	if src.TlsRequires != nil {
+		tlsRequires := TlsRequires(*src.TlsRequires)
+		dst.TlsRequires = &tlsRequires
+	}
+	if src.RoleSettings != nil {
+		dst.RoleSettings = map[string]string{}
+		for key, value := range src.RoleSettings {
+			dst.RoleSettings[key] = value
+		}
+	}
+	if src.ConnectionSecret != nil {
+		dst.ConnectionSecret = &ConnectionSecret{
+			SecretName: src.ConnectionSecret.SecretName,
+			Db:         src.ConnectionSecret.Db,
+			Templates:  copyStringMap(src.ConnectionSecret.Templates),
+		}
+	}
+	return dst
+}
+
+func userSpecToHub(src UserSpec) dboperatorv1alpha1.UserSpec {
+	serverName, serverRef := serverRefToHub(src.ServerRef)
+	dst := dboperatorv1alpha1.UserSpec{
+		UserName:           src.UserName,
+		Host:               src.Host,
+		SecretName:         src.SecretName,
+		GenerateSecret:     src.GenerateSecret,
+		PasswordKey:        src.PasswordKey,
+		CaCertKey:          src.CaCertKey,
+		TlsCrtKey:          src.TlsCrtKey,
+		TlsKeyKey:          src.TlsKeyKey,
+		DbServerName:       serverName,
+		DbServerRef:        serverRef,
+		DbPrivs:            privilegesToHub(src.Privileges),
+		ServerPrivs:        strings.Join(src.ServerPrivileges, ","),
+		DropOnDeletion:     src.DropOnDeletion,
+		Adopt:              src.Adopt,
+		DropAdopted:        src.DropAdopted,
+		MaxQueriesPerHour:  copyIntPtr(src.MaxQueriesPerHour),
+		MaxUserConnections: copyIntPtr(src.MaxUserConnections),
+		ConnectionLimit:    copyIntPtr(src.ConnectionLimit),
+		ValidUntil:         src.ValidUntil,
+		DryRun:             src.DryRun,
+		ServiceBinding:     src.ServiceBinding,
+	}
+	if src.DropOptions != nil {
+		dst.DropUserOptions = &dboperatorv1alpha1.DropUserOptions{
+			RevokePrivileges: src.DropOptions.RevokePrivileges,
+			DropOwned:        src.DropOptions.DropOwned,
+			ReassingOwnedTo:  src.DropOptions.ReassignOwnedTo,
+		}
+	}
+	if src.TlsRequires != nil {
+		tlsRequires := dboperatorv1alpha1.TlsRequires(*src.TlsRequires)
+		dst.TlsRequires = &tlsRequires
+	}
+	if src.RoleSettings != nil {
+		dst.RoleSettings = map[string]string{}
+		for key, value := range src.RoleSettings {
+			dst.RoleSettings[key] = value
+		}
+	}
+	if src.ConnectionSecret != nil {
+		dst.ConnectionSecret = &dboperatorv1alpha1.ConnectionSecret{
+			SecretName: src.ConnectionSecret.SecretName,
+			Db:         src.ConnectionSecret.Db,
+			Templates:  copyStringMap(src.ConnectionSecret.Templates),
+		}
+	}
+	return dst
+}
+
+func privsPlanFromHub(src *dboperatorv1alpha1.PrivsPlan) *PrivsPlan {
+	if src == nil {
+		return nil
+	}
+	dst := &PrivsPlan{Hash: src.Hash, PlannedAt: src.PlannedAt}
+	for _, statement := range src.Statements {
+		dst.Statements = append(dst.Statements, PlannedStatement(statement))
+	}
+	return dst
+}
+
+func privsPlanToHub(src *PrivsPlan) *dboperatorv1alpha1.PrivsPlan {
+	if src == nil {
+		return nil
+	}
+	dst := &dboperatorv1alpha1.PrivsPlan{Hash: src.Hash, PlannedAt: src.PlannedAt}
+	for _, statement := range src.Statements {
+		dst.Statements = append(dst.Statements, dboperatorv1alpha1.PlannedStatement(statement))
+	}
+	return dst
+}
+
+// ConvertTo converts this User to the Hub version (v1alpha1)
+// An unchanged spec restores the saved v1alpha1 spec. When the spec was changed through v1beta1, the saved
+// db_privs that can't be expressed in v1beta1 are added to it, so editing a User doesn't revoke those privileges
+func (src *User) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*dboperatorv1alpha1.User)
+	var saved string
+	dst.ObjectMeta, saved = copyObjectMeta(src.ObjectMeta)
+	dst.Spec = userSpecToHub(src.Spec)
+	dst.Status.Conditions = copyConditions(src.Status.Conditions)
+	dst.Status.PrivsPlan = privsPlanToHub(src.Status.PrivsPlan)
+	dst.Status.Binding = bindingToHub(src.Status.Binding)
+
+	savedSpec := dboperatorv1alpha1.UserSpec{}
+	found, err := restoreHubSpec(saved, &savedSpec)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return nil
+	}
+	if equality.Semantic.DeepEqual(userSpecFromHub(savedSpec), src.Spec) {
+		dst.Spec = savedSpec
+	} else {
+		dst.Spec.DbPrivs = append(dst.Spec.DbPrivs, hiddenDbPrivs(savedSpec.DbPrivs)...)
+	}
+	return nil
+}
+
+// ConvertFrom converts from the Hub version (v1alpha1) to this version
+func (dst *User) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*dboperatorv1alpha1.User)
+	dst.ObjectMeta, _ = copyObjectMeta(src.ObjectMeta)
+	dst.Spec = userSpecFromHub(src.Spec)
+	dst.Status.Conditions = copyConditions(src.Status.Conditions)
+	dst.Status.PrivsPlan = privsPlanFromHub(src.Status.PrivsPlan)
+	dst.Status.Binding = bindingFromHub(src.Status.Binding)
+
+	if !equality.Semantic.DeepEqual(userSpecToHub(dst.Spec), src.Spec) {
+		return saveHubSpec(&dst.ObjectMeta, src.Spec)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// global and routine are MySQL only, schema, column and defaultTable are Postgres only
+// +kubebuilder:validation:Enum=global;database;schema;table;column;routine;defaultTable
+type PrivilegeType string
+
+const (
+	PrivilegeTypeGlobal       PrivilegeType = "global"
+	PrivilegeTypeDatabase     PrivilegeType = "database"
+	PrivilegeTypeSchema       PrivilegeType = "schema"
+	PrivilegeTypeTable        PrivilegeType = "table"
+	PrivilegeTypeColumn       PrivilegeType = "column"
+	PrivilegeTypeRoutine      PrivilegeType = "routine"
+	PrivilegeTypeDefaultTable PrivilegeType = "defaultTable"
+)
+
+// Privileges of a User on a single object, the scope has the same format as for the priv_types of v1alpha1
+type Privilege struct {
+	Type  PrivilegeType `json:"type"`
+	Scope string        `json:"scope,omitempty"`
+	// +kubebuilder:validation:MinItems=1
+	Privileges []string `json:"privileges"`
+	// Column privileges only, every privilege is granted on every column
+	Columns []string `json:"columns,omitempty"`
+	// User that grants the privileges, for schema and default privileges
+	Grantor string `json:"grantor,omitempty"`
+}
+
+// +kubebuilder:validation:XValidation:rule="!(has(self.dropOwned) && self.dropOwned && has(self.reassignOwnedTo))",message="dropOwned and reassignOwnedTo are mutually exclusive"
+type DropUserOptions struct {
+	RevokePrivileges bool   `json:"revokePrivileges,omitempty"`
+	DropOwned        bool   `json:"dropOwned,omitempty"`
+	ReassignOwnedTo  string `json:"reassignOwnedTo,omitempty"`
+}
+
+// MySQL only, the transport requirements for the account (REQUIRE clause)
+// SSL and X509 are mutually exclusive with each other and with Subject, Issuer and Cipher
+// An empty TlsRequires results in REQUIRE NONE
+type TlsRequires struct {
+	SSL     bool   `json:"ssl,omitempty"`
+	X509    bool   `json:"x509,omitempty"`
+	Subject string `json:"subject,omitempty"`
+	Issuer  string `json:"issuer,omitempty"`
+	Cipher  string `json:"cipher,omitempty"`
+}
+
+// A Secret with what an application needs to connect as the user, kept up to date by the operator
+type ConnectionSecret struct {
+	// Name of the Secret, it's owned by the User and can't be the secret with the password of the user
+	// +kubebuilder:validation:MinLength=1
+	SecretName string `json:"secretName"`
+	// Name of the Db in the namespace of the User to connect to, defaults to the database of the DbServer
+	Db string `json:"db,omitempty"`
+	// Keys of the Secret with Go templates for their values, the templates can use
+	// .Uri, .JdbcUrl, .Libpq, .Host, .Port, .Database, .UserName, .Password and .SslMode
+	// Defaults to the keys uri, jdbc_url, libpq (Postgres only), host, port, database, username and password
+	Templates map[string]string `json:"templates,omitempty"`
+}
+
+// UserSpec defines the desired state of User
+type UserSpec struct {
+	// +kubebuilder:validation:MinLength=1
+	UserName string `json:"userName"`
+	// MySQL only, defaults to %
+	Host string `json:"host,omitempty"`
+	// +kubebuilder:validation:MinLength=1
+	SecretName     string `json:"secretName"`
+	GenerateSecret bool   `json:"generateSecret,omitempty"`
+	// Defaults to password
+	PasswordKey      string           `json:"passwordKey,omitempty"`
+	CaCertKey        string           `json:"caCertKey,omitempty"`
+	TlsCrtKey        string           `json:"tlsCertKey,omitempty"`
+	TlsKeyKey        string           `json:"tlsKeyKey,omitempty"`
+	ServerRef        DbServerRef      `json:"serverRef"`
+	Privileges       []Privilege      `json:"privileges,omitempty"`
+	ServerPrivileges []string         `json:"serverPrivileges,omitempty"`
+	DropOnDeletion   bool             `json:"dropOnDeletion,omitempty"`
+	DropOptions      *DropUserOptions `json:"dropOptions,omitempty"`
+	// MySQL only, when not set the TLS requirements of the account are not managed
+	TlsRequires *TlsRequires `json:"tlsRequires,omitempty"`
+	// MySQL only, 0 means no limit, when not set the limit is not managed
+	// +kubebuilder:validation:Minimum:=0
+	MaxQueriesPerHour *int `json:"maxQueriesPerHour,omitempty"`
+	// +kubebuilder:validation:Minimum:=0
+	MaxUserConnections *int `json:"maxUserConnections,omitempty"`
+	// Postgres only, -1 means no limit, when not set the limit is not managed
+	// +kubebuilder:validation:Minimum:=-1
+	ConnectionLimit *int `json:"connectionLimit,omitempty"`
+	// Postgres only, a timestamp or infinity, when empty the expiry is not managed
+	ValidUntil string `json:"validUntil,omitempty"`
+	// Postgres only, per role settings like statement_timeout, search_path or work_mem
+	// Settings that are not listed are reset, when not set the settings are not managed
+	RoleSettings map[string]string `json:"roleSettings,omitempty"`
+	// Only plan the privilege changes, they are applied once the approve-privs-plan annotation matches the hash of the plan
+	DryRun bool `json:"dryRun,omitempty"`
+	// What to do when the user already exists on the server and wasn't created by this resource, defaults to ifExists.
+	// An adopted user is only dropped with dropAdopted
+	// +kubebuilder:validation:Enum=never;ifExists;require
+	Adopt string `json:"adopt,omitempty"`
+	// With dropOnDeletion also drop the user when it was adopted instead of created by this resource
+	DropAdopted      bool              `json:"dropAdopted,omitempty"`
+	ConnectionSecret *ConnectionSecret `json:"connectionSecret,omitempty"`
+	// Generate a Service Binding Secret for the user, see https://servicebinding.io
+	ServiceBinding bool `json:"serviceBinding,omitempty"`
+}
+
+// A statement of a privileges plan, Database and RunAs are empty when it runs on the default
+// database of the DbServer as the DbServer user
+type PlannedStatement struct {
+	Database  string `json:"database,omitempty"`
+	RunAs     string `json:"runAs,omitempty"`
+	Statement string `json:"statement"`
+}
+
+// The GRANT and REVOKE statements that would bring the privileges of a User in line with its spec
+type PrivsPlan struct {
+	Hash       string             `json:"hash"`
+	Statements []PlannedStatement `json:"statements"`
+	PlannedAt  metav1.Time        `json:"plannedAt"`
+}
+
+// UserStatus defines the observed state of User
+type UserStatus struct {
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// Pending privilege changes of a User in dry run mode
+	PrivsPlan *PrivsPlan        `json:"privsPlan,omitempty"`
+	Binding   *BindingReference `json:"binding,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:unservedversion
+
+// User is the Schema for the users API
+type User struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   UserSpec   `json:"spec,omitempty"`
+	Status UserStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// UserList contains a list of User
+type UserList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []User `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&User{}, &UserList{})
+}
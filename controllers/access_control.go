@@ -0,0 +1,168 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/shared"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+
+const (
+	CONDITION_AUTHORIZED         = "Authorized"
+	REASON_ALLOWED               = "Allowed"
+	REASON_NAMESPACE_NOT_ALLOWED = "NamespaceNotAllowed"
+	REASON_PRIVILEGE_NOT_ALLOWED = "PrivilegeNotAllowed"
+)
+
+// Returned when a resource isn't allowed to use a DbServer, the Reason ends up in the status and in an event
+type AccessDeniedError struct {
+	Reason  string
+	Message string
+}
+
+func (e *AccessDeniedError) Error() string {
+	return e.Message
+}
+
+func (r *Reco) CheckNamespaceAllowed(dbServer *dboperatorv1alpha1.DbServer, namespace string) error {
+	return CheckNamespaceAllowed(r.Ctx, r.Client, dbServer, namespace)
+}
+
+// Checks the allowed_namespaces of a DbServer, resources in the namespace of the DbServer are always allowed
+func CheckNamespaceAllowed(ctx context.Context, apiClient client.Client, dbServer *dboperatorv1alpha1.DbServer, namespace string) error {
+	return checkAllowedNamespaces(ctx, apiClient, dbServer.Spec.AllowedNamespaces, dbServer.Namespace, namespace, fmt.Sprintf("DbServer %s.%s", dbServer.Namespace, dbServer.Name))
+}
+
+// Checks the allowed_namespaces of a ClusterS3Storage, the storage is passed as a S3Storage in the operator namespace
+func CheckStorageNamespaceAllowed(ctx context.Context, apiClient client.Client, s3Storage *dboperatorv1alpha1.S3Storage, namespace string) error {
+	return checkAllowedNamespaces(ctx, apiClient, s3Storage.Spec.AllowedNamespaces, s3Storage.Namespace, namespace, fmt.Sprintf("%s %s", shared.Nvl(s3Storage.Kind, "S3Storage"), s3Storage.Name))
+}
+
+func checkAllowedNamespaces(ctx context.Context, apiClient client.Client, allowed *dboperatorv1alpha1.AllowedNamespaces, ownNamespace string, namespace string, description string) error {
+	if allowed == nil || namespace == ownNamespace {
+		return nil
+	}
+	for _, name := range allowed.Names {
+		if name == namespace {
+			return nil
+		}
+	}
+
+	if allowed.Selector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(allowed.Selector)
+		if err != nil {
+			return fmt.Errorf("invalid allowed_namespaces selector on %s: %s", description, err)
+		}
+		ns := &v1.Namespace{}
+		err = apiClient.Get(ctx, types.NamespacedName{Name: namespace}, ns)
+		if err != nil {
+			return fmt.Errorf("failed getting namespace %s: %s", namespace, err)
+		}
+		if selector.Matches(labels.Set(ns.Labels)) {
+			return nil
+		}
+	}
+
+	return &AccessDeniedError{
+		Reason:  REASON_NAMESPACE_NOT_ALLOWED,
+		Message: fmt.Sprintf("namespace %s is not allowed to use %s", namespace, description),
+	}
+}
+
+func containsFold(items []string, item string) bool {
+	for _, candidate := range items {
+		if strings.EqualFold(strings.TrimSpace(candidate), item) {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks the server_privs and priv_types of a User against the allowlists of the DbServer
+// When priv types are restricted every db_priv needs an explicit priv_type, otherwise it would be guessed from the scope
+func CheckUserPrivsAllowed(dbServer *dboperatorv1alpha1.DbServer, userSpec dboperatorv1alpha1.UserSpec) error {
+	if dbServer.Spec.AllowedServerPrivs != nil {
+		for _, serverPriv := range strings.Split(userSpec.ServerPrivs, ",") {
+			serverPriv = strings.TrimSpace(serverPriv)
+			if serverPriv == "" {
+				continue
+			}
+			if !containsFold(dbServer.Spec.AllowedServerPrivs, serverPriv) {
+				return &AccessDeniedError{
+					Reason:  REASON_PRIVILEGE_NOT_ALLOWED,
+					Message: fmt.Sprintf("server privilege %s is not allowed on DbServer %s.%s", serverPriv, dbServer.Namespace, dbServer.Name),
+				}
+			}
+		}
+	}
+
+	if dbServer.Spec.AllowedPrivTypes != nil {
+		for _, dbPriv := range userSpec.DbPrivs {
+			if dbPriv.PrivType == "" {
+				return &AccessDeniedError{
+					Reason:  REASON_PRIVILEGE_NOT_ALLOWED,
+					Message: fmt.Sprintf("db_privs on %s need a priv_type because DbServer %s.%s restricts priv types", dbPriv.Scope, dbServer.Namespace, dbServer.Name),
+				}
+			}
+			if !containsFold(dbServer.Spec.AllowedPrivTypes, dbPriv.PrivType) {
+				return &AccessDeniedError{
+					Reason:  REASON_PRIVILEGE_NOT_ALLOWED,
+					Message: fmt.Sprintf("priv_type %s is not allowed on DbServer %s.%s", dbPriv.PrivType, dbServer.Namespace, dbServer.Name),
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Checks whether the resource being reconciled may use the DbServer and records the outcome in the Authorized condition
+// An event is emitted when access gets denied
+func (r *Reco) AuthorizeDbServerUse(dbServer *dboperatorv1alpha1.DbServer, cr client.Object, conditions *[]metav1.Condition, userSpec *dboperatorv1alpha1.UserSpec) error {
+	err := r.CheckNamespaceAllowed(dbServer, r.NsNm.Namespace)
+	if err == nil && userSpec != nil {
+		err = CheckUserPrivsAllowed(dbServer, *userSpec)
+	}
+
+	condition := metav1.Condition{
+		Type:               CONDITION_AUTHORIZED,
+		Status:             metav1.ConditionTrue,
+		Reason:             REASON_ALLOWED,
+		Message:            fmt.Sprintf("allowed to use DbServer %s.%s", dbServer.Namespace, dbServer.Name),
+		ObservedGeneration: cr.GetGeneration(),
+	}
+	accessDenied, denied := err.(*AccessDeniedError)
+	if err != nil && !denied {
+		return err
+	}
+	if denied {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = accessDenied.Reason
+		condition.Message = accessDenied.Message
+	}
+
+	current := meta.FindStatusCondition(*conditions, CONDITION_AUTHORIZED)
+	changed := current == nil || current.Status != condition.Status || current.Reason != condition.Reason ||
+		current.Message != condition.Message || current.ObservedGeneration != condition.ObservedGeneration
+	if changed {
+		meta.SetStatusCondition(conditions, condition)
+		statusErr := r.Client.Status().Update(r.Ctx, cr)
+		if statusErr != nil {
+			r.LogError(statusErr, "failed updating Authorized condition")
+		}
+		if denied && r.Recorder != nil {
+			r.Recorder.Event(cr, v1.EventTypeWarning, accessDenied.Reason, accessDenied.Message)
+		}
+	}
+	return err
+}
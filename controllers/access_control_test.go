@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/shared"
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newFakeClient(t *testing.T, objs ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if err := dboperatorv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	return fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objs...).
+		WithIndex(&dboperatorv1alpha1.DbServer{}, DB_SERVER_NAME_INDEX, func(obj client.Object) []string {
+			return []string{obj.GetName()}
+		}).
+		Build()
+}
+
+func sharedDbServer(allowed *dboperatorv1alpha1.AllowedNamespaces) *dboperatorv1alpha1.DbServer {
+	return &dboperatorv1alpha1.DbServer{
+		ObjectMeta: metav1.ObjectMeta{Name: "postgres", Namespace: "databases"},
+		Spec: dboperatorv1alpha1.DbServerSpec{
+			ServerType:        "postgres",
+			SecretName:        "postgres-credentials",
+			AllowedNamespaces: allowed,
+		},
+	}
+}
+
+func isAccessDenied(err error) bool {
+	var accessDenied *AccessDeniedError
+	return errors.As(err, &accessDenied)
+}
+
+func TestGetDbServerChecksAllowedNamespaces(t *testing.T) {
+	apiClient := newFakeClient(t,
+		sharedDbServer(&dboperatorv1alpha1.AllowedNamespaces{
+			Names:    []string{"shop"},
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "payments"}},
+		}),
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "billing", Labels: map[string]string{"team": "payments"}}},
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "blog"}},
+	)
+	ref := dboperatorv1alpha1.DbServerRef{Name: "postgres"}
+
+	testCases := []struct {
+		namespace string
+		allowed   bool
+	}{
+		{"databases", true},
+		{"shop", true},
+		{"billing", true},
+		{"blog", false},
+	}
+	for _, tc := range testCases {
+		dbServer, err := GetDbServer(ref, apiClient, tc.namespace)
+		if tc.allowed {
+			if err != nil {
+				t.Errorf("expected namespace %s to be allowed, unexpected error %s", tc.namespace, err)
+			} else if dbServer.Name != "postgres" {
+				t.Errorf("expected DbServer postgres got %s", dbServer.Name)
+			}
+			continue
+		}
+		if !isAccessDenied(err) {
+			t.Errorf("expected namespace %s to be denied got %v", tc.namespace, err)
+		}
+		_, err = LookupDbServer(ref, apiClient, tc.namespace)
+		if err != nil {
+			t.Errorf("expected the lookup without checks to succeed, unexpected error %s", err)
+		}
+	}
+}
+
+func TestGetDbServerFromDbNameChecksAllowedNamespaces(t *testing.T) {
+	db := func(namespace string) *dboperatorv1alpha1.Db {
+		return &dboperatorv1alpha1.Db{
+			ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: namespace},
+			Spec: dboperatorv1alpha1.DbSpec{
+				ServerRef: &dboperatorv1alpha1.DbServerRef{Name: "postgres", Namespace: "databases"},
+				DbName:    "shop",
+			},
+		}
+	}
+	apiClient := newFakeClient(t,
+		sharedDbServer(&dboperatorv1alpha1.AllowedNamespaces{Names: []string{"shop"}}),
+		db("shop"),
+		db("blog"),
+	)
+
+	for namespace, allowed := range map[string]bool{"shop": true, "blog": false} {
+		reco := Reco{shared.K8sClient{
+			Client: apiClient,
+			Ctx:    context.Background(),
+			NsNm:   types.NamespacedName{Name: "copy", Namespace: namespace},
+			Log:    zap.NewNop(),
+		}}
+		_, err := reco.GetServerActionsFromDbName("shop")
+		if allowed && isAccessDenied(err) {
+			t.Errorf("expected namespace %s to be allowed got %s", namespace, err)
+		}
+		if !allowed && !isAccessDenied(err) {
+			t.Errorf("expected namespace %s to be denied got %v", namespace, err)
+		}
+	}
+}
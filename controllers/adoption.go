@@ -0,0 +1,164 @@
+package controllers
+
+import (
+	"fmt"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/shared"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	CONDITION_MANAGED         = "Managed"
+	REASON_CREATED            = "Created"
+	REASON_ADOPTED            = "Adopted"
+	REASON_MARKER_FOUND       = "MarkerFound"
+	REASON_NOT_ADOPTED        = "NotAdopted"
+	REASON_MANAGED_ELSEWHERE  = "ManagedElsewhere"
+	REASON_NOT_FOUND          = "NotFound"
+	EVENT_REASON_ADOPTED      = "Adopted"
+	EVENT_REASON_DROP_REFUSED = "DropRefused"
+)
+
+// The object on the server and the resource that manages it
+type ManagedObject struct {
+	Conn        shared.DbServerConnectionInterface
+	Cr          client.Object
+	Conditions  *[]metav1.Condition
+	Kind        string
+	ObjectType  string
+	ObjectName  string
+	Adopt       string
+	DropAdopted bool
+}
+
+// The marker that is recorded on the server for objects created by a resource
+func (o ManagedObject) Marker() string {
+	return fmt.Sprintf("%s%s %s/%s", shared.MANAGED_BY_PREFIX, o.Kind, o.Cr.GetNamespace(), o.Cr.GetName())
+}
+
+// Adopted objects get a marker of their own, they are only dropped when the resource opts in
+func (o ManagedObject) AdoptionMarker() string {
+	return fmt.Sprintf("%s (adopted)", o.Marker())
+}
+
+// Records that the resource created the object
+func (r *Reco) MarkCreated(obj ManagedObject) error {
+	err := obj.Conn.SetManagedBy(obj.ObjectType, obj.ObjectName, obj.Marker())
+	if err != nil {
+		return err
+	}
+	return r.setManagedCondition(obj, metav1.Condition{
+		Status:  metav1.ConditionTrue,
+		Reason:  REASON_CREATED,
+		Message: fmt.Sprintf("created %s %s", obj.ObjectType, obj.ObjectName),
+	})
+}
+
+// With adopt set to require the object is never created, returns false in that case
+func (r *Reco) MayCreate(obj ManagedObject) (bool, error) {
+	if obj.Adopt != dboperatorv1alpha1.ADOPT_REQUIRE {
+		return true, nil
+	}
+	return false, r.setManagedCondition(obj, metav1.Condition{
+		Status:  metav1.ConditionFalse,
+		Reason:  REASON_NOT_FOUND,
+		Message: fmt.Sprintf("%s %s doesn't exist and adopt is %s", obj.ObjectType, obj.ObjectName, obj.Adopt),
+	})
+}
+
+// Adopts an existing object when the adopt policy allows it
+// Returns false when the object is not managed by the resource and should be left alone
+func (r *Reco) EnsureManaged(obj ManagedObject) (bool, error) {
+	current, err := obj.Conn.GetManagedBy(obj.ObjectType, obj.ObjectName)
+	if err != nil {
+		return false, err
+	}
+
+	condition := metav1.Condition{Status: metav1.ConditionTrue}
+	switch {
+	case current == obj.Marker() || current == obj.AdoptionMarker():
+		existing := meta.FindStatusCondition(*obj.Conditions, CONDITION_MANAGED)
+		if existing != nil && existing.Status == metav1.ConditionTrue {
+			condition.Reason = existing.Reason
+			condition.Message = existing.Message
+		} else {
+			condition.Reason = REASON_MARKER_FOUND
+			condition.Message = fmt.Sprintf("%s %s is managed by this resource", obj.ObjectType, obj.ObjectName)
+		}
+	case current != "":
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = REASON_MANAGED_ELSEWHERE
+		condition.Message = fmt.Sprintf("%s %s is %s", obj.ObjectType, obj.ObjectName, current)
+	case obj.Adopt == dboperatorv1alpha1.ADOPT_NEVER:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = REASON_NOT_ADOPTED
+		condition.Message = fmt.Sprintf("%s %s already exists and adopt is %s", obj.ObjectType, obj.ObjectName, obj.Adopt)
+	case managedBeforeMarkers(obj):
+		err = obj.Conn.SetManagedBy(obj.ObjectType, obj.ObjectName, obj.Marker())
+		if err != nil {
+			return false, err
+		}
+		condition.Reason = REASON_CREATED
+		condition.Message = fmt.Sprintf("%s %s was managed by this resource before markers were recorded", obj.ObjectType, obj.ObjectName)
+		r.Log.Info(condition.Message)
+	default:
+		err = obj.Conn.SetManagedBy(obj.ObjectType, obj.ObjectName, obj.AdoptionMarker())
+		if err != nil {
+			return false, err
+		}
+		condition.Reason = REASON_ADOPTED
+		condition.Message = fmt.Sprintf("adopted existing %s %s", obj.ObjectType, obj.ObjectName)
+		r.Log.Info(condition.Message)
+		if r.Recorder != nil {
+			r.Recorder.Event(obj.Cr, v1.EventTypeNormal, EVENT_REASON_ADOPTED, condition.Message)
+		}
+	}
+	return condition.Status == metav1.ConditionTrue, r.setManagedCondition(obj, condition)
+}
+
+// The finalizer is only added after an object was created or reconciled, a resource that has it without a Managed condition
+// was reconciled by an operator version that didn't record markers. Its object counts as created so drop_on_deletion keeps working
+func managedBeforeMarkers(obj ManagedObject) bool {
+	return controllerutil.ContainsFinalizer(obj.Cr, DB_OPERATOR_FINALIZER) && meta.FindStatusCondition(*obj.Conditions, CONDITION_MANAGED) == nil
+}
+
+// Objects are only dropped when they were created by the resource, or adopted by it with drop_adopted set
+func (r *Reco) MayDrop(obj ManagedObject) (bool, error) {
+	current, err := obj.Conn.GetManagedBy(obj.ObjectType, obj.ObjectName)
+	if err != nil {
+		return false, err
+	}
+	if current == obj.Marker() || (obj.DropAdopted && current == obj.AdoptionMarker()) {
+		return true, nil
+	}
+	message := fmt.Sprintf("not dropping %s %s, it wasn't created or adopted by this resource", obj.ObjectType, obj.ObjectName)
+	if current == obj.AdoptionMarker() {
+		message = fmt.Sprintf("not dropping %s %s, it was adopted by this resource and drop_adopted is not set", obj.ObjectType, obj.ObjectName)
+	}
+	r.Log.Info(message)
+	if r.Recorder != nil {
+		r.Recorder.Event(obj.Cr, v1.EventTypeWarning, EVENT_REASON_DROP_REFUSED, message)
+	}
+	return false, nil
+}
+
+func (r *Reco) setManagedCondition(obj ManagedObject, condition metav1.Condition) error {
+	condition.Type = CONDITION_MANAGED
+	condition.ObservedGeneration = obj.Cr.GetGeneration()
+	current := meta.FindStatusCondition(*obj.Conditions, CONDITION_MANAGED)
+	changed := current == nil || current.Status != condition.Status || current.Reason != condition.Reason ||
+		current.Message != condition.Message || current.ObservedGeneration != condition.ObservedGeneration
+	if !changed {
+		return nil
+	}
+	meta.SetStatusCondition(obj.Conditions, condition)
+	if condition.Status == metav1.ConditionFalse && r.Recorder != nil {
+		r.Recorder.Event(obj.Cr, v1.EventTypeWarning, condition.Reason, condition.Message)
+	}
+	return r.Client.Status().Update(r.Ctx, obj.Cr)
+}
@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"testing"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/shared"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// Keeps the markers in a map, the other methods of the interface are never called
+type markerConnection struct {
+	shared.DbServerConnectionInterface
+	markers map[string]string
+}
+
+func (c *markerConnection) GetManagedBy(objectType string, name string) (string, error) {
+	return c.markers[objectType+"/"+name], nil
+}
+
+func (c *markerConnection) SetManagedBy(objectType string, name string, managedBy string) error {
+	c.markers[objectType+"/"+name] = managedBy
+	return nil
+}
+
+func TestAdoptedObjectsAreOnlyDroppedWithDropAdopted(t *testing.T) {
+	db := &dboperatorv1alpha1.Db{
+		ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "shop"},
+		Spec:       dboperatorv1alpha1.DbSpec{DbName: "shop", DropOnDeletion: true},
+	}
+	scheme := runtime.NewScheme()
+	if err := dboperatorv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	apiClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(db).WithStatusSubresource(db).Build()
+	reco := Reco{*newK8sClient(apiClient, "shop")}
+	conn := &markerConnection{markers: map[string]string{}}
+	obj := ManagedObject{
+		Conn:       conn,
+		Cr:         db,
+		Conditions: &db.Status.Conditions,
+		Kind:       "Db",
+		ObjectType: shared.MANAGED_DATABASE,
+		ObjectName: "shop",
+	}
+
+	managed, err := reco.EnsureManaged(obj)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if !managed || conn.markers["database/shop"] != "managed by db-operator Db shop/shop (adopted)" {
+		t.Errorf("expected the database to be adopted got marker %s", conn.markers["database/shop"])
+	}
+	managed, err = reco.EnsureManaged(obj)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if !managed {
+		t.Errorf("expected the adopted database to stay managed")
+	}
+
+	mayDrop, err := reco.MayDrop(obj)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if mayDrop {
+		t.Errorf("expected an adopted database not to be dropped without drop_adopted")
+	}
+	obj.DropAdopted = true
+	mayDrop, err = reco.MayDrop(obj)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if !mayDrop {
+		t.Errorf("expected an adopted database to be dropped with drop_adopted")
+	}
+
+	obj.DropAdopted = false
+	conn.markers["database/shop"] = obj.Marker()
+	mayDrop, err = reco.MayDrop(obj)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if !mayDrop {
+		t.Errorf("expected a created database to be dropped")
+	}
+
+	conn.markers["database/shop"] = "managed by db-operator Db blog/shop"
+	mayDrop, err = reco.MayDrop(obj)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if mayDrop {
+		t.Errorf("expected a database of another resource not to be dropped")
+	}
+}
+
+func TestObjectsManagedBeforeMarkersAreDropped(t *testing.T) {
+	// Reconciled by an operator version without markers, it has the finalizer but no Managed condition
+	db := &dboperatorv1alpha1.Db{
+		ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "shop", Finalizers: []string{DB_OPERATOR_FINALIZER}},
+		Spec:       dboperatorv1alpha1.DbSpec{DbName: "shop", DropOnDeletion: true},
+	}
+	scheme := runtime.NewScheme()
+	if err := dboperatorv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	apiClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(db).WithStatusSubresource(db).Build()
+	reco := Reco{*newK8sClient(apiClient, "shop")}
+	conn := &markerConnection{markers: map[string]string{}}
+	obj := ManagedObject{
+		Conn:       conn,
+		Cr:         db,
+		Conditions: &db.Status.Conditions,
+		Kind:       "Db",
+		ObjectType: shared.MANAGED_DATABASE,
+		ObjectName: "shop",
+	}
+
+	managed, err := reco.EnsureManaged(obj)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if !managed || conn.markers["database/shop"] != obj.Marker() {
+		t.Errorf("expected the database to be marked as created got marker %s", conn.markers["database/shop"])
+	}
+	mayDrop, err := reco.MayDrop(obj)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if !mayDrop {
+		t.Errorf("expected the database to be dropped with drop_on_deletion")
+	}
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/shared"
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// ClusterDbServerReconciler reconciles a ClusterDbServer object
+type ClusterDbServerReconciler struct {
+	client.Client
+	Log    *zap.Logger
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=db-operator.kubemaster.com,resources=clusterdbservers,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=db-operator.kubemaster.com,resources=clusterdbservers/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=db-operator.kubemaster.com,resources=clusterdbservers/finalizers,verbs=update
+
+func (r *ClusterDbServerReconciler) LogError(err error, message string) {
+	r.Log.Error(fmt.Sprintf("%s Error: %s", message, err))
+}
+
+func (r *ClusterDbServerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	r.Log.Info("Reconcile", zap.String("Name", req.Name))
+	clusterDbServer := &dboperatorv1alpha1.ClusterDbServer{}
+	err := r.Get(ctx, req.NamespacedName, clusterDbServer)
+	if err != nil {
+		if !shared.CannotFindError(err, r.Log, "ClusterDbServer", req.Namespace, req.Name) {
+			r.LogError(err, fmt.Sprintf("Failed to get clusterDbServer: %s", req.Name))
+		} else {
+			shared.SharedConnectionPool.InvalidateServer(GetClusterDbServerKey(req.Name))
+		}
+		return ctrl.Result{}, nil
+	}
+
+	reco := Reco{shared.K8sClient{Client: r.Client, Ctx: ctx, NsNm: req.NamespacedName, Log: r.Log}}
+
+	markedToBeDeleted := clusterDbServer.GetDeletionTimestamp() != nil
+	if markedToBeDeleted {
+		shared.SharedConnectionPool.InvalidateServer(GetClusterDbServerKey(clusterDbServer.Name))
+		err = reco.RemoveFinalizer(clusterDbServer)
+		if err != nil {
+			r.LogError(err, "failed removing finalizer")
+			return shared.RetryAfter(3), nil
+		}
+		return ctrl.Result{}, nil
+	}
+
+	operatorNamespace, err := shared.GetOperatorNamespace()
+	if err != nil {
+		r.LogError(err, "unable to reconcile ClusterDbServer")
+		return shared.GradualBackoffRetry(clusterDbServer.GetCreationTimestamp().Time), nil
+	}
+
+	status, res := ProbeDbServer(reco, clusterDbServer.AsDbServer(operatorNamespace))
+	err = SetDbServerStatus(reco, clusterDbServer, &clusterDbServer.Status, status)
+	if !res.IsZero() {
+		return res, nil
+	}
+	if err != nil {
+		return shared.RetryAfter(3), nil
+	}
+	r.Log.Info("Reconcile Done", zap.String("Name", req.Name))
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ClusterDbServerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&dboperatorv1alpha1.ClusterDbServer{}).
+		Watches(&v1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.clusterDbServersForSecret)).
+		Complete(r)
+}
+
+func (r *ClusterDbServerReconciler) clusterDbServersForSecret(ctx context.Context, secret client.Object) []reconcile.Request {
+	operatorNamespace, err := shared.GetOperatorNamespace()
+	if err != nil || secret.GetNamespace() != operatorNamespace {
+		return nil
+	}
+	clusterDbServerList := dboperatorv1alpha1.ClusterDbServerList{}
+	err = r.List(ctx, &clusterDbServerList)
+	if err != nil {
+		r.LogError(err, "failed listing ClusterDbServers for Secret")
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, clusterDbServer := range clusterDbServerList.Items {
+		if clusterDbServer.Spec.SecretName == secret.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: clusterDbServer.Name}})
+		}
+	}
+	return requests
+}
+
+// ClusterDbServers get their own prefix in the shared connection pool so they don't collide with DbServers in the operator namespace
+func GetClusterDbServerKey(name string) string {
+	return fmt.Sprintf("cluster/%s", name)
+}
+
+// Gets a ClusterDbServer as a DbServer whose Secrets are read from the operator namespace
+func GetClusterDbServer(name string, apiClient client.Client) (*dboperatorv1alpha1.DbServer, error) {
+	clusterDbServer := &dboperatorv1alpha1.ClusterDbServer{}
+	err := apiClient.Get(context.Background(), types.NamespacedName{Name: name}, clusterDbServer)
+	if err != nil {
+		return nil, err
+	}
+	operatorNamespace, err := shared.GetOperatorNamespace()
+	if err != nil {
+		return nil, err
+	}
+	return clusterDbServer.AsDbServer(operatorNamespace), nil
+}
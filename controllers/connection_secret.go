@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/dbservers"
+	"github.com/obeleh/db-operator/shared"
+)
+
+const EVENT_REASON_CONNECTION_SECRET_UPDATED = "ConnectionSecretUpdated"
+
+// Connection values of user on dbServer, dbName is left empty for the default database of the flavor
+func getUserConnectionValues(k8sClient client.Client, ctx context.Context, user *dboperatorv1alpha1.User, dbServer *dboperatorv1alpha1.DbServer, dbName string) (shared.ConnectionValues, *shared.Credentials, error) {
+	creds, err := GetUserCredentials(user, k8sClient, ctx)
+	if err != nil {
+		return shared.ConnectionValues{}, nil, err
+	}
+	if creds == nil {
+		creds = &shared.Credentials{UserName: user.Spec.UserName}
+	}
+	connectInfo := &shared.DbServerConnectInfo{
+		Host:        dbServer.Spec.Address,
+		Port:        dbServer.Spec.Port,
+		Credentials: *creds,
+		Database:    dbName,
+		Options:     dbServer.Spec.Options,
+	}
+	values, err := dbservers.GetConnectionValues(dbServer.Spec.ServerType, connectInfo)
+	return values, creds, err
+}
+
+// Creates secret with owner as its controller or updates the data of the existing one
+// A Secret with the same name that isn't controlled by owner is left alone
+func applyOwnedSecret(k8sClient client.Client, ctx context.Context, scheme *runtime.Scheme, owner client.Object, secret v1.Secret) (controllerutil.OperationResult, error) {
+	existing := v1.Secret{}
+	err := k8sClient.Get(ctx, types.NamespacedName{Namespace: secret.Namespace, Name: secret.Name}, &existing)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return controllerutil.OperationResultNone, err
+		}
+		err = controllerutil.SetControllerReference(owner, &secret, scheme)
+		if err != nil {
+			return controllerutil.OperationResultNone, err
+		}
+		return controllerutil.OperationResultCreated, k8sClient.Create(ctx, &secret)
+	}
+
+	if !metav1.IsControlledBy(&existing, owner) {
+		return controllerutil.OperationResultNone, fmt.Errorf("secret %s already exists and isn't owned by %s", secret.Name, owner.GetName())
+	}
+	if reflect.DeepEqual(existing.Data, secret.Data) {
+		return controllerutil.OperationResultNone, nil
+	}
+	existing.Data = secret.Data
+	return controllerutil.OperationResultUpdated, k8sClient.Update(ctx, &existing)
+}
+
+// Renders the connection secret of the user from the DbServer, the Db and the credentials of the user
+func (r *UserReco) buildConnectionSecretData() (map[string][]byte, error) {
+	spec := r.user.Spec.ConnectionSecret
+	if spec.SecretName == r.user.Spec.SecretName {
+		return nil, fmt.Errorf("connection_secret can't be the secret %s with the password of the user", spec.SecretName)
+	}
+	dbName := ""
+	if spec.Db != "" {
+		db := dboperatorv1alpha1.Db{}
+		err := r.Client.Get(r.Ctx, types.NamespacedName{Namespace: r.user.Namespace, Name: spec.Db}, &db)
+		if err != nil {
+			return nil, err
+		}
+		dbName = db.Spec.DbName
+	}
+
+	values, _, err := getUserConnectionValues(r.Client, r.Ctx, &r.user, r.dbServer, dbName)
+	if err != nil {
+		return nil, err
+	}
+	return shared.RenderConnectionSecret(spec.Templates, values)
+}
+
+// Creates the connection secret of the user or updates it when one of its inputs changed
+func (r *UserReco) updateConnectionSecret() error {
+	if r.user.Spec.ConnectionSecret == nil {
+		return nil
+	}
+	data, err := r.buildConnectionSecretData()
+	if err != nil {
+		return err
+	}
+
+	secret := v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      r.user.Spec.ConnectionSecret.SecretName,
+			Namespace: r.user.Namespace,
+		},
+		Data: data,
+	}
+	result, err := applyOwnedSecret(r.Client, r.Ctx, r.scheme, &r.user, secret)
+	if err != nil {
+		return err
+	}
+	if result == controllerutil.OperationResultNone {
+		return nil
+	}
+	message := fmt.Sprintf("%s connection secret %s of user %s", result, secret.Name, r.user.Name)
+	r.Log.Info(message)
+	if result == controllerutil.OperationResultUpdated && r.Recorder != nil {
+		r.Recorder.Event(&r.user, v1.EventTypeNormal, EVENT_REASON_CONNECTION_SECRET_UPDATED, message)
+	}
+	return nil
+}
@@ -20,10 +20,18 @@ import (
 	"context"
 	"fmt"
 
+	funk "github.com/thoas/go-funk"
 	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
 	"github.com/obeleh/db-operator/shared"
@@ -32,19 +40,25 @@ import (
 // DbReconciler reconciles a Db object
 type DbReconciler struct {
 	client.Client
-	Log    *zap.Logger
-	Scheme *runtime.Scheme
+	Log      *zap.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=db-operator.kubemaster.com,resources=dbs,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=db-operator.kubemaster.com,resources=dbs/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=db-operator.kubemaster.com,resources=dbs/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;delete
+
+const EVENT_REASON_OWNER_CHANGED = "OwnerChanged"
 
 type DbReco struct {
 	Reco
-	db   dboperatorv1alpha1.Db
-	dbs  map[string]shared.DbSideDb
-	conn shared.DbServerConnectionInterface
+	db       dboperatorv1alpha1.Db
+	dbs      map[string]shared.DbSideDb
+	conn     shared.DbServerConnectionInterface
+	dbServer *dboperatorv1alpha1.DbServer
+	scheme   *runtime.Scheme
 }
 
 func (r *DbReco) LoadCR() (ctrl.Result, error) {
@@ -59,7 +73,7 @@ func (r *DbReco) LoadCR() (ctrl.Result, error) {
 func (r *DbReco) LoadObj() (bool, error) {
 	var err error
 	// First create conninfo without db name because we don't know whether it exists
-	dbServer, err := GetDbServer(r.db.Spec.Server, r.Client, r.NsNm.Namespace)
+	dbServer, err := LookupDbServer(r.db.Spec.GetServerRef(), r.Client, r.NsNm.Namespace)
 	if err != nil {
 		if !shared.CannotFindError(err, r.Log, "DbServer", r.NsNm.Namespace, r.NsNm.Name) {
 			r.LogError(err, "failed getting DbServer")
@@ -67,6 +81,11 @@ func (r *DbReco) LoadObj() (bool, error) {
 		}
 		return false, nil
 	}
+	err = r.AuthorizeDbServerUse(dbServer, &r.db, &r.db.Status.Conditions, nil)
+	if err != nil {
+		return false, err
+	}
+	r.dbServer = dbServer
 
 	// Do not point to DB in this controller
 	// Otherwise we would be connected to a database we potentially want to drop
@@ -86,8 +105,20 @@ func (r *DbReco) LoadObj() (bool, error) {
 	return exists, nil
 }
 
+func (r *DbReco) managedObject() ManagedObject {
+	return ManagedObject{
+		Conn:        r.conn,
+		Cr:          &r.db,
+		Conditions:  &r.db.Status.Conditions,
+		Kind:        "Db",
+		ObjectType:  shared.MANAGED_DATABASE,
+		ObjectName:  r.db.Spec.DbName,
+		Adopt:       r.db.Spec.Adopt,
+		DropAdopted: r.db.Spec.DropAdopted,
+	}
+}
+
 func (r *DbReco) CreateObj() (ctrl.Result, error) {
-	r.Log.Info(fmt.Sprintf("Creating db %s", r.db.Spec.DbName))
 	var err error
 	if r.conn == nil {
 		message := "no database connection possible"
@@ -95,11 +126,29 @@ func (r *DbReco) CreateObj() (ctrl.Result, error) {
 		r.LogError(err, message)
 		return r.LogAndBackoffCreation(err, r.GetCR())
 	}
-	err = r.conn.CreateDb(r.db.Spec.DbName)
+	mayCreate, err := r.MayCreate(r.managedObject())
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	if !mayCreate {
+		r.Log.Info(fmt.Sprintf("db %s doesn't exist and adopt is %s", r.db.Spec.DbName, r.db.Spec.Adopt))
+		return shared.GradualBackoffRetry(r.db.GetCreationTimestamp().Time), nil
+	}
+	r.Log.Info(fmt.Sprintf("Creating db %s", r.db.Spec.DbName))
+	err = r.conn.CreateDb(r.db.Spec)
 	if err != nil {
 		r.LogError(err, fmt.Sprintf("failed to Create DB: %s", r.db.Spec.DbName))
 		return shared.GradualBackoffRetry(r.db.GetCreationTimestamp().Time), nil
 	}
+	err = r.MarkCreated(r.managedObject())
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	err = r.updateOwner()
+	if err != nil {
+		// The owner is set again by EnsureCorrect, the User may not have been created yet
+		r.LogError(err, fmt.Sprintf("failed setting the owner of db %s", r.db.Spec.DbName))
+	}
 	r.NotifyChanges()
 	if r.db.Spec.AfterCreateSQL != "" {
 		err = r.conn.Execute(r.db.Spec.AfterCreateSQL, nil)
@@ -117,16 +166,28 @@ func (r *DbReco) CreateObj() (ctrl.Result, error) {
 }
 
 func (r *DbReco) RemoveObj() (ctrl.Result, error) {
+	mayDrop := false
 	if r.db.Spec.DropOnDeletion {
+		var err error
+		mayDrop, err = r.MayDrop(r.managedObject())
+		if err != nil {
+			return r.LogAndBackoffDeletion(err, r.GetCR())
+		}
+	} else {
+		r.Log.Info(fmt.Sprintf("did not drop db %s as per spec", r.db.Spec.DbName))
+	}
+	if mayDrop {
 		r.Log.Info(fmt.Sprintf("dropping db %s", r.db.Spec.DbName))
 		err := r.conn.DropDb(r.db.Spec.DbName, r.db.Spec.CascadeOnDrop)
 		if err != nil {
 			r.LogError(err, fmt.Sprintf("failed to drop db %s\n%s", r.db.Spec.DbName, err))
 			return r.LogAndBackoffDeletion(err, r.GetCR())
 		}
+		err = r.conn.ForgetManagedBy(shared.MANAGED_DATABASE, r.db.Spec.DbName)
+		if err != nil {
+			return r.LogAndBackoffDeletion(err, r.GetCR())
+		}
 		r.Log.Info(fmt.Sprintf("finalized db %s", r.db.Spec.DbName))
-	} else {
-		r.Log.Info(fmt.Sprintf("did not drop db %s as per spec", r.db.Spec.DbName))
 	}
 	r.NotifyChanges()
 	return ctrl.Result{}, nil
@@ -139,7 +200,7 @@ func (r *DbReco) GetCR() client.Object {
 func (r *DbReco) NotifyChanges() {
 	r.Log.Info("Notifying of DB changes")
 	// getting dbServer because we need to figure out in what namespace it lives
-	dbServer, err := GetDbServer(r.db.Spec.Server, r.Client, r.db.Namespace)
+	dbServer, err := GetDbServer(r.db.Spec.GetServerRef(), r.Client, r.db.Namespace)
 	if err != nil {
 		r.LogError(err, "failed notifying DBServer")
 		return
@@ -148,9 +209,60 @@ func (r *DbReco) NotifyChanges() {
 }
 
 func (r *DbReco) EnsureCorrect() (ctrl.Result, error) {
+	managed, err := r.EnsureManaged(r.managedObject())
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	if !managed {
+		return ctrl.Result{}, nil
+	}
+	err = r.updateOwner()
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	err = r.updateOptions()
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	err = r.updateServiceBinding()
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
 	return ctrl.Result{}, nil
 }
 
+// Hands the database over to the User in the owner field
+func (r *DbReco) updateOwner() error {
+	if r.db.Spec.Owner == "" {
+		return nil
+	}
+	owner := dboperatorv1alpha1.User{}
+	err := r.Client.Get(r.Ctx, types.NamespacedName{Namespace: r.db.Namespace, Name: r.db.Spec.Owner}, &owner)
+	if err != nil {
+		return fmt.Errorf("failed getting owner %s of db %s: %s", r.db.Spec.Owner, r.db.Spec.DbName, err)
+	}
+	ownerServer, err := GetDbServer(owner.Spec.GetDbServerRef(), r.Client, owner.Namespace)
+	if err != nil {
+		return err
+	}
+	if GetDbServerKey(ownerServer) != GetDbServerKey(r.dbServer) {
+		return fmt.Errorf("owner %s of db %s uses a different DbServer", r.db.Spec.Owner, r.db.Spec.DbName)
+	}
+
+	changed, err := r.conn.UpdateDbOwner(r.db.Spec.DbName, owner.Spec)
+	if err != nil {
+		return err
+	}
+	if changed {
+		message := fmt.Sprintf("made %s the owner of db %s", owner.Spec.UserName, r.db.Spec.DbName)
+		r.Log.Info(message)
+		if r.Recorder != nil {
+			r.Recorder.Event(&r.db, v1.EventTypeNormal, EVENT_REASON_OWNER_CHANGED, message)
+		}
+	}
+	return nil
+}
+
 func (r *DbReco) CleanupConn() {
 	if r.conn != nil {
 		r.conn.Close()
@@ -159,14 +271,73 @@ func (r *DbReco) CleanupConn() {
 
 func (r *DbReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.With(zap.String("Namespace", req.Namespace)).With(zap.String("Name", req.Name))
-	dr := DbReco{}
-	dr.Reco = Reco{shared.K8sClient{Client: r.Client, Ctx: ctx, NsNm: req.NamespacedName, Log: log}}
+	dr := DbReco{
+		Reco:   Reco{shared.K8sClient{Client: r.Client, Ctx: ctx, NsNm: req.NamespacedName, Log: log, Recorder: r.Recorder}},
+		scheme: r.Scheme,
+	}
 	return dr.Reco.Reconcile(&dr)
 }
 
 // SetupWithManager sets up the controller with the Manager.
+// Binding secrets are kept up to date with the owner, its password secret and the DbServer they're built from
 func (r *DbReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&dboperatorv1alpha1.Db{}).
+		Owns(&v1.Secret{}).
+		Watches(&v1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.dbsForSecret)).
+		Watches(&dboperatorv1alpha1.User{}, handler.EnqueueRequestsFromMapFunc(r.dbsForUser), builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&dboperatorv1alpha1.DbServer{}, handler.EnqueueRequestsFromMapFunc(r.dbsForDbServer), builder.WithPredicates(predicate.GenerationChangedPredicate{})).
 		Complete(r)
 }
+
+// Dbs with a binding secret whose inputs match
+func (r *DbReconciler) dbsWithServiceBinding(ctx context.Context, namespace string, matches func(db dboperatorv1alpha1.Db) bool) []reconcile.Request {
+	dbList := dboperatorv1alpha1.DbList{}
+	err := r.List(ctx, &dbList, client.InNamespace(namespace))
+	if err != nil {
+		r.Log.Error(fmt.Sprintf("failed listing Dbs for binding secrets Error: %s", err))
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, db := range dbList.Items {
+		if db.Spec.ServiceBinding && matches(db) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: db.Name, Namespace: db.Namespace}})
+		}
+	}
+	return requests
+}
+
+func (r *DbReconciler) dbsForSecret(ctx context.Context, secret client.Object) []reconcile.Request {
+	userList := dboperatorv1alpha1.UserList{}
+	err := r.List(ctx, &userList, client.InNamespace(secret.GetNamespace()))
+	if err != nil {
+		r.Log.Error(fmt.Sprintf("failed listing Users for binding secrets Error: %s", err))
+		return nil
+	}
+	owners := []string{}
+	for _, user := range userList.Items {
+		if user.Spec.SecretName == secret.GetName() {
+			owners = append(owners, user.Name)
+		}
+	}
+	if len(owners) == 0 {
+		return nil
+	}
+	return r.dbsWithServiceBinding(ctx, secret.GetNamespace(), func(db dboperatorv1alpha1.Db) bool {
+		return funk.ContainsString(owners, db.Spec.Owner)
+	})
+}
+
+func (r *DbReconciler) dbsForUser(ctx context.Context, user client.Object) []reconcile.Request {
+	return r.dbsWithServiceBinding(ctx, user.GetNamespace(), func(db dboperatorv1alpha1.Db) bool {
+		return db.Spec.Owner == user.GetName()
+	})
+}
+
+// Only Dbs in the namespace of the DbServer, others pick up changes when they're reconciled again
+func (r *DbReconciler) dbsForDbServer(ctx context.Context, dbServer client.Object) []reconcile.Request {
+	return r.dbsWithServiceBinding(ctx, dbServer.GetNamespace(), func(db dboperatorv1alpha1.Db) bool {
+		ref := db.Spec.GetServerRef()
+		return ref.Name == dbServer.GetName() && shared.Nvl(ref.Namespace, db.Namespace) == dbServer.GetNamespace() && ref.Kind != dboperatorv1alpha1.CLUSTER_DB_SERVER_KIND
+	})
+}
@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	CONDITION_OPTIONS_IN_SYNC       = "OptionsInSync"
+	REASON_OPTIONS_IN_SYNC          = "OptionsInSync"
+	REASON_IMMUTABLE_OPTIONS_DIFFER = "ImmutableOptionsDiffer"
+	EVENT_REASON_OPTIONS_CHANGED    = "OptionsChanged"
+)
+
+// Applies the options of the database that can be changed after creation,
+// options that are fixed at creation and differ from the spec are reported on the OptionsInSync condition
+func (r *DbReco) updateOptions() error {
+	changed, drift, err := r.conn.UpdateDbOptions(r.db.Spec)
+	if err != nil {
+		return err
+	}
+	if changed {
+		message := fmt.Sprintf("updated the options of db %s", r.db.Spec.DbName)
+		r.Log.Info(message)
+		if r.Recorder != nil {
+			r.Recorder.Event(&r.db, v1.EventTypeNormal, EVENT_REASON_OPTIONS_CHANGED, message)
+		}
+	}
+
+	condition := metav1.Condition{
+		Type:               CONDITION_OPTIONS_IN_SYNC,
+		Status:             metav1.ConditionTrue,
+		Reason:             REASON_OPTIONS_IN_SYNC,
+		Message:            "options match the spec",
+		ObservedGeneration: r.db.GetGeneration(),
+	}
+	if len(drift) > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = REASON_IMMUTABLE_OPTIONS_DIFFER
+		condition.Message = fmt.Sprintf("options of db %s that can't be changed after creation differ from the spec: %s", r.db.Spec.DbName, strings.Join(drift, ", "))
+	}
+
+	current := meta.FindStatusCondition(r.db.Status.Conditions, CONDITION_OPTIONS_IN_SYNC)
+	if current != nil && current.Status == condition.Status && current.Reason == condition.Reason &&
+		current.Message == condition.Message && current.ObservedGeneration == condition.ObservedGeneration {
+		return nil
+	}
+	meta.SetStatusCondition(&r.db.Status.Conditions, condition)
+	if condition.Status == metav1.ConditionFalse {
+		r.Log.Info(condition.Message)
+		if r.Recorder != nil {
+			r.Recorder.Event(&r.db, v1.EventTypeWarning, condition.Reason, condition.Message)
+		}
+	}
+	return r.Client.Status().Update(r.Ctx, &r.db)
+}
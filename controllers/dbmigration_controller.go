@@ -0,0 +1,257 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/shared"
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	CONDITION_MIGRATIONS_APPLIED   = "MigrationsApplied"
+	REASON_MIGRATIONS_UP_TO_DATE   = "UpToDate"
+	REASON_CHECKSUM_MISMATCH       = "ChecksumMismatch"
+	REASON_MIGRATION_OUT_OF_ORDER  = "OutOfOrder"
+	REASON_MIGRATION_FAILED        = "MigrationFailed"
+	EVENT_REASON_MIGRATION_APPLIED = "MigrationApplied"
+)
+
+// DbMigrationReconciler reconciles a DbMigration object
+type DbMigrationReconciler struct {
+	client.Client
+	Log      *zap.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=db-operator.kubemaster.com,resources=dbmigrations,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=db-operator.kubemaster.com,resources=dbmigrations/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=db-operator.kubemaster.com,resources=dbmigrations/finalizers,verbs=update
+
+type DbMigrationReco struct {
+	Reco
+	dbMigration dboperatorv1alpha1.DbMigration
+	conn        shared.DbServerConnectionInterface
+}
+
+// Migrations are the .sql keys of the ConfigMap in the order of their keys
+func (r *DbMigrationReco) loadMigrations() ([]shared.Migration, error) {
+	configMap := v1.ConfigMap{}
+	configMapNsm := types.NamespacedName{Namespace: r.NsNm.Namespace, Name: r.dbMigration.Spec.ConfigMapName}
+	err := r.Client.Get(r.Ctx, configMapNsm, &configMap)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting ConfigMap %s: %s", configMapNsm, err)
+	}
+	versions := []string{}
+	for key := range configMap.Data {
+		if strings.HasSuffix(key, ".sql") {
+			versions = append(versions, key)
+		}
+	}
+	sort.Strings(versions)
+
+	migrations := []shared.Migration{}
+	for _, version := range versions {
+		migrations = append(migrations, shared.NewMigration(version, configMap.Data[version]))
+	}
+	return migrations, nil
+}
+
+func (r *DbMigrationReco) connect() error {
+	db := dboperatorv1alpha1.Db{}
+	err := r.Client.Get(r.Ctx, types.NamespacedName{Namespace: r.NsNm.Namespace, Name: r.dbMigration.Spec.Db}, &db)
+	if err != nil {
+		return fmt.Errorf("failed getting Db %s: %s", r.dbMigration.Spec.Db, err)
+	}
+	dbServer, err := LookupDbServer(db.Spec.GetServerRef(), r.Client, r.NsNm.Namespace)
+	if err != nil {
+		return err
+	}
+	err = r.AuthorizeDbServerUse(dbServer, &r.dbMigration, &r.dbMigration.Status.Conditions, nil)
+	if err != nil {
+		return err
+	}
+	userNames := []string{}
+	if r.dbMigration.Spec.User != "" {
+		userNames = append(userNames, r.dbMigration.Spec.User)
+	}
+	conn, err := r.GetDbConnection(dbServer, userNames, &db.Spec.DbName)
+	if err != nil {
+		return err
+	}
+	r.conn = conn
+	return nil
+}
+
+func (r *DbMigrationReco) getUserName() *string {
+	if r.dbMigration.Spec.User == "" {
+		return nil
+	}
+	return &r.dbMigration.Spec.User
+}
+
+func (r *DbMigrationReco) getHistoryTable() string {
+	return shared.Nvl(r.dbMigration.Spec.HistoryTable, shared.DEFAULT_MIGRATIONS_HISTORY_TABLE)
+}
+
+// Applies the pending migrations in order, migrations that were changed after they were applied
+// or that sort before the applied version block all pending migrations
+func (r *DbMigrationReco) migrate() (ctrl.Result, error) {
+	migrations, err := r.loadMigrations()
+	if err != nil {
+		return r.LogAndBackoffCreation(err, &r.dbMigration)
+	}
+	err = r.connect()
+	if err != nil {
+		return r.LogAndBackoffCreation(err, &r.dbMigration)
+	}
+	applied, err := r.conn.GetAppliedMigrations(r.getHistoryTable(), r.getUserName())
+	if err != nil {
+		return r.LogAndBackoffCreation(err, &r.dbMigration)
+	}
+
+	status := r.dbMigration.Status.DeepCopy()
+	status.AppliedVersion = ""
+	for version := range applied {
+		if version > status.AppliedVersion {
+			status.AppliedVersion = version
+		}
+	}
+
+	pending := []shared.Migration{}
+	blockReason, blockMessage := "", ""
+	for _, migration := range migrations {
+		checksum, found := applied[migration.Version]
+		if found {
+			if checksum != migration.Checksum && blockReason == "" {
+				blockReason = REASON_CHECKSUM_MISMATCH
+				blockMessage = fmt.Sprintf("migration %s was changed after it was applied", migration.Version)
+			}
+			continue
+		}
+		if migration.Version < status.AppliedVersion && blockReason == "" {
+			blockReason = REASON_MIGRATION_OUT_OF_ORDER
+			blockMessage = fmt.Sprintf("migration %s sorts before the applied version %s", migration.Version, status.AppliedVersion)
+		}
+		pending = append(pending, migration)
+	}
+	if blockReason != "" {
+		// Nothing is applied until the ConfigMap is fixed, which triggers a new reconcile
+		return ctrl.Result{}, r.setStatus(status, pending, metav1.ConditionFalse, blockReason, blockMessage)
+	}
+
+	for i, migration := range pending {
+		r.Log.Info(fmt.Sprintf("applying migration %s", migration.Version))
+		err = r.conn.ApplyMigration(r.getHistoryTable(), migration, r.getUserName())
+		if err != nil {
+			r.LogError(err, fmt.Sprintf("failed applying migration %s", migration.Version))
+			err = r.setStatus(status, pending[i:], metav1.ConditionFalse, REASON_MIGRATION_FAILED, err.Error())
+			if err != nil {
+				r.LogError(err, "failed updating status")
+			}
+			return shared.GradualBackoffRetry(r.dbMigration.GetCreationTimestamp().Time), nil
+		}
+		status.AppliedVersion = migration.Version
+		if r.Recorder != nil {
+			r.Recorder.Event(&r.dbMigration, v1.EventTypeNormal, EVENT_REASON_MIGRATION_APPLIED, fmt.Sprintf("applied migration %s", migration.Version))
+		}
+	}
+	return ctrl.Result{}, r.setStatus(status, nil, metav1.ConditionTrue, REASON_MIGRATIONS_UP_TO_DATE, "all migrations are applied")
+}
+
+func (r *DbMigrationReco) setStatus(status *dboperatorv1alpha1.DbMigrationStatus, pending []shared.Migration, conditionStatus metav1.ConditionStatus, reason string, message string) error {
+	status.PendingMigrations = nil
+	for _, migration := range pending {
+		status.PendingMigrations = append(status.PendingMigrations, migration.Version)
+	}
+	current := meta.FindStatusCondition(status.Conditions, CONDITION_MIGRATIONS_APPLIED)
+	if conditionStatus == metav1.ConditionFalse && r.Recorder != nil && (current == nil || current.Reason != reason || current.Message != message) {
+		r.Recorder.Event(&r.dbMigration, v1.EventTypeWarning, reason, message)
+	}
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               CONDITION_MIGRATIONS_APPLIED,
+		Status:             conditionStatus,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: r.dbMigration.GetGeneration(),
+	})
+	if reflect.DeepEqual(r.dbMigration.Status, *status) {
+		return nil
+	}
+	r.dbMigration.Status = *status
+	return r.Client.Status().Update(r.Ctx, &r.dbMigration)
+}
+
+func (r *DbMigrationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.With(zap.String("Namespace", req.Namespace)).With(zap.String("Name", req.Name))
+	mr := DbMigrationReco{}
+	mr.Reco = Reco{shared.K8sClient{Client: r.Client, Ctx: ctx, NsNm: req.NamespacedName, Log: log, Recorder: r.Recorder}}
+	err := r.Get(ctx, req.NamespacedName, &mr.dbMigration)
+	if err != nil {
+		if !shared.CannotFindError(err, log, "DbMigration", req.Namespace, req.Name) {
+			mr.LogError(err, fmt.Sprintf("Failed to get DbMigration: %s", req.Name))
+		}
+		return ctrl.Result{}, nil
+	}
+	defer func() {
+		if mr.conn != nil {
+			mr.conn.Close()
+		}
+	}()
+	return mr.migrate()
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *DbMigrationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&dboperatorv1alpha1.DbMigration{}).
+		Watches(&v1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.dbMigrationsForConfigMap)).
+		Complete(r)
+}
+
+func (r *DbMigrationReconciler) dbMigrationsForConfigMap(ctx context.Context, configMap client.Object) []reconcile.Request {
+	dbMigrationList := dboperatorv1alpha1.DbMigrationList{}
+	err := r.List(ctx, &dbMigrationList, client.InNamespace(configMap.GetNamespace()))
+	if err != nil {
+		r.Log.Error(fmt.Sprintf("failed listing DbMigrations for ConfigMap Error: %s", err))
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, dbMigration := range dbMigrationList.Items {
+		if dbMigration.Spec.ConfigMapName == configMap.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: dbMigration.Name, Namespace: dbMigration.Namespace}})
+		}
+	}
+	return requests
+}
@@ -22,22 +22,28 @@ import (
 	"net/http"
 	"reflect"
 	"sort"
+	"strings"
 
 	_ "github.com/lib/pq"
 	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
 	"github.com/obeleh/db-operator/shared"
 	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
+const DB_SERVER_NAME_INDEX = "metadata.name"
+
 // https://yash-kukreja-98.medium.com/develop-on-kubernetes-series-demystifying-the-for-vs-owns-vs-watches-controller-builders-in-c11ab32a046e
 var reconcileDbServerChannel chan event.GenericEvent
 
@@ -61,7 +67,7 @@ type DbServerReconciler struct {
 //+kubebuilder:rbac:groups=db-operator.kubemaster.com,resources=dbservers/finalizers,verbs=update
 
 // Generic Kubebuilder rules:
-// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update
 // +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
 
 func (r *DbServerReconciler) LogError(err error, message string) {
@@ -75,6 +81,8 @@ func (r *DbServerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 	if err != nil {
 		if !shared.CannotFindError(err, r.Log, "DbServer", req.Namespace, req.Name) {
 			r.LogError(err, fmt.Sprintf("Failed to get dbServer: %s", req.Name))
+		} else {
+			shared.SharedConnectionPool.InvalidateServer(req.NamespacedName.String())
 		}
 		return ctrl.Result{}, nil
 	}
@@ -84,6 +92,7 @@ func (r *DbServerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 	deletionTimestamp := dbServer.GetDeletionTimestamp()
 	markedToBeDeleted := deletionTimestamp != nil
 	if markedToBeDeleted {
+		shared.SharedConnectionPool.InvalidateServer(GetDbServerKey(dbServer))
 		err = reco.RemoveFinalizer(dbServer)
 		if err != nil {
 			r.LogError(err, "failed removing finalizer")
@@ -92,72 +101,72 @@ func (r *DbServerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		return ctrl.Result{}, nil
 	}
 
-	databaseNames := []string{}
-	userNames := []string{}
-	var message string
+	status, res := ProbeDbServer(reco, dbServer)
+	err = SetDbServerStatus(reco, dbServer, &dbServer.Status, status)
+	if !res.IsZero() {
+		return res, nil
+	}
+	if err != nil {
+		return shared.RetryAfter(3), nil
+	}
+	r.Log.Info("Reconcile Done", zap.String("Namespace", req.Namespace), zap.String("Name", req.Name))
+	return ctrl.Result{}, nil
+}
+
+// Connects to the server and lists its databases and users, shared by DbServer and ClusterDbServer
+func ProbeDbServer(reco Reco, dbServer *dboperatorv1alpha1.DbServer) (dboperatorv1alpha1.DbServerStatus, ctrl.Result) {
+	status := dboperatorv1alpha1.DbServerStatus{Databases: []string{}, Users: []string{}}
 	conn, err := reco.GetDbConnection(dbServer, nil, nil)
 	if err != nil {
-		r.SetStatus(dbServer, ctx, databaseNames, userNames, false, message, reco)
+		status.Message = fmt.Sprintf("failed building dbConnection %s", err)
 		if !shared.IsHandledErr(err) {
-			message = fmt.Sprintf("failed building dbConnection %s", err)
-			r.LogError(err, message)
+			reco.LogError(err, status.Message)
 		}
-		return shared.GradualBackoffRetry(dbServer.GetCreationTimestamp().Time), nil
+		return status, shared.GradualBackoffRetry(dbServer.GetCreationTimestamp().Time)
 	}
 
 	defer conn.Close()
 	databases, err := conn.GetDbs()
 	if err != nil {
-		message = fmt.Sprintf("Failed reading databases: %s", err)
-		r.LogError(err, message)
-		r.SetStatus(dbServer, ctx, databaseNames, userNames, false, message, reco)
-		return shared.RetryAfter(3), nil
+		status.Message = fmt.Sprintf("Failed reading databases: %s", err)
+		reco.LogError(err, status.Message)
+		return status, shared.RetryAfter(3)
 	}
 	for name := range databases {
-		//r.Log.Info(fmt.Sprintf("Found DB %s", name))
-		databaseNames = append(databaseNames, name)
+		status.Databases = append(status.Databases, name)
 	}
 
 	users, err := conn.GetUsers()
 	if err != nil {
-		message = fmt.Sprintf("Failed reading users: %s", err)
-		r.LogError(err, message)
-		err = r.SetStatus(dbServer, ctx, databaseNames, userNames, false, message, reco)
-		if err != nil {
-			return shared.RetryAfter(3), nil
-		}
-		return ctrl.Result{}, nil
+		status.Message = fmt.Sprintf("Failed reading users: %s", err)
+		reco.LogError(err, status.Message)
+		return status, ctrl.Result{}
 	}
 	for name := range users {
-		userNames = append(userNames, name)
-		//r.Log.Info(fmt.Sprintf("Found user %s", name))
+		status.Users = append(status.Users, name)
 	}
 
-	err = r.SetStatus(dbServer, ctx, databaseNames, userNames, true, "successfully connected to database and retrieved users and databases", reco)
-	if err != nil {
-		return shared.RetryAfter(3), nil
-	}
-	r.Log.Info("Reconcile Done", zap.String("Namespace", req.Namespace), zap.String("Name", req.Name))
-	return ctrl.Result{}, nil
+	sort.Strings(status.Databases)
+	sort.Strings(status.Users)
+	status.ConnectionAvailable = true
+	status.Message = "successfully connected to database and retrieved users and databases"
+	return status, ctrl.Result{}
 }
 
-func (r *DbServerReconciler) SetStatus(dbServer *dboperatorv1alpha1.DbServer, ctx context.Context, databaseNames []string, userNames []string, connectionAvailable bool, statusMessage string, reco Reco) error {
-	sort.Strings(databaseNames)
-	sort.Strings(userNames)
-	changed := reco.AddFinalizerToCr(dbServer)
-	newStatus := dboperatorv1alpha1.DbServerStatus{Databases: databaseNames, Users: userNames, ConnectionAvailable: connectionAvailable, Message: statusMessage}
+func SetDbServerStatus(reco Reco, cr client.Object, status *dboperatorv1alpha1.DbServerStatus, newStatus dboperatorv1alpha1.DbServerStatus) error {
+	changed := reco.AddFinalizerToCr(cr)
 	if changed {
-		dbServer.Status = newStatus
-		err := reco.Client.Update(reco.Ctx, dbServer)
+		*status = newStatus
+		err := reco.Client.Update(reco.Ctx, cr)
 		if err != nil {
 			return err
 		}
-	} else if !reflect.DeepEqual(dbServer.Status, newStatus) {
-		dbServer.Status = newStatus
-		err := r.Status().Update(ctx, dbServer)
+	} else if !reflect.DeepEqual(*status, newStatus) {
+		*status = newStatus
+		err := reco.Client.Status().Update(reco.Ctx, cr)
 		if err != nil {
 			message := fmt.Sprintf("failed patching status %s", err)
-			r.Log.Info(message)
+			reco.Log.Info(message)
 			return fmt.Errorf(message)
 		}
 	}
@@ -168,12 +177,46 @@ func (r *DbServerReconciler) SetStatus(dbServer *dboperatorv1alpha1.DbServer, ct
 func (r *DbServerReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	channelSource := source.Channel{Source: reconcileDbServerChannel}
 
+	// Lets GetDbServer find DbServers by name across namespaces without listing all of them
+	err := mgr.GetFieldIndexer().IndexField(context.Background(), &dboperatorv1alpha1.DbServer{}, DB_SERVER_NAME_INDEX, func(obj client.Object) []string {
+		return []string{obj.GetName()}
+	})
+	if err != nil {
+		return err
+	}
+
+	// Reconciling after a Secret change replaces the pooled connections that use the old credentials
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&dboperatorv1alpha1.DbServer{}).
 		WatchesRawSource(&channelSource, &handler.EnqueueRequestForObject{}).
+		Watches(&v1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.dbServersForSecret)).
 		Complete(r)
 }
 
+func (r *DbServerReconciler) dbServersForSecret(ctx context.Context, secret client.Object) []reconcile.Request {
+	dbServerList := dboperatorv1alpha1.DbServerList{}
+	err := r.List(ctx, &dbServerList, client.InNamespace(secret.GetNamespace()))
+	if err != nil {
+		r.LogError(err, "failed listing DbServers for Secret")
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, dbServer := range dbServerList.Items {
+		if dbServer.Spec.SecretName == secret.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: dbServer.Name, Namespace: dbServer.Namespace}})
+		}
+	}
+	return requests
+}
+
+// Identifies the DbServer in the shared connection pool
+func GetDbServerKey(dbServer *dboperatorv1alpha1.DbServer) string {
+	if dbServer.Kind == dboperatorv1alpha1.CLUSTER_DB_SERVER_KIND {
+		return GetClusterDbServerKey(dbServer.Name)
+	}
+	return types.NamespacedName{Name: dbServer.Name, Namespace: dbServer.Namespace}.String()
+}
+
 func (rc *DbServerReconciler) AddFinalizerToCr(cr client.Object) bool {
 	if !controllerutil.ContainsFinalizer(cr, DB_OPERATOR_FINALIZER) {
 		controllerutil.AddFinalizer(cr, DB_OPERATOR_FINALIZER)
@@ -182,32 +225,60 @@ func (rc *DbServerReconciler) AddFinalizerToCr(cr client.Object) bool {
 	return false
 }
 
-// Gets DB server, it will prefer the local namespace but can go through Global namespaces as well
-func GetDbServer(dbServerName string, apiClient client.Client, localNamespace string) (*dboperatorv1alpha1.DbServer, error) {
-	dbServerList := dboperatorv1alpha1.DbServerList{}
-
-	err := apiClient.List(context.Background(), &dbServerList, &client.ListOptions{})
+// Gets DB server, without a namespace in the reference it will prefer the local namespace but can go through Global namespaces as well
+// Returns an AccessDeniedError when the local namespace isn't in the allowed_namespaces of the DbServer
+func GetDbServer(dbServerRef dboperatorv1alpha1.DbServerRef, apiClient client.Client, localNamespace string) (*dboperatorv1alpha1.DbServer, error) {
+	dbServer, err := LookupDbServer(dbServerRef, apiClient, localNamespace)
 	if err != nil {
 		return nil, err
 	}
+	err = CheckNamespaceAllowed(context.Background(), apiClient, dbServer, localNamespace)
+	if err != nil {
+		return nil, err
+	}
+	return dbServer, nil
+}
 
-	var dbServers []dboperatorv1alpha1.DbServer
-	for _, dbServer := range dbServerList.Items {
-		if dbServer.Name == dbServerName {
-			dbServers = append(dbServers, dbServer)
-			if dbServer.Namespace == localNamespace {
-				return &dbServer, nil
-			}
+// Resolves a DbServer reference like GetDbServer without checking access
+// Callers have to authorize the use of the DbServer themselves, usually with AuthorizeDbServerUse to record the outcome
+func LookupDbServer(dbServerRef dboperatorv1alpha1.DbServerRef, apiClient client.Client, localNamespace string) (*dboperatorv1alpha1.DbServer, error) {
+	ctx := context.Background()
+	if dbServerRef.Kind == dboperatorv1alpha1.CLUSTER_DB_SERVER_KIND {
+		return GetClusterDbServer(dbServerRef.Name, apiClient)
+	}
+
+	dbServer := &dboperatorv1alpha1.DbServer{}
+	if dbServerRef.Namespace != "" {
+		err := apiClient.Get(ctx, types.NamespacedName{Name: dbServerRef.Name, Namespace: dbServerRef.Namespace}, dbServer)
+		if err != nil {
+			return nil, err
 		}
+		return dbServer, nil
+	}
+
+	err := apiClient.Get(ctx, types.NamespacedName{Name: dbServerRef.Name, Namespace: localNamespace}, dbServer)
+	if err == nil {
+		return dbServer, nil
+	}
+	if !errors.IsNotFound(err) {
+		return nil, err
+	}
+
+	dbServerList := dboperatorv1alpha1.DbServerList{}
+	err = apiClient.List(ctx, &dbServerList, client.MatchingFields{DB_SERVER_NAME_INDEX: dbServerRef.Name})
+	if err != nil {
+		return nil, err
 	}
 
-	cnt := len(dbServers)
+	cnt := len(dbServerList.Items)
 	if cnt == 1 {
-		dbServer := dbServers[0]
-		return &dbServer, nil
+		return &dbServerList.Items[0], nil
 	}
 
 	if cnt == 0 {
+		if dbServerRef.Kind == "" {
+			return GetClusterDbServer(dbServerRef.Name, apiClient)
+		}
 		return nil, &errors.StatusError{ErrStatus: metav1.Status{
 			Status: metav1.StatusFailure,
 			Code:   http.StatusNotFound,
@@ -216,5 +287,9 @@ func GetDbServer(dbServerName string, apiClient client.Client, localNamespace st
 	}
 
 	// cnt > 1
-	return nil, fmt.Errorf("got %d results, unable to pick", cnt)
+	namespaces := []string{}
+	for _, dbServer := range dbServerList.Items {
+		namespaces = append(namespaces, dbServer.Namespace)
+	}
+	return nil, fmt.Errorf("got %d DbServers named %s in namespaces %s, unable to pick, set the namespace in the DbServer reference", cnt, dbServerRef.Name, strings.Join(namespaces, ", "))
 }
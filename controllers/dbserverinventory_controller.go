@@ -0,0 +1,354 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/shared"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const DEFAULT_INVENTORY_INTERVAL = time.Hour
+
+// Objects every server has and the database with the managed-by markers, these are never reported as orphans
+var BUILT_IN_USERS = []string{"postgres", "root", "admin", "mysql.*"}
+var BUILT_IN_DATABASES = []string{"postgres", "defaultdb", "system", "db_operator"}
+var BUILT_IN_SCHEMAS = []string{"public", "pg_*", "crdb_internal"}
+
+// DbServerInventoryReconciler reconciles a DbServerInventory object
+type DbServerInventoryReconciler struct {
+	client.Client
+	Log    *zap.Logger
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=db-operator.kubemaster.com,resources=dbserverinventories,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=db-operator.kubemaster.com,resources=dbserverinventories/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=db-operator.kubemaster.com,resources=dbserverinventories/finalizers,verbs=update
+
+func (r *DbServerInventoryReconciler) LogError(err error, message string) {
+	r.Log.Error(fmt.Sprintf("%s Error: %s", message, err))
+}
+
+func (r *DbServerInventoryReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	r.Log.Info("Reconcile", zap.String("Namespace", req.Namespace), zap.String("Name", req.Name))
+	inventory := &dboperatorv1alpha1.DbServerInventory{}
+	err := r.Get(ctx, req.NamespacedName, inventory)
+	if err != nil {
+		if !shared.CannotFindError(err, r.Log, "DbServerInventory", req.Namespace, req.Name) {
+			r.LogError(err, fmt.Sprintf("Failed to get DbServerInventory: %s", req.Name))
+		}
+		return ctrl.Result{}, nil
+	}
+
+	interval := DEFAULT_INVENTORY_INTERVAL
+	if inventory.Spec.Interval != nil {
+		interval = inventory.Spec.Interval.Duration
+	}
+
+	reco := Reco{shared.K8sClient{Client: r.Client, Ctx: ctx, NsNm: req.NamespacedName, Log: r.Log}}
+	status, err := r.takeInventory(reco, inventory)
+	if err != nil {
+		r.LogError(err, "failed taking inventory")
+		status = inventory.Status
+		status.Message = fmt.Sprintf("failed taking inventory: %s", err)
+	}
+
+	if !reflect.DeepEqual(inventory.Status, status) {
+		inventory.Status = status
+		err = r.Status().Update(ctx, inventory)
+		if err != nil {
+			r.LogError(err, "failed updating DbServerInventory status")
+			return shared.RetryAfter(3), nil
+		}
+	}
+	r.Log.Info("Reconcile Done", zap.String("Namespace", req.Namespace), zap.String("Name", req.Name))
+	return ctrl.Result{RequeueAfter: interval}, nil
+}
+
+// Reads the databases and users on the server and cross references them with the CRs
+func (r *DbServerInventoryReconciler) takeInventory(reco Reco, inventory *dboperatorv1alpha1.DbServerInventory) (dboperatorv1alpha1.DbServerInventoryStatus, error) {
+	status := dboperatorv1alpha1.DbServerInventoryStatus{}
+	dbServer, err := LookupDbServer(inventory.Spec.DbServerRef, r.Client, inventory.Namespace)
+	if err != nil {
+		return status, err
+	}
+	err = reco.CheckNamespaceAllowed(dbServer, inventory.Namespace)
+	if err != nil {
+		return status, err
+	}
+
+	conn, err := reco.GetDbConnection(dbServer, nil, nil)
+	if err != nil {
+		return status, err
+	}
+	defer conn.Close()
+	serverDbs, err := conn.GetDbs()
+	if err != nil {
+		return status, err
+	}
+	serverUsers, err := conn.GetUsers()
+	if err != nil {
+		return status, err
+	}
+
+	getSchemas := func(dbName string) (map[string]shared.DbSideSchema, error) {
+		return getServerSchemas(reco, dbServer, dbName)
+	}
+	status, err = r.crossReference(reco.Ctx, inventory, dbServer, serverDbs, serverUsers, getSchemas)
+	if err != nil {
+		return status, err
+	}
+
+	takenAt := metav1.Now()
+	if inventory.Status.TakenAt != nil && sameInventory(inventory.Status, status) {
+		// Keeps the status unchanged when nothing changed so the inventory isn't rewritten every interval
+		takenAt = *inventory.Status.TakenAt
+	}
+	status.TakenAt = &takenAt
+	status.Message = fmt.Sprintf("%d orphan databases, %d orphan users, %d orphan schemas and %d missing objects",
+		len(status.OrphanDatabases), len(status.OrphanUsers), len(status.OrphanSchemas), len(status.MissingObjects))
+	return status, nil
+}
+
+// Cross references the objects on the server with the Db, User and Schema CRs that use the server.
+// An inventory in the namespace of the DbServer, the operator namespace for a ClusterDbServer, covers every namespace.
+// An inventory in another namespace only lists the missing objects of its own CRs and the orphan schemas in its own
+// databases, the CRs of other namespaces and the objects nobody manages are not shown to it.
+func (r *DbServerInventoryReconciler) crossReference(ctx context.Context, inventory *dboperatorv1alpha1.DbServerInventory, dbServer *dboperatorv1alpha1.DbServer,
+	serverDbs map[string]shared.DbSideDb, serverUsers map[string]shared.DbSideUser, getSchemas func(dbName string) (map[string]shared.DbSideSchema, error)) (dboperatorv1alpha1.DbServerInventoryStatus, error) {
+	status := dboperatorv1alpha1.DbServerInventoryStatus{}
+	allNamespaces := inventory.Namespace == dbServer.Namespace
+	isReported := func(namespace string) bool {
+		return allNamespaces || namespace == inventory.Namespace
+	}
+
+	serverKey := GetDbServerKey(dbServer)
+	usesServer := r.serverMatcher(ctx, serverKey)
+
+	dbList := dboperatorv1alpha1.DbList{}
+	err := r.List(ctx, &dbList)
+	if err != nil {
+		return status, err
+	}
+	userList := dboperatorv1alpha1.UserList{}
+	err = r.List(ctx, &userList)
+	if err != nil {
+		return status, err
+	}
+	schemaList := dboperatorv1alpha1.SchemaList{}
+	err = r.List(ctx, &schemaList)
+	if err != nil {
+		return status, err
+	}
+
+	isMySql := strings.ToLower(dbServer.Spec.ServerType) == "mysql"
+	// Objects managed by a CR in any namespace are never orphans, reportedDbs are the databases of the reported namespaces
+	managedDbs := map[string]bool{}
+	reportedDbs := map[string]bool{}
+	// Db CR names by namespace, Schemas refer to their Db by CR name
+	dbNames := map[types.NamespacedName]string{}
+	for _, db := range dbList.Items {
+		if !usesServer(db.Namespace, db.Spec.GetServerRef()) {
+			continue
+		}
+		managedDbs[db.Spec.DbName] = true
+		dbNames[types.NamespacedName{Namespace: db.Namespace, Name: db.Name}] = db.Spec.DbName
+		if !isReported(db.Namespace) {
+			continue
+		}
+		reportedDbs[db.Spec.DbName] = true
+		if _, exists := serverDbs[db.Spec.DbName]; !exists {
+			status.MissingObjects = append(status.MissingObjects, dboperatorv1alpha1.MissingObject{
+				Kind: "Db", Namespace: db.Namespace, Name: db.Name, Object: db.Spec.DbName,
+			})
+		}
+	}
+
+	managedUsers := map[string]bool{dbServer.Spec.UserName: true}
+	for _, user := range userList.Items {
+		if !usesServer(user.Namespace, user.Spec.GetDbServerRef()) {
+			continue
+		}
+		managedUsers[user.Spec.UserName] = true
+		if !isReported(user.Namespace) {
+			continue
+		}
+		if _, exists := serverUsers[user.Spec.UserName]; !exists {
+			status.MissingObjects = append(status.MissingObjects, dboperatorv1alpha1.MissingObject{
+				Kind: "User", Namespace: user.Namespace, Name: user.Name, Object: user.Spec.UserName,
+			})
+		}
+	}
+
+	// Schemas by database of the reported namespaces, MySQL schemas are databases
+	managedSchemas := map[string]map[string]bool{}
+	for _, schema := range schemaList.Items {
+		if !usesServer(schema.Namespace, schema.Spec.GetServerRef()) {
+			continue
+		}
+		if isMySql {
+			managedDbs[schema.Spec.Name] = true
+			if !isReported(schema.Namespace) {
+				continue
+			}
+			if _, exists := serverDbs[schema.Spec.Name]; !exists {
+				status.MissingObjects = append(status.MissingObjects, dboperatorv1alpha1.MissingObject{
+					Kind: "Schema", Namespace: schema.Namespace, Name: schema.Name, Object: schema.Spec.Name,
+				})
+			}
+			continue
+		}
+		if !isReported(schema.Namespace) {
+			continue
+		}
+		dbName := schemaDbName(schema, dbNames)
+		if managedSchemas[dbName] == nil {
+			managedSchemas[dbName] = map[string]bool{}
+		}
+		managedSchemas[dbName][schema.Spec.Name] = true
+	}
+
+	if !isMySql {
+		// Only the databases that are reported or have reported schemas are scanned for schemas
+		scannedDbs := map[string]bool{}
+		for dbName := range reportedDbs {
+			scannedDbs[dbName] = true
+		}
+		for dbName := range managedSchemas {
+			scannedDbs[dbName] = true
+		}
+		for dbName := range scannedDbs {
+			// In a database that doesn't exist every Schema is missing
+			var serverSchemas map[string]shared.DbSideSchema
+			if _, exists := serverDbs[dbName]; exists {
+				serverSchemas, err = getSchemas(dbName)
+				if err != nil {
+					return status, err
+				}
+			}
+			for schemaName := range serverSchemas {
+				if !managedSchemas[dbName][schemaName] && reportedDbs[dbName] && !matchesAny(schemaName, BUILT_IN_SCHEMAS, inventory.Spec.IgnoreSchemas) {
+					status.OrphanSchemas = append(status.OrphanSchemas, fmt.Sprintf("%s.%s", dbName, schemaName))
+				}
+			}
+			for _, schema := range schemaList.Items {
+				if !isReported(schema.Namespace) || !usesServer(schema.Namespace, schema.Spec.GetServerRef()) || schemaDbName(schema, dbNames) != dbName {
+					continue
+				}
+				if _, exists := serverSchemas[schema.Spec.Name]; !exists {
+					status.MissingObjects = append(status.MissingObjects, dboperatorv1alpha1.MissingObject{
+						Kind: "Schema", Namespace: schema.Namespace, Name: schema.Name, Object: fmt.Sprintf("%s.%s", dbName, schema.Spec.Name),
+					})
+				}
+			}
+		}
+	}
+
+	if allNamespaces {
+		for dbName := range serverDbs {
+			if !managedDbs[dbName] && !matchesAny(dbName, BUILT_IN_DATABASES, inventory.Spec.IgnoreDatabases) {
+				status.OrphanDatabases = append(status.OrphanDatabases, dbName)
+			}
+		}
+		for userName := range serverUsers {
+			if !managedUsers[userName] && !matchesAny(userName, BUILT_IN_USERS, inventory.Spec.IgnoreUsers) {
+				status.OrphanUsers = append(status.OrphanUsers, userName)
+			}
+		}
+	}
+
+	sort.Strings(status.OrphanDatabases)
+	sort.Strings(status.OrphanUsers)
+	sort.Strings(status.OrphanSchemas)
+	sort.Slice(status.MissingObjects, func(i, j int) bool {
+		a, b := status.MissingObjects[i], status.MissingObjects[j]
+		return fmt.Sprintf("%s/%s/%s", a.Kind, a.Namespace, a.Name) < fmt.Sprintf("%s/%s/%s", b.Kind, b.Namespace, b.Name)
+	})
+
+	return status, nil
+}
+
+// Returns a function that tells whether a CR in a namespace with a DbServer reference uses the server, lookups are cached
+func (r *DbServerInventoryReconciler) serverMatcher(ctx context.Context, serverKey string) func(namespace string, ref dboperatorv1alpha1.DbServerRef) bool {
+	cache := map[string]bool{}
+	return func(namespace string, ref dboperatorv1alpha1.DbServerRef) bool {
+		cacheKey := fmt.Sprintf("%s/%s/%s/%s", namespace, ref.Kind, ref.Namespace, ref.Name)
+		uses, found := cache[cacheKey]
+		if !found {
+			dbServer, err := LookupDbServer(ref, r.Client, namespace)
+			uses = err == nil && GetDbServerKey(dbServer) == serverKey
+			cache[cacheKey] = uses
+		}
+		return uses
+	}
+}
+
+func getServerSchemas(reco Reco, dbServer *dboperatorv1alpha1.DbServer, dbName string) (map[string]shared.DbSideSchema, error) {
+	conn, err := reco.GetDbConnection(dbServer, nil, &dbName)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return conn.GetSchemas(nil)
+}
+
+// Schemas refer to the Db CR, its db_name is the database on the server
+func schemaDbName(schema dboperatorv1alpha1.Schema, dbNames map[types.NamespacedName]string) string {
+	dbName, found := dbNames[types.NamespacedName{Namespace: schema.Namespace, Name: schema.Spec.DbName}]
+	if !found {
+		return schema.Spec.DbName
+	}
+	return dbName
+}
+
+func matchesAny(name string, patternLists ...[]string) bool {
+	for _, patterns := range patternLists {
+		for _, pattern := range patterns {
+			matched, err := path.Match(pattern, name)
+			if err == nil && matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sameInventory(a dboperatorv1alpha1.DbServerInventoryStatus, b dboperatorv1alpha1.DbServerInventoryStatus) bool {
+	return reflect.DeepEqual(a.OrphanDatabases, b.OrphanDatabases) && reflect.DeepEqual(a.OrphanUsers, b.OrphanUsers) &&
+		reflect.DeepEqual(a.OrphanSchemas, b.OrphanSchemas) && reflect.DeepEqual(a.MissingObjects, b.MissingObjects)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *DbServerInventoryReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&dboperatorv1alpha1.DbServerInventory{}).
+		Complete(r)
+}
@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/shared"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestInventoryCrossReference(t *testing.T) {
+	postgresRef := &dboperatorv1alpha1.DbServerRef{Name: "postgres", Namespace: "databases"}
+	db := func(namespace string, name string, dbName string, ref *dboperatorv1alpha1.DbServerRef) *dboperatorv1alpha1.Db {
+		return &dboperatorv1alpha1.Db{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       dboperatorv1alpha1.DbSpec{DbName: dbName, ServerRef: ref},
+		}
+	}
+	user := func(namespace string, name string, userName string) *dboperatorv1alpha1.User {
+		return &dboperatorv1alpha1.User{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       dboperatorv1alpha1.UserSpec{UserName: userName, DbServerRef: postgresRef},
+		}
+	}
+	wiki := db("blog", "wiki", "wiki", nil)
+	// Found through the DbServer name index, there is only one DbServer named postgres
+	wiki.Spec.Server = "postgres"
+	apiClient := newFakeClient(t,
+		&dboperatorv1alpha1.DbServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "postgres", Namespace: "databases"},
+			Spec:       dboperatorv1alpha1.DbServerSpec{ServerType: "postgres", UserName: "postgres"},
+		},
+		&dboperatorv1alpha1.DbServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "shop"},
+			Spec:       dboperatorv1alpha1.DbServerSpec{ServerType: "postgres", UserName: "postgres"},
+		},
+		db("shop", "shop", "shop", postgresRef),
+		db("shop", "cache", "cache", postgresRef),
+		db("shop", "reports", "reports", &dboperatorv1alpha1.DbServerRef{Name: "other"}),
+		db("blog", "blog", "blog", postgresRef),
+		wiki,
+		user("shop", "shop", "shop_user"),
+		user("shop", "reports", "reports_user"),
+		user("blog", "blog", "blog_user"),
+		&dboperatorv1alpha1.Schema{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "shop"},
+			Spec:       dboperatorv1alpha1.SchemaSpec{DbName: "shop", Name: "app", ServerRef: postgresRef},
+		},
+	)
+	dbServer, err := LookupDbServer(*postgresRef, apiClient, "databases")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	serverDbs := map[string]shared.DbSideDb{"postgres": {}, "shop": {}, "blog": {}, "legacy": {}}
+	serverUsers := map[string]shared.DbSideUser{"postgres": {}, "shop_user": {}, "blog_user": {}, "stray": {}}
+	scannedDbs := []string{}
+	getSchemas := func(dbName string) (map[string]shared.DbSideSchema, error) {
+		scannedDbs = append(scannedDbs, dbName)
+		return map[string]shared.DbSideSchema{"public": {}, "app": {}, "scratch": {}}, nil
+	}
+	reconciler := &DbServerInventoryReconciler{Client: apiClient, Log: zap.NewNop()}
+
+	inventory := &dboperatorv1alpha1.DbServerInventory{ObjectMeta: metav1.ObjectMeta{Name: "inventory", Namespace: "databases"}}
+	status, err := reconciler.crossReference(context.Background(), inventory, dbServer, serverDbs, serverUsers, getSchemas)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	expected := dboperatorv1alpha1.DbServerInventoryStatus{
+		OrphanDatabases: []string{"legacy"},
+		OrphanUsers:     []string{"stray"},
+		OrphanSchemas:   []string{"blog.app", "blog.scratch", "shop.scratch"},
+		MissingObjects: []dboperatorv1alpha1.MissingObject{
+			{Kind: "Db", Namespace: "blog", Name: "wiki", Object: "wiki"},
+			{Kind: "Db", Namespace: "shop", Name: "cache", Object: "cache"},
+			{Kind: "User", Namespace: "shop", Name: "reports", Object: "reports_user"},
+		},
+	}
+	if !reflect.DeepEqual(status, expected) {
+		t.Errorf("expected the inventory of every namespace %+v got %+v", expected, status)
+	}
+
+	// Other namespaces don't see each others resources or the objects nobody manages
+	scannedDbs = []string{}
+	inventory.Namespace = "shop"
+	status, err = reconciler.crossReference(context.Background(), inventory, dbServer, serverDbs, serverUsers, getSchemas)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	expected = dboperatorv1alpha1.DbServerInventoryStatus{
+		OrphanSchemas: []string{"shop.scratch"},
+		MissingObjects: []dboperatorv1alpha1.MissingObject{
+			{Kind: "Db", Namespace: "shop", Name: "cache", Object: "cache"},
+			{Kind: "User", Namespace: "shop", Name: "reports", Object: "reports_user"},
+		},
+	}
+	if !reflect.DeepEqual(status, expected) {
+		t.Errorf("expected the inventory of namespace shop %+v got %+v", expected, status)
+	}
+	if !reflect.DeepEqual(scannedDbs, []string{"shop"}) {
+		t.Errorf("expected only the shop database to be scanned got %v", scannedDbs)
+	}
+}
@@ -0,0 +1,215 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/shared"
+)
+
+// ExtensionReconciler reconciles a Extension object
+type ExtensionReconciler struct {
+	client.Client
+	Log      *zap.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=db-operator.kubemaster.com,resources=extensions,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=db-operator.kubemaster.com,resources=extensions/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=db-operator.kubemaster.com,resources=extensions/finalizers,verbs=update
+
+const EVENT_REASON_EXTENSION_UPDATED = "ExtensionUpdated"
+
+type ExtensionReco struct {
+	Reco
+	db         dboperatorv1alpha1.Db
+	extension  dboperatorv1alpha1.Extension
+	extensions map[string]shared.DbSideExtension
+	conn       shared.DbServerConnectionInterface
+}
+
+func (r *ExtensionReco) LoadCR() (ctrl.Result, error) {
+	err := r.Client.Get(r.Ctx, r.NsNm, &r.extension)
+	if err != nil {
+		r.Log.Info(fmt.Sprintf("%T: %s does not exist, %s", r.extension, r.NsNm.Name, err))
+		return ctrl.Result{}, err
+	}
+
+	dbNsm := types.NamespacedName{
+		Namespace: r.NsNm.Namespace,
+		Name:      r.extension.Spec.Db,
+	}
+	err = r.Client.Get(r.Ctx, dbNsm, &r.db)
+	if err != nil {
+		if r.extension.GetDeletionTimestamp() != nil {
+			// Db got deleted before the extension did
+			err = r.RemoveFinalizer(&r.extension)
+			if err != nil {
+				return shared.GradualBackoffRetry(r.extension.GetCreationTimestamp().Time), nil
+			}
+			return ctrl.Result{}, nil
+		}
+		r.Log.Info(fmt.Sprintf("%T: %s does not exist, %s", r.db, dbNsm, err))
+		return shared.GradualBackoffRetry(r.extension.GetCreationTimestamp().Time), nil
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *ExtensionReco) LoadObj() (bool, error) {
+	dbServer, err := LookupDbServer(r.db.Spec.GetServerRef(), r.Client, r.NsNm.Namespace)
+	if err != nil {
+		if !shared.CannotFindError(err, r.Log, "DbServer", r.NsNm.Namespace, r.NsNm.Name) {
+			r.LogError(err, "failed getting DbServer")
+			return false, err
+		}
+		return false, nil
+	}
+	err = r.AuthorizeDbServerUse(dbServer, &r.extension, &r.extension.Status.Conditions, nil)
+	if err != nil {
+		return false, err
+	}
+
+	conn, err := r.GetDbConnection(dbServer, nil, &r.db.Spec.DbName)
+	if err != nil {
+		r.LogError(err, "failed building dbConnection")
+		return false, err
+	}
+	r.conn = conn
+
+	extensions, err := r.conn.GetExtensions()
+	if err != nil {
+		r.LogError(err, "failed getting Extensions")
+		return false, err
+	}
+	r.extensions = extensions
+	_, exists := r.extensions[r.extension.Spec.Name]
+	return exists, nil
+}
+
+func (r *ExtensionReco) CreateObj() (ctrl.Result, error) {
+	if r.conn == nil {
+		err := fmt.Errorf("no database connection possible")
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	r.Log.Info(fmt.Sprintf("Creating extension %s in db %s", r.extension.Spec.Name, r.db.Spec.DbName))
+	err := r.conn.CreateExtension(r.extension.Spec)
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	err = r.updateStatus(true, true)
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *ExtensionReco) RemoveObj() (ctrl.Result, error) {
+	if !r.extension.Spec.DropOnDeletion {
+		r.Log.Info(fmt.Sprintf("did not drop extension %s in db %s as per spec", r.extension.Spec.Name, r.db.Spec.DbName))
+		return ctrl.Result{}, nil
+	}
+	if !r.extension.Status.Created {
+		// The extension was already installed, other objects in the database may depend on it
+		message := fmt.Sprintf("not dropping extension %s in db %s, it wasn't installed by this resource", r.extension.Spec.Name, r.db.Spec.DbName)
+		r.Log.Info(message)
+		if r.Recorder != nil {
+			r.Recorder.Event(&r.extension, v1.EventTypeWarning, EVENT_REASON_DROP_REFUSED, message)
+		}
+		return ctrl.Result{}, nil
+	}
+	r.Log.Info(fmt.Sprintf("dropping extension %s in db %s", r.extension.Spec.Name, r.db.Spec.DbName))
+	err := r.conn.DropExtension(r.extension.Spec.Name, r.extension.Spec.CascadeOnDrop)
+	if err != nil {
+		return r.LogAndBackoffDeletion(err, r.GetCR())
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *ExtensionReco) GetCR() client.Object {
+	return &r.extension
+}
+
+func (r *ExtensionReco) EnsureCorrect() (ctrl.Result, error) {
+	changed, err := r.conn.UpdateExtension(r.extension.Spec)
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	if changed {
+		message := fmt.Sprintf("updated extension %s in db %s", r.extension.Spec.Name, r.db.Spec.DbName)
+		r.Log.Info(message)
+		if r.Recorder != nil {
+			r.Recorder.Event(&r.extension, v1.EventTypeNormal, EVENT_REASON_EXTENSION_UPDATED, message)
+		}
+	}
+	err = r.updateStatus(changed, false)
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	return ctrl.Result{}, nil
+}
+
+// Shows the installed version and schema from pg_extension, reload after changing the extension
+func (r *ExtensionReco) updateStatus(reload bool, created bool) error {
+	if reload {
+		extensions, err := r.conn.GetExtensions()
+		if err != nil {
+			return err
+		}
+		r.extensions = extensions
+	}
+	installed := r.extensions[r.extension.Spec.Name]
+	if r.extension.Status.InstalledVersion == installed.Version && r.extension.Status.InstalledSchema == installed.Schema &&
+		(r.extension.Status.Created || !created) {
+		return nil
+	}
+	r.extension.Status.InstalledVersion = installed.Version
+	r.extension.Status.InstalledSchema = installed.Schema
+	r.extension.Status.Created = r.extension.Status.Created || created
+	return r.Client.Status().Update(r.Ctx, &r.extension)
+}
+
+func (r *ExtensionReco) CleanupConn() {
+	if r.conn != nil {
+		r.conn.Close()
+	}
+}
+
+func (r *ExtensionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.With(zap.String("Namespace", req.Namespace)).With(zap.String("Name", req.Name))
+	er := ExtensionReco{}
+	er.Reco = Reco{shared.K8sClient{Client: r.Client, Ctx: ctx, NsNm: req.NamespacedName, Log: log, Recorder: r.Recorder}}
+	return er.Reco.Reconcile(&er)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ExtensionReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&dboperatorv1alpha1.Extension{}).
+		Complete(r)
+}
@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"testing"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/shared"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Records the dropped extensions, the other methods of the interface are never called
+type extensionConnection struct {
+	shared.DbServerConnectionInterface
+	dropped []string
+}
+
+func (c *extensionConnection) DropExtension(name string, cascade bool) error {
+	c.dropped = append(c.dropped, name)
+	return nil
+}
+
+func TestExtensionRemoveObjOnlyDropsCreatedExtensions(t *testing.T) {
+	conn := &extensionConnection{}
+	reco := ExtensionReco{
+		Reco: Reco{*newK8sClient(newFakeClient(t), "shop")},
+		db:   dboperatorv1alpha1.Db{Spec: dboperatorv1alpha1.DbSpec{DbName: "shop"}},
+		extension: dboperatorv1alpha1.Extension{
+			ObjectMeta: metav1.ObjectMeta{Name: "pgcrypto", Namespace: "shop"},
+			Spec:       dboperatorv1alpha1.ExtensionSpec{Db: "shop", Name: "pgcrypto", DropOnDeletion: true},
+		},
+		conn: conn,
+	}
+
+	_, err := reco.RemoveObj()
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if len(conn.dropped) != 0 {
+		t.Errorf("expected an extension that was already installed to be kept got %v", conn.dropped)
+	}
+
+	reco.extension.Status.Created = true
+	_, err = reco.RemoveObj()
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if len(conn.dropped) != 1 || conn.dropped[0] != "pgcrypto" {
+		t.Errorf("expected the installed extension to be dropped got %v", conn.dropped)
+	}
+}
@@ -0,0 +1,365 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/dbservers/postgres"
+	"github.com/obeleh/db-operator/shared"
+	funk "github.com/thoas/go-funk"
+)
+
+// ForeignServerReconciler reconciles a ForeignServer object
+type ForeignServerReconciler struct {
+	client.Client
+	Log      *zap.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=db-operator.kubemaster.com,resources=foreignservers,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=db-operator.kubemaster.com,resources=foreignservers/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=db-operator.kubemaster.com,resources=foreignservers/finalizers,verbs=update
+
+const (
+	EVENT_REASON_FOREIGN_SERVER_UPDATED  = "ForeignServerUpdated"
+	EVENT_REASON_FOREIGN_SCHEMA_IMPORTED = "ForeignSchemaImported"
+)
+
+type ForeignServerReco struct {
+	Reco
+	db                  dboperatorv1alpha1.Db
+	remoteDb            dboperatorv1alpha1.Db
+	foreignServer       dboperatorv1alpha1.ForeignServer
+	dbSideForeignServer *shared.DbSideForeignServer
+	remoteServer        *dboperatorv1alpha1.DbServer
+	conn                shared.DbServerConnectionInterface
+}
+
+func (r *ForeignServerReco) LoadCR() (ctrl.Result, error) {
+	err := r.Client.Get(r.Ctx, r.NsNm, &r.foreignServer)
+	if err != nil {
+		r.Log.Info(fmt.Sprintf("%T: %s does not exist, %s", r.foreignServer, r.NsNm.Name, err))
+		return ctrl.Result{}, err
+	}
+
+	dbNsm := types.NamespacedName{
+		Namespace: r.NsNm.Namespace,
+		Name:      r.foreignServer.Spec.Db,
+	}
+	err = r.Client.Get(r.Ctx, dbNsm, &r.db)
+	if err != nil {
+		if r.foreignServer.GetDeletionTimestamp() != nil {
+			// Db got deleted before the foreign server did
+			err = r.RemoveFinalizer(&r.foreignServer)
+			if err != nil {
+				return shared.GradualBackoffRetry(r.foreignServer.GetCreationTimestamp().Time), nil
+			}
+			return ctrl.Result{}, nil
+		}
+		r.Log.Info(fmt.Sprintf("%T: %s does not exist, %s", r.db, dbNsm, err))
+		return shared.GradualBackoffRetry(r.foreignServer.GetCreationTimestamp().Time), nil
+	}
+	if r.foreignServer.GetDeletionTimestamp() != nil {
+		// Dropping the server only needs the local db
+		return ctrl.Result{}, nil
+	}
+
+	remoteDbNsm := types.NamespacedName{
+		Namespace: r.NsNm.Namespace,
+		Name:      r.foreignServer.Spec.RemoteDb,
+	}
+	err = r.Client.Get(r.Ctx, remoteDbNsm, &r.remoteDb)
+	if err != nil {
+		r.Log.Info(fmt.Sprintf("%T: %s does not exist, %s", r.remoteDb, remoteDbNsm, err))
+		return shared.GradualBackoffRetry(r.foreignServer.GetCreationTimestamp().Time), nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// User that runs IMPORT FOREIGN SCHEMA, it needs a user mapping to connect to the remote db
+func (r *ForeignServerReco) getImportUser() (string, error) {
+	importSpec := r.foreignServer.Spec.ImportForeignSchema
+	if importSpec.User != "" {
+		return importSpec.User, nil
+	}
+	if len(r.foreignServer.Spec.UserMappings) == 0 {
+		return "", fmt.Errorf("import_foreign_schema needs a user in user_mappings")
+	}
+	return r.foreignServer.Spec.UserMappings[0].User, nil
+}
+
+func (r *ForeignServerReco) LoadObj() (bool, error) {
+	dbServer, err := LookupDbServer(r.db.Spec.GetServerRef(), r.Client, r.NsNm.Namespace)
+	if err != nil {
+		if !shared.CannotFindError(err, r.Log, "DbServer", r.NsNm.Namespace, r.NsNm.Name) {
+			r.LogError(err, "failed getting DbServer")
+			return false, err
+		}
+		return false, nil
+	}
+	err = r.AuthorizeDbServerUse(dbServer, &r.foreignServer, &r.foreignServer.Status.Conditions, nil)
+	if err != nil {
+		return false, err
+	}
+
+	userNames := []string{}
+	if r.foreignServer.GetDeletionTimestamp() == nil {
+		r.remoteServer, err = LookupDbServer(r.remoteDb.Spec.GetServerRef(), r.Client, r.NsNm.Namespace)
+		if err != nil {
+			r.LogError(err, "failed getting remote DbServer")
+			return false, err
+		}
+		err = r.CheckNamespaceAllowed(r.remoteServer, r.NsNm.Namespace)
+		if err != nil {
+			return false, err
+		}
+		if r.foreignServer.Spec.ImportForeignSchema != nil {
+			importUser, err := r.getImportUser()
+			if err != nil {
+				return false, err
+			}
+			userNames = append(userNames, importUser)
+		}
+	}
+
+	conn, err := r.GetDbConnection(dbServer, userNames, &r.db.Spec.DbName)
+	if err != nil {
+		r.LogError(err, "failed building dbConnection")
+		return false, err
+	}
+	r.conn = conn
+
+	r.dbSideForeignServer, err = r.conn.GetForeignServer(r.foreignServer.Spec.Name)
+	if err != nil {
+		r.LogError(err, "failed getting ForeignServer")
+		return false, err
+	}
+	return r.dbSideForeignServer != nil, nil
+}
+
+func (r *ForeignServerReco) getOptions() map[string]string {
+	return postgres.BuildForeignServerOptions(r.remoteServer.Spec.Address, r.remoteServer.Spec.Port, r.remoteDb.Spec.DbName, r.remoteServer.Spec.Options)
+}
+
+// Remote credentials by the name of the local user, read from the secrets of the remote users
+func (r *ForeignServerReco) getUserMappings() (map[string]shared.Credentials, error) {
+	userMappings := map[string]shared.Credentials{}
+	for _, mapping := range r.foreignServer.Spec.UserMappings {
+		user := dboperatorv1alpha1.User{}
+		err := r.Client.Get(r.Ctx, types.NamespacedName{Namespace: r.NsNm.Namespace, Name: mapping.User}, &user)
+		if err != nil {
+			return nil, err
+		}
+		remoteUser := user
+		if mapping.RemoteUser != "" && mapping.RemoteUser != mapping.User {
+			err = r.Client.Get(r.Ctx, types.NamespacedName{Namespace: r.NsNm.Namespace, Name: mapping.RemoteUser}, &remoteUser)
+			if err != nil {
+				return nil, err
+			}
+		}
+		creds, err := GetUserCredentials(&remoteUser, r.Client, r.Ctx)
+		if err != nil {
+			return nil, err
+		}
+		if creds == nil || creds.Password == nil {
+			return nil, fmt.Errorf("user %s has no password for the user mapping of %s", remoteUser.Name, user.Name)
+		}
+		userMappings[user.Spec.UserName] = *creds
+	}
+	return userMappings, nil
+}
+
+func (r *ForeignServerReco) CreateObj() (ctrl.Result, error) {
+	if r.conn == nil {
+		err := fmt.Errorf("no database connection possible")
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	r.Log.Info(fmt.Sprintf("Creating foreign server %s in db %s", r.foreignServer.Spec.Name, r.db.Spec.DbName))
+	err := r.conn.CreateForeignServer(r.foreignServer.Spec.Name, r.getOptions())
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	r.foreignServer.Status.Created = true
+	err = r.Client.Status().Update(r.Ctx, &r.foreignServer)
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	return r.EnsureCorrect()
+}
+
+func (r *ForeignServerReco) RemoveObj() (ctrl.Result, error) {
+	if !r.foreignServer.Spec.DropOnDeletion {
+		r.Log.Info(fmt.Sprintf("did not drop foreign server %s in db %s as per spec", r.foreignServer.Spec.Name, r.db.Spec.DbName))
+		return ctrl.Result{}, nil
+	}
+	if !r.foreignServer.Status.Created {
+		// The server was already there, it may be used by foreign tables that aren't ours
+		message := fmt.Sprintf("not dropping foreign server %s in db %s, it wasn't created by this resource", r.foreignServer.Spec.Name, r.db.Spec.DbName)
+		r.Log.Info(message)
+		if r.Recorder != nil {
+			r.Recorder.Event(&r.foreignServer, v1.EventTypeWarning, EVENT_REASON_DROP_REFUSED, message)
+		}
+		return ctrl.Result{}, nil
+	}
+	r.Log.Info(fmt.Sprintf("dropping foreign server %s in db %s", r.foreignServer.Spec.Name, r.db.Spec.DbName))
+	err := r.conn.DropForeignServer(r.foreignServer.Spec.Name, r.foreignServer.Status.CreatedUserMappings, r.foreignServer.Spec.CascadeOnDrop)
+	if err != nil {
+		return r.LogAndBackoffDeletion(err, r.GetCR())
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *ForeignServerReco) GetCR() client.Object {
+	return &r.foreignServer
+}
+
+func (r *ForeignServerReco) EnsureCorrect() (ctrl.Result, error) {
+	userMappings, err := r.getUserMappings()
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	changed, createdMappings, err := r.conn.UpdateForeignServer(r.foreignServer.Spec.Name, r.getOptions(), userMappings, r.getCreatedMappings())
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	if changed {
+		message := fmt.Sprintf("updated foreign server %s in db %s", r.foreignServer.Spec.Name, r.db.Spec.DbName)
+		r.Log.Info(message)
+		if r.Recorder != nil {
+			r.Recorder.Event(&r.foreignServer, v1.EventTypeNormal, EVENT_REASON_FOREIGN_SERVER_UPDATED, message)
+		}
+	}
+
+	var tables []string
+	importSpec := r.foreignServer.Spec.ImportForeignSchema
+	if importSpec != nil {
+		importUser, err := r.getImportUser()
+		if err != nil {
+			return r.LogAndBackoffCreation(err, r.GetCR())
+		}
+		tables, err = r.conn.ImportForeignSchema(r.foreignServer.Spec.Name, *importSpec, &importUser)
+		if err != nil {
+			return r.LogAndBackoffCreation(err, r.GetCR())
+		}
+		if len(tables) > len(r.foreignServer.Status.ImportedTables) {
+			message := fmt.Sprintf("imported foreign schema %s of server %s into schema %s", importSpec.RemoteSchema, r.foreignServer.Spec.Name, importSpec.Schema)
+			r.Log.Info(message)
+			if r.Recorder != nil {
+				r.Recorder.Event(&r.foreignServer, v1.EventTypeNormal, EVENT_REASON_FOREIGN_SCHEMA_IMPORTED, message)
+			}
+		}
+	}
+
+	err = r.updateStatus(funk.Keys(userMappings).([]string), createdMappings, tables)
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	return ctrl.Result{}, nil
+}
+
+// The user mappings this resource created with the hash of the options it set, empty for mappings of an earlier version without hashes
+func (r *ForeignServerReco) getCreatedMappings() map[string]string {
+	createdMappings := map[string]string{}
+	for _, userName := range r.foreignServer.Status.CreatedUserMappings {
+		createdMappings[userName] = r.foreignServer.Status.UserMappingHashes[userName]
+	}
+	return createdMappings
+}
+
+func (r *ForeignServerReco) updateStatus(userMappings []string, createdMappings map[string]string, tables []string) error {
+	status := r.foreignServer.Status.DeepCopy()
+	status.UserMappings = nil
+	if len(userMappings) > 0 {
+		sort.Strings(userMappings)
+		status.UserMappings = userMappings
+	}
+	status.CreatedUserMappings = nil
+	status.UserMappingHashes = nil
+	if len(createdMappings) > 0 {
+		status.CreatedUserMappings = funk.Keys(createdMappings).([]string)
+		sort.Strings(status.CreatedUserMappings)
+		status.UserMappingHashes = createdMappings
+	}
+	status.ImportedTables = nil
+	if len(tables) > 0 {
+		status.ImportedTables = tables
+	}
+	if reflect.DeepEqual(&r.foreignServer.Status, status) {
+		return nil
+	}
+	r.foreignServer.Status = *status
+	return r.Client.Status().Update(r.Ctx, &r.foreignServer)
+}
+
+func (r *ForeignServerReco) CleanupConn() {
+	if r.conn != nil {
+		r.conn.Close()
+	}
+}
+
+func (r *ForeignServerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.With(zap.String("Namespace", req.Namespace)).With(zap.String("Name", req.Name))
+	fr := ForeignServerReco{}
+	fr.Reco = Reco{shared.K8sClient{Client: r.Client, Ctx: ctx, NsNm: req.NamespacedName, Log: log, Recorder: r.Recorder}}
+	return fr.Reco.Reconcile(&fr)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+// The user mappings are updated when a password in the secret of a mapped user changes
+func (r *ForeignServerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&dboperatorv1alpha1.ForeignServer{}).
+		Watches(&v1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.foreignServersForSecret)).
+		Complete(r)
+}
+
+func (r *ForeignServerReconciler) foreignServersForSecret(ctx context.Context, secret client.Object) []reconcile.Request {
+	foreignServerList := dboperatorv1alpha1.ForeignServerList{}
+	err := r.List(ctx, &foreignServerList, client.InNamespace(secret.GetNamespace()))
+	if err != nil {
+		r.Log.Error(fmt.Sprintf("failed listing ForeignServers for Secret Error: %s", err))
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, foreignServer := range foreignServerList.Items {
+		for _, mapping := range foreignServer.Spec.UserMappings {
+			user := dboperatorv1alpha1.User{}
+			userNsm := types.NamespacedName{Namespace: foreignServer.Namespace, Name: shared.Nvl(mapping.RemoteUser, mapping.User)}
+			if r.Get(ctx, userNsm, &user) == nil && user.Spec.SecretName == secret.GetName() {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: foreignServer.Name, Namespace: foreignServer.Namespace}})
+				break
+			}
+		}
+	}
+	return requests
+}
@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/shared"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Records the dropped foreign servers, the other methods of the interface are never called
+type foreignServerConnection struct {
+	shared.DbServerConnectionInterface
+	dropped         []string
+	droppedMappings []string
+	cascade         bool
+}
+
+func (c *foreignServerConnection) DropForeignServer(name string, userMappings []string, cascade bool) error {
+	c.dropped = append(c.dropped, name)
+	c.droppedMappings = append(c.droppedMappings, userMappings...)
+	c.cascade = cascade
+	return nil
+}
+
+func TestForeignServerRemoveObjOnlyDropsCreatedServers(t *testing.T) {
+	conn := &foreignServerConnection{}
+	reco := ForeignServerReco{
+		Reco: Reco{*newK8sClient(newFakeClient(t), "shop")},
+		db:   dboperatorv1alpha1.Db{Spec: dboperatorv1alpha1.DbSpec{DbName: "shop"}},
+		foreignServer: dboperatorv1alpha1.ForeignServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "prod", Namespace: "shop"},
+			Spec:       dboperatorv1alpha1.ForeignServerSpec{Db: "shop", Name: "prod", DropOnDeletion: true},
+			Status:     dboperatorv1alpha1.ForeignServerStatus{CreatedUserMappings: []string{"reporting"}},
+		},
+		conn: conn,
+	}
+
+	_, err := reco.RemoveObj()
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if len(conn.dropped) != 0 {
+		t.Errorf("expected a server that already existed to be kept got %v", conn.dropped)
+	}
+
+	reco.foreignServer.Status.Created = true
+	_, err = reco.RemoveObj()
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if !reflect.DeepEqual(conn.dropped, []string{"prod"}) || !reflect.DeepEqual(conn.droppedMappings, []string{"reporting"}) || conn.cascade {
+		t.Errorf("expected the created server and its mappings to be dropped without cascade got %v %v %t", conn.dropped, conn.droppedMappings, conn.cascade)
+	}
+}
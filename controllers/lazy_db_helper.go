@@ -51,7 +51,7 @@ func (h *LazyDbHelper) GetDbServer() (*dboperatorv1alpha1.DbServer, error) {
 			return nil, err
 		}
 
-		h.lazyDbServerHelper = NewLazyDbServerHelper(h.K8sClient, db.Spec.Server)
+		h.lazyDbServerHelper = NewLazyDbServerHelper(h.K8sClient, db.Spec.GetServerRef())
 	}
 
 	return h.lazyDbServerHelper.GetDbServer()
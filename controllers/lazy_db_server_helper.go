@@ -11,7 +11,7 @@ import (
 
 type LazyDbServerHelper struct {
 	*shared.K8sClient
-	DbServerName string
+	DbServerRef dboperatorv1alpha1.DbServerRef
 	*dboperatorv1alpha1.DbServer
 	dbServerCredentials *shared.Credentials
 	userCredentials     map[string]*LazyUserHelper
@@ -26,16 +26,16 @@ func (h *LazyDbServerHelper) GetUser(userName string) (*LazyUserHelper, error) {
 	return lazyUserHelper, nil
 }
 
-func NewLazyDbServerHelper(k8sClient *shared.K8sClient, dbServerName string) *LazyDbServerHelper {
+func NewLazyDbServerHelper(k8sClient *shared.K8sClient, dbServerRef dboperatorv1alpha1.DbServerRef) *LazyDbServerHelper {
 	return &LazyDbServerHelper{
-		K8sClient:    k8sClient,
-		DbServerName: dbServerName,
+		K8sClient:   k8sClient,
+		DbServerRef: dbServerRef,
 	}
 }
 
 func (h *LazyDbServerHelper) GetDbServer() (*dboperatorv1alpha1.DbServer, error) {
 	if h.DbServer == nil {
-		dbServer, err := GetDbServer(h.DbServerName, h.Client, h.NsNm.Namespace)
+		dbServer, err := GetDbServer(h.DbServerRef, h.Client, h.NsNm.Namespace)
 		if err != nil {
 			return nil, err
 		}
@@ -64,6 +64,8 @@ func (h *LazyDbServerHelper) GetConnectInfo(databaseName *string) (*shared.DbSer
 	if len(dbServer.Spec.Options) > 0 {
 		connectInfo.Options = dbServer.Spec.Options
 	}
+	connectInfo.ServerKey = GetDbServerKey(dbServer)
+	connectInfo.MaxOpenConnections = dbServer.Spec.MaxOpenConnections
 
 	if databaseName != nil {
 		connectInfo.Database = *databaseName
@@ -4,9 +4,7 @@ import (
 	"fmt"
 	"strings"
 
-	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
 	"github.com/obeleh/db-operator/shared"
-	"k8s.io/apimachinery/pkg/types"
 )
 
 type LazyStorageActionsHelper struct {
@@ -28,18 +26,13 @@ func NewLazyStorageActionsHelper(k8sClient *shared.K8sClient, storageType string
 func (h *LazyStorageActionsHelper) GetStorageActions() (StorageActions, error) {
 	if !h.storageActionsLoaded {
 		if strings.ToLower(h.StorageType) == "s3" {
-			s3Storage := &dboperatorv1alpha1.S3Storage{}
-			nsName := types.NamespacedName{
-				Name:      h.StorageLocation,
-				Namespace: h.NsNm.Namespace,
-			}
-
-			err := h.Client.Get(h.Ctx, nsName, s3Storage)
+			s3Storage, err := GetS3Storage(h.K8sClient, h.StorageLocation)
 			if err != nil {
 				return nil, err
 			}
-			s3StorageInfo := &S3StorageInfo{
-				S3Storage: *s3Storage,
+			s3StorageInfo, err := NewS3StorageInfo(h.K8sClient, *s3Storage)
+			if err != nil {
+				return nil, err
 			}
 			h.storageActions = s3StorageInfo
 			h.storageActionsLoaded = true
@@ -1,7 +1,6 @@
 package controllers
 
 import (
-	"github.com/obeleh/db-operator/dbservers"
 	"github.com/obeleh/db-operator/dbservers/postgres"
 	"github.com/obeleh/db-operator/shared"
 )
@@ -81,7 +80,7 @@ func (h *LazyTargetHelperBase) GetServerActions() (shared.DbActions, error) {
 		return nil, err
 	}
 
-	return dbservers.GetServerActions(dbServer, db, dbServer.Spec.Options)
+	return GetServerActions(h.K8sClient, dbServer, db)
 }
 
 func (h *LazyTargetHelperBase) GetStorageInfoAndActions() (StorageActions, shared.DbActions, error) {
@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/shared"
+	"github.com/thoas/go-funk"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	REASON_PRIVS_DRIFT_DETECTED        = "DriftDetected"
+	EVENT_REASON_PRIVS_DRIFT           = "PrivsDrift"
+	EVENT_REASON_PRIVS_DRIFT_CORRECTED = "PrivsDriftCorrected"
+)
+
+func (r *UserReco) getDriftPolicy() string {
+	if r.dbServer == nil || r.dbServer.Spec.DriftPolicy == "" {
+		return dboperatorv1alpha1.DRIFT_POLICY_ENFORCE
+	}
+	return r.dbServer.Spec.DriftPolicy
+}
+
+func (r *UserReco) getResyncInterval() time.Duration {
+	if r.dbServer == nil || r.dbServer.Spec.ResyncInterval == nil {
+		return 0
+	}
+	return r.dbServer.Spec.ResyncInterval.Duration
+}
+
+// Whether the privileges of the current generation of the spec were applied before,
+// only then the drift policy decides what happens with privileges that were changed on the server
+func (r *UserReco) isSpecApplied() bool {
+	existing := meta.FindStatusCondition(r.user.Status.Conditions, CONDITION_PRIVS_IN_SYNC)
+	if existing == nil || existing.ObservedGeneration != r.user.GetGeneration() {
+		return false
+	}
+	return funk.ContainsString([]string{REASON_PRIVS_IN_SYNC, REASON_PRIVS_DRIFT_DETECTED, REASON_PRIVS_PLAN_APPLIED}, existing.Reason)
+}
+
+// Applies the privileges of a User, once applied privileges changed on the server are handled according to the drift_policy of the DbServer
+// Returns whether privileges were changed
+func (r *UserReco) reconcilePrivs() (bool, error) {
+	condition := metav1.Condition{
+		Type:               CONDITION_PRIVS_IN_SYNC,
+		Status:             metav1.ConditionTrue,
+		Reason:             REASON_PRIVS_IN_SYNC,
+		Message:            "privileges match the spec",
+		ObservedGeneration: r.user.GetGeneration(),
+	}
+
+	if !r.isSpecApplied() {
+		changes, err := r.conn.UpdateUserPrivs(r.privsSpec)
+		if err != nil {
+			return changes, err
+		}
+		return changes, r.setPrivsPlan(nil, &condition)
+	}
+
+	policy := r.getDriftPolicy()
+	if policy == dboperatorv1alpha1.DRIFT_POLICY_IGNORE {
+		return false, r.setPrivsPlan(nil, meta.FindStatusCondition(r.user.Status.Conditions, CONDITION_PRIVS_IN_SYNC))
+	}
+
+	drifts, err := r.conn.GetUserPrivsDrift(r.privsSpec)
+	if err != nil {
+		return false, err
+	}
+
+	if policy == dboperatorv1alpha1.DRIFT_POLICY_REPORT {
+		if len(drifts) > 0 {
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = REASON_PRIVS_DRIFT_DETECTED
+			condition.Message = formatPrivsDrift(drifts)
+			existing := meta.FindStatusCondition(r.user.Status.Conditions, CONDITION_PRIVS_IN_SYNC)
+			if r.Recorder != nil && (existing.Reason != condition.Reason || existing.Message != condition.Message) {
+				r.Recorder.Event(&r.user, v1.EventTypeWarning, EVENT_REASON_PRIVS_DRIFT, condition.Message)
+			}
+		}
+		return false, r.setPrivsPlan(nil, &condition)
+	}
+
+	if len(drifts) > 0 {
+		message := formatPrivsDrift(drifts)
+		r.Log.Info(fmt.Sprintf("Correcting privileges drift of user %s: %s", r.user.Spec.UserName, message))
+		if r.Recorder != nil {
+			r.Recorder.Event(&r.user, v1.EventTypeWarning, EVENT_REASON_PRIVS_DRIFT_CORRECTED, message)
+		}
+	}
+	changes, err := r.conn.UpdateUserPrivs(r.privsSpec)
+	if err != nil {
+		return changes, err
+	}
+	revoked, err := r.conn.RevokeExtraUserPrivs(r.privsSpec)
+	if err != nil {
+		return changes, err
+	}
+	return changes || revoked, r.setPrivsPlan(nil, &condition)
+}
+
+func formatPrivsDrift(drifts []shared.PrivsDrift) string {
+	parts := []string{}
+	for _, drift := range drifts {
+		part := drift.Scope + ":"
+		if len(drift.Extra) > 0 {
+			part += fmt.Sprintf(" extra %s", strings.Join(drift.Extra, ", "))
+		}
+		if len(drift.Missing) > 0 {
+			part += fmt.Sprintf(" missing %s", strings.Join(drift.Missing, ", "))
+		}
+		parts = append(parts, part)
+	}
+	return "privileges differ from the spec, " + strings.Join(parts, "; ")
+}
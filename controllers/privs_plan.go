@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"fmt"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/shared"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// Set to the hash of the privs_plan in the status of a User to apply it
+	APPROVE_PRIVS_PLAN_ANNOTATION = "db-operator.kubemaster.com/approve-privs-plan"
+
+	CONDITION_PRIVS_IN_SYNC    = "PrivsInSync"
+	REASON_PRIVS_IN_SYNC       = "InSync"
+	REASON_PRIVS_PLAN_PENDING  = "PlanPending"
+	REASON_PRIVS_PLAN_APPLIED  = "PlanApplied"
+	EVENT_REASON_PRIVS_PLANNED = "PrivsPlanned"
+	EVENT_REASON_PRIVS_APPLIED = "PrivsPlanApplied"
+)
+
+func (r *UserReco) isDryRun() bool {
+	return r.user.Spec.DryRun || (r.dbServer != nil && r.dbServer.Spec.DryRun)
+}
+
+// Plans the privilege changes of a User in dry run mode and applies them once the plan is approved
+// Returns whether privileges were changed
+func (r *UserReco) planOrApplyPrivs() (bool, error) {
+	statements, err := r.conn.PlanUserPrivs(r.privsSpec)
+	if err != nil {
+		return false, err
+	}
+
+	condition := metav1.Condition{
+		Type:               CONDITION_PRIVS_IN_SYNC,
+		Status:             metav1.ConditionTrue,
+		Reason:             REASON_PRIVS_IN_SYNC,
+		Message:            "privileges match the spec",
+		ObservedGeneration: r.user.GetGeneration(),
+	}
+	var plan *dboperatorv1alpha1.PrivsPlan
+	changes := false
+
+	if len(statements) > 0 {
+		hash := shared.HashPrivsPlan(statements)
+		if r.user.GetAnnotations()[APPROVE_PRIVS_PLAN_ANNOTATION] == hash {
+			r.Log.Info(fmt.Sprintf("Applying approved privileges plan %s", hash))
+			changes, err = r.conn.UpdateUserPrivs(r.privsSpec)
+			if err != nil {
+				return changes, err
+			}
+			// An approval is only good for one apply, when the same statements are planned again later they need a new approval
+			err = r.clearPrivsPlanApproval()
+			if err != nil {
+				return changes, err
+			}
+			condition.Reason = REASON_PRIVS_PLAN_APPLIED
+			condition.Message = fmt.Sprintf("applied privileges plan %s", hash)
+			if r.Recorder != nil {
+				r.Recorder.Event(&r.user, v1.EventTypeNormal, EVENT_REASON_PRIVS_APPLIED, condition.Message)
+			}
+		} else {
+			plan = &dboperatorv1alpha1.PrivsPlan{Hash: hash, Statements: statements, PlannedAt: metav1.Now()}
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = REASON_PRIVS_PLAN_PENDING
+			condition.Message = fmt.Sprintf("%d statements planned, set the %s annotation to %s to apply them", len(statements), APPROVE_PRIVS_PLAN_ANNOTATION, hash)
+		}
+	}
+
+	return changes, r.setPrivsPlan(plan, &condition)
+}
+
+func (r *UserReco) clearPrivsPlanApproval() error {
+	patch := client.MergeFrom(r.user.DeepCopy())
+	delete(r.user.Annotations, APPROVE_PRIVS_PLAN_ANNOTATION)
+	return r.Client.Patch(r.Ctx, &r.user, patch)
+}
+
+// Stores the plan and the PrivsInSync condition, nothing is written when neither changed
+func (r *UserReco) setPrivsPlan(plan *dboperatorv1alpha1.PrivsPlan, condition *metav1.Condition) error {
+	current := r.user.Status.PrivsPlan
+	planChanged := (current == nil) != (plan == nil) || (current != nil && current.Hash != plan.Hash)
+	if !planChanged && plan != nil {
+		// Keep the time the plan was first made
+		plan = current
+	}
+
+	conditionChanged := false
+	if condition != nil {
+		existing := meta.FindStatusCondition(r.user.Status.Conditions, condition.Type)
+		conditionChanged = existing == nil || existing.Status != condition.Status || existing.Reason != condition.Reason ||
+			existing.Message != condition.Message || existing.ObservedGeneration != condition.ObservedGeneration
+		if conditionChanged {
+			meta.SetStatusCondition(&r.user.Status.Conditions, *condition)
+		}
+	} else if meta.FindStatusCondition(r.user.Status.Conditions, CONDITION_PRIVS_IN_SYNC) != nil {
+		meta.RemoveStatusCondition(&r.user.Status.Conditions, CONDITION_PRIVS_IN_SYNC)
+		conditionChanged = true
+	}
+
+	if !planChanged && !conditionChanged {
+		return nil
+	}
+	r.user.Status.PrivsPlan = plan
+	if planChanged && plan != nil && r.Recorder != nil {
+		r.Recorder.Event(&r.user, v1.EventTypeNormal, EVENT_REASON_PRIVS_PLANNED, condition.Message)
+	}
+	return r.Client.Status().Update(r.Ctx, &r.user)
+}
@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"testing"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/shared"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// Only implements the privilege planning, the other methods of the interface are never called
+type plannedConnection struct {
+	shared.DbServerConnectionInterface
+	statements []dboperatorv1alpha1.PlannedStatement
+	applied    int
+}
+
+func (c *plannedConnection) PlanUserPrivs(userSpec dboperatorv1alpha1.UserSpec) ([]dboperatorv1alpha1.PlannedStatement, error) {
+	return c.statements, nil
+}
+
+func (c *plannedConnection) UpdateUserPrivs(userSpec dboperatorv1alpha1.UserSpec) (bool, error) {
+	c.applied++
+	return true, nil
+}
+
+func TestPlanOrApplyPrivsUsesApprovalOnce(t *testing.T) {
+	statements := []dboperatorv1alpha1.PlannedStatement{{Database: "shop", Statement: "GRANT SELECT ON ALL TABLES IN SCHEMA public TO shop;"}}
+	hash := shared.HashPrivsPlan(statements)
+	user := &dboperatorv1alpha1.User{
+		ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "shop", Annotations: map[string]string{APPROVE_PRIVS_PLAN_ANNOTATION: hash}},
+		Spec:       dboperatorv1alpha1.UserSpec{UserName: "shop", DryRun: true},
+	}
+	scheme := runtime.NewScheme()
+	if err := dboperatorv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	apiClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(user).WithStatusSubresource(user).Build()
+	conn := &plannedConnection{statements: statements}
+	reco := UserReco{Reco: Reco{*newK8sClient(apiClient, "shop")}, conn: conn}
+	if err := apiClient.Get(reco.Ctx, client.ObjectKeyFromObject(user), &reco.user); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	changes, err := reco.planOrApplyPrivs()
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if !changes || conn.applied != 1 {
+		t.Errorf("expected the approved plan to be applied")
+	}
+	stored := &dboperatorv1alpha1.User{}
+	if err := apiClient.Get(reco.Ctx, client.ObjectKeyFromObject(user), stored); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if _, found := stored.Annotations[APPROVE_PRIVS_PLAN_ANNOTATION]; found {
+		t.Errorf("expected the approval to be removed after applying the plan")
+	}
+
+	// The same statements come back, for instance because the privileges were revoked on the server again
+	changes, err = reco.planOrApplyPrivs()
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if changes || conn.applied != 1 {
+		t.Errorf("expected the plan to wait for a new approval")
+	}
+	if reco.user.Status.PrivsPlan == nil || reco.user.Status.PrivsPlan.Hash != hash {
+		t.Errorf("expected the plan %s in the status got %+v", hash, reco.user.Status.PrivsPlan)
+	}
+	condition := meta.FindStatusCondition(reco.user.Status.Conditions, CONDITION_PRIVS_IN_SYNC)
+	if condition == nil || condition.Reason != REASON_PRIVS_PLAN_PENDING {
+		t.Errorf("expected a pending plan got %+v", condition)
+	}
+}
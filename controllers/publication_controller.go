@@ -0,0 +1,205 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/shared"
+)
+
+// PublicationReconciler reconciles a Publication object
+type PublicationReconciler struct {
+	client.Client
+	Log      *zap.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=db-operator.kubemaster.com,resources=publications,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=db-operator.kubemaster.com,resources=publications/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=db-operator.kubemaster.com,resources=publications/finalizers,verbs=update
+
+const EVENT_REASON_PUBLICATION_UPDATED = "PublicationUpdated"
+
+type PublicationReco struct {
+	Reco
+	db                dboperatorv1alpha1.Db
+	publication       dboperatorv1alpha1.Publication
+	dbSidePublication *shared.DbSidePublication
+	conn              shared.DbServerConnectionInterface
+}
+
+func (r *PublicationReco) LoadCR() (ctrl.Result, error) {
+	err := r.Client.Get(r.Ctx, r.NsNm, &r.publication)
+	if err != nil {
+		r.Log.Info(fmt.Sprintf("%T: %s does not exist, %s", r.publication, r.NsNm.Name, err))
+		return ctrl.Result{}, err
+	}
+
+	dbNsm := types.NamespacedName{
+		Namespace: r.NsNm.Namespace,
+		Name:      r.publication.Spec.Db,
+	}
+	err = r.Client.Get(r.Ctx, dbNsm, &r.db)
+	if err != nil {
+		if r.publication.GetDeletionTimestamp() != nil {
+			// Db got deleted before the publication did
+			err = r.RemoveFinalizer(&r.publication)
+			if err != nil {
+				return shared.GradualBackoffRetry(r.publication.GetCreationTimestamp().Time), nil
+			}
+			return ctrl.Result{}, nil
+		}
+		r.Log.Info(fmt.Sprintf("%T: %s does not exist, %s", r.db, dbNsm, err))
+		return shared.GradualBackoffRetry(r.publication.GetCreationTimestamp().Time), nil
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *PublicationReco) LoadObj() (bool, error) {
+	dbServer, err := LookupDbServer(r.db.Spec.GetServerRef(), r.Client, r.NsNm.Namespace)
+	if err != nil {
+		if !shared.CannotFindError(err, r.Log, "DbServer", r.NsNm.Namespace, r.NsNm.Name) {
+			r.LogError(err, "failed getting DbServer")
+			return false, err
+		}
+		return false, nil
+	}
+	err = r.AuthorizeDbServerUse(dbServer, &r.publication, &r.publication.Status.Conditions, nil)
+	if err != nil {
+		return false, err
+	}
+
+	conn, err := r.GetDbConnection(dbServer, nil, &r.db.Spec.DbName)
+	if err != nil {
+		r.LogError(err, "failed building dbConnection")
+		return false, err
+	}
+	r.conn = conn
+
+	r.dbSidePublication, err = r.conn.GetPublication(r.publication.Spec.Name)
+	if err != nil {
+		r.LogError(err, "failed getting Publication")
+		return false, err
+	}
+	return r.dbSidePublication != nil, nil
+}
+
+func (r *PublicationReco) CreateObj() (ctrl.Result, error) {
+	if r.conn == nil {
+		err := fmt.Errorf("no database connection possible")
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	r.Log.Info(fmt.Sprintf("Creating publication %s in db %s", r.publication.Spec.Name, r.db.Spec.DbName))
+	err := r.conn.CreatePublication(r.publication.Spec)
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	err = r.updateStatus(true)
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *PublicationReco) RemoveObj() (ctrl.Result, error) {
+	if !r.publication.Spec.DropOnDeletion {
+		r.Log.Info(fmt.Sprintf("did not drop publication %s in db %s as per spec", r.publication.Spec.Name, r.db.Spec.DbName))
+		return ctrl.Result{}, nil
+	}
+	r.Log.Info(fmt.Sprintf("dropping publication %s in db %s", r.publication.Spec.Name, r.db.Spec.DbName))
+	err := r.conn.DropPublication(r.publication.Spec.Name)
+	if err != nil {
+		return r.LogAndBackoffDeletion(err, r.GetCR())
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *PublicationReco) GetCR() client.Object {
+	return &r.publication
+}
+
+func (r *PublicationReco) EnsureCorrect() (ctrl.Result, error) {
+	changed, err := r.conn.UpdatePublication(r.publication.Spec)
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	if changed {
+		message := fmt.Sprintf("updated publication %s in db %s", r.publication.Spec.Name, r.db.Spec.DbName)
+		r.Log.Info(message)
+		if r.Recorder != nil {
+			r.Recorder.Event(&r.publication, v1.EventTypeNormal, EVENT_REASON_PUBLICATION_UPDATED, message)
+		}
+	}
+	err = r.updateStatus(changed)
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	return ctrl.Result{}, nil
+}
+
+// Shows the tables from pg_publication_tables, reload after changing the publication
+func (r *PublicationReco) updateStatus(reload bool) error {
+	if reload {
+		publication, err := r.conn.GetPublication(r.publication.Spec.Name)
+		if err != nil {
+			return err
+		}
+		r.dbSidePublication = publication
+	}
+	var tables []string
+	if r.dbSidePublication != nil && len(r.dbSidePublication.Tables) > 0 {
+		tables = r.dbSidePublication.Tables
+	}
+	if reflect.DeepEqual(r.publication.Status.Tables, tables) {
+		return nil
+	}
+	r.publication.Status.Tables = tables
+	return r.Client.Status().Update(r.Ctx, &r.publication)
+}
+
+func (r *PublicationReco) CleanupConn() {
+	if r.conn != nil {
+		r.conn.Close()
+	}
+}
+
+func (r *PublicationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.With(zap.String("Namespace", req.Namespace)).With(zap.String("Name", req.Name))
+	pr := PublicationReco{}
+	pr.Reco = Reco{shared.K8sClient{Client: r.Client, Ctx: ctx, NsNm: req.NamespacedName, Log: log, Recorder: r.Recorder}}
+	return pr.Reco.Reconcile(&pr)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *PublicationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&dboperatorv1alpha1.Publication{}).
+		Complete(r)
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"strings"
@@ -89,7 +90,15 @@ func (rc *Reco) Reconcile(rcl Reconcilable) (ctrl.Result, error) {
 
 	exists, err := rcl.LoadObj()
 	if err != nil {
-		if !shared.CannotFindError(err, rc.Log, "", rc.NsNm.Namespace, rc.NsNm.Name) {
+		accessDenied := &AccessDeniedError{}
+		if errors.As(err, &accessDenied) {
+			rc.Log.Info(fmt.Sprintf("%s.%s: %s", rc.NsNm.Namespace, rc.NsNm.Name, accessDenied.Message))
+			if markedToBeDeleted {
+				// Never touch the database on behalf of a namespace that isn't allowed to use the DbServer
+				rc.RemoveFinalizer(cr)
+				return ctrl.Result{}, nil
+			}
+		} else if !shared.CannotFindError(err, rc.Log, "", rc.NsNm.Namespace, rc.NsNm.Name) {
 			rc.LogError(err, fmt.Sprintf("failed loadObj for %s.%s", rc.NsNm.Namespace, rc.NsNm.Name))
 		} else if markedToBeDeleted {
 			// if it's a "cannot find error" and current obj is marked to be deleted
@@ -112,7 +121,8 @@ func (rc *Reco) Reconcile(rcl Reconcilable) (ctrl.Result, error) {
 		} else {
 			res, err = rcl.EnsureCorrect()
 			if err == nil && !res.Requeue {
-				res, err = rc.EnsureFinalizer(cr)
+				// Keep RequeueAfter of EnsureCorrect, it's used to resync periodically
+				_, err = rc.EnsureFinalizer(cr)
 				if err != nil {
 					// should be able to retry quickly since only we couldn't add the finalizer
 					res = shared.RetryAfter(3)
@@ -220,13 +230,7 @@ func (r *Reco) EnsureScripts() error {
 }
 
 func (r *Reco) GetS3Storage(storageLocation string) (dboperatorv1alpha1.S3Storage, error) {
-	s3Storage := &dboperatorv1alpha1.S3Storage{}
-	nsName := types.NamespacedName{
-		Name:      storageLocation,
-		Namespace: r.NsNm.Namespace,
-	}
-
-	err := r.Client.Get(r.Ctx, nsName, s3Storage)
+	s3Storage, err := GetS3Storage(&r.K8sClient, storageLocation)
 	return *s3Storage, err
 }
 
@@ -301,7 +305,7 @@ func (r *Reco) GetDbServerFromDbName(dbName string) (*dboperatorv1alpha1.Db, *db
 	if err != nil {
 		return nil, nil, err
 	}
-	dbServer, err := GetDbServer(db.Spec.Server, r.Client, r.NsNm.Namespace)
+	dbServer, err := GetDbServer(db.Spec.GetServerRef(), r.Client, r.NsNm.Namespace)
 	return db, dbServer, err
 }
 
@@ -310,7 +314,24 @@ func (r *Reco) GetServerActionsFromDbName(dbName string) (shared.DbActions, erro
 	if err != nil {
 		return nil, err
 	}
-	return dbservers.GetServerActions(dbServer, db, dbServer.Spec.Options)
+	return GetServerActions(&r.K8sClient, dbServer, db)
+}
+
+// Jobs run in the namespace of the Db and can only read Secrets from that namespace
+// When the Secret of the DbServer lives in another namespace, like the one of a ClusterDbServer, the Jobs connect as the owner of the Db
+func GetServerActions(k8sClient *shared.K8sClient, dbServer *dboperatorv1alpha1.DbServer, db *dboperatorv1alpha1.Db) (shared.DbActions, error) {
+	var jobUser *dboperatorv1alpha1.User
+	if dbServer.Namespace != db.Namespace {
+		if db.Spec.Owner == "" {
+			return nil, fmt.Errorf("the Secret of %s %s is in namespace %s, Jobs on Db %s need an owner to connect as", shared.Nvl(dbServer.Kind, "DbServer"), dbServer.Name, dbServer.Namespace, db.Name)
+		}
+		jobUser = &dboperatorv1alpha1.User{}
+		err := k8sClient.Client.Get(k8sClient.Ctx, types.NamespacedName{Name: db.Spec.Owner, Namespace: db.Namespace}, jobUser)
+		if err != nil {
+			return nil, fmt.Errorf("failed getting owner %s of Db %s: %s", db.Spec.Owner, db.Name, err)
+		}
+	}
+	return dbservers.GetServerActions(dbServer, db, jobUser, dbServer.Spec.Options)
 }
 
 func (r *Reco) GetJobMap() (map[string]batchv1.Job, error) {
@@ -440,6 +461,8 @@ func (r *Reco) GetConnectInfo(dbServer *dboperatorv1alpha1.DbServer, databaseNam
 	if len(dbServer.Spec.Options) > 0 {
 		connectInfo.Options = dbServer.Spec.Options
 	}
+	connectInfo.ServerKey = GetDbServerKey(dbServer)
+	connectInfo.MaxOpenConnections = dbServer.Spec.MaxOpenConnections
 
 	if databaseName != nil {
 		connectInfo.Database = *databaseName
@@ -454,8 +477,9 @@ func (r *Reco) GetStorageActions(storageType string, storageLocation string) (St
 		if err != nil {
 			return nil, err
 		}
-		storage := &S3StorageInfo{
-			S3Storage: s3,
+		storage, err := NewS3StorageInfo(&r.K8sClient, s3)
+		if err != nil {
+			return nil, err
 		}
 		return storage, nil
 	} else {
@@ -25,6 +25,7 @@ import (
 	"go.uber.org/zap"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -35,8 +36,9 @@ import (
 // SchemaReconciler reconciles a Schema object
 type SchemaReconciler struct {
 	client.Client
-	Log    *zap.Logger
-	Scheme *runtime.Scheme
+	Log      *zap.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=db-operator.kubemaster.com,resources=schemas,verbs=get;list;watch;create;update;patch;delete
@@ -84,7 +86,7 @@ func (s *SchemaReco) LoadCR() (ctrl.Result, error) {
 func (s *SchemaReco) LoadObj() (bool, error) {
 	var err error
 	// First create conninfo without db name because we don't know whether it exists
-	dbServer, err := GetDbServer(s.schema.Spec.Server, s.Client, s.NsNm.Namespace)
+	dbServer, err := LookupDbServer(s.schema.Spec.GetServerRef(), s.Client, s.NsNm.Namespace)
 	if err != nil {
 		if !shared.CannotFindError(err, s.Log, "Schema", s.NsNm.Namespace, s.NsNm.Name) {
 			s.LogError(err, "failed getting Schema")
@@ -92,6 +94,10 @@ func (s *SchemaReco) LoadObj() (bool, error) {
 		}
 		return false, nil
 	}
+	err = s.AuthorizeDbServerUse(dbServer, &s.schema, &s.schema.Status.Conditions, nil)
+	if err != nil {
+		return false, err
+	}
 
 	creators := []string{}
 	if s.schema.Spec.Creator != nil {
@@ -122,8 +128,20 @@ func (s *SchemaReco) LoadObj() (bool, error) {
 	return exists, nil
 }
 
+func (s *SchemaReco) managedObject() ManagedObject {
+	return ManagedObject{
+		Conn:        s.conn,
+		Cr:          &s.schema,
+		Conditions:  &s.schema.Status.Conditions,
+		Kind:        "Schema",
+		ObjectType:  shared.MANAGED_SCHEMA,
+		ObjectName:  s.schema.Spec.Name,
+		Adopt:       s.schema.Spec.Adopt,
+		DropAdopted: s.schema.Spec.DropAdopted,
+	}
+}
+
 func (s *SchemaReco) CreateObj() (ctrl.Result, error) {
-	s.Log.Info(fmt.Sprintf("Creating schema %s", s.schema.Spec.Name))
 	var err error
 	if s.conn == nil {
 		message := "no database connection possible"
@@ -131,10 +149,23 @@ func (s *SchemaReco) CreateObj() (ctrl.Result, error) {
 		s.LogError(err, message)
 		return shared.GradualBackoffRetry(s.schema.GetCreationTimestamp().Time), nil
 	}
+	mayCreate, err := s.MayCreate(s.managedObject())
+	if err != nil {
+		return s.LogAndBackoffCreation(err, s.GetCR())
+	}
+	if !mayCreate {
+		s.Log.Info(fmt.Sprintf("schema %s doesn't exist and adopt is %s", s.schema.Spec.Name, s.schema.Spec.Adopt))
+		return shared.GradualBackoffRetry(s.schema.GetCreationTimestamp().Time), nil
+	}
+	s.Log.Info(fmt.Sprintf("Creating schema %s", s.schema.Spec.Name))
 	err = s.conn.CreateSchema(s.schema.Spec.Name, s.schema.Spec.Creator)
 	if err != nil {
 		return s.LogAndBackoffCreation(err, s.GetCR())
 	}
+	err = s.MarkCreated(s.managedObject())
+	if err != nil {
+		return s.LogAndBackoffCreation(err, s.GetCR())
+	}
 	if !s.schema.Status.Created {
 		s.SetStatus(&s.schema, true)
 	}
@@ -142,7 +173,8 @@ func (s *SchemaReco) CreateObj() (ctrl.Result, error) {
 }
 
 func (s *SchemaReco) SetStatus(schema *dboperatorv1alpha1.Schema, created bool) error {
-	newStatus := dboperatorv1alpha1.SchemaStatus{Created: created}
+	newStatus := *schema.Status.DeepCopy()
+	newStatus.Created = created
 	if !reflect.DeepEqual(schema.Status, newStatus) {
 		schema.Status = newStatus
 		err := s.Client.Status().Update(s.Ctx, schema)
@@ -156,16 +188,28 @@ func (s *SchemaReco) SetStatus(schema *dboperatorv1alpha1.Schema, created bool)
 }
 
 func (s *SchemaReco) RemoveObj() (ctrl.Result, error) {
+	mayDrop := false
 	if s.schema.Spec.DropOnDeletion {
-		s.Log.Info(fmt.Sprintf("dropping schema %s.%s", s.schema.Spec.DbName, s.schema.Name))
-		err := s.conn.DropSchema(s.schema.Name, s.schema.Spec.Creator, s.schema.Spec.CascadeOnDrop)
+		var err error
+		mayDrop, err = s.MayDrop(s.managedObject())
 		if err != nil {
-			return s.LogAndBackoffCreation(err, s.GetCR())
+			return s.LogAndBackoffDeletion(err, s.GetCR())
 		}
-		s.Log.Info(fmt.Sprintf("finalized schema %s.%s", s.schema.Spec.DbName, s.schema.Spec.Name))
 	} else {
 		s.Log.Info(fmt.Sprintf("did not drop db %s.%s", s.schema.Spec.DbName, s.schema.Spec.Name))
 	}
+	if mayDrop {
+		s.Log.Info(fmt.Sprintf("dropping schema %s.%s", s.schema.Spec.DbName, s.schema.Spec.Name))
+		err := s.conn.DropSchema(s.schema.Spec.Name, s.schema.Spec.Creator, s.schema.Spec.CascadeOnDrop)
+		if err != nil {
+			return s.LogAndBackoffCreation(err, s.GetCR())
+		}
+		err = s.conn.ForgetManagedBy(shared.MANAGED_SCHEMA, s.schema.Spec.Name)
+		if err != nil {
+			return s.LogAndBackoffDeletion(err, s.GetCR())
+		}
+		s.Log.Info(fmt.Sprintf("finalized schema %s.%s", s.schema.Spec.DbName, s.schema.Spec.Name))
+	}
 	return ctrl.Result{}, nil
 }
 
@@ -174,6 +218,10 @@ func (s *SchemaReco) GetCR() client.Object {
 }
 
 func (r *SchemaReco) EnsureCorrect() (ctrl.Result, error) {
+	_, err := r.EnsureManaged(r.managedObject())
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
 	return ctrl.Result{}, nil
 }
 
@@ -186,7 +234,7 @@ func (s *SchemaReco) CleanupConn() {
 func (s *SchemaReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := s.Log.With(zap.String("Namespace", req.Namespace)).With(zap.String("Name", req.Name))
 	sr := SchemaReco{}
-	sr.Reco = Reco{shared.K8sClient{Client: s.Client, Ctx: ctx, NsNm: req.NamespacedName, Log: log}}
+	sr.Reco = Reco{shared.K8sClient{Client: s.Client, Ctx: ctx, NsNm: req.NamespacedName, Log: log, Recorder: s.Recorder}}
 	return sr.Reco.Reconcile(&sr)
 }
 
@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/shared"
+)
+
+const EVENT_REASON_BINDING_SECRET_UPDATED = "BindingSecretUpdated"
+
+// Name of the Service Binding Secret of a Db or User, the kind keeps a Db and a User with the same name apart
+func ServiceBindingSecretName(kind string, name string) string {
+	return fmt.Sprintf("%s-%s-binding", name, strings.ToLower(kind))
+}
+
+// The CA certificate from the secret of the user, or else from the secret of the DbServer
+func (r *Reco) getBindingCaCert(creds *shared.Credentials, dbServer *dboperatorv1alpha1.DbServer) (*string, error) {
+	if creds.CaCert != nil {
+		return creds.CaCert, nil
+	}
+	if dbServer.Spec.CaCertKey == "" {
+		return nil, nil
+	}
+	serverCreds, err := r.GetCredentials(dbServer)
+	if err != nil {
+		return nil, err
+	}
+	return serverCreds.CaCert, nil
+}
+
+// Writes the Service Binding Secret of cr and points status.binding at it
+func (r *Reco) applyServiceBinding(cr client.Object, kind string, binding **dboperatorv1alpha1.BindingReference, scheme *runtime.Scheme, dbServer *dboperatorv1alpha1.DbServer, values shared.ConnectionValues, creds *shared.Credentials) error {
+	caCert, err := r.getBindingCaCert(creds, dbServer)
+	if err != nil {
+		return err
+	}
+	secret := v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ServiceBindingSecretName(kind, cr.GetName()),
+			Namespace: cr.GetNamespace(),
+		},
+		Type: v1.SecretType(shared.ServiceBindingSecretType(dbServer.Spec.ServerType)),
+		Data: shared.BuildServiceBindingData(dbServer.Spec.ServerType, values, caCert),
+	}
+	result, err := applyOwnedSecret(r.Client, r.Ctx, scheme, cr, secret)
+	if err != nil {
+		return err
+	}
+	if result != controllerutil.OperationResultNone {
+		message := fmt.Sprintf("%s binding secret %s of %s", result, secret.Name, cr.GetName())
+		r.Log.Info(message)
+		if result == controllerutil.OperationResultUpdated && r.Recorder != nil {
+			r.Recorder.Event(cr, v1.EventTypeNormal, EVENT_REASON_BINDING_SECRET_UPDATED, message)
+		}
+	}
+
+	if *binding != nil && (*binding).Name == secret.Name {
+		return nil
+	}
+	// The secret got another name, for instance the <name>-binding secrets of older versions
+	if *binding != nil {
+		err = r.deleteBindingSecret(cr, (*binding).Name)
+		if err != nil {
+			return err
+		}
+	}
+	*binding = &dboperatorv1alpha1.BindingReference{Name: secret.Name}
+	return r.Client.Status().Update(r.Ctx, cr)
+}
+
+// Deletes the binding secret when it's owned by cr
+func (r *Reco) deleteBindingSecret(cr client.Object, name string) error {
+	secret := v1.Secret{}
+	err := r.Client.Get(r.Ctx, types.NamespacedName{Namespace: cr.GetNamespace(), Name: name}, &secret)
+	if err == nil && metav1.IsControlledBy(&secret, cr) {
+		r.Log.Info(fmt.Sprintf("removing binding secret %s of %s", secret.Name, cr.GetName()))
+		err = r.Client.Delete(r.Ctx, &secret)
+	}
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// Removes the Service Binding Secret of cr once service_binding is turned off and clears status.binding
+func (r *Reco) removeServiceBinding(cr client.Object, binding **dboperatorv1alpha1.BindingReference) error {
+	if *binding == nil {
+		return nil
+	}
+	err := r.deleteBindingSecret(cr, (*binding).Name)
+	if err != nil {
+		return err
+	}
+	*binding = nil
+	return r.Client.Status().Update(r.Ctx, cr)
+}
+
+// Binds to the DbServer as the user, on the default database of the flavor
+func (r *UserReco) updateServiceBinding() error {
+	if !r.user.Spec.ServiceBinding {
+		return r.removeServiceBinding(&r.user, &r.user.Status.Binding)
+	}
+	values, creds, err := getUserConnectionValues(r.Client, r.Ctx, &r.user, r.dbServer, "")
+	if err != nil {
+		return err
+	}
+	return r.applyServiceBinding(&r.user, "User", &r.user.Status.Binding, r.scheme, r.dbServer, values, creds)
+}
+
+// Binds to the database as its owner
+func (r *DbReco) updateServiceBinding() error {
+	if !r.db.Spec.ServiceBinding {
+		return r.removeServiceBinding(&r.db, &r.db.Status.Binding)
+	}
+	if r.db.Spec.Owner == "" {
+		return fmt.Errorf("service_binding of db %s needs an owner, the binding has the credentials of the owner", r.db.Spec.DbName)
+	}
+	owner := dboperatorv1alpha1.User{}
+	err := r.Client.Get(r.Ctx, types.NamespacedName{Namespace: r.db.Namespace, Name: r.db.Spec.Owner}, &owner)
+	if err != nil {
+		return fmt.Errorf("failed getting owner %s of db %s: %s", r.db.Spec.Owner, r.db.Spec.DbName, err)
+	}
+	values, creds, err := getUserConnectionValues(r.Client, r.Ctx, &owner, r.dbServer, r.db.Spec.DbName)
+	if err != nil {
+		return err
+	}
+	return r.applyServiceBinding(&r.db, "Db", &r.db.Status.Binding, r.scheme, r.dbServer, values, creds)
+}
@@ -0,0 +1,264 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/shared"
+	"go.uber.org/zap"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// SqlCronJobReconciler reconciles a SqlCronJob object
+type SqlCronJobReconciler struct {
+	client.Client
+	Log    *zap.Logger
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=db-operator.kubemaster.com,resources=sqlcronjobs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=db-operator.kubemaster.com,resources=sqlcronjobs/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=db-operator.kubemaster.com,resources=sqlcronjobs/finalizers,verbs=update
+
+type SqlCronJobReco struct {
+	Reco
+	sqlCronJob   dboperatorv1alpha1.SqlCronJob
+	cronJob      *batchv1.CronJob
+	StatusWriter client.StatusWriter
+	scheme       *runtime.Scheme
+}
+
+func (r *SqlCronJobReco) LoadCR() (ctrl.Result, error) {
+	err := r.Client.Get(r.Ctx, r.NsNm, &r.sqlCronJob)
+	if err != nil {
+		r.Log.Info(fmt.Sprintf("%T: %s does not exist", r.sqlCronJob, r.NsNm.Name))
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *SqlCronJobReco) LoadObj() (bool, error) {
+	cronJobs, err := r.GetCronJobMap()
+	if err != nil {
+		return false, err
+	}
+	cronJob, exists := cronJobs[r.sqlCronJob.Name]
+	if exists {
+		r.cronJob = &cronJob
+	}
+	return exists, nil
+}
+
+// Statements are sent to the client in one script, each ends with a semicolon
+func (r *SqlCronJobReco) getSql() string {
+	statements := []string{}
+	for _, statement := range r.sqlCronJob.Spec.Statements {
+		statement = strings.TrimSuffix(strings.TrimSpace(statement), ";")
+		if statement != "" {
+			statements = append(statements, statement+";")
+		}
+	}
+	return strings.Join(statements, "\n")
+}
+
+func (r *SqlCronJobReco) buildCronJob() (*batchv1.CronJob, error) {
+	actions, err := r.GetServerActionsFromDbName(r.sqlCronJob.Spec.Db)
+	if err != nil {
+		return nil, err
+	}
+	// SqlCronJobs created before user was required would otherwise run as the DbServer user
+	if r.sqlCronJob.Spec.User == "" {
+		return nil, fmt.Errorf("SqlCronJob %s has no user to run the statements as", r.sqlCronJob.Name)
+	}
+	user := &dboperatorv1alpha1.User{}
+	err = r.Client.Get(r.Ctx, types.NamespacedName{Namespace: r.NsNm.Namespace, Name: r.sqlCronJob.Spec.User}, user)
+	if err != nil {
+		return nil, fmt.Errorf("failed getting User %s: %s", r.sqlCronJob.Spec.User, err)
+	}
+	if user.Spec.SecretName == "" {
+		return nil, fmt.Errorf("User %s has no Secret with a password", r.sqlCronJob.Spec.User)
+	}
+
+	container := actions.BuildRunSqlContainer(r.getSql(), user)
+	cronJob := r.BuildCronJob(
+		[]v1.Container{},
+		container,
+		r.sqlCronJob.Name,
+		r.sqlCronJob.Spec.Interval,
+		r.sqlCronJob.Spec.Suspend,
+		r.sqlCronJob.Spec.ServiceAccount,
+	)
+	// Maintenance runs shouldn't pile up when a run takes longer than the interval
+	cronJob.Spec.ConcurrencyPolicy = batchv1.ForbidConcurrent
+	if r.sqlCronJob.Spec.Timeout != nil {
+		timeoutSecs := int64(r.sqlCronJob.Spec.Timeout.Seconds())
+		cronJob.Spec.JobTemplate.Spec.ActiveDeadlineSeconds = &timeoutSecs
+	}
+	// Changes of the CronJob status, like a finished run, reconcile the SqlCronJob
+	err = controllerutil.SetControllerReference(&r.sqlCronJob, &cronJob, r.scheme)
+	if err != nil {
+		return nil, err
+	}
+	return &cronJob, nil
+}
+
+func (r *SqlCronJobReco) CreateObj() (ctrl.Result, error) {
+	r.Log.Info(fmt.Sprintf("creating sqlCronJob %s", r.sqlCronJob.Name))
+	err := r.EnsureScripts()
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	cronJob, err := r.buildCronJob()
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	err = r.Client.Create(r.Ctx, cronJob)
+	if err != nil && !shared.AlreadyExistsError(err, r.Log, cronJob.Kind, cronJob.Namespace, cronJob.Name) {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	r.cronJob = cronJob
+	return ctrl.Result{}, r.UpdateStatus()
+}
+
+func (r *SqlCronJobReco) RemoveObj() (ctrl.Result, error) {
+	r.Log.Info(fmt.Sprintf("Removing SqlCronJob %s", r.sqlCronJob.Name))
+	err := r.Client.Delete(r.Ctx, r.cronJob)
+	return ctrl.Result{}, err
+}
+
+func (r *SqlCronJobReco) GetCR() client.Object {
+	return &r.sqlCronJob
+}
+
+// Updates the CronJob when the spec changed and records the last run
+func (r *SqlCronJobReco) EnsureCorrect() (ctrl.Result, error) {
+	err := r.EnsureScripts()
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	desired, err := r.buildCronJob()
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	if !equality.Semantic.DeepDerivative(desired.Spec, r.cronJob.Spec) {
+		r.Log.Info(fmt.Sprintf("updating cronJob %s", r.cronJob.Name))
+		r.cronJob.Spec = desired.Spec
+		err = r.Client.Update(r.Ctx, r.cronJob)
+		if err != nil {
+			return r.LogAndBackoffCreation(err, r.GetCR())
+		}
+	}
+	return ctrl.Result{}, r.UpdateStatus()
+}
+
+func (r *SqlCronJobReco) getLastRun() (*dboperatorv1alpha1.SqlCronJobRun, error) {
+	jobs := batchv1.JobList{}
+	err := r.Client.List(r.Ctx, &jobs, client.InNamespace(r.NsNm.Namespace))
+	if err != nil {
+		return nil, err
+	}
+	var lastJob *batchv1.Job
+	for i, job := range jobs.Items {
+		owner := metav1.GetControllerOf(&job)
+		if owner == nil || owner.Kind != "CronJob" || owner.Name != r.sqlCronJob.Name {
+			continue
+		}
+		if lastJob == nil || lastJob.CreationTimestamp.Before(&job.CreationTimestamp) {
+			lastJob = &jobs.Items[i]
+		}
+	}
+	if lastJob == nil {
+		return nil, nil
+	}
+
+	run := &dboperatorv1alpha1.SqlCronJobRun{
+		JobName:   lastJob.Name,
+		StartTime: lastJob.Status.StartTime,
+		Result:    dboperatorv1alpha1.SQL_RUN_RUNNING,
+	}
+	var endTime *metav1.Time
+	for _, condition := range lastJob.Status.Conditions {
+		if condition.Status != v1.ConditionTrue {
+			continue
+		}
+		if condition.Type == batchv1.JobComplete {
+			run.Result = dboperatorv1alpha1.SQL_RUN_SUCCEEDED
+			endTime = lastJob.Status.CompletionTime
+		} else if condition.Type == batchv1.JobFailed {
+			run.Result = dboperatorv1alpha1.SQL_RUN_FAILED
+			run.Message = condition.Message
+			endTime = &condition.LastTransitionTime
+		}
+	}
+	if run.StartTime != nil && endTime != nil {
+		run.Duration = &metav1.Duration{Duration: endTime.Sub(run.StartTime.Time).Round(time.Second)}
+	}
+	return run, nil
+}
+
+func (r *SqlCronJobReco) UpdateStatus() error {
+	lastRun, err := r.getLastRun()
+	if err != nil {
+		return err
+	}
+	newStatus := dboperatorv1alpha1.SqlCronJobStatus{
+		Exists:      true,
+		CronJobName: r.sqlCronJob.Name,
+		LastRun:     lastRun,
+	}
+	if reflect.DeepEqual(r.sqlCronJob.Status, newStatus) {
+		return nil
+	}
+	r.sqlCronJob.Status = newStatus
+	return r.StatusWriter.Update(r.Ctx, &r.sqlCronJob)
+}
+
+func (r *SqlCronJobReco) CleanupConn() {
+}
+
+func (r *SqlCronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.With(zap.String("Namespace", req.Namespace)).With(zap.String("Name", req.Name))
+
+	sr := SqlCronJobReco{
+		Reco:         Reco{shared.K8sClient{Client: r.Client, Ctx: ctx, NsNm: req.NamespacedName, Log: log}},
+		StatusWriter: r.Status(),
+		scheme:       r.Scheme,
+	}
+	return sr.Reco.Reconcile(&sr)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *SqlCronJobReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&dboperatorv1alpha1.SqlCronJob{}).
+		Owns(&batchv1.CronJob{}).
+		Complete(r)
+}
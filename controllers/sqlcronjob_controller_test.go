@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"testing"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestBuildSqlCronJob(t *testing.T) {
+	db := func(namespace string) *dboperatorv1alpha1.Db {
+		return &dboperatorv1alpha1.Db{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: namespace},
+			Spec: dboperatorv1alpha1.DbSpec{
+				DbName:    "app",
+				Owner:     "app-owner",
+				ServerRef: &dboperatorv1alpha1.DbServerRef{Name: "postgres", Namespace: "databases"},
+			},
+		}
+	}
+	user := func(name string, secretName string) *dboperatorv1alpha1.User {
+		return &dboperatorv1alpha1.User{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop"},
+			Spec:       dboperatorv1alpha1.UserSpec{UserName: name, SecretName: secretName},
+		}
+	}
+	apiClient := newFakeClient(t,
+		sharedDbServer(&dboperatorv1alpha1.AllowedNamespaces{Names: []string{"shop"}}),
+		db("shop"),
+		db("blog"),
+		user("app-owner", "app-owner-credentials"),
+		user("app-maintenance", "app-maintenance-credentials"),
+	)
+	sqlCronJob := func(namespace string, userName string) SqlCronJobReco {
+		return SqlCronJobReco{
+			Reco: Reco{*newK8sClient(apiClient, namespace)},
+			sqlCronJob: dboperatorv1alpha1.SqlCronJob{
+				ObjectMeta: metav1.ObjectMeta{Name: "vacuum", Namespace: namespace},
+				Spec: dboperatorv1alpha1.SqlCronJobSpec{
+					Db: "app", User: userName, Interval: "0 3 * * *", Statements: []string{"VACUUM ANALYZE"},
+				},
+			},
+			scheme: apiClient.Scheme(),
+		}
+	}
+
+	reco := sqlCronJob("shop", "app-maintenance")
+	cronJob, err := reco.buildCronJob()
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	secrets := []string{}
+	for _, envVar := range cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Env {
+		if envVar.ValueFrom != nil && envVar.ValueFrom.SecretKeyRef != nil {
+			secrets = append(secrets, envVar.ValueFrom.SecretKeyRef.Name)
+		}
+	}
+	if len(secrets) != 1 || secrets[0] != "app-maintenance-credentials" {
+		t.Errorf("expected the statements to run as app-maintenance got Secrets %v", secrets)
+	}
+
+	reco = sqlCronJob("shop", "")
+	_, err = reco.buildCronJob()
+	if err == nil {
+		t.Errorf("expected an error for a SqlCronJob without a user")
+	}
+
+	reco = sqlCronJob("blog", "app-maintenance")
+	_, err = reco.buildCronJob()
+	if !isAccessDenied(err) {
+		t.Errorf("expected namespace blog to be denied got %v", err)
+	}
+}
@@ -1,13 +1,18 @@
 package controllers
 
 import (
+	"fmt"
 	path "path/filepath"
 
 	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
 	"github.com/obeleh/db-operator/shared"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
 )
 
+//+kubebuilder:rbac:groups=db-operator.kubemaster.com,resources=clusters3storages,verbs=get;list;watch
+
 type StorageActions interface {
 	BuildContainer(script string, fixedFileName *string) v1.Container
 	BuildUploadContainer(fixedFileName *string) v1.Container
@@ -19,6 +24,50 @@ type S3StorageInfo struct {
 	S3Storage dboperatorv1alpha1.S3Storage
 }
 
+// Gets the S3Storage in the local namespace, or the ClusterS3Storage with that name when there is none
+func GetS3Storage(k8sClient *shared.K8sClient, storageLocation string) (*dboperatorv1alpha1.S3Storage, error) {
+	s3Storage := &dboperatorv1alpha1.S3Storage{}
+	nsName := types.NamespacedName{
+		Name:      storageLocation,
+		Namespace: k8sClient.NsNm.Namespace,
+	}
+	err := k8sClient.Client.Get(k8sClient.Ctx, nsName, s3Storage)
+	if err == nil || !errors.IsNotFound(err) {
+		return s3Storage, err
+	}
+
+	clusterS3Storage := &dboperatorv1alpha1.ClusterS3Storage{}
+	clusterErr := k8sClient.Client.Get(k8sClient.Ctx, types.NamespacedName{Name: storageLocation}, clusterS3Storage)
+	if clusterErr != nil {
+		if errors.IsNotFound(clusterErr) {
+			// Report the S3Storage as missing, that's what most users will be looking for
+			return s3Storage, err
+		}
+		return s3Storage, clusterErr
+	}
+	operatorNamespace, err := shared.GetOperatorNamespace()
+	if err != nil {
+		return s3Storage, err
+	}
+	return clusterS3Storage.AsS3Storage(operatorNamespace), nil
+}
+
+// A ClusterS3Storage lives in the operator namespace, the namespace of the Job has to be allowed to use it
+// Pods can only use Secrets from their own namespace and the access key Secret is never copied, so the Job
+// has to get access through assume_role_arn and its service account
+func NewS3StorageInfo(k8sClient *shared.K8sClient, s3Storage dboperatorv1alpha1.S3Storage) (*S3StorageInfo, error) {
+	if s3Storage.Namespace != k8sClient.NsNm.Namespace {
+		err := CheckStorageNamespaceAllowed(k8sClient.Ctx, k8sClient.Client, &s3Storage, k8sClient.NsNm.Namespace)
+		if err != nil {
+			return nil, err
+		}
+		if s3Storage.Spec.AccessKeyK8sSecret != "" {
+			return nil, fmt.Errorf("%s %s has an access key Secret in namespace %s that Jobs in namespace %s can't read, use assume_role_arn instead", shared.Nvl(s3Storage.Kind, "S3Storage"), s3Storage.Name, s3Storage.Namespace, k8sClient.NsNm.Namespace)
+		}
+	}
+	return &S3StorageInfo{S3Storage: s3Storage}, nil
+}
+
 func (s *S3StorageInfo) GetBucketStorageInfo(k8sClient shared.K8sClient) (shared.BucketStorageInfo, error) {
 	storageInfo := shared.BucketStorageInfo{
 		StorageTypeName: "s3",
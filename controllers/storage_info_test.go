@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/dbservers/postgres"
+	"github.com/obeleh/db-operator/shared"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newK8sClient(apiClient client.Client, namespace string) *shared.K8sClient {
+	return &shared.K8sClient{
+		Client: apiClient,
+		Ctx:    context.Background(),
+		NsNm:   types.NamespacedName{Name: "backup", Namespace: namespace},
+		Log:    zap.NewNop(),
+	}
+}
+
+func TestNewS3StorageInfoForClusterS3Storage(t *testing.T) {
+	apiClient := newFakeClient(t)
+	clusterS3Storage := dboperatorv1alpha1.ClusterS3Storage{
+		ObjectMeta: metav1.ObjectMeta{Name: "backups"},
+		Spec: dboperatorv1alpha1.S3StorageSpec{
+			BucketName:        "backups",
+			RoleArn:           "arn:aws:iam::123456789012:role/backups",
+			AllowedNamespaces: &dboperatorv1alpha1.AllowedNamespaces{Names: []string{"shop"}},
+		},
+	}
+	s3Storage := clusterS3Storage.AsS3Storage("db-operator")
+
+	storageInfo, err := NewS3StorageInfo(newK8sClient(apiClient, "shop"), *s3Storage)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	container := storageInfo.BuildUploadContainer(nil)
+	for _, envVar := range container.Env {
+		if envVar.ValueFrom != nil {
+			t.Errorf("expected no Secret references in the container, got %s", envVar.Name)
+		}
+	}
+
+	_, err = NewS3StorageInfo(newK8sClient(apiClient, "blog"), *s3Storage)
+	if !isAccessDenied(err) {
+		t.Errorf("expected namespace blog to be denied got %v", err)
+	}
+
+	s3Storage.Spec.AccesKeyId = "AKIAEXAMPLE"
+	s3Storage.Spec.AccessKeyK8sSecret = "s3-credentials"
+	_, err = NewS3StorageInfo(newK8sClient(apiClient, "shop"), *s3Storage)
+	if err == nil {
+		t.Errorf("expected an error for an access key Secret in the operator namespace")
+	}
+}
+
+func TestGetServerActionsForClusterDbServer(t *testing.T) {
+	clusterDbServer := dboperatorv1alpha1.ClusterDbServer{
+		ObjectMeta: metav1.ObjectMeta{Name: "shared-postgres"},
+		Spec:       dboperatorv1alpha1.DbServerSpec{ServerType: "postgres", UserName: "postgres", SecretName: "postgres-credentials"},
+	}
+	dbServer := clusterDbServer.AsDbServer("db-operator")
+	owner := &dboperatorv1alpha1.User{
+		ObjectMeta: metav1.ObjectMeta{Name: "shop-owner", Namespace: "shop"},
+		Spec:       dboperatorv1alpha1.UserSpec{UserName: "shop_owner", SecretName: "shop-owner-secret"},
+	}
+	apiClient := newFakeClient(t, owner)
+	k8sClient := newK8sClient(apiClient, "shop")
+
+	db := &dboperatorv1alpha1.Db{
+		ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "shop"},
+		Spec:       dboperatorv1alpha1.DbSpec{DbName: "shop"},
+	}
+	_, err := GetServerActions(k8sClient, dbServer, db)
+	if err == nil {
+		t.Errorf("expected an error for a Db without an owner on a ClusterDbServer")
+	}
+
+	db.Spec.Owner = "shop-owner"
+	actions, err := GetServerActions(k8sClient, dbServer, db)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	userName, secretName, _ := actions.(*postgres.PostgresActions).GetJobCredentials()
+	if userName != "shop_owner" || secretName != "shop-owner-secret" {
+		t.Errorf("expected the Jobs to connect as the owner got %s with secret %s", userName, secretName)
+	}
+
+	localDbServer := &dboperatorv1alpha1.DbServer{
+		ObjectMeta: metav1.ObjectMeta{Name: "postgres", Namespace: "shop"},
+		Spec:       dboperatorv1alpha1.DbServerSpec{ServerType: "postgres", UserName: "postgres", SecretName: "postgres-credentials"},
+	}
+	actions, err = GetServerActions(k8sClient, localDbServer, db)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	userName, secretName, _ = actions.(*postgres.PostgresActions).GetJobCredentials()
+	if userName != "postgres" || secretName != "postgres-credentials" {
+		t.Errorf("expected the Jobs to connect as the DbServer user got %s with secret %s", userName, secretName)
+	}
+}
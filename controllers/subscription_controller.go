@@ -0,0 +1,306 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/dbservers/postgres"
+	"github.com/obeleh/db-operator/shared"
+	funk "github.com/thoas/go-funk"
+)
+
+// SubscriptionReconciler reconciles a Subscription object
+type SubscriptionReconciler struct {
+	client.Client
+	Log      *zap.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=db-operator.kubemaster.com,resources=subscriptions,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=db-operator.kubemaster.com,resources=subscriptions/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=db-operator.kubemaster.com,resources=subscriptions/finalizers,verbs=update
+
+const EVENT_REASON_SUBSCRIPTION_UPDATED = "SubscriptionUpdated"
+
+// Interval to refresh the lag in the status and to pick up tables added to the publications
+const SUBSCRIPTION_RESYNC_INTERVAL = time.Minute
+
+type SubscriptionReco struct {
+	Reco
+	db                 dboperatorv1alpha1.Db
+	sourceDb           dboperatorv1alpha1.Db
+	subscription       dboperatorv1alpha1.Subscription
+	dbSideSubscription *shared.DbSideSubscription
+	sourceServer       *dboperatorv1alpha1.DbServer
+	conn               shared.DbServerConnectionInterface
+}
+
+func (r *SubscriptionReco) LoadCR() (ctrl.Result, error) {
+	err := r.Client.Get(r.Ctx, r.NsNm, &r.subscription)
+	if err != nil {
+		r.Log.Info(fmt.Sprintf("%T: %s does not exist, %s", r.subscription, r.NsNm.Name, err))
+		return ctrl.Result{}, err
+	}
+
+	dbNsm := types.NamespacedName{
+		Namespace: r.NsNm.Namespace,
+		Name:      r.subscription.Spec.Db,
+	}
+	err = r.Client.Get(r.Ctx, dbNsm, &r.db)
+	if err != nil {
+		if r.subscription.GetDeletionTimestamp() != nil {
+			// Db got deleted before the subscription did
+			err = r.RemoveFinalizer(&r.subscription)
+			if err != nil {
+				return shared.GradualBackoffRetry(r.subscription.GetCreationTimestamp().Time), nil
+			}
+			return ctrl.Result{}, nil
+		}
+		r.Log.Info(fmt.Sprintf("%T: %s does not exist, %s", r.db, dbNsm, err))
+		return shared.GradualBackoffRetry(r.subscription.GetCreationTimestamp().Time), nil
+	}
+	if r.subscription.GetDeletionTimestamp() != nil {
+		// Dropping the subscription only needs the target db
+		return ctrl.Result{}, nil
+	}
+
+	sourceDbNsm := types.NamespacedName{
+		Namespace: r.NsNm.Namespace,
+		Name:      r.subscription.Spec.SourceDb,
+	}
+	err = r.Client.Get(r.Ctx, sourceDbNsm, &r.sourceDb)
+	if err != nil {
+		r.Log.Info(fmt.Sprintf("%T: %s does not exist, %s", r.sourceDb, sourceDbNsm, err))
+		return shared.GradualBackoffRetry(r.subscription.GetCreationTimestamp().Time), nil
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *SubscriptionReco) LoadObj() (bool, error) {
+	dbServer, err := LookupDbServer(r.db.Spec.GetServerRef(), r.Client, r.NsNm.Namespace)
+	if err != nil {
+		if !shared.CannotFindError(err, r.Log, "DbServer", r.NsNm.Namespace, r.NsNm.Name) {
+			r.LogError(err, "failed getting DbServer")
+			return false, err
+		}
+		return false, nil
+	}
+	err = r.AuthorizeDbServerUse(dbServer, &r.subscription, &r.subscription.Status.Conditions, nil)
+	if err != nil {
+		return false, err
+	}
+	if r.subscription.GetDeletionTimestamp() == nil {
+		r.sourceServer, err = LookupDbServer(r.sourceDb.Spec.GetServerRef(), r.Client, r.NsNm.Namespace)
+		if err != nil {
+			r.LogError(err, "failed getting source DbServer")
+			return false, err
+		}
+		// The credentials of the source DbServer end up in the subscription
+		err = r.CheckNamespaceAllowed(r.sourceServer, r.NsNm.Namespace)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	conn, err := r.GetDbConnection(dbServer, nil, &r.db.Spec.DbName)
+	if err != nil {
+		r.LogError(err, "failed building dbConnection")
+		return false, err
+	}
+	r.conn = conn
+
+	r.dbSideSubscription, err = r.conn.GetSubscription(r.subscription.Spec.Name)
+	if err != nil {
+		r.LogError(err, "failed getting Subscription")
+		return false, err
+	}
+	return r.dbSideSubscription != nil, nil
+}
+
+// Connection string to the source db with the credentials of the source DbServer
+func (r *SubscriptionReco) getConnInfo() (string, error) {
+	connectInfo, err := r.GetConnectInfo(r.sourceServer, &r.sourceDb.Spec.DbName)
+	if err != nil {
+		return "", err
+	}
+	return postgres.BuildConnInfo(connectInfo), nil
+}
+
+// Tables the publications of the subscription publish in the source db
+func (r *SubscriptionReco) getPublishedTables() ([]string, error) {
+	sourceConn, err := r.GetDbConnection(r.sourceServer, nil, &r.sourceDb.Spec.DbName)
+	if err != nil {
+		return nil, err
+	}
+	defer sourceConn.Close()
+
+	tables := []string{}
+	for _, name := range r.subscription.Spec.Publications {
+		publication, err := sourceConn.GetPublication(name)
+		if err != nil {
+			return nil, err
+		}
+		if publication == nil {
+			return nil, fmt.Errorf("publication %s does not exist in db %s", name, r.sourceDb.Spec.DbName)
+		}
+		for _, table := range publication.Tables {
+			if !funk.ContainsString(tables, table) {
+				tables = append(tables, table)
+			}
+		}
+	}
+	return tables, nil
+}
+
+func (r *SubscriptionReco) CreateObj() (ctrl.Result, error) {
+	if r.conn == nil {
+		err := fmt.Errorf("no database connection possible")
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	connInfo, err := r.getConnInfo()
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	r.Log.Info(fmt.Sprintf("Creating subscription %s in db %s", r.subscription.Spec.Name, r.db.Spec.DbName))
+	err = r.conn.CreateSubscription(r.subscription.Spec, connInfo)
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	err = r.updateStatus(true, shared.HashSecretValue(connInfo))
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	return ctrl.Result{RequeueAfter: SUBSCRIPTION_RESYNC_INTERVAL}, nil
+}
+
+func (r *SubscriptionReco) RemoveObj() (ctrl.Result, error) {
+	if !r.subscription.Spec.DropOnDeletion {
+		r.Log.Info(fmt.Sprintf("did not drop subscription %s in db %s as per spec", r.subscription.Spec.Name, r.db.Spec.DbName))
+		return ctrl.Result{}, nil
+	}
+	r.Log.Info(fmt.Sprintf("dropping subscription %s in db %s", r.subscription.Spec.Name, r.db.Spec.DbName))
+	err := r.conn.DropSubscription(r.subscription.Spec.Name)
+	if err != nil {
+		return r.LogAndBackoffDeletion(err, r.GetCR())
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *SubscriptionReco) GetCR() client.Object {
+	return &r.subscription
+}
+
+func (r *SubscriptionReco) EnsureCorrect() (ctrl.Result, error) {
+	connInfo, err := r.getConnInfo()
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	publishedTables, err := r.getPublishedTables()
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	// The connection of the subscription can't be read without superuser, the hash in the status tells whether it changed
+	connInfoHash := shared.HashSecretValue(connInfo)
+	if r.subscription.Status.ConnInfoHash == connInfoHash {
+		connInfo = ""
+	}
+	changed, err := r.conn.UpdateSubscription(r.subscription.Spec, connInfo, publishedTables)
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	if changed {
+		message := fmt.Sprintf("updated subscription %s in db %s", r.subscription.Spec.Name, r.db.Spec.DbName)
+		r.Log.Info(message)
+		if r.Recorder != nil {
+			r.Recorder.Event(&r.subscription, v1.EventTypeNormal, EVENT_REASON_SUBSCRIPTION_UPDATED, message)
+		}
+	}
+	err = r.updateStatus(changed, connInfoHash)
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	return ctrl.Result{RequeueAfter: SUBSCRIPTION_RESYNC_INTERVAL}, nil
+}
+
+// Shows the subscribed tables and the replication lag from pg_stat_subscription, reload after changing the subscription
+// connInfoHash is the hash of the connection string the subscription uses now
+func (r *SubscriptionReco) updateStatus(reload bool, connInfoHash string) error {
+	if reload {
+		subscription, err := r.conn.GetSubscription(r.subscription.Spec.Name)
+		if err != nil {
+			return err
+		}
+		r.dbSideSubscription = subscription
+	}
+	status := r.subscription.Status.DeepCopy()
+	status.Tables = nil
+	status.ReceivedLsn = ""
+	status.LatestEndLsn = ""
+	status.Lag = nil
+	status.ConnInfoHash = connInfoHash
+	if r.dbSideSubscription != nil {
+		if len(r.dbSideSubscription.Tables) > 0 {
+			status.Tables = r.dbSideSubscription.Tables
+		}
+		status.ReceivedLsn = r.dbSideSubscription.ReceivedLsn
+		status.LatestEndLsn = r.dbSideSubscription.LatestEndLsn
+		if r.dbSideSubscription.Lag != nil {
+			// Sub-second precision would update the status on every resync
+			status.Lag = &metav1.Duration{Duration: r.dbSideSubscription.Lag.Round(time.Second)}
+		}
+	}
+	if reflect.DeepEqual(&r.subscription.Status, status) {
+		return nil
+	}
+	r.subscription.Status = *status
+	return r.Client.Status().Update(r.Ctx, &r.subscription)
+}
+
+func (r *SubscriptionReco) CleanupConn() {
+	if r.conn != nil {
+		r.conn.Close()
+	}
+}
+
+func (r *SubscriptionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.With(zap.String("Namespace", req.Namespace)).With(zap.String("Name", req.Name))
+	sr := SubscriptionReco{}
+	sr.Reco = Reco{shared.K8sClient{Client: r.Client, Ctx: ctx, NsNm: req.NamespacedName, Log: log, Recorder: r.Recorder}}
+	return sr.Reco.Reconcile(&sr)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *SubscriptionReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&dboperatorv1alpha1.Subscription{}).
+		Complete(r)
+}
@@ -28,8 +28,13 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
 )
@@ -37,8 +42,9 @@ import (
 // UserReconciler reconciles a User object
 type UserReconciler struct {
 	client.Client
-	Log    *zap.Logger
-	Scheme *runtime.Scheme
+	Log      *zap.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=db-operator.kubemaster.com,resources=users,verbs=get;list;watch;create;update;patch;delete
@@ -47,17 +53,26 @@ type UserReconciler struct {
 
 type UserReco struct {
 	Reco
-	user  dboperatorv1alpha1.User
-	users map[string]shared.DbSideUser
-	conn  shared.DbServerConnectionInterface
+	user     dboperatorv1alpha1.User
+	users    map[string]shared.DbSideUser
+	conn     shared.DbServerConnectionInterface
+	dbServer *dboperatorv1alpha1.DbServer
+	scheme   *runtime.Scheme
+	// The spec the privileges are reconciled with, see getPrivsSpec
+	privsSpec dboperatorv1alpha1.UserSpec
 }
 
 func (r *UserReco) LoadObj() (bool, error) {
 	var err error
-	dbServer, err := GetDbServer(r.user.Spec.DbServerName, r.Client, r.NsNm.Namespace)
+	dbServer, err := LookupDbServer(r.user.Spec.GetDbServerRef(), r.Client, r.NsNm.Namespace)
 	if err != nil {
 		return false, err
 	}
+	err = r.AuthorizeDbServerUse(dbServer, &r.user, &r.user.Status.Conditions, &r.user.Spec)
+	if err != nil {
+		return false, err
+	}
+	r.dbServer = dbServer
 
 	grantorUserNames := GetGrantorNamesFromDbPrivs(r.user.Spec.DbPrivs)
 	conn, err := r.GetDbConnection(dbServer, grantorUserNames, nil)
@@ -113,7 +128,29 @@ func (r *UserReco) generateSecret() error {
 	return r.Client.Create(context.TODO(), secret)
 }
 
+func (r *UserReco) managedObject() ManagedObject {
+	return ManagedObject{
+		Conn:        r.conn,
+		Cr:          &r.user,
+		Conditions:  &r.user.Status.Conditions,
+		Kind:        "User",
+		ObjectType:  shared.MANAGED_USER,
+		ObjectName:  r.user.Spec.UserName,
+		Adopt:       r.user.Spec.Adopt,
+		DropAdopted: r.user.Spec.DropAdopted,
+	}
+}
+
 func (r *UserReco) CreateObj() (ctrl.Result, error) {
+	mayCreate, err := r.MayCreate(r.managedObject())
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	if !mayCreate {
+		r.Log.Info(fmt.Sprintf("user %s doesn't exist and adopt is %s", r.user.Spec.UserName, r.user.Spec.Adopt))
+		return shared.GradualBackoffRetry(r.user.GetCreationTimestamp().Time), nil
+	}
+
 	if r.user.Spec.GenerateSecret {
 		err := r.generateSecret()
 		if err != nil {
@@ -127,7 +164,11 @@ func (r *UserReco) CreateObj() (ctrl.Result, error) {
 		return r.LogAndBackoffCreation(err, r.GetCR())
 	}
 	r.Log.Info(fmt.Sprintf("Creating user %s", r.user.Spec.UserName))
-	err = r.conn.CreateUser(r.user.Spec.UserName, *creds.Password)
+	err = r.conn.CreateUser(r.user.Spec, *creds.Password)
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	err = r.MarkCreated(r.managedObject())
 	if err != nil {
 		return r.LogAndBackoffCreation(err, r.GetCR())
 	}
@@ -140,12 +181,24 @@ func (r *UserReco) CreateObj() (ctrl.Result, error) {
 }
 
 func (r *UserReco) RemoveObj() (ctrl.Result, error) {
+	mayDrop := false
 	if r.user.Spec.DropOnDeletion {
+		var err error
+		mayDrop, err = r.MayDrop(r.managedObject())
+		if err != nil {
+			return r.LogAndBackoffDeletion(err, r.GetCR())
+		}
+	}
+	if mayDrop {
 		r.Log.Info(fmt.Sprintf("Dropping user %s", r.user.Spec.UserName))
 		err := r.conn.DropUser(r.user.Spec)
 		if err != nil {
 			return r.LogAndBackoffDeletion(err, r.GetCR())
 		}
+		err = r.conn.ForgetManagedBy(shared.MANAGED_USER, r.user.Spec.UserName)
+		if err != nil {
+			return r.LogAndBackoffDeletion(err, r.GetCR())
+		}
 		r.Log.Info(fmt.Sprintf("finalized user %s", r.user.Spec.UserName))
 	}
 	r.NotifyChanges()
@@ -196,14 +249,62 @@ func (r *UserReco) EnsureCorrect() (ctrl.Result, error) {
 			}
 		}
 	*/
-	changes, err := r.conn.UpdateUserPrivs(r.user.Spec.UserName, r.user.Spec.ServerPrivs, r.user.Spec.DbPrivs)
+	managed, err := r.EnsureManaged(r.managedObject())
 	if err != nil {
 		return r.LogAndBackoffCreation(err, r.GetCR())
 	}
-	if changes {
+	if !managed {
+		// Options and privileges of a user that isn't managed by this resource are left alone
+		return ctrl.Result{}, nil
+	}
+	r.privsSpec, err = r.getPrivsSpec()
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	optionChanges, err := r.conn.UpdateUserOptions(r.user.Spec)
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	var changes bool
+	if r.isDryRun() {
+		changes, err = r.planOrApplyPrivs()
+	} else {
+		changes, err = r.reconcilePrivs()
+	}
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	if changes || optionChanges {
 		r.NotifyChanges()
 	}
-	return ctrl.Result{}, nil
+	err = r.updateConnectionSecret()
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	err = r.updateServiceBinding()
+	if err != nil {
+		return r.LogAndBackoffCreation(err, r.GetCR())
+	}
+	return ctrl.Result{RequeueAfter: r.getResyncInterval()}, nil
+}
+
+// On MySQL the owner of a Db gets ALL on the database, those privileges are added to the spec so they aren't revoked
+func (r *UserReco) getPrivsSpec() (dboperatorv1alpha1.UserSpec, error) {
+	spec := *r.user.Spec.DeepCopy()
+	if r.dbServer == nil || strings.ToLower(r.dbServer.Spec.ServerType) != "mysql" {
+		return spec, nil
+	}
+	dbList := dboperatorv1alpha1.DbList{}
+	err := r.Client.List(r.Ctx, &dbList, client.InNamespace(r.user.Namespace))
+	if err != nil {
+		return spec, err
+	}
+	for _, db := range dbList.Items {
+		if db.Spec.Owner == r.user.Name {
+			spec.DbPrivs = append(spec.DbPrivs, dboperatorv1alpha1.DbPriv{Scope: fmt.Sprintf("%s.*", db.Spec.DbName), Privs: "ALL"})
+		}
+	}
+	return spec, nil
 }
 
 func (r *UserReco) CleanupConn() {
@@ -215,7 +316,7 @@ func (r *UserReco) CleanupConn() {
 func (r *UserReco) NotifyChanges() {
 	r.Log.Info("Notifying of User changes")
 	// getting dbServer because we need to figure out in what namespace it lives
-	dbServer, err := GetDbServer(r.user.Spec.DbServerName, r.Client, r.user.Namespace)
+	dbServer, err := GetDbServer(r.user.Spec.GetDbServerRef(), r.Client, r.user.Namespace)
 	if err != nil {
 		r.LogError(err, "failed notifying DBServer")
 	}
@@ -225,18 +326,61 @@ func (r *UserReco) NotifyChanges() {
 func (r *UserReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.With(zap.String("Namespace", req.Namespace)).With(zap.String("Name", req.Name))
 	ur := UserReco{
-		Reco: Reco{shared.K8sClient{Client: r.Client, Ctx: ctx, NsNm: req.NamespacedName, Log: log}},
+		Reco:   Reco{shared.K8sClient{Client: r.Client, Ctx: ctx, NsNm: req.NamespacedName, Log: log, Recorder: r.Recorder}},
+		scheme: r.Scheme,
 	}
 	return ur.Reco.Reconcile(&ur)
 }
 
 // SetupWithManager sets up the controller with the Manager.
+// Connection secrets and binding secrets are kept up to date with the password secret, the Db and the DbServer they're built from
 func (r *UserReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&dboperatorv1alpha1.User{}).
+		Owns(&v1.Secret{}).
+		Watches(&v1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.usersForSecret)).
+		Watches(&dboperatorv1alpha1.Db{}, handler.EnqueueRequestsFromMapFunc(r.usersForDb), builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&dboperatorv1alpha1.DbServer{}, handler.EnqueueRequestsFromMapFunc(r.usersForDbServer), builder.WithPredicates(predicate.GenerationChangedPredicate{})).
 		Complete(r)
 }
 
+// Users with a connection secret or a binding secret whose inputs match
+func (r *UserReconciler) usersWithGeneratedSecrets(ctx context.Context, namespace string, matches func(user dboperatorv1alpha1.User) bool) []reconcile.Request {
+	userList := dboperatorv1alpha1.UserList{}
+	err := r.List(ctx, &userList, client.InNamespace(namespace))
+	if err != nil {
+		r.Log.Error(fmt.Sprintf("failed listing Users for generated secrets Error: %s", err))
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, user := range userList.Items {
+		if (user.Spec.ConnectionSecret != nil || user.Spec.ServiceBinding) && matches(user) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: user.Name, Namespace: user.Namespace}})
+		}
+	}
+	return requests
+}
+
+func (r *UserReconciler) usersForSecret(ctx context.Context, secret client.Object) []reconcile.Request {
+	return r.usersWithGeneratedSecrets(ctx, secret.GetNamespace(), func(user dboperatorv1alpha1.User) bool {
+		return user.Spec.SecretName == secret.GetName()
+	})
+}
+
+func (r *UserReconciler) usersForDb(ctx context.Context, db client.Object) []reconcile.Request {
+	return r.usersWithGeneratedSecrets(ctx, db.GetNamespace(), func(user dboperatorv1alpha1.User) bool {
+		return user.Spec.ConnectionSecret != nil && user.Spec.ConnectionSecret.Db == db.GetName()
+	})
+}
+
+// Only Users in the namespace of the DbServer, others pick up changes on their next resync
+func (r *UserReconciler) usersForDbServer(ctx context.Context, dbServer client.Object) []reconcile.Request {
+	return r.usersWithGeneratedSecrets(ctx, dbServer.GetNamespace(), func(user dboperatorv1alpha1.User) bool {
+		ref := user.Spec.GetDbServerRef()
+		return ref.Name == dbServer.GetName() && shared.Nvl(ref.Namespace, user.Namespace) == dbServer.GetNamespace() && ref.Kind != dboperatorv1alpha1.CLUSTER_DB_SERVER_KIND
+	})
+}
+
 func GetGrantorNamesFromDbPrivs(privs []dboperatorv1alpha1.DbPriv) []string {
 	userNames := []string{}
 	for _, priv := range privs {
@@ -10,7 +10,8 @@ import (
 	"github.com/obeleh/db-operator/shared"
 )
 
-func GetServerActions(dbServer *dboperatorv1alpha1.DbServer, db *dboperatorv1alpha1.Db, options map[string]string) (shared.DbActions, error) {
+// Jobs connect as the DbServer user, or as jobUser when it's set
+func GetServerActions(dbServer *dboperatorv1alpha1.DbServer, db *dboperatorv1alpha1.Db, jobUser *dboperatorv1alpha1.User, options map[string]string) (shared.DbActions, error) {
 	serverType := dbServer.Spec.ServerType
 	if strings.ToLower(serverType) == "postgres" || strings.ToLower(serverType) == "cockroachdb" {
 		return &postgres.PostgresActions{
@@ -18,6 +19,7 @@ func GetServerActions(dbServer *dboperatorv1alpha1.DbServer, db *dboperatorv1alp
 				DbServer: dbServer,
 				Db:       db,
 				Options:  options,
+				JobUser:  jobUser,
 			},
 		}, nil
 	} else if strings.ToLower(serverType) == "mysql" {
@@ -26,6 +28,7 @@ func GetServerActions(dbServer *dboperatorv1alpha1.DbServer, db *dboperatorv1alp
 				DbServer: dbServer,
 				Db:       db,
 				Options:  options,
+				JobUser:  jobUser,
 			},
 		}, nil
 	} else {
@@ -44,3 +47,57 @@ func GetServerConnection(serverType string, connectInfo *shared.DbServerConnectI
 		return nil, fmt.Errorf("expected either mysql or postgres server")
 	}
 }
+
+var SERVER_TYPES = []string{"postgres", "cockroachdb", "mysql"}
+
+func ValidateServerType(serverType string) error {
+	for _, validType := range SERVER_TYPES {
+		if strings.ToLower(serverType) == validType {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid server_type %s, expected one of %s", serverType, strings.Join(SERVER_TYPES, ", "))
+}
+
+// Runs the same privilege parsing as the reconcilers do, without connecting to the server
+func ValidateDbPriv(serverType string, dbPriv dboperatorv1alpha1.DbPriv) error {
+	flavor := strings.ToLower(serverType)
+	if flavor == "postgres" || flavor == "cockroachdb" {
+		return postgres.ValidateDbPriv(dbPriv, flavor)
+	} else if flavor == "mysql" {
+		return mysql.ValidateDbPriv(dbPriv)
+	}
+	return ValidateServerType(serverType)
+}
+
+func ValidateServerPrivs(serverType string, serverPrivs string) error {
+	flavor := strings.ToLower(serverType)
+	if flavor == "postgres" || flavor == "cockroachdb" {
+		return postgres.ValidateServerPrivs(serverPrivs)
+	} else if flavor == "mysql" {
+		return mysql.ValidateServerPrivs(serverPrivs)
+	}
+	return ValidateServerType(serverType)
+}
+
+// Checks that the creation options of a Db are supported by the server type
+func ValidateDbOptions(serverType string, dbSpec dboperatorv1alpha1.DbSpec) error {
+	flavor := strings.ToLower(serverType)
+	if flavor == "postgres" || flavor == "cockroachdb" {
+		return postgres.ValidateDbOptions(dbSpec, flavor)
+	} else if flavor == "mysql" {
+		return mysql.ValidateDbOptions(dbSpec)
+	}
+	return ValidateServerType(serverType)
+}
+
+// Values for the connection secret of a user, connectInfo holds the credentials of the user
+func GetConnectionValues(serverType string, connectInfo *shared.DbServerConnectInfo) (shared.ConnectionValues, error) {
+	flavor := strings.ToLower(serverType)
+	if flavor == "postgres" || flavor == "cockroachdb" {
+		return postgres.BuildConnectionValues(connectInfo), nil
+	} else if flavor == "mysql" {
+		return mysql.BuildConnectionValues(connectInfo), nil
+	}
+	return shared.ConnectionValues{}, ValidateServerType(serverType)
+}
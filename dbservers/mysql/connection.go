@@ -2,6 +2,7 @@ package mysql
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 
 	_ "github.com/go-sql-driver/mysql"
@@ -9,6 +10,8 @@ import (
 	"github.com/obeleh/db-operator/shared"
 )
 
+var UNESCAPE_RE = regexp.MustCompile(`\\(.)`)
+
 type MySqlConnection struct {
 	shared.ConnectionsStore
 	Flavor string
@@ -25,12 +28,12 @@ func NewMySqlConnection(connectionInfo *shared.DbServerConnectInfo, userCredenti
 	}
 }
 
-func (m *MySqlConnection) CreateUser(userName string, password string) error {
+func (m *MySqlConnection) CreateUser(userSpec dboperatorv1alpha1.UserSpec, password string) error {
 	conn, err := m.GetDbConnection(nil, nil)
 	if err != nil {
 		return err
 	}
-	_, err = conn.Exec(fmt.Sprintf(`CREATE USER '%s'@'%%' IDENTIFIED BY '%s';`, userName, password))
+	_, err = conn.Exec("CREATE USER ?@? IDENTIFIED BY ?;", userSpec.UserName, getUserHost(userSpec), password)
 	return err
 }
 
@@ -50,7 +53,7 @@ func (m *MySqlConnection) DropUser(userSpec dboperatorv1alpha1.UserSpec) error {
 	if err != nil {
 		return err
 	}
-	_, err = conn.Exec(fmt.Sprintf(`DROP USER '%s'@'%%';`, userSpec.UserName))
+	_, err = conn.Exec("DROP USER ?@?;", userSpec.UserName, getUserHost(userSpec))
 	return err
 }
 
@@ -89,8 +92,110 @@ func (m *MySqlConnection) Execute(query string, userName *string) error {
 	return err
 }
 
-func (m *MySqlConnection) CreateDb(dbName string) error {
-	return m.Execute(fmt.Sprintf("CREATE DATABASE `%s`;", dbName), nil)
+func (m *MySqlConnection) CreateDb(dbSpec dboperatorv1alpha1.DbSpec) error {
+	query, err := BuildCreateDb(dbSpec)
+	if err != nil {
+		return err
+	}
+	return m.Execute(query, nil)
+}
+
+// MySQL has no database options that are fixed at creation, so there is never drift to report
+func (m *MySqlConnection) UpdateDbOptions(dbSpec dboperatorv1alpha1.DbSpec) (bool, []string, error) {
+	err := ValidateDbOptions(dbSpec)
+	if err != nil {
+		return false, nil, err
+	}
+	conn, err := m.GetDbConnection(nil, nil)
+	if err != nil {
+		return false, nil, err
+	}
+	changed, err := UpdateDbOptions(conn, dbSpec)
+	return changed, nil, err
+}
+
+func (m *MySqlConnection) GetAppliedMigrations(historyTable string, userName *string) (map[string]string, error) {
+	conn, err := m.GetDbConnection(userName, nil)
+	if err != nil {
+		return nil, err
+	}
+	return GetAppliedMigrations(conn, historyTable)
+}
+
+func (m *MySqlConnection) ApplyMigration(historyTable string, migration shared.Migration, userName *string) error {
+	conn, err := m.GetDbConnection(userName, nil)
+	if err != nil {
+		return err
+	}
+	return ApplyMigration(conn, historyTable, migration)
+}
+
+func (m *MySqlConnection) GetExtensions() (map[string]shared.DbSideExtension, error) {
+	return nil, fmt.Errorf("extensions are only supported for Postgres")
+}
+
+func (m *MySqlConnection) CreateExtension(extensionSpec dboperatorv1alpha1.ExtensionSpec) error {
+	return fmt.Errorf("extensions are only supported for Postgres")
+}
+
+func (m *MySqlConnection) UpdateExtension(extensionSpec dboperatorv1alpha1.ExtensionSpec) (bool, error) {
+	return false, fmt.Errorf("extensions are only supported for Postgres")
+}
+
+func (m *MySqlConnection) DropExtension(name string, cascade bool) error {
+	return fmt.Errorf("extensions are only supported for Postgres")
+}
+
+func (m *MySqlConnection) GetPublication(name string) (*shared.DbSidePublication, error) {
+	return nil, fmt.Errorf("logical replication is only supported for Postgres")
+}
+
+func (m *MySqlConnection) CreatePublication(publicationSpec dboperatorv1alpha1.PublicationSpec) error {
+	return fmt.Errorf("logical replication is only supported for Postgres")
+}
+
+func (m *MySqlConnection) UpdatePublication(publicationSpec dboperatorv1alpha1.PublicationSpec) (bool, error) {
+	return false, fmt.Errorf("logical replication is only supported for Postgres")
+}
+
+func (m *MySqlConnection) DropPublication(name string) error {
+	return fmt.Errorf("logical replication is only supported for Postgres")
+}
+
+func (m *MySqlConnection) GetSubscription(name string) (*shared.DbSideSubscription, error) {
+	return nil, fmt.Errorf("logical replication is only supported for Postgres")
+}
+
+func (m *MySqlConnection) CreateSubscription(subscriptionSpec dboperatorv1alpha1.SubscriptionSpec, connInfo string) error {
+	return fmt.Errorf("logical replication is only supported for Postgres")
+}
+
+func (m *MySqlConnection) UpdateSubscription(subscriptionSpec dboperatorv1alpha1.SubscriptionSpec, connInfo string, publishedTables []string) (bool, error) {
+	return false, fmt.Errorf("logical replication is only supported for Postgres")
+}
+
+func (m *MySqlConnection) DropSubscription(name string) error {
+	return fmt.Errorf("logical replication is only supported for Postgres")
+}
+
+func (m *MySqlConnection) GetForeignServer(name string) (*shared.DbSideForeignServer, error) {
+	return nil, fmt.Errorf("foreign servers are only supported for Postgres")
+}
+
+func (m *MySqlConnection) CreateForeignServer(name string, options map[string]string) error {
+	return fmt.Errorf("foreign servers are only supported for Postgres")
+}
+
+func (m *MySqlConnection) UpdateForeignServer(name string, options map[string]string, userMappings map[string]shared.Credentials, createdMappings map[string]string) (bool, map[string]string, error) {
+	return false, nil, fmt.Errorf("foreign servers are only supported for Postgres")
+}
+
+func (m *MySqlConnection) ImportForeignSchema(name string, importSpec dboperatorv1alpha1.ForeignSchemaImport, userName *string) ([]string, error) {
+	return nil, fmt.Errorf("foreign servers are only supported for Postgres")
+}
+
+func (m *MySqlConnection) DropForeignServer(name string, userMappings []string, cascade bool) error {
+	return fmt.Errorf("foreign servers are only supported for Postgres")
 }
 
 func (m *MySqlConnection) DropDb(dbName string, cascade bool) error {
@@ -136,27 +241,101 @@ func (m *MySqlConnection) GetDbs() (map[string]shared.DbSideDb, error) {
 	return databases, nil
 }
 
+// In MySQL SCHEMA is a synonym for DATABASE, so schemas are managed as databases
 func (m *MySqlConnection) GetSchemas(userName *string) (map[string]shared.DbSideSchema, error) {
-	return nil, fmt.Errorf("TODO check if there is a difference between schemas and dbs in MySQL")
+	dbs, err := m.GetDbs()
+	if err != nil {
+		return nil, err
+	}
+	schemas := make(map[string]shared.DbSideSchema)
+	for dbName := range dbs {
+		schemas[dbName] = shared.DbSideSchema{SchemaName: dbName}
+	}
+	return schemas, nil
 }
 
 func (m *MySqlConnection) CreateSchema(schemaName string, creator *string) error {
-	return fmt.Errorf("TODO check if there is a difference between schemas and dbs in MySQL")
+	if creator != nil {
+		return fmt.Errorf("creator option not possible for MySQL, schemas are databases and are created by the DbServer user")
+	}
+	return m.Execute(fmt.Sprintf("CREATE DATABASE %s;", quoteMySQLIdentifier(schemaName)), nil)
 }
 
 func (m *MySqlConnection) DropSchema(schemaName string, userName *string, cascade bool) error {
-	return fmt.Errorf("TODO check if there is a difference between schemas and dbs in MySQL")
+	if userName != nil {
+		return fmt.Errorf("creator option not possible for MySQL, schemas are databases and are dropped by the DbServer user")
+	}
+	if cascade {
+		return fmt.Errorf("CASCADE option not posible for MySQL")
+	}
+	return m.Execute(fmt.Sprintf("DROP DATABASE %s;", quoteMySQLIdentifier(schemaName)), nil)
+}
+
+func (p *MySqlConnection) UpdateUserPrivs(userSpec dboperatorv1alpha1.UserSpec) (bool, error) {
+	conn, err := p.GetDbConnection(nil, nil)
+	if err != nil {
+		return false, err
+	}
+
+	return UpdateUserPrivs(conn, userSpec.UserName, getUserHost(userSpec), userSpec.ServerPrivs, userSpec.DbPrivs)
+}
+
+func (p *MySqlConnection) PlanUserPrivs(userSpec dboperatorv1alpha1.UserSpec) ([]dboperatorv1alpha1.PlannedStatement, error) {
+	conn, err := p.GetDbConnection(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return PlanUserPrivs(conn, userSpec.UserName, getUserHost(userSpec), userSpec.ServerPrivs, userSpec.DbPrivs)
 }
 
-func (p *MySqlConnection) UpdateUserPrivs(userName string, serverPrivs string, dbPrivs []dboperatorv1alpha1.DbPriv) (bool, error) {
+func (p *MySqlConnection) GetUserPrivsDrift(userSpec dboperatorv1alpha1.UserSpec) ([]shared.PrivsDrift, error) {
+	conn, err := p.GetDbConnection(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return GetUserPrivsDrift(conn, userSpec.UserName, getUserHost(userSpec), userSpec.ServerPrivs, userSpec.DbPrivs)
+}
+
+// UpdateUserPrivs already revokes the grants on scopes that are not in db_privs
+func (p *MySqlConnection) RevokeExtraUserPrivs(userSpec dboperatorv1alpha1.UserSpec) (bool, error) {
+	return false, nil
+}
+
+func (p *MySqlConnection) UpdateDbOwner(dbName string, owner dboperatorv1alpha1.UserSpec) (bool, error) {
 	conn, err := p.GetDbConnection(nil, nil)
 	if err != nil {
 		return false, err
 	}
+	return UpdateDbOwner(conn, dbName, owner.UserName, getUserHost(owner))
+}
 
-	return UpdateUserPrivs(conn, userName, serverPrivs, dbPrivs)
+func (p *MySqlConnection) UpdateUserOptions(userSpec dboperatorv1alpha1.UserSpec) (bool, error) {
+	if userSpec.ConnectionLimit != nil || userSpec.ValidUntil != "" || userSpec.RoleSettings != nil {
+		return false, fmt.Errorf("connection_limit, valid_until and role_settings are only supported for Postgres and CockroachDB")
+	}
+	conn, err := p.GetDbConnection(nil, nil)
+	if err != nil {
+		return false, err
+	}
+
+	// The account is looked up by name, a changed host is applied before anything else touches user@host
+	hostChanged, err := UpdateUserHost(conn, userSpec.UserName, getUserHost(userSpec))
+	if err != nil {
+		return false, err
+	}
+	optionsChanged, err := UpdateUserOptions(conn, userSpec.UserName, getUserHost(userSpec), userSpec.TlsRequires, userSpec.MaxQueriesPerHour, userSpec.MaxUserConnections)
+	return hostChanged || optionsChanged, err
+}
+
+func getUserHost(userSpec dboperatorv1alpha1.UserSpec) string {
+	return shared.Nvl(userSpec.Host, "%")
 }
 
 func quoteMySQLIdentifier(identifier string) string {
 	return "`" + strings.ReplaceAll(identifier, "`", "``") + "`"
 }
+
+func unquoteMySQLLiteral(literal string) string {
+	literal = strings.ReplaceAll(literal, "''", "'")
+	return UNESCAPE_RE.ReplaceAllString(literal, "$1")
+}
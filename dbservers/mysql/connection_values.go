@@ -0,0 +1,57 @@
+package mysql
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/obeleh/db-operator/shared"
+)
+
+// ssl-mode of MySQL clients for each sslmode of the DbServer
+var CLIENT_SSL_MODES = map[string]string{
+	shared.SSL_MODE_DISABLE:     "DISABLED",
+	SSL_MODE_PREFERRED:          "PREFERRED",
+	shared.SSL_MODE_REQUIRE:     "REQUIRED",
+	shared.SSL_MODE_VERIFY_CA:   "VERIFY_CA",
+	shared.SSL_MODE_VERIFY_FULL: "VERIFY_IDENTITY",
+}
+
+// Values for the connection secret of a user, the URI and JDBC URL have no database when it isn't set
+func BuildConnectionValues(connectInfo *shared.DbServerConnectInfo) shared.ConnectionValues {
+	sslMode := getSslMode(connectInfo.Options, nil, nil)
+	password := ""
+	if connectInfo.Password != nil {
+		password = *connectInfo.Password
+	}
+	hostPort := net.JoinHostPort(connectInfo.Host, strconv.Itoa(connectInfo.Port))
+	clientSslMode, found := CLIENT_SSL_MODES[sslMode]
+	if !found {
+		clientSslMode = strings.ToUpper(sslMode)
+	}
+
+	uri := url.URL{
+		Scheme:   "mysql",
+		Host:     hostPort,
+		Path:     "/" + connectInfo.Database,
+		RawQuery: url.Values{"ssl-mode": []string{clientSslMode}}.Encode(),
+	}
+	if connectInfo.Password != nil {
+		uri.User = url.UserPassword(connectInfo.UserName, password)
+	} else {
+		uri.User = url.User(connectInfo.UserName)
+	}
+
+	return shared.ConnectionValues{
+		Host:     connectInfo.Host,
+		Port:     connectInfo.Port,
+		Database: connectInfo.Database,
+		UserName: connectInfo.UserName,
+		Password: password,
+		SslMode:  sslMode,
+		Uri:      uri.String(),
+		JdbcUrl:  fmt.Sprintf("jdbc:mysql://%s/%s?%s", hostPort, url.PathEscape(connectInfo.Database), url.Values{"sslMode": []string{clientSslMode}}.Encode()),
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/obeleh/db-operator/shared"
 )
 
@@ -11,37 +12,63 @@ type MySqlConnector struct {
 }
 
 func (m *MySqlConnector) Connect(connectInfo *shared.DbServerConnectInfo, credentials *shared.Credentials, databaseName *string) (*sql.DB, error) {
-	// "username:password@tcp(127.0.0.1:3306)/test"
-	var connStr string
+	tlsConfigMutex.Lock()
+	defer tlsConfigMutex.Unlock()
+	cfg, err := getConfig(connectInfo, credentials, databaseName)
+	if err != nil {
+		return nil, err
+	}
+	defer deregisterTlsConfig(cfg.TLSConfig)
+	return sql.Open("mysql", cfg.FormatDSN())
+}
+
+func getConfig(connectInfo *shared.DbServerConnectInfo, credentials *shared.Credentials, databaseName *string) (*mysql.Config, error) {
 	if databaseName == nil {
 		databaseName = &connectInfo.Database
 	}
 
+	// User connections use their own client certificate but trust the CA of the DbServer unless they bring their own
+	var userName string
+	var password, caCert, tlsCrt, tlsKey *string
 	if credentials == nil {
-		if connectInfo.Password == nil {
-			return nil, fmt.Errorf("Passwordless connections not yet implemented for MySQL connections")
-			//TODO: https://stackoverflow.com/questions/67109556/connect-to-mysql-mariadb-with-ssl-and-certs-in-go
-		}
-		connStr = fmt.Sprintf("%s:%s@tcp(%s:%d)/%s",
-			connectInfo.UserName,
-			*connectInfo.Password,
-			connectInfo.Host,
-			connectInfo.Port,
-			*databaseName,
-		)
+		userName = connectInfo.UserName
+		password = connectInfo.Password
+		caCert = connectInfo.CaCert
+		tlsCrt = connectInfo.TlsCrt
+		tlsKey = connectInfo.TlsKey
 	} else {
-		if credentials.Password == nil {
-			return nil, fmt.Errorf("Passwordless connections not yet implemented for MySQL connections")
-			//TODO: https://stackoverflow.com/questions/67109556/connect-to-mysql-mariadb-with-ssl-and-certs-in-go
+		userName = credentials.UserName
+		password = credentials.Password
+		caCert = credentials.CaCert
+		if caCert == nil {
+			caCert = connectInfo.CaCert
 		}
-		connStr = fmt.Sprintf("%s:%s@tcp(%s:%d)/%s",
-			credentials.UserName,
-			*credentials.Password,
-			connectInfo.Host,
-			connectInfo.Port,
-			*databaseName,
-		)
+		tlsCrt = credentials.TlsCrt
+		tlsKey = credentials.TlsKey
+	}
+
+	if password == nil && (tlsCrt == nil || tlsKey == nil) {
+		return nil, fmt.Errorf("MySQL connections need either a password or a client certificate and key")
+	}
+
+	cfg := mysql.NewConfig()
+	cfg.User = userName
+	if password != nil {
+		cfg.Passwd = *password
+	}
+	cfg.Net = "tcp"
+	cfg.Addr = fmt.Sprintf("%s:%d", connectInfo.Host, connectInfo.Port)
+	cfg.DBName = *databaseName
+	// Account names and the strings of IDENTIFIED BY and REQUIRE can't be parameters of a server side prepared statement,
+	// the driver fills in the ? placeholders itself
+	cfg.InterpolateParams = true
+
+	sslMode := getSslMode(connectInfo.Options, tlsCrt, tlsKey)
+	tlsConfigName, err := registerTlsConfig(sslMode, connectInfo.Host, caCert, tlsCrt, tlsKey)
+	if err != nil {
+		return nil, err
 	}
+	cfg.TLSConfig = tlsConfigName
 
-	return sql.Open("mysql", connStr)
+	return cfg, nil
 }
@@ -0,0 +1,90 @@
+package mysql
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/obeleh/db-operator/shared"
+)
+
+func newTestConnectInfo(options map[string]string) *shared.DbServerConnectInfo {
+	password := "paswoord"
+	return &shared.DbServerConnectInfo{
+		Host:        "mysql.example.com",
+		Port:        3306,
+		Credentials: shared.Credentials{UserName: "root", Password: &password},
+		Options:     options,
+	}
+}
+
+func TestGetConfigWithoutTls(t *testing.T) {
+	cfg, err := getConfig(newTestConnectInfo(nil), nil, nil)
+	if err != nil {
+		t.Fatalf("getConfig failed: %s", err)
+	}
+	expected := "root:paswoord@tcp(mysql.example.com:3306)/?interpolateParams=true&tls=false"
+	if cfg.FormatDSN() != expected {
+		t.Errorf("expected %s, got %s", expected, cfg.FormatDSN())
+	}
+
+	// A CA certificate alone doesn't turn TLS on, only an explicit sslmode does
+	caCert := "-----BEGIN CERTIFICATE-----"
+	connectInfo := newTestConnectInfo(nil)
+	connectInfo.CaCert = &caCert
+	cfg, err = getConfig(connectInfo, nil, nil)
+	if err != nil {
+		t.Fatalf("getConfig failed: %s", err)
+	}
+	if cfg.TLSConfig != "false" {
+		t.Errorf("expected no TLS without sslmode, got %s", cfg.TLSConfig)
+	}
+}
+
+func TestGetConfigSslModeRequire(t *testing.T) {
+	cfg, err := getConfig(newTestConnectInfo(map[string]string{"sslmode": "require"}), nil, nil)
+	if err != nil {
+		t.Fatalf("getConfig failed: %s", err)
+	}
+	if !strings.HasPrefix(cfg.TLSConfig, TLS_CONFIG_PREFIX) {
+		t.Errorf("expected a registered tls config, got %s", cfg.TLSConfig)
+	}
+}
+
+func TestConnectDeregistersTlsConfig(t *testing.T) {
+	connectInfo := newTestConnectInfo(map[string]string{"sslmode": "require"})
+	cfg, err := getConfig(connectInfo, nil, nil)
+	if err != nil {
+		t.Fatalf("getConfig failed: %s", err)
+	}
+	deregisterTlsConfig(cfg.TLSConfig)
+
+	conn, err := (&MySqlConnector{}).Connect(connectInfo, nil, nil)
+	if err != nil {
+		t.Fatalf("Connect failed: %s", err)
+	}
+	defer conn.Close()
+	_, err = mysql.ParseDSN(cfg.FormatDSN())
+	if err == nil {
+		t.Errorf("expected the tls config %s to be deregistered after connecting", cfg.TLSConfig)
+	}
+}
+
+func TestGetConnectionStringInvalidSslMode(t *testing.T) {
+	_, err := getConfig(newTestConnectInfo(map[string]string{"sslmode": "sometimes"}), nil, nil)
+	if err == nil {
+		t.Errorf("expected error for invalid sslmode")
+	}
+
+	_, err = getConfig(newTestConnectInfo(map[string]string{"sslmode": "verify-ca"}), nil, nil)
+	if err == nil {
+		t.Errorf("expected error for verify-ca without CA certificate")
+	}
+}
+
+func TestGetConnectionStringPasswordless(t *testing.T) {
+	_, err := getConfig(newTestConnectInfo(nil), &shared.Credentials{UserName: "jantje"}, nil)
+	if err == nil {
+		t.Errorf("expected error for user without password or client certificate")
+	}
+}
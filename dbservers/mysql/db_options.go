@@ -0,0 +1,93 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+)
+
+// Character sets and collations are keywords, they can't be quoted
+var CHARSET_NAME_RE = regexp.MustCompile("^[A-Za-z0-9_]+$")
+
+func ValidateDbOptions(dbSpec dboperatorv1alpha1.DbSpec) error {
+	if dbSpec.Encoding != "" || dbSpec.LcCollate != "" || dbSpec.LcCtype != "" || dbSpec.Template != "" || dbSpec.ConnectionLimit != nil || dbSpec.Tablespace != "" {
+		return fmt.Errorf("encoding, lc_collate, lc_ctype, template, connection_limit and tablespace are only supported for Postgres and CockroachDB")
+	}
+	if dbSpec.PrimaryRegion != "" || len(dbSpec.Regions) > 0 || dbSpec.Survive != "" {
+		return fmt.Errorf("primary_region, regions and survive are only supported for CockroachDB")
+	}
+	if dbSpec.CharacterSet != "" && !CHARSET_NAME_RE.MatchString(dbSpec.CharacterSet) {
+		return fmt.Errorf("invalid character_set %s", dbSpec.CharacterSet)
+	}
+	if dbSpec.Collate != "" && !CHARSET_NAME_RE.MatchString(dbSpec.Collate) {
+		return fmt.Errorf("invalid collate %s", dbSpec.Collate)
+	}
+	return nil
+}
+
+func charsetClauses(characterSet string, collate string) []string {
+	clauses := []string{}
+	if characterSet != "" {
+		clauses = append(clauses, "CHARACTER SET "+characterSet)
+	}
+	if collate != "" {
+		clauses = append(clauses, "COLLATE "+collate)
+	}
+	return clauses
+}
+
+func BuildCreateDb(dbSpec dboperatorv1alpha1.DbSpec) (string, error) {
+	err := ValidateDbOptions(dbSpec)
+	if err != nil {
+		return "", err
+	}
+	clauses := append([]string{"CREATE DATABASE " + quoteMySQLIdentifier(dbSpec.DbName)}, charsetClauses(dbSpec.CharacterSet, dbSpec.Collate)...)
+	return strings.Join(clauses, " ") + ";", nil
+}
+
+// Reconciles the default CHARACTER SET and COLLATE of a database
+// Existing tables keep their character set, only new tables get the new default
+func UpdateDbOptions(conn *sql.DB, dbSpec dboperatorv1alpha1.DbSpec) (bool, error) {
+	if dbSpec.CharacterSet == "" && dbSpec.Collate == "" {
+		return false, nil
+	}
+	rows, err := conn.Query("SELECT DEFAULT_CHARACTER_SET_NAME, DEFAULT_COLLATION_NAME FROM information_schema.SCHEMATA WHERE SCHEMA_NAME = ?", dbSpec.DbName)
+	if err != nil {
+		return false, fmt.Errorf("unable to read character set of db %s %s", dbSpec.DbName, err)
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return false, fmt.Errorf("db %s not found in information_schema.SCHEMATA", dbSpec.DbName)
+	}
+	var curCharacterSet, curCollate string
+	err = rows.Scan(&curCharacterSet, &curCollate)
+	if err != nil {
+		return false, fmt.Errorf("unable to load character set of db %s %s", dbSpec.DbName, err)
+	}
+	rows.Close()
+
+	characterSet := ""
+	if dbSpec.CharacterSet != "" && !strings.EqualFold(curCharacterSet, dbSpec.CharacterSet) {
+		characterSet = dbSpec.CharacterSet
+	}
+	collate := ""
+	if dbSpec.Collate != "" && !strings.EqualFold(curCollate, dbSpec.Collate) {
+		collate = dbSpec.Collate
+	}
+	if characterSet == "" && collate == "" {
+		return false, nil
+	}
+	// Changing the character set resets the collation to the default of the new character set
+	if characterSet != "" && dbSpec.Collate != "" {
+		collate = dbSpec.Collate
+	}
+	clauses := append([]string{"ALTER DATABASE " + quoteMySQLIdentifier(dbSpec.DbName)}, charsetClauses(characterSet, collate)...)
+	_, err = conn.Exec(strings.Join(clauses, " ") + ";")
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
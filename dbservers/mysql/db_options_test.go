@@ -0,0 +1,62 @@
+package mysql
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+)
+
+func TestBuildCreateDb(t *testing.T) {
+	query, err := BuildCreateDb(dboperatorv1alpha1.DbSpec{DbName: "app", CharacterSet: "utf8mb4", Collate: "utf8mb4_0900_ai_ci"})
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	expected := "CREATE DATABASE `app` CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci;"
+	if query != expected {
+		t.Errorf("expected %s got %s", expected, query)
+	}
+
+	_, err = BuildCreateDb(dboperatorv1alpha1.DbSpec{DbName: "app", CharacterSet: "utf8mb4; DROP DATABASE mysql"})
+	if err == nil {
+		t.Errorf("expected an invalid character set to be refused")
+	}
+	_, err = BuildCreateDb(dboperatorv1alpha1.DbSpec{DbName: "app", Encoding: "UTF8"})
+	if err == nil {
+		t.Errorf("expected encoding to be refused on mysql")
+	}
+}
+
+func TestUpdateDbOptions(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	schemataQuery := "SELECT DEFAULT_CHARACTER_SET_NAME, DEFAULT_COLLATION_NAME FROM information_schema.SCHEMATA WHERE SCHEMA_NAME = ?"
+	dbSpec := dboperatorv1alpha1.DbSpec{DbName: "app", CharacterSet: "utf8mb4", Collate: "utf8mb4_bin"}
+
+	mock.ExpectQuery(schemataQuery).WithArgs("app").WillReturnRows(sqlmock.NewRows([]string{"DEFAULT_CHARACTER_SET_NAME", "DEFAULT_COLLATION_NAME"}).AddRow("latin1", "latin1_swedish_ci"))
+	mock.ExpectExec("ALTER DATABASE `app` CHARACTER SET utf8mb4 COLLATE utf8mb4_bin;").WillReturnResult(sqlmock.NewResult(0, 0))
+	changed, err := UpdateDbOptions(db, dbSpec)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if !changed {
+		t.Errorf("expected the character set to change")
+	}
+
+	mock.ExpectQuery(schemataQuery).WithArgs("app").WillReturnRows(sqlmock.NewRows([]string{"DEFAULT_CHARACTER_SET_NAME", "DEFAULT_COLLATION_NAME"}).AddRow("utf8mb4", "utf8mb4_bin"))
+	changed, err = UpdateDbOptions(db, dbSpec)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if changed {
+		t.Errorf("expected no changes")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
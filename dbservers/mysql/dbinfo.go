@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	_ "github.com/go-sql-driver/mysql"
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
 	"github.com/obeleh/db-operator/shared"
 	v1 "k8s.io/api/core/v1"
 )
@@ -16,16 +17,21 @@ type MySqlActions struct {
 }
 
 func (i *MySqlActions) BuildContainer(scriptName string) v1.Container {
+	userName, secretName, passwordKey := i.GetJobCredentials()
+	return i.buildContainer(scriptName, userName, secretName, passwordKey)
+}
+
+func (i *MySqlActions) buildContainer(scriptName string, userName string, secretName string, passwordKey string) v1.Container {
 	dbServer := i.DbServer
 	envVars := []v1.EnvVar{
 		{Name: "MYSQL_HOST", Value: dbServer.Spec.Address},
-		{Name: "MYSQL_USER", Value: dbServer.Spec.UserName},
+		{Name: "MYSQL_USER", Value: userName},
 		{Name: "MYSQL_PWD", ValueFrom: &v1.EnvVarSource{
 			SecretKeyRef: &v1.SecretKeySelector{
 				LocalObjectReference: v1.LocalObjectReference{
-					Name: dbServer.Spec.SecretName,
+					Name: secretName,
 				},
-				Key: shared.Nvl(dbServer.Spec.PasswordKey, "password"),
+				Key: shared.Nvl(passwordKey, "password"),
 			},
 		}},
 		{Name: "MYSQL_DATABASE", Value: i.Db.Spec.DbName},
@@ -49,3 +55,14 @@ func (i *MySqlActions) BuildBackupContainer() v1.Container {
 func (i *MySqlActions) BuildRestoreContainer() v1.Container {
 	return i.BuildContainer(shared.RESTORE_MYSQL)
 }
+
+func (i *MySqlActions) BuildRunSqlContainer(sql string, user *dboperatorv1alpha1.User) v1.Container {
+	var container v1.Container
+	if user == nil {
+		container = i.BuildContainer(shared.RUN_SQL_MYSQL)
+	} else {
+		container = i.buildContainer(shared.RUN_SQL_MYSQL, user.Spec.UserName, user.Spec.SecretName, user.Spec.PasswordKey)
+	}
+	container.Env = append(container.Env, v1.EnvVar{Name: "SQL", Value: sql})
+	return container
+}
@@ -0,0 +1,35 @@
+package mysql
+
+import (
+	"testing"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/shared"
+	v1 "k8s.io/api/core/v1"
+)
+
+func getEnvVar(container v1.Container, name string) *v1.EnvVar {
+	for i, envVar := range container.Env {
+		if envVar.Name == name {
+			return &container.Env[i]
+		}
+	}
+	return nil
+}
+
+func TestBuildContainerAsJobUser(t *testing.T) {
+	actions := MySqlActions{DbActionsBase: shared.DbActionsBase{
+		Db:       &dboperatorv1alpha1.Db{Spec: dboperatorv1alpha1.DbSpec{DbName: "app"}},
+		DbServer: &dboperatorv1alpha1.DbServer{Spec: dboperatorv1alpha1.DbServerSpec{Address: "mysql", Port: 3306, UserName: "root", SecretName: "mysql-admin"}},
+		JobUser:  &dboperatorv1alpha1.User{Spec: dboperatorv1alpha1.UserSpec{UserName: "app_owner", SecretName: "app-owner-secret", PasswordKey: "pw"}},
+	}}
+
+	container := actions.BuildRestoreContainer()
+	if getEnvVar(container, "MYSQL_USER").Value != "app_owner" {
+		t.Errorf("expected the restore to connect as the job user")
+	}
+	secretRef := getEnvVar(container, "MYSQL_PWD").ValueFrom.SecretKeyRef
+	if secretRef.Name != "app-owner-secret" || secretRef.Key != "pw" {
+		t.Errorf("expected the password from the secret of the job user, got %s/%s", secretRef.Name, secretRef.Key)
+	}
+}
@@ -0,0 +1,65 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// MySQL has no comments on databases, so the markers are kept in a table
+const MANAGED_OBJECTS_TABLE = "`db_operator`.`managed_objects`"
+
+func (m *MySqlConnection) GetManagedBy(objectType string, name string) (string, error) {
+	conn, err := m.GetDbConnection(nil, nil)
+	if err != nil {
+		return "", err
+	}
+	return GetManagedBy(conn, objectType, name)
+}
+
+func (m *MySqlConnection) SetManagedBy(objectType string, name string, managedBy string) error {
+	conn, err := m.GetDbConnection(nil, nil)
+	if err != nil {
+		return err
+	}
+	return SetManagedBy(conn, objectType, name, managedBy)
+}
+
+func (m *MySqlConnection) ForgetManagedBy(objectType string, name string) error {
+	conn, err := m.GetDbConnection(nil, nil)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Exec(fmt.Sprintf("DELETE FROM %s WHERE object_type = ? AND object_name = ?", MANAGED_OBJECTS_TABLE), objectType, name)
+	return err
+}
+
+func ensureManagedObjectsTable(conn *sql.DB) error {
+	_, err := conn.Exec("CREATE DATABASE IF NOT EXISTS `db_operator`")
+	if err != nil {
+		return err
+	}
+	_, err = conn.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (object_type VARCHAR(16) NOT NULL, object_name VARCHAR(255) NOT NULL, managed_by VARCHAR(512) NOT NULL, PRIMARY KEY (object_type, object_name))", MANAGED_OBJECTS_TABLE))
+	return err
+}
+
+func GetManagedBy(conn *sql.DB, objectType string, name string) (string, error) {
+	err := ensureManagedObjectsTable(conn)
+	if err != nil {
+		return "", err
+	}
+	managedBy := ""
+	err = conn.QueryRow(fmt.Sprintf("SELECT managed_by FROM %s WHERE object_type = ? AND object_name = ?", MANAGED_OBJECTS_TABLE), objectType, name).Scan(&managedBy)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return managedBy, err
+}
+
+func SetManagedBy(conn *sql.DB, objectType string, name string, managedBy string) error {
+	err := ensureManagedObjectsTable(conn)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Exec(fmt.Sprintf("INSERT INTO %s (object_type, object_name, managed_by) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE managed_by = VALUES(managed_by)", MANAGED_OBJECTS_TABLE), objectType, name, managedBy)
+	return err
+}
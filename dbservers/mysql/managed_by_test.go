@@ -0,0 +1,32 @@
+package mysql
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/obeleh/db-operator/shared"
+)
+
+func TestManagedByWithoutMarker(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec("CREATE DATABASE IF NOT EXISTS `db_operator`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS `db_operator`.`managed_objects` (object_type VARCHAR(16) NOT NULL, object_name VARCHAR(255) NOT NULL, managed_by VARCHAR(512) NOT NULL, PRIMARY KEY (object_type, object_name))").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT managed_by FROM `db_operator`.`managed_objects` WHERE object_type = ? AND object_name = ?").WithArgs(shared.MANAGED_DATABASE, "legacy").WillReturnRows(sqlmock.NewRows([]string{"managed_by"}))
+
+	managedBy, err := GetManagedBy(db, shared.MANAGED_DATABASE, "legacy")
+	if err != nil {
+		t.Fatalf("GetManagedBy failed: %s", err)
+	}
+	if managedBy != "" {
+		t.Errorf("expected no marker, got %s", managedBy)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
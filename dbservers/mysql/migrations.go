@@ -0,0 +1,194 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/obeleh/db-operator/shared"
+)
+
+// Stored programs whose body was split on its semicolons, they need a DELIMITER
+var compoundStatementRegex = regexp.MustCompile(`(?is)^(CREATE\s+(OR\s+REPLACE\s+)?(DEFINER\s*=\s*\S+\s+)?(AGGREGATE\s+)?(PROCEDURE|FUNCTION|TRIGGER|EVENT)\b.*\bBEGIN\b|BEGIN\s+NOT\s+ATOMIC\b)`)
+
+// Splits a migration on the delimiter outside of quotes and comments,
+// the driver only runs one statement per Exec.
+// Like the mysql client a DELIMITER line changes the delimiter, so stored programs with a BEGIN ... END body can be used
+func splitStatements(script string) ([]string, error) {
+	statements := []string{}
+	delimiter := ";"
+	current := strings.Builder{}
+	// Only whitespace and comments since the last delimiter, where a DELIMITER command may start
+	atStart := true
+	flush := func() error {
+		statement := strings.TrimSpace(current.String())
+		current.Reset()
+		atStart = true
+		if statement == "" {
+			return nil
+		}
+		if delimiter == ";" && compoundStatementRegex.MatchString(statement) {
+			firstLine := strings.SplitN(statement, "\n", 2)[0]
+			return fmt.Errorf("the compound statement '%s' contains semicolons, set another delimiter with DELIMITER before it", firstLine)
+		}
+		statements = append(statements, statement)
+		return nil
+	}
+
+	runes := []rune(script)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case atStart && isDelimiterCommand(runes[i:]):
+			end := i
+			for end < len(runes) && runes[end] != '\n' {
+				end++
+			}
+			delimiter = strings.TrimSpace(string(runes[i+len("DELIMITER") : end]))
+			if delimiter == "" || strings.ContainsAny(delimiter, "'\"`") {
+				return nil, fmt.Errorf("invalid DELIMITER '%s'", delimiter)
+			}
+			current.Reset()
+			i = end
+		case c == '\'' || c == '"' || c == '`':
+			atStart = false
+			current.WriteRune(c)
+			for i++; i < len(runes); i++ {
+				current.WriteRune(runes[i])
+				if runes[i] == '\\' && c != '`' && i+1 < len(runes) {
+					i++
+					current.WriteRune(runes[i])
+				} else if runes[i] == c {
+					break
+				}
+			}
+		case c == '#' || (c == '-' && i+2 < len(runes) && runes[i+1] == '-' && strings.ContainsRune(" \t\r\n", runes[i+2])):
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			current.WriteRune('\n')
+		case c == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			for i += 2; i < len(runes) && !(runes[i] == '*' && i+1 < len(runes) && runes[i+1] == '/'); i++ {
+			}
+			i++
+			current.WriteRune(' ')
+		case hasPrefix(runes[i:], delimiter):
+			err := flush()
+			if err != nil {
+				return nil, err
+			}
+			i += len([]rune(delimiter)) - 1
+		default:
+			atStart = atStart && unicode.IsSpace(c)
+			current.WriteRune(c)
+		}
+	}
+	err := flush()
+	if err != nil {
+		return nil, err
+	}
+	return statements, nil
+}
+
+func hasPrefix(runes []rune, prefix string) bool {
+	length := len([]rune(prefix))
+	return len(runes) >= length && string(runes[:length]) == prefix
+}
+
+// DELIMITER is a command of the mysql client, the server doesn't know it
+func isDelimiterCommand(runes []rune) bool {
+	keyword := len("DELIMITER")
+	return len(runes) > keyword && strings.EqualFold(string(runes[:keyword]), "DELIMITER") && (runes[keyword] == ' ' || runes[keyword] == '\t')
+}
+
+func GetAppliedMigrations(conn *sql.DB, historyTable string) (map[string]string, error) {
+	quotedTable := quoteMySQLIdentifier(historyTable)
+	_, err := conn.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		version VARCHAR(255) NOT NULL PRIMARY KEY,
+		checksum CHAR(64) NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`, quotedTable))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create migrations history table %s %s", historyTable, err)
+	}
+
+	rows, err := conn.Query(fmt.Sprintf("SELECT version, checksum FROM %s", quotedTable))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read migrations history %s", err)
+	}
+	defer rows.Close()
+
+	applied := map[string]string{}
+	for rows.Next() {
+		var version, checksum string
+		err = rows.Scan(&version, &checksum)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load migration %s", err)
+		}
+		applied[version] = checksum
+	}
+	return applied, nil
+}
+
+// Seconds to wait for another DbMigration that is applying migrations to the same database
+const MIGRATION_LOCK_TIMEOUT = 60
+
+// The lock is named after the database and the history table, lock names are server wide and at most 64 characters
+const MIGRATION_LOCK_NAME = "LEFT(CONCAT('db-operator ', DATABASE(), '.', ?), 64)"
+
+// DDL statements commit implicitly in MySQL, only migrations without DDL are rolled back as a whole.
+// A named lock keeps two DbMigrations from applying the same migration, it's held by the session so one connection is used
+func ApplyMigration(conn *sql.DB, historyTable string, migration shared.Migration) error {
+	statements, err := splitStatements(migration.SQL)
+	if err != nil {
+		return fmt.Errorf("migration %s can't be split into statements: %s", migration.Version, err)
+	}
+
+	ctx := context.Background()
+	session, err := conn.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	locked := sql.NullInt64{}
+	err = session.QueryRowContext(ctx, fmt.Sprintf("SELECT GET_LOCK(%s, ?)", MIGRATION_LOCK_NAME), historyTable, MIGRATION_LOCK_TIMEOUT).Scan(&locked)
+	if err != nil {
+		return fmt.Errorf("unable to lock the migrations history %s", err)
+	}
+	if locked.Int64 != 1 {
+		return fmt.Errorf("timed out waiting for the lock on the migrations history %s", historyTable)
+	}
+	defer session.ExecContext(ctx, fmt.Sprintf("DO RELEASE_LOCK(%s)", MIGRATION_LOCK_NAME), historyTable)
+
+	quotedTable := quoteMySQLIdentifier(historyTable)
+	checksum := ""
+	err = session.QueryRowContext(ctx, fmt.Sprintf("SELECT checksum FROM %s WHERE version = ?", quotedTable), migration.Version).Scan(&checksum)
+	if err == nil {
+		return migration.CheckAlreadyApplied(checksum)
+	}
+	if err != sql.ErrNoRows {
+		return fmt.Errorf("unable to read migrations history %s", err)
+	}
+
+	tx, err := session.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for _, statement := range statements {
+		_, err = tx.Exec(statement)
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %s failed: %s", migration.Version, err)
+		}
+	}
+	_, err = tx.Exec(fmt.Sprintf("INSERT INTO %s (version, checksum) VALUES (?, ?)", quotedTable), migration.Version, migration.Checksum)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("unable to record migration %s %s", migration.Version, err)
+	}
+	return tx.Commit()
+}
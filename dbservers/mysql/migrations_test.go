@@ -0,0 +1,110 @@
+package mysql
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/obeleh/db-operator/shared"
+)
+
+func TestSplitStatements(t *testing.T) {
+	script := `-- users
+CREATE TABLE users (id INT PRIMARY KEY, name VARCHAR(50) DEFAULT 'a;b');
+/* seed; data */
+INSERT INTO users VALUES (1, 'it\'s; fine'); # trailing; comment
+INSERT INTO ` + "`semi;colon`" + ` VALUES (2)`
+	expected := []string{
+		"CREATE TABLE users (id INT PRIMARY KEY, name VARCHAR(50) DEFAULT 'a;b')",
+		"INSERT INTO users VALUES (1, 'it\\'s; fine')",
+		"INSERT INTO `semi;colon` VALUES (2)",
+	}
+	statements, err := splitStatements(script)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if !reflect.DeepEqual(statements, expected) {
+		t.Errorf("expected %q got %q", expected, statements)
+	}
+}
+
+func TestSplitStatementsWithDelimiter(t *testing.T) {
+	script := `CREATE TABLE counters (id INT PRIMARY KEY, hits INT);
+DELIMITER //
+CREATE PROCEDURE hit(IN counter INT)
+BEGIN
+  UPDATE counters SET hits = hits + 1 WHERE id = counter;
+  SELECT hits FROM counters WHERE id = counter;
+END //
+delimiter ;
+INSERT INTO counters VALUES (1, 0);`
+	expected := []string{
+		"CREATE TABLE counters (id INT PRIMARY KEY, hits INT)",
+		"CREATE PROCEDURE hit(IN counter INT)\nBEGIN\n  UPDATE counters SET hits = hits + 1 WHERE id = counter;\n  SELECT hits FROM counters WHERE id = counter;\nEND",
+		"INSERT INTO counters VALUES (1, 0)",
+	}
+	statements, err := splitStatements(script)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if !reflect.DeepEqual(statements, expected) {
+		t.Errorf("expected %q got %q", expected, statements)
+	}
+
+	for _, script := range []string{
+		"CREATE PROCEDURE hit()\nBEGIN\n  UPDATE counters SET hits = hits + 1;\nEND;",
+		"CREATE DEFINER=`app`@`%` TRIGGER count_hit AFTER INSERT ON hits FOR EACH ROW BEGIN UPDATE counters SET hits = hits + 1; END;",
+		"BEGIN NOT ATOMIC SELECT 1; END;",
+		"DELIMITER \nSELECT 1;",
+	} {
+		_, err = splitStatements(script)
+		if err == nil {
+			t.Errorf("expected an error for %q", script)
+		}
+	}
+}
+
+func TestApplyMigration(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	migration := shared.NewMigration("0002_seed.sql", "INSERT INTO users VALUES (1);\nINSERT INTO users VALUES (2);\n")
+	lockQuery := "SELECT GET_LOCK(LEFT(CONCAT('db-operator ', DATABASE(), '.', ?), 64), ?)"
+	unlockQuery := "DO RELEASE_LOCK(LEFT(CONCAT('db-operator ', DATABASE(), '.', ?), 64))"
+	historyQuery := "SELECT checksum FROM `db_operator_migrations` WHERE version = ?"
+
+	mock.ExpectQuery(lockQuery).WithArgs("db_operator_migrations", MIGRATION_LOCK_TIMEOUT).WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(1))
+	mock.ExpectQuery(historyQuery).WithArgs("0002_seed.sql").WillReturnRows(sqlmock.NewRows([]string{"checksum"}))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO users VALUES (1)").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO users VALUES (2)").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO `db_operator_migrations` (version, checksum) VALUES (?, ?)").WithArgs("0002_seed.sql", migration.Checksum).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(unlockQuery).WithArgs("db_operator_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	err = ApplyMigration(db, "db_operator_migrations", migration)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	// Applied by another DbMigration while this one waited for the lock
+	mock.ExpectQuery(lockQuery).WithArgs("db_operator_migrations", MIGRATION_LOCK_TIMEOUT).WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(1))
+	mock.ExpectQuery(historyQuery).WithArgs("0002_seed.sql").WillReturnRows(sqlmock.NewRows([]string{"checksum"}).AddRow(migration.Checksum))
+	mock.ExpectExec(unlockQuery).WithArgs("db_operator_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	err = ApplyMigration(db, "db_operator_migrations", migration)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	mock.ExpectQuery(lockQuery).WithArgs("db_operator_migrations", MIGRATION_LOCK_TIMEOUT).WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(0))
+	err = ApplyMigration(db, "db_operator_migrations", migration)
+	if err == nil {
+		t.Errorf("expected an error when the lock times out")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -91,6 +91,9 @@ var VALID_PRIVS = []string{
 	"REPLICA MONITOR",
 }
 
+// Privileges that can be granted on a FUNCTION or PROCEDURE
+var VALID_ROUTINE_PRIVS = []string{"EXECUTE", "ALTER ROUTINE", "GRANT", "GRANT OPTION", "ALL", "ALL PRIVILEGES"}
+
 // TODO: Does this cover all database versions of "ALL" priveges???
 var ALL_PRIVS = []string{
 	"SELECT", "INSERT", "UPDATE", "DELETE", "CREATE", "DROP", "RELOAD", "SHUTDOWN", "PROCESS", "FILE",
@@ -109,6 +112,12 @@ var ALL_PRIVS = []string{
 
 var GRANTS_RE = regexp.MustCompile("GRANT (?P<privs>.+) ON (?P<on>.+) TO (['`\"]).*(['`\"])@(['`\"]).*(['`\"])( IDENTIFIED BY PASSWORD (['`\"]).+(['`\"]))? ?(?P<lastgroup>.*)")
 
+const requireCriteriumPattern = `(SUBJECT|ISSUER|CIPHER)\s+'((?:[^'\\]|\\.|'')*)'`
+
+// Matches the REQUIRE clause in SHOW CREATE USER or SHOW GRANTS output, the PASSWORD REQUIRE CURRENT clause is not matched
+var REQUIRE_RE = regexp.MustCompile(`REQUIRE\s+(NONE|SSL|X509|` + requireCriteriumPattern + `(?:\s+(?:AND\s+)?` + requireCriteriumPattern + `)*)`)
+var REQUIRE_CRITERIUM_RE = regexp.MustCompile(requireCriteriumPattern)
+
 // tls_requires description:
 // - Set requirement for secure transport as a dictionary of requirements (see the examples).
 // - Valid requirements are SSL, X509, SUBJECT, ISSUER, CIPHER.
@@ -122,7 +131,26 @@ type TlsRequires struct {
 }
 
 func (t TlsRequires) HasTlsRequirements() bool {
-	return t.RequiresMap != nil && t.RequiresStr != nil
+	return t.RequiresMap != nil || t.RequiresStr != nil
+}
+
+// Builds the part after REQUIRE with placeholders for the criteria, e.g. SSL or SUBJECT ? AND ISSUER ?
+func (t TlsRequires) RequiresClause() (string, []string) {
+	if t.RequiresStr != nil {
+		return *t.RequiresStr, nil
+	}
+	if t.RequiresMap == nil {
+		return "NONE", nil
+	}
+	keys := funk.Keys(t.RequiresMap).([]string)
+	sort.Strings(keys)
+	criteria := []string{}
+	params := []string{}
+	for _, key := range keys {
+		criteria = append(criteria, key+" ?")
+		params = append(params, t.RequiresMap[key])
+	}
+	return strings.Join(criteria, " AND "), params
 }
 
 func getMode(conn *sql.DB) (string, error) {
@@ -160,19 +188,9 @@ func userExists(conn *sql.DB, user string, host string, hostAll bool) (bool, err
 
 func (t TlsRequires) Mogrify(query string, params []string) (string, []string) {
 	if t.HasTlsRequirements() {
-		var requiresQuery string
-		if t.RequiresMap != nil {
-			keys := funk.Keys(t.RequiresMap).([]string)
-			values := funk.Values(t.RequiresMap).([]string)
-			criteria := funk.Map(keys, func(key string) string {
-				return fmt.Sprintf("%s %%s", key)
-			}).([]string)
-			requiresQuery = strings.Join(criteria, " AND ")
-			params = append(params, values...)
-		} else {
-			requiresQuery = *t.RequiresStr
-		}
-		query = fmt.Sprintf("%s REQUIRE %s", query, requiresQuery)
+		clause, clauseParams := t.RequiresClause()
+		query = fmt.Sprintf("%s REQUIRE %s;", strings.TrimSuffix(query, ";"), clause)
+		params = append(params, clauseParams...)
 	}
 	return query, params
 }
@@ -263,15 +281,15 @@ func getTlsRequires(conn *sql.DB, serverInfo ServerInfo, user string, host strin
 	requireList := funk.Filter(grants, func(s string) bool {
 		return strings.Contains(s, "REQUIRE")
 	}).([]string)
-	var requireLine string
 	if len(requireList) == 0 {
 		return TlsRequires{}, nil
 	}
-	requireLine = requireList[0]
-	requireLine = query_utils.GetStringInBetween(requireLine, "REQUIRE", "PASSWORD")
-	words := strings.Fields(requireLine)
-	firstRequire := words[0]
-	if strings.HasPrefix(firstRequire, "NONE") {
+	requireMatch := REQUIRE_RE.FindStringSubmatch(requireList[0])
+	if requireMatch == nil {
+		return TlsRequires{}, nil
+	}
+	firstRequire := requireMatch[1]
+	if firstRequire == "NONE" {
 		return TlsRequires{}, nil
 	}
 	if firstRequire == "SSL" || firstRequire == "X509" {
@@ -279,8 +297,8 @@ func getTlsRequires(conn *sql.DB, serverInfo ServerInfo, user string, host strin
 	}
 
 	requiresMap := map[string]string{}
-	for _, word := range words {
-		requiresMap[word] = word
+	for _, criterium := range REQUIRE_CRITERIUM_RE.FindAllStringSubmatch(firstRequire, -1) {
+		requiresMap[criterium[1]] = unquoteMySQLLiteral(criterium[2])
 	}
 	return TlsRequires{RequiresMap: requiresMap}, nil
 }
@@ -528,6 +546,62 @@ func normalizeColGrants(privileges []string) []string {
 	return privileges
 }
 
+/*
+Translate a DbPriv with a priv_type into the parts of a MySQL grant target
+
+	global:   *.*                             -> *.*
+	database: mydb                            -> mydb.*
+	table:    mydb.mytable                    -> mydb.mytable
+	routine:  FUNCTION mydb.my_function       -> FUNCTION mydb.my_function
+	          PROCEDURE mydb.my_procedure     -> PROCEDURE mydb.my_procedure
+
+Returns the object type (with trailing space) and the db and table parts.
+*/
+func normalizeDbPrivScope(dbPriv dboperatorv1alpha1.DbPriv) (string, []string, error) {
+	if dbPriv.DefaultPrivs != "" {
+		return "", nil, fmt.Errorf("default_privs are not supported for MySQL")
+	}
+	scope := strings.TrimSpace(dbPriv.Scope)
+
+	switch dbPriv.PrivType {
+	case "global":
+		if scope != "" && scope != "*.*" {
+			return "", nil, fmt.Errorf("expected scope '*.*' for global privileges, got '%s'", scope)
+		}
+		return "", []string{"*", "*"}, nil
+	case "database":
+		if scope == "" || strings.Contains(scope, ".") {
+			return "", nil, fmt.Errorf("expected database name as scope for database privileges, got '%s'", scope)
+		}
+		return "", []string{scope, "*"}, nil
+	case "table":
+		parts := strings.Split(scope, ".")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return "", nil, fmt.Errorf("expected scope 'db.table' for table privileges, got '%s'", scope)
+		}
+		if parts[0] == "*" || parts[1] == "*" {
+			return "", nil, fmt.Errorf("wildcards are not allowed in table privileges, use priv_type database or global instead")
+		}
+		return "", parts, nil
+	case "routine":
+		routineParts := strings.SplitN(scope, " ", 2)
+		if len(routineParts) != 2 {
+			return "", nil, fmt.Errorf("expected scope 'FUNCTION db.name' or 'PROCEDURE db.name' for routine privileges, got '%s'", scope)
+		}
+		objectType := strings.ToUpper(routineParts[0])
+		if objectType != "FUNCTION" && objectType != "PROCEDURE" {
+			return "", nil, fmt.Errorf("expected FUNCTION or PROCEDURE for routine privileges, got '%s'", routineParts[0])
+		}
+		parts := strings.Split(strings.TrimSpace(routineParts[1]), ".")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" || parts[0] == "*" || parts[1] == "*" {
+			return "", nil, fmt.Errorf("expected scope 'FUNCTION db.name' or 'PROCEDURE db.name' for routine privileges, got '%s'", scope)
+		}
+		return objectType + " ", parts, nil
+	default:
+		return "", nil, fmt.Errorf("invalid privType: %s", dbPriv.PrivType)
+	}
+}
+
 /*
 Take a privileges string, typically passed as a parameter, and unserialize
 it into a dictionary, the same format as privileges_get() above. We have this
@@ -539,31 +613,42 @@ of a privileges string:
 The privilege USAGE stands for no privileges, so we add that in on *.* if it's
 not specified in the string, as MySQL will always provide this by default.
 */
-func privilegesUnpack(dbPrivs []dboperatorv1alpha1.DbPriv, mode string) (map[string][]string, error) {
-	var quote string
+// Quotes a database or table name the way SHOW GRANTS does in the sql_mode of the server
+func quoteForMode(name string, mode string) string {
 	if mode == "ANSI" {
-		quote = "\""
-	} else {
-		quote = "`"
+		return fmt.Sprintf("\"%s\"", name)
 	}
+	return fmt.Sprintf("`%s`", name)
+}
+
+func privilegesUnpack(dbPrivs []dboperatorv1alpha1.DbPriv, mode string) (map[string][]string, error) {
 	output := map[string][]string{}
 
 	for _, item := range dbPrivs {
-		dbPriv := strings.Split(item.Scope, ".")
-
-		// Check for FUNCTION or PROCEDURE object types
-		parts := strings.SplitN(item.Privs, " ", 2)
+		var dbPriv []string
 		objectType := ""
-		if len(parts) > 1 && (parts[0] == "FUNCTION" || parts[0] == "PROCEDURE") {
-			objectType = parts[0] + " "
-			dbPriv[0] = parts[1]
+		if item.PrivType != "" {
+			var err error
+			objectType, dbPriv, err = normalizeDbPrivScope(item)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			dbPriv = strings.Split(item.Scope, ".")
+
+			// Check for FUNCTION or PROCEDURE object types
+			parts := strings.SplitN(item.Privs, " ", 2)
+			if len(parts) > 1 && (parts[0] == "FUNCTION" || parts[0] == "PROCEDURE") {
+				objectType = parts[0] + " "
+				dbPriv[0] = parts[1]
+			}
 		}
 
 		// Do not escape if privilege is for database or table, i.e.
 		// neither quote *. nor .*
 		for i, side := range dbPriv {
 			if strings.Trim(side, "`") != "*" {
-				dbPriv[i] = fmt.Sprintf("%s%s%s", quote, strings.TrimSpace(side), quote)
+				dbPriv[i] = quoteForMode(strings.TrimSpace(side), mode)
 			}
 		}
 		item.Scope = objectType + strings.Join(dbPriv, ".")
@@ -574,6 +659,12 @@ func privilegesUnpack(dbPrivs []dboperatorv1alpha1.DbPriv, mode string) (map[str
 		if len(invalidPrivs) > 0 {
 			return nil, fmt.Errorf("invalid privileges found %s", invalidPrivs)
 		}
+		if objectType != "" {
+			invalidPrivs = funk.Subtract(privsStripped, VALID_ROUTINE_PRIVS).([]string)
+			if len(invalidPrivs) > 0 {
+				return nil, fmt.Errorf("invalid privileges found for %s: %s", item.Scope, invalidPrivs)
+			}
+		}
 
 		// Handle cases when there's privs like GRANT SELECT (colA, ...) in privs.
 		output[item.Scope] = normalizeColGrants(output[item.Scope])
@@ -587,11 +678,11 @@ func privilegesUnpack(dbPrivs []dboperatorv1alpha1.DbPriv, mode string) (map[str
 	return output, nil
 }
 
-func privilegesRevoke(conn *sql.DB, user string, host string, dbTable string, priv []string, grantOption bool) error {
+func privilegesRevoke(conn shared.Executor, user string, host string, dbTable string, priv []string, grantOption bool) error {
 	if isQuoted(host) || isQuoted(user) {
 		return fmt.Errorf("quoted user or host")
 	}
-	if dbTable != "*.*" && !isQuoted(dbTable) {
+	if dbTable != "*.*" && !isQuoted(stripObjectType(dbTable)) {
 		return fmt.Errorf("unquoted dbTable")
 	}
 
@@ -619,11 +710,21 @@ func isQuoted(input string) bool {
 	return input[0] == '"' || input[0] == '`'
 }
 
-func privilegesGrant(conn *sql.DB, user string, host string, dbTable string, priv []string, tlsRequires TlsRequires, si ServerInfo) error {
+// FUNCTION `db`.`fn` -> `db`.`fn`
+func stripObjectType(dbTable string) string {
+	for _, objectType := range []string{"FUNCTION ", "PROCEDURE "} {
+		if strings.HasPrefix(dbTable, objectType) {
+			return strings.TrimPrefix(dbTable, objectType)
+		}
+	}
+	return dbTable
+}
+
+func privilegesGrant(conn shared.Executor, user string, host string, dbTable string, priv []string, tlsRequires TlsRequires, si ServerInfo) error {
 	if isQuoted(host) || isQuoted(user) {
 		return fmt.Errorf("quoted user or host")
 	}
-	if dbTable != "*.*" && !isQuoted(dbTable) {
+	if dbTable != "*.*" && !isQuoted(stripObjectType(dbTable)) {
 		return fmt.Errorf("unquoted dbTable")
 	}
 
@@ -711,8 +812,19 @@ func getPrivileges(conn *sql.DB, userName string, host string) (map[string][]str
 	return grants, nil
 }
 
-func UpdateUserPrivs(conn *sql.DB, userName string, serverPrivs string, dbPrivs []dboperatorv1alpha1.DbPriv) (bool, error) {
-	host := "%" // Not yet supporting other host names in crd spec
+func UpdateUserPrivs(conn *sql.DB, userName string, host string, serverPrivs string, dbPrivs []dboperatorv1alpha1.DbPriv) (bool, error) {
+	return updateUserPrivs(conn, conn, userName, host, serverPrivs, dbPrivs)
+}
+
+// Returns the statements UpdateUserPrivs would run
+func PlanUserPrivs(conn *sql.DB, userName string, host string, serverPrivs string, dbPrivs []dboperatorv1alpha1.DbPriv) ([]dboperatorv1alpha1.PlannedStatement, error) {
+	plan := []dboperatorv1alpha1.PlannedStatement{}
+	_, err := updateUserPrivs(conn, shared.StatementRecorder{Statements: &plan}, userName, host, serverPrivs, dbPrivs)
+	return plan, err
+}
+
+// Reads the current privileges through conn and runs the changes through exec
+func updateUserPrivs(conn *sql.DB, exec shared.Executor, userName string, host string, serverPrivs string, dbPrivs []dboperatorv1alpha1.DbPriv) (bool, error) {
 	si, err := getServerInfo(conn)
 	if err != nil {
 		return false, fmt.Errorf("failed to getServerInfo for UpdateUserPrivs %s", err)
@@ -723,41 +835,25 @@ func UpdateUserPrivs(conn *sql.DB, userName string, serverPrivs string, dbPrivs
 		return false, fmt.Errorf("failed to getTlsRequires for UpdateUserPrivs %s", err)
 	}
 
-	curPrivs, err := getPrivileges(conn, userName, host)
+	curPrivs, desiredPrivs, err := getCurrentAndDesiredPrivs(conn, *si, userName, host, serverPrivs, dbPrivs)
 	if err != nil {
-		return false, fmt.Errorf("failed to UpdateUserPrivs %s", err)
+		return false, err
 	}
 
-	var allPrivs []dboperatorv1alpha1.DbPriv
-	if len(serverPrivs) == 0 {
-		allPrivs = dbPrivs
-	} else {
-		allPrivs = append(dbPrivs, dboperatorv1alpha1.DbPriv{Scope: "*.*", Privs: serverPrivs})
-	}
-
-	desiredPrivs, err := privilegesUnpack(allPrivs, si.Mode)
-	if err != nil {
-		return false, fmt.Errorf("failed to privilegesUnpack desiredPrivs %s", err)
-	}
+	// Sorted so a plan of the statements always comes out the same
+	curDbTables := funk.Keys(curPrivs).([]string)
+	sort.Strings(curDbTables)
+	desiredDbTables := funk.Keys(desiredPrivs).([]string)
+	sort.Strings(desiredDbTables)
 
 	changes := false
-	for dbTable, curDbTablePrivs := range curPrivs {
+	for _, dbTable := range curDbTables {
+		curDbTablePrivs := curPrivs[dbTable]
 		desiredDbTablePrivs, desiredFound := desiredPrivs[dbTable]
 		if !desiredFound {
 			desiredDbTablePrivs = []string{}
 		}
 
-		if len(curDbTablePrivs) > 50 {
-			subtracted := funk.Subtract(ALL_PRIVS, curDbTablePrivs).([]string)
-			if len(subtracted) == 0 {
-				curDbTablePrivs = []string{"ALL"}
-				curPrivs[dbTable] = curDbTablePrivs
-			} else {
-				joined := strings.Join(subtracted, ", ")
-				panic(fmt.Sprintf("Not entirely sure if current list of privileges is equal to ALL privileges %s", joined))
-			}
-		}
-
 		grantOption := false
 		grantIndex := funk.IndexOfString(curDbTablePrivs, "GRANT")
 		if grantIndex != -1 {
@@ -767,7 +863,7 @@ func UpdateUserPrivs(conn *sql.DB, userName string, serverPrivs string, dbPrivs
 
 		toRevoke := funk.SubtractString(curDbTablePrivs, desiredDbTablePrivs)
 		if len(toRevoke) > 0 {
-			privilegesRevoke(conn, userName, host, dbTable, toRevoke, grantOption)
+			err = privilegesRevoke(exec, userName, host, dbTable, toRevoke, grantOption)
 			if err != nil {
 				return changes, err
 			}
@@ -775,7 +871,8 @@ func UpdateUserPrivs(conn *sql.DB, userName string, serverPrivs string, dbPrivs
 		}
 	}
 
-	for dbTable, desiredDbTablePrivs := range desiredPrivs {
+	for _, dbTable := range desiredDbTables {
+		desiredDbTablePrivs := desiredPrivs[dbTable]
 		curDbTablePrivs, exists := curPrivs[dbTable]
 
 		if !exists {
@@ -784,7 +881,7 @@ func UpdateUserPrivs(conn *sql.DB, userName string, serverPrivs string, dbPrivs
 
 		toGrant := funk.SubtractString(desiredDbTablePrivs, curDbTablePrivs)
 		if len(toGrant) > 0 {
-			err = privilegesGrant(conn, userName, host, dbTable, toGrant, tlsRequires, *si)
+			err = privilegesGrant(exec, userName, host, dbTable, toGrant, tlsRequires, *si)
 			if err != nil {
 				return changes, err
 			}
@@ -794,6 +891,85 @@ func UpdateUserPrivs(conn *sql.DB, userName string, serverPrivs string, dbPrivs
 	return changes, nil
 }
 
+// Returns the current and the desired privileges of the user, both keyed by db.table
+func getCurrentAndDesiredPrivs(conn *sql.DB, si ServerInfo, userName string, host string, serverPrivs string, dbPrivs []dboperatorv1alpha1.DbPriv) (map[string][]string, map[string][]string, error) {
+	curPrivs, err := getPrivileges(conn, userName, host)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to UpdateUserPrivs %s", err)
+	}
+
+	var allPrivs []dboperatorv1alpha1.DbPriv
+	if len(serverPrivs) == 0 {
+		allPrivs = dbPrivs
+	} else {
+		allPrivs = append(dbPrivs, dboperatorv1alpha1.DbPriv{Scope: "*.*", Privs: serverPrivs})
+	}
+
+	desiredPrivs, err := privilegesUnpack(allPrivs, si.Mode)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to privilegesUnpack desiredPrivs %s", err)
+	}
+
+	for dbTable, curDbTablePrivs := range curPrivs {
+		if len(curDbTablePrivs) > 50 {
+			subtracted := funk.Subtract(ALL_PRIVS, curDbTablePrivs).([]string)
+			if len(subtracted) == 0 {
+				curPrivs[dbTable] = []string{"ALL"}
+			} else {
+				joined := strings.Join(subtracted, ", ")
+				panic(fmt.Sprintf("Not entirely sure if current list of privileges is equal to ALL privileges %s", joined))
+			}
+		}
+	}
+	return curPrivs, desiredPrivs, nil
+}
+
+func GetUserPrivsDrift(conn *sql.DB, userName string, host string, serverPrivs string, dbPrivs []dboperatorv1alpha1.DbPriv) ([]shared.PrivsDrift, error) {
+	si, err := getServerInfo(conn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to getServerInfo for GetUserPrivsDrift %s", err)
+	}
+	curPrivs, desiredPrivs, err := getCurrentAndDesiredPrivs(conn, *si, userName, host, serverPrivs, dbPrivs)
+	if err != nil {
+		return nil, err
+	}
+
+	dbTables := funk.UniqString(append(funk.Keys(curPrivs).([]string), funk.Keys(desiredPrivs).([]string)...))
+	sort.Strings(dbTables)
+	drifts := []shared.PrivsDrift{}
+	for _, dbTable := range dbTables {
+		extra := funk.SubtractString(curPrivs[dbTable], desiredPrivs[dbTable])
+		missing := funk.SubtractString(desiredPrivs[dbTable], curPrivs[dbTable])
+		if len(extra) > 0 || len(missing) > 0 {
+			drifts = append(drifts, shared.PrivsDrift{Scope: dbTable, Extra: extra, Missing: missing})
+		}
+	}
+	return drifts, nil
+}
+
+// MySQL has no database owners, the owner gets ALL on the database instead
+func UpdateDbOwner(conn *sql.DB, dbName string, userName string, host string) (bool, error) {
+	si, err := getServerInfo(conn)
+	if err != nil {
+		return false, fmt.Errorf("failed to getServerInfo for UpdateDbOwner %s", err)
+	}
+	ownerPrivs := []dboperatorv1alpha1.DbPriv{{Scope: fmt.Sprintf("%s.*", dbName), Privs: "ALL"}}
+	curPrivs, desiredPrivs, err := getCurrentAndDesiredPrivs(conn, *si, userName, host, "", ownerPrivs)
+	if err != nil {
+		return false, err
+	}
+	// Only the database itself, privilegesUnpack also adds USAGE on *.* which the owner has anyway
+	dbTable := fmt.Sprintf("%s.*", quoteForMode(dbName, si.Mode))
+	if funk.ContainsString(curPrivs[dbTable], "ALL") {
+		return false, nil
+	}
+	err = privilegesGrant(conn, userName, host, dbTable, desiredPrivs[dbTable], TlsRequires{}, *si)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 func propertiesMapToMySqlVersion(properties map[string]interface{}) (*MySQLVersion, error) {
 	version, found := properties["version"]
 	if !found {
@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/shared"
 )
 
 func TestIntegrationPrivilegesGrant(t *testing.T) {
@@ -73,7 +74,7 @@ func TestIntegrationPrivilegesGrant(t *testing.T) {
 
 func TestIntegrationUpdateUserPrivs(t *testing.T) {
 
-	// UpdateUserPrivs(conn *sql.DB, userName string, serverPrivs string, dbPrivs []dboperatorv1alpha1.DbPriv)
+	// UpdateUserPrivs(conn *sql.DB, userName string, host string, serverPrivs string, dbPrivs []dboperatorv1alpha1.DbPriv)
 
 	serverConn, err := sql.Open("mysql", "root:mysqlPassword@tcp(127.0.0.1:3306)/")
 	if err != nil {
@@ -94,11 +95,11 @@ func TestIntegrationUpdateUserPrivs(t *testing.T) {
 	_, err = db.Exec("CREATE TABLE IF NOT EXISTS chair (name VARCHAR(20));")
 	dbPrivs := []dboperatorv1alpha1.DbPriv{
 		{
-			DbName: "kitchen.chair",
-			Privs:  "SELECT,UPDATE",
+			Scope: "kitchen.chair",
+			Privs: "SELECT,UPDATE",
 		},
 	}
-	changes, err := UpdateUserPrivs(db, "jantje", "TODO", dbPrivs)
+	changes, err := UpdateUserPrivs(db, "jantje", "%", "TODO", dbPrivs)
 	if err != nil {
 		t.Errorf("UpdateUserPrivs failed: %s", err)
 	}
@@ -107,3 +108,44 @@ func TestIntegrationUpdateUserPrivs(t *testing.T) {
 		t.Error("Expected changes")
 	}
 }
+
+// Runs the account statements through the connector, a real server refuses them as prepared statements
+func TestIntegrationUserAccountStatements(t *testing.T) {
+	password := "mysqlPassword"
+	conn := NewMySqlConnection(&shared.DbServerConnectInfo{
+		Host:        "127.0.0.1",
+		Port:        3306,
+		Credentials: shared.Credentials{UserName: "root", Password: &password},
+	}, map[string]*shared.Credentials{}, "mysql")
+	defer conn.Close()
+
+	userSpec := dboperatorv1alpha1.UserSpec{UserName: "o'brien"}
+	err := conn.CreateUser(userSpec, `pass'word\`)
+	if err != nil {
+		t.Fatalf("CreateUser failed: %s", err)
+	}
+
+	maxUserConnections := 5
+	userSpec.Host = "10.0.0.%"
+	userSpec.TlsRequires = &dboperatorv1alpha1.TlsRequires{Subject: "/CN=o'brien", Issuer: "/CN=ca"}
+	userSpec.MaxUserConnections = &maxUserConnections
+	changed, err := conn.UpdateUserOptions(userSpec)
+	if err != nil {
+		t.Fatalf("UpdateUserOptions failed: %s", err)
+	}
+	if !changed {
+		t.Errorf("expected the host and options to change")
+	}
+	changed, err = conn.UpdateUserOptions(userSpec)
+	if err != nil {
+		t.Fatalf("UpdateUserOptions failed: %s", err)
+	}
+	if changed {
+		t.Errorf("expected no changes the second time")
+	}
+
+	err = conn.DropUser(userSpec)
+	if err != nil {
+		t.Fatalf("DropUser failed: %s", err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/shared"
 	"github.com/thoas/go-funk"
 )
 
@@ -156,6 +157,62 @@ func TestPrivilegesUnpackNonAnsi(t *testing.T) {
 	}
 }
 
+func TestPrivilegesUnpackPrivTypes(t *testing.T) {
+	privs := []dboperatorv1alpha1.DbPriv{
+		{
+			Scope:    "mydb",
+			Privs:    "INSERT,UPDATE",
+			PrivType: "database",
+		},
+		{
+			Scope:    "mydb.mytable",
+			Privs:    "SELECT",
+			PrivType: "table",
+		},
+		{
+			Scope:    "FUNCTION mydb.my_function",
+			Privs:    "EXECUTE",
+			PrivType: "routine",
+		},
+		{
+			Scope:    "*.*",
+			Privs:    "PROCESS",
+			PrivType: "global",
+		},
+	}
+	privMap, err := privilegesUnpack(privs, "NOTANSI")
+	if err != nil {
+		t.Fatalf("Failed unpacking privileges %s", err)
+	}
+
+	expected := map[string][]string{
+		"`mydb`.*":                      {"INSERT", "UPDATE"},
+		"`mydb`.`mytable`":              {"SELECT"},
+		"FUNCTION `mydb`.`my_function`": {"EXECUTE"},
+		"*.*":                           {"PROCESS"},
+	}
+
+	if !reflect.DeepEqual(privMap, expected) {
+		t.Fatalf("privileges unpacking returned unexpected map of privs %s", privMap)
+	}
+}
+
+func TestPrivilegesUnpackInvalidPrivTypes(t *testing.T) {
+	invalidPrivs := []dboperatorv1alpha1.DbPriv{
+		{Scope: "mydb.mytable", Privs: "SELECT", PrivType: "database"},
+		{Scope: "mydb", Privs: "SELECT", PrivType: "table"},
+		{Scope: "mydb.my_function", Privs: "EXECUTE", PrivType: "routine"},
+		{Scope: "FUNCTION mydb.my_function", Privs: "SELECT", PrivType: "routine"},
+		{Scope: "mydb", Privs: "SELECT", PrivType: "schema"},
+	}
+	for _, dbPriv := range invalidPrivs {
+		_, err := privilegesUnpack([]dboperatorv1alpha1.DbPriv{dbPriv}, "NOTANSI")
+		if err == nil {
+			t.Errorf("expected error unpacking %s %s", dbPriv.PrivType, dbPriv.Scope)
+		}
+	}
+}
+
 func TestParsePrivPiece(t *testing.T) {
 	result, resultStripped := parsePrivPiece("INSERT,SELECT(col1,col2),UPDATE")
 	expected := []string{"INSERT", "SELECT(col1,col2)", "UPDATE"}
@@ -335,6 +392,22 @@ func TestPrivilegesRevoke(t *testing.T) {
 	}
 }
 
+func TestPrivilegesRevokePlan(t *testing.T) {
+	plan := []dboperatorv1alpha1.PlannedStatement{}
+	err := privilegesRevoke(shared.StatementRecorder{Statements: &plan}, "jantje", "myhost", "`chair`", []string{"SELECT"}, true)
+	if err != nil {
+		t.Errorf("privilegesRevoke failed: %s", err)
+	}
+
+	expected := []dboperatorv1alpha1.PlannedStatement{
+		{Statement: "REVOKE GRANT OPTION ON '`chair`' FROM 'jantje'@'myhost';"},
+		{Statement: "REVOKE SELECT ON `chair` FROM 'jantje'@'myhost';"},
+	}
+	if !reflect.DeepEqual(plan, expected) {
+		t.Errorf("expected plan %v, got %v", expected, plan)
+	}
+}
+
 /*
 func TestGetPrivileges(t *testing.T) {
 	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
@@ -440,3 +513,80 @@ func TestCreateUser(t *testing.T) {
 		t.Errorf("TestCreateUser: there were unfulfilled expectations: %s", err)
 	}
 }
+
+func TestPrivilegesGrantRoutine(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	si, err := NewServerInfo("8.0.25", "NOTANSI")
+	if err != nil {
+		t.Errorf("NewServerInfo failed: %s", err)
+	}
+
+	mock.ExpectExec("GRANT EXECUTE ON FUNCTION `mydb`.`my_function` TO 'jantje'@'%';").WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err = privilegesGrant(db, "jantje", "%", "FUNCTION `mydb`.`my_function`", []string{"EXECUTE"}, TlsRequires{}, *si)
+	if err != nil {
+		t.Errorf("privilegesGrant failed: %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("TestPrivilegesGrantRoutine: there were unfulfilled expectations: %s", err)
+	}
+}
+
+func expectOwnerGrants(mock sqlmock.Sqlmock, grants ...string) {
+	expectVersionQuery(mock)
+	expectSQLModeQuery(mock)
+	mockOutput := sqlmock.NewRows([]string{"Grants for owner@%"})
+	for _, grant := range grants {
+		mockOutput.AddRow(grant)
+	}
+	mock.ExpectQuery("SHOW GRANTS for 'owner'@'%';").WillReturnRows(mockOutput)
+}
+
+func TestUpdateDbOwnerAlreadyOwner(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	expectOwnerGrants(mock, `GRANT USAGE ON *.* TO "owner"@"%"`, `GRANT ALL PRIVILEGES ON "shop".* TO "owner"@"%"`)
+	changed, err := UpdateDbOwner(db, "shop", "owner", "%")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if changed {
+		t.Errorf("expected no changes for an owner that has ALL on the database")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUpdateDbOwnerGrantsOnlyTheDatabase(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	expectOwnerGrants(mock, `GRANT USAGE ON *.* TO "owner"@"%"`)
+	mock.ExpectExec(`GRANT ALL ON "shop".* TO 'owner'@'%';`).WillReturnResult(sqlmock.NewResult(0, 0))
+	changed, err := UpdateDbOwner(db, "shop", "owner", "%")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if !changed {
+		t.Errorf("expected ALL to be granted on the database")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
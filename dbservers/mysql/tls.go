@@ -0,0 +1,79 @@
+package mysql
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/obeleh/db-operator/shared"
+)
+
+// The sslmode values are the Postgres ones so a DbServer is configured the same way for both
+// preferred is added because that's what MySQL clients use, prefer is accepted as an alias
+const SSL_MODE_PREFERRED = "preferred"
+
+// Custom TLS configs are registered with the driver under a name with this prefix
+const TLS_CONFIG_PREFIX = "db-operator-"
+
+// A registration is only needed until the driver parsed the DSN, registering, opening and
+// deregistering is done under this lock so connections with the same certificates don't race
+var tlsConfigMutex sync.Mutex
+
+// Without an explicit sslmode TLS is only used for client certificates, a CA certificate
+// alone doesn't turn it on so DbServers that connect by IP keep working
+func getSslMode(options map[string]string, tlsCrt, tlsKey *string) string {
+	sslMode, found := options["sslmode"]
+	if found {
+		if sslMode == "prefer" {
+			return SSL_MODE_PREFERRED
+		}
+		return sslMode
+	}
+	if tlsCrt != nil || tlsKey != nil {
+		return shared.SSL_MODE_REQUIRE
+	}
+	return shared.SSL_MODE_DISABLE
+}
+
+// Returns the value for the tls parameter of the DSN
+// Custom configs are registered with the driver under a key derived from their contents,
+// deregisterTlsConfig removes them again once the connection is opened
+func registerTlsConfig(sslMode string, serverName string, caCert, tlsCrt, tlsKey *string) (string, error) {
+	switch sslMode {
+	case shared.SSL_MODE_DISABLE:
+		return "false", nil
+	case SSL_MODE_PREFERRED:
+		if tlsCrt != nil || tlsKey != nil {
+			return "", fmt.Errorf("sslmode %s can't be combined with client certificates", sslMode)
+		}
+		return "preferred", nil
+	}
+
+	tlsConfig, err := shared.BuildTlsConfig(sslMode, serverName, caCert, tlsCrt, tlsKey)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	for _, part := range []*string{&sslMode, &serverName, caCert, tlsCrt, tlsKey} {
+		if part != nil {
+			hash.Write([]byte(*part))
+		}
+		hash.Write([]byte{0})
+	}
+	key := fmt.Sprintf("%s%x", TLS_CONFIG_PREFIX, hash.Sum(nil))
+	err = mysql.RegisterTLSConfig(key, tlsConfig)
+	if err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// The driver keeps its own copy of the config after parsing the DSN, so rotated certificates don't leave registrations behind
+func deregisterTlsConfig(tlsConfigName string) {
+	if strings.HasPrefix(tlsConfigName, TLS_CONFIG_PREFIX) {
+		mysql.DeregisterTLSConfig(tlsConfigName)
+	}
+}
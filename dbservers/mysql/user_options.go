@@ -0,0 +1,168 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	funk "github.com/thoas/go-funk"
+)
+
+func tlsRequiresFromSpec(spec dboperatorv1alpha1.TlsRequires) (TlsRequires, error) {
+	requiresMap := map[string]string{}
+	if spec.Subject != "" {
+		requiresMap["SUBJECT"] = spec.Subject
+	}
+	if spec.Issuer != "" {
+		requiresMap["ISSUER"] = spec.Issuer
+	}
+	if spec.Cipher != "" {
+		requiresMap["CIPHER"] = spec.Cipher
+	}
+
+	if spec.SSL && spec.X509 {
+		return TlsRequires{}, fmt.Errorf("tls_requires ssl and x509 are mutually exclusive")
+	}
+	if (spec.SSL || spec.X509) && len(requiresMap) > 0 {
+		return TlsRequires{}, fmt.Errorf("tls_requires ssl and x509 are mutually exclusive with subject, issuer and cipher")
+	}
+
+	if spec.SSL {
+		requiresStr := "SSL"
+		return TlsRequires{RequiresStr: &requiresStr}, nil
+	}
+	if spec.X509 {
+		requiresStr := "X509"
+		return TlsRequires{RequiresStr: &requiresStr}, nil
+	}
+	if len(requiresMap) > 0 {
+		return TlsRequires{RequiresMap: requiresMap}, nil
+	}
+	return TlsRequires{}, nil
+}
+
+func getResourceLimits(conn *sql.DB, user string, host string) (int, int, error) {
+	rows, err := conn.Query("SELECT max_questions, max_user_connections FROM mysql.user WHERE user = ? AND host = ?", user, host)
+	if err != nil {
+		return 0, 0, fmt.Errorf("unable to read resource limits from mysql.user %s", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return 0, 0, fmt.Errorf("user %s@%s not found in mysql.user", user, host)
+	}
+	var maxQueriesPerHour, maxUserConnections int
+	err = rows.Scan(&maxQueriesPerHour, &maxUserConnections)
+	if err != nil {
+		return 0, 0, fmt.Errorf("unable to load resource limits %s", err)
+	}
+	return maxQueriesPerHour, maxUserConnections, nil
+}
+
+func getUserHosts(conn *sql.DB, user string) ([]string, error) {
+	rows, err := conn.Query("SELECT host FROM mysql.user WHERE user = ? ORDER BY host", user)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read hosts of user %s from mysql.user %s", user, err)
+	}
+	defer rows.Close()
+	hosts := []string{}
+	for rows.Next() {
+		var host string
+		err = rows.Scan(&host)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load hosts of user %s %s", user, err)
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts, nil
+}
+
+// Moves the account of userName to host when it only exists with another host, RENAME USER keeps its grants
+// With accounts on several other hosts it's unclear which one to move so that's refused
+func UpdateUserHost(conn *sql.DB, userName string, host string) (bool, error) {
+	hosts, err := getUserHosts(conn, userName)
+	if err != nil {
+		return false, err
+	}
+	if funk.ContainsString(hosts, host) {
+		return false, nil
+	}
+	if len(hosts) == 0 {
+		return false, fmt.Errorf("user %s does not exist", userName)
+	}
+	if len(hosts) > 1 {
+		return false, fmt.Errorf("user %s exists for hosts %s, unable to tell which account should move to %s", userName, strings.Join(hosts, ", "), host)
+	}
+	_, err = conn.Exec("RENAME USER ?@? TO ?@?;", userName, hosts[0], userName, host)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Reconciles the TLS requirements and resource limits of an account
+// Options that are nil are left as they are on the server
+func UpdateUserOptions(conn *sql.DB, userName string, host string, tlsRequiresSpec *dboperatorv1alpha1.TlsRequires, maxQueriesPerHour *int, maxUserConnections *int) (bool, error) {
+	if tlsRequiresSpec == nil && maxQueriesPerHour == nil && maxUserConnections == nil {
+		return false, nil
+	}
+
+	si, err := getServerInfo(conn)
+	if err != nil {
+		return false, fmt.Errorf("failed to getServerInfo for UpdateUserOptions %s", err)
+	}
+
+	clauses := []string{}
+	params := []interface{}{userName, host}
+	if tlsRequiresSpec != nil {
+		desiredTlsRequires, err := tlsRequiresFromSpec(*tlsRequiresSpec)
+		if err != nil {
+			return false, err
+		}
+		curTlsRequires, err := getTlsRequires(conn, *si, userName, host)
+		if err != nil {
+			return false, fmt.Errorf("failed to getTlsRequires for UpdateUserOptions %s", err)
+		}
+		if !reflect.DeepEqual(curTlsRequires, desiredTlsRequires) {
+			clause, clauseParams := desiredTlsRequires.RequiresClause()
+			clauses = append(clauses, "REQUIRE "+clause)
+			params = append(params, stringArrayToInterfaceArray(clauseParams)...)
+		}
+	}
+
+	if maxQueriesPerHour != nil || maxUserConnections != nil {
+		curMaxQueriesPerHour, curMaxUserConnections, err := getResourceLimits(conn, userName, host)
+		if err != nil {
+			return false, err
+		}
+		limits := []string{}
+		if maxQueriesPerHour != nil && *maxQueriesPerHour != curMaxQueriesPerHour {
+			limits = append(limits, fmt.Sprintf("MAX_QUERIES_PER_HOUR %d", *maxQueriesPerHour))
+		}
+		if maxUserConnections != nil && *maxUserConnections != curMaxUserConnections {
+			limits = append(limits, fmt.Sprintf("MAX_USER_CONNECTIONS %d", *maxUserConnections))
+		}
+		if len(limits) > 0 {
+			clauses = append(clauses, "WITH "+strings.Join(limits, " "))
+		}
+	}
+
+	if len(clauses) == 0 {
+		return false, nil
+	}
+
+	var query string
+	if si.UseOldUserMgmt() {
+		// Before ALTER USER supported these options they were set through GRANT
+		query = fmt.Sprintf("GRANT USAGE ON *.* TO ?@? %s;", strings.Join(clauses, " "))
+	} else {
+		query = fmt.Sprintf("ALTER USER ?@? %s;", strings.Join(clauses, " "))
+	}
+	_, err = conn.Exec(query, params...)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,185 @@
+package mysql
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+)
+
+func TestGetTlsRequiresSubjectIssuer(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	expectSQLQuery(
+		mock,
+		"SHOW CREATE USER 'user'@'%'",
+		"CREATE USER for user@%",
+		"CREATE USER 'user'@'%' IDENTIFIED WITH 'caching_sha2_password' REQUIRE SUBJECT '/CN=alice' AND ISSUER '/O=Bob\\'s CA' WITH MAX_USER_CONNECTIONS 5 PASSWORD EXPIRE DEFAULT ACCOUNT UNLOCK PASSWORD REQUIRE CURRENT DEFAULT",
+	)
+
+	serverInfo, _ := NewServerInfo("8.0.25", "ANSI")
+	tlsRequires, err := getTlsRequires(db, *serverInfo, "user", "%")
+	if err != nil {
+		t.Fatalf("failed parsing TLS requirements, %s", err)
+	}
+
+	expectedTlsRequires := TlsRequires{RequiresMap: map[string]string{"SUBJECT": "/CN=alice", "ISSUER": "/O=Bob's CA"}}
+	if !reflect.DeepEqual(tlsRequires, expectedTlsRequires) {
+		t.Fatalf("unexpected requires %v", tlsRequires)
+	}
+}
+
+func TestTlsRequiresFromSpecInvalid(t *testing.T) {
+	invalidSpecs := []dboperatorv1alpha1.TlsRequires{
+		{SSL: true, X509: true},
+		{SSL: true, Subject: "/CN=alice"},
+		{X509: true, Cipher: "EDH-RSA-DES-CBC3-SHA"},
+	}
+	for _, spec := range invalidSpecs {
+		_, err := tlsRequiresFromSpec(spec)
+		if err == nil {
+			t.Errorf("expected error for %v", spec)
+		}
+	}
+}
+
+func TestRequiresClause(t *testing.T) {
+	tlsRequires, err := tlsRequiresFromSpec(dboperatorv1alpha1.TlsRequires{Subject: "/CN=alice", Issuer: "/CN=ca", Cipher: "EDH-RSA-DES-CBC3-SHA"})
+	if err != nil {
+		t.Fatalf("tlsRequiresFromSpec failed: %s", err)
+	}
+	expected := "CIPHER ? AND ISSUER ? AND SUBJECT ?"
+	clause, params := tlsRequires.RequiresClause()
+	if clause != expected {
+		t.Errorf("expected %s, got %s", expected, clause)
+	}
+	if !reflect.DeepEqual(params, []string{"EDH-RSA-DES-CBC3-SHA", "/CN=ca", "/CN=alice"}) {
+		t.Errorf("unexpected params %v", params)
+	}
+
+	clause, _ = (TlsRequires{}).RequiresClause()
+	if clause != "NONE" {
+		t.Errorf("expected NONE for empty TlsRequires")
+	}
+}
+
+func expectUserOptionsQueries(mock sqlmock.Sqlmock, createUser string, maxQuestions int, maxUserConnections int) {
+	expectVersionQuery(mock)
+	expectSQLModeQuery(mock)
+	expectSQLQuery(mock, "SHOW CREATE USER 'jantje'@'%'", "CREATE USER for jantje@%", createUser)
+	limitRows := sqlmock.NewRows([]string{"max_questions", "max_user_connections"})
+	limitRows.AddRow(maxQuestions, maxUserConnections)
+	mock.ExpectQuery("SELECT max_questions, max_user_connections FROM mysql.user WHERE user = ? AND host = ?").WithArgs("jantje", "%").WillReturnRows(limitRows)
+}
+
+func TestUpdateUserOptions(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	expectUserOptionsQueries(mock, "CREATE USER 'jantje'@'%' IDENTIFIED WITH 'caching_sha2_password' REQUIRE NONE PASSWORD EXPIRE DEFAULT", 0, 10)
+	mock.ExpectExec("ALTER USER ?@? REQUIRE X509 WITH MAX_QUERIES_PER_HOUR 100;").WithArgs("jantje", "%").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	maxQueriesPerHour := 100
+	maxUserConnections := 10
+	changes, err := UpdateUserOptions(db, "jantje", "%", &dboperatorv1alpha1.TlsRequires{X509: true}, &maxQueriesPerHour, &maxUserConnections)
+	if err != nil {
+		t.Fatalf("UpdateUserOptions failed: %s", err)
+	}
+	if !changes {
+		t.Errorf("expected changes")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUpdateUserOptionsNoChanges(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	expectUserOptionsQueries(mock, "CREATE USER 'jantje'@'%' IDENTIFIED WITH 'caching_sha2_password' REQUIRE SSL WITH MAX_USER_CONNECTIONS 10 PASSWORD EXPIRE DEFAULT", 0, 10)
+
+	maxUserConnections := 10
+	changes, err := UpdateUserOptions(db, "jantje", "%", &dboperatorv1alpha1.TlsRequires{SSL: true}, nil, &maxUserConnections)
+	if err != nil {
+		t.Fatalf("UpdateUserOptions failed: %s", err)
+	}
+	if changes {
+		t.Errorf("expected no changes")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUpdateUserOptionsSubject(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	expectUserOptionsQueries(mock, "CREATE USER 'jantje'@'%' IDENTIFIED WITH 'caching_sha2_password' REQUIRE NONE PASSWORD EXPIRE DEFAULT", 0, 10)
+	mock.ExpectExec("ALTER USER ?@? REQUIRE SUBJECT ?;").WithArgs("jantje", "%", `/CN=o'brien\\`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	maxUserConnections := 10
+	_, err = UpdateUserOptions(db, "jantje", "%", &dboperatorv1alpha1.TlsRequires{Subject: `/CN=o'brien\\`}, nil, &maxUserConnections)
+	if err != nil {
+		t.Fatalf("UpdateUserOptions failed: %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUpdateUserHost(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	hostsQuery := "SELECT host FROM mysql.user WHERE user = ? ORDER BY host"
+	mock.ExpectQuery(hostsQuery).WithArgs("jantje").WillReturnRows(sqlmock.NewRows([]string{"host"}).AddRow("%"))
+	mock.ExpectExec("RENAME USER ?@? TO ?@?;").WithArgs("jantje", "%", "jantje", "10.0.0.%").WillReturnResult(sqlmock.NewResult(0, 0))
+	changed, err := UpdateUserHost(db, "jantje", "10.0.0.%")
+	if err != nil {
+		t.Fatalf("UpdateUserHost failed: %s", err)
+	}
+	if !changed {
+		t.Errorf("expected the host to change")
+	}
+
+	mock.ExpectQuery(hostsQuery).WithArgs("jantje").WillReturnRows(sqlmock.NewRows([]string{"host"}).AddRow("10.0.0.%"))
+	changed, err = UpdateUserHost(db, "jantje", "10.0.0.%")
+	if err != nil {
+		t.Fatalf("UpdateUserHost failed: %s", err)
+	}
+	if changed {
+		t.Errorf("expected no changes")
+	}
+
+	mock.ExpectQuery(hostsQuery).WithArgs("jantje").WillReturnRows(sqlmock.NewRows([]string{"host"}).AddRow("%").AddRow("localhost"))
+	_, err = UpdateUserHost(db, "jantje", "10.0.0.%")
+	if err == nil {
+		t.Errorf("expected an error when the account exists for several hosts")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,19 @@
+package mysql
+
+import (
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+)
+
+// Validation without a connection, the sql mode only affects quoting so it doesn't matter here
+
+func ValidateDbPriv(dbPriv dboperatorv1alpha1.DbPriv) error {
+	_, err := privilegesUnpack([]dboperatorv1alpha1.DbPriv{dbPriv}, "")
+	return err
+}
+
+func ValidateServerPrivs(serverPrivs string) error {
+	if serverPrivs == "" {
+		return nil
+	}
+	return ValidateDbPriv(dboperatorv1alpha1.DbPriv{Scope: "*.*", Privs: serverPrivs})
+}
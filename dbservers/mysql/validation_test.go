@@ -0,0 +1,31 @@
+package mysql
+
+import (
+	"testing"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+)
+
+func TestValidateDbPriv(t *testing.T) {
+	err := ValidateDbPriv(dboperatorv1alpha1.DbPriv{Scope: "mydb.*", Privs: "SELECT,INSERT"})
+	if err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+	err = ValidateDbPriv(dboperatorv1alpha1.DbPriv{Scope: "mydb.*", Privs: "SELCT"})
+	if err == nil {
+		t.Errorf("expected error for invalid privilege")
+	}
+	err = ValidateDbPriv(dboperatorv1alpha1.DbPriv{Scope: "mydb.mytable", Privs: "SELECT", PrivType: "database"})
+	if err == nil {
+		t.Errorf("expected error for scope not matching priv_type")
+	}
+}
+
+func TestValidateServerPrivs(t *testing.T) {
+	if err := ValidateServerPrivs("PROCESS,REPLICATION CLIENT"); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+	if ValidateServerPrivs("PROCES") == nil {
+		t.Errorf("expected error for invalid server privilege")
+	}
+}
@@ -0,0 +1,196 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/shared"
+	funk "github.com/thoas/go-funk"
+)
+
+// Column privileges are kept as one item per privilege/column pair, e.g. SELECT(email)
+// That way the generic set diff of the PrivsReconciler also works for columns
+func NewColumnPrivsReconciler(privs dboperatorv1alpha1.DbPriv, conn *sql.DB, userName string, tableName string, normalizedPrivSet []string, serverVersion *PostgresVersion) (*PrivsReconciler, error) {
+	return &PrivsReconciler{
+		DbPriv:         privs,
+		DesiredPrivSet: normalizedPrivSet,
+		UserName:       userName,
+		scopedName:     tableName,
+		conn:           conn,
+		grantFun:       grantColumnPrivileges,
+		revokeFun:      revokeColumnPrivileges,
+		privsGetFun:    getColumnPrivileges,
+	}, nil
+}
+
+func toColumnPriv(priv string, column string) string {
+	return fmt.Sprintf("%s(%s)", priv, column)
+}
+
+func splitColumnPriv(columnPriv string) (string, string, error) {
+	start := strings.Index(columnPriv, "(")
+	if start == -1 || !strings.HasSuffix(columnPriv, ")") {
+		return "", "", fmt.Errorf("invalid column privilege %q, expected format PRIV(column)", columnPriv)
+	}
+	return columnPriv[:start], columnPriv[start+1 : len(columnPriv)-1], nil
+}
+
+// Parses a privs string like "SELECT(col1, col2),UPDATE(col2)" into
+// ["SELECT(col1)", "SELECT(col2)", "UPDATE(col2)"]
+// Privileges are uppercased, column names are kept as is because they're case sensitive
+func parseColumnPrivs(privs string) ([]string, error) {
+	columnPrivs := []string{}
+	inParens := false
+	current := ""
+	pieces := []string{}
+	for _, char := range privs {
+		if char == '(' {
+			if inParens {
+				return nil, fmt.Errorf("unexpected '(' in column privileges %q", privs)
+			}
+			inParens = true
+		} else if char == ')' {
+			if !inParens {
+				return nil, fmt.Errorf("unexpected ')' in column privileges %q", privs)
+			}
+			inParens = false
+		}
+
+		if char == ',' && !inParens {
+			pieces = append(pieces, current)
+			current = ""
+		} else {
+			current += string(char)
+		}
+	}
+	if inParens {
+		return nil, fmt.Errorf("missing ')' in column privileges %q", privs)
+	}
+	pieces = append(pieces, current)
+
+	for _, piece := range funk.FilterString(funk.Map(pieces, strings.TrimSpace).([]string), StringNotEmpty) {
+		priv, columnsStr, err := splitColumnPriv(piece)
+		if err != nil {
+			return nil, err
+		}
+		priv = strings.ToUpper(strings.TrimSpace(priv))
+		for _, column := range strings.Split(columnsStr, ",") {
+			column = stripOuterChars(strings.TrimSpace(column), "\"")
+			if column == "" {
+				return nil, fmt.Errorf("empty column name in column privileges %q", privs)
+			}
+			columnPrivs = append(columnPrivs, toColumnPriv(priv, column))
+		}
+	}
+	return columnPrivs, nil
+}
+
+func NormalizeColumnPrivileges(columnPrivs []string, serverVersion *PostgresVersion) ([]string, error) {
+	validPrivs := serverVersion.GetValidPrivs("column")
+	normalized := []string{}
+	for _, columnPriv := range columnPrivs {
+		priv, column, err := splitColumnPriv(columnPriv)
+		if err != nil {
+			return nil, err
+		}
+		if !funk.ContainsString(validPrivs, priv) {
+			return nil, fmt.Errorf("invalid privs specified for column: %s", priv)
+		}
+		for _, normalizedPriv := range NormalizePrivileges([]string{priv}, "column", serverVersion) {
+			normalized = append(normalized, toColumnPriv(normalizedPriv, column))
+		}
+	}
+	return funk.UniqString(normalized), nil
+}
+
+func splitTableName(table string) (string, string) {
+	if strings.Contains(table, ".") {
+		parts := strings.SplitN(table, ".", 2)
+		return parts[0], parts[1]
+	}
+	return "public", table
+}
+
+func getColumnPrivileges(conn *sql.DB, user string, table string) ([]string, error) {
+	schema, table := splitTableName(table)
+
+	// information_schema.column_privileges also lists the columns of a table wide grant, which would hide
+	// a column grant of the same privilege. The ACLs of the columns only have the grants on the columns themselves
+	query := `SELECT acl.privilege_type, att.attname
+	FROM pg_attribute att
+	JOIN pg_class c ON c.oid = att.attrelid
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+	CROSS JOIN LATERAL aclexplode(att.attacl) acl
+	WHERE acl.grantee = (SELECT oid FROM pg_roles WHERE rolname = $1)
+	AND n.nspname = $2
+	AND c.relname = $3
+	AND att.attnum > 0
+	AND NOT att.attisdropped;`
+	rows, err := conn.Query(query, user, schema, table)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read columnPrivs %s", err)
+	}
+	defer rows.Close()
+
+	columnPrivs := []string{}
+	for rows.Next() {
+		var privType, column string
+		err := rows.Scan(&privType, &column)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load column privType")
+		}
+		columnPrivs = append(columnPrivs, toColumnPriv(privType, column))
+	}
+	return columnPrivs, nil
+}
+
+// Builds "SELECT ("col1", "col2"), UPDATE ("col2")" from ["SELECT(col1)", "SELECT(col2)", "UPDATE(col2)"]
+func columnPrivsToSql(columnPrivs []string) (string, error) {
+	columnsPerPriv := map[string][]string{}
+	for _, columnPriv := range columnPrivs {
+		priv, column, err := splitColumnPriv(columnPriv)
+		if err != nil {
+			return "", err
+		}
+		columnsPerPriv[priv] = append(columnsPerPriv[priv], pq.QuoteIdentifier(column))
+	}
+
+	privs := funk.Keys(columnsPerPriv).([]string)
+	sort.Strings(privs)
+	sqlParts := []string{}
+	for _, priv := range privs {
+		columns := columnsPerPriv[priv]
+		sort.Strings(columns)
+		sqlParts = append(sqlParts, fmt.Sprintf("%s (%s)", priv, strings.Join(columns, ", ")))
+	}
+	return strings.Join(sqlParts, ", "), nil
+}
+
+func quoteTableName(table string) string {
+	schema, table := splitTableName(table)
+	return fmt.Sprintf("%s.%s", pq.QuoteIdentifier(schema), pq.QuoteIdentifier(table))
+}
+
+func grantColumnPrivileges(conn shared.Executor, user string, table string, columnPrivs []string) error {
+	privSet, err := columnPrivsToSql(columnPrivs)
+	if err != nil {
+		return err
+	}
+	quotedUserName := pq.QuoteIdentifier(user)
+	_, err = conn.Exec(fmt.Sprintf("GRANT %s ON TABLE %s TO %s", privSet, quoteTableName(table), quotedUserName)) // nosemgrep, sql query is constructed from sanitized strings
+	return err
+}
+
+func revokeColumnPrivileges(conn shared.Executor, user string, table string, columnPrivs []string) error {
+	privSet, err := columnPrivsToSql(columnPrivs)
+	if err != nil {
+		return err
+	}
+	quotedUserName := pq.QuoteIdentifier(user)
+	_, err = conn.Exec(fmt.Sprintf("REVOKE %s ON TABLE %s FROM %s", privSet, quoteTableName(table), quotedUserName)) // nosemgrep, sql query is constructed from sanitized strings
+	return err
+}
@@ -1,6 +1,7 @@
 package postgres
 
 import (
+	"database/sql"
 	"fmt"
 	"net/url"
 	"strings"
@@ -12,15 +13,15 @@ import (
 	"github.com/obeleh/db-operator/shared"
 )
 
+// Runs on the database that is handed over, with the quoted name of the new owner
 const PG_GRANT_SCRIPT string = `
-GRANT USAGE ON SCHEMA public TO "%s";
-GRANT ALL ON ALL TABLES IN SCHEMA public TO "%s";
-GRANT ALL ON ALL SEQUENCES IN SCHEMA public TO "%s";
-ALTER DEFAULT PRIVILEGES FOR ROLE "%s" IN SCHEMA public
-GRANT ALL ON TABLES TO "%s";
-ALTER DEFAULT PRIVILEGES FOR ROLE "%s" IN SCHEMA public
-GRANT ALL ON SEQUENCES TO "%s";
-ALTER DATABASE "%s" OWNER TO "%s";
+GRANT USAGE ON SCHEMA public TO %[1]s;
+GRANT ALL ON ALL TABLES IN SCHEMA public TO %[1]s;
+GRANT ALL ON ALL SEQUENCES IN SCHEMA public TO %[1]s;
+ALTER DEFAULT PRIVILEGES FOR ROLE %[1]s IN SCHEMA public
+GRANT ALL ON TABLES TO %[1]s;
+ALTER DEFAULT PRIVILEGES FOR ROLE %[1]s IN SCHEMA public
+GRANT ALL ON SEQUENCES TO %[1]s;
 `
 
 type PostgresConnection struct {
@@ -39,13 +40,13 @@ func NewPostgresConnection(connectionInfo *shared.DbServerConnectInfo, userCrede
 	}
 }
 
-func (p *PostgresConnection) CreateUser(userName string, password string) error {
+func (p *PostgresConnection) CreateUser(userSpec dboperatorv1alpha1.UserSpec, password string) error {
 	conn, err := p.GetDbConnection(nil, nil)
 	if err != nil {
 		return err
 	}
 
-	quotedUserName := pq.QuoteIdentifier(userName)
+	quotedUserName := pq.QuoteIdentifier(userSpec.UserName)
 	quotedPassword := pq.QuoteLiteral(password)
 	_, err = conn.Exec(fmt.Sprintf(`CREATE USER %s LOGIN PASSWORD %s;`, quotedUserName, quotedPassword))
 	return err
@@ -107,16 +108,84 @@ func (p *PostgresConnection) MakeUserDbOwner(userName string, dbName string) err
 	if err != nil {
 		return err
 	}
-	_, err = conn.Exec(fmt.Sprintf(PG_GRANT_SCRIPT, userName, userName, userName, userName, userName, userName, userName, dbName, userName))
+	return MakeUserDbOwner(conn, userName, dbName, p.GetDbConnection)
+}
+
+func (p *PostgresConnection) UpdateDbOwner(dbName string, owner dboperatorv1alpha1.UserSpec) (bool, error) {
+	conn, err := p.GetDbConnection(nil, nil)
+	if err != nil {
+		return false, err
+	}
+	return UpdateDbOwner(conn, dbName, owner.UserName, p.GetDbConnection)
+}
+
+func MakeUserDbOwner(conn *sql.DB, userName string, dbName string, connectionGetter ConnectionGetter) error {
+	quotedUserName := pq.QuoteIdentifier(userName)
+	_, err := conn.Exec(fmt.Sprintf("ALTER DATABASE %s OWNER TO %s;", pq.QuoteIdentifier(dbName), quotedUserName))
+	if err != nil {
+		return err
+	}
+	dbConn, err := connectionGetter(nil, &dbName)
+	if err != nil {
+		return err
+	}
+	_, err = dbConn.Exec(fmt.Sprintf(PG_GRANT_SCRIPT, quotedUserName))
 	return err
 }
 
-func (p *PostgresConnection) UpdateUserPrivs(userName string, serverPrivs string, dbPrivs []dboperatorv1alpha1.DbPriv) (bool, error) {
+// Hands the database over to the user when it's owned by someone else, returns whether the owner changed
+func UpdateDbOwner(conn *sql.DB, dbName string, userName string, connectionGetter ConnectionGetter) (bool, error) {
+	currentOwner, err := query_utils.SelectFirstValueString(conn, "SELECT pg_catalog.pg_get_userbyid(d.datdba) FROM pg_catalog.pg_database d WHERE d.datname = $1", dbName)
+	if err != nil {
+		return false, err
+	}
+	if currentOwner == userName {
+		return false, nil
+	}
+	return true, MakeUserDbOwner(conn, userName, dbName, connectionGetter)
+}
+
+func (p *PostgresConnection) UpdateUserPrivs(userSpec dboperatorv1alpha1.UserSpec) (bool, error) {
 	conn, err := p.GetDbConnection(nil, nil)
 	if err != nil {
 		return false, err
 	}
-	return UpdateUserPrivs(conn, userName, serverPrivs, dbPrivs, p.GetDbConnection)
+	return UpdateUserPrivs(conn, userSpec.UserName, userSpec.ServerPrivs, userSpec.DbPrivs, p.GetDbConnection)
+}
+
+func (p *PostgresConnection) PlanUserPrivs(userSpec dboperatorv1alpha1.UserSpec) ([]dboperatorv1alpha1.PlannedStatement, error) {
+	conn, err := p.GetDbConnection(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return PlanUserPrivs(conn, userSpec.UserName, userSpec.ServerPrivs, userSpec.DbPrivs, p.GetDbConnection)
+}
+
+func (p *PostgresConnection) GetUserPrivsDrift(userSpec dboperatorv1alpha1.UserSpec) ([]shared.PrivsDrift, error) {
+	conn, err := p.GetDbConnection(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return GetUserPrivsDrift(conn, userSpec.UserName, userSpec.ServerPrivs, userSpec.DbPrivs, p.GetDbConnection)
+}
+
+func (p *PostgresConnection) RevokeExtraUserPrivs(userSpec dboperatorv1alpha1.UserSpec) (bool, error) {
+	conn, err := p.GetDbConnection(nil, nil)
+	if err != nil {
+		return false, err
+	}
+	return RevokeExtraUserPrivs(conn, userSpec.UserName, userSpec.DbPrivs, p.GetDbConnection)
+}
+
+func (p *PostgresConnection) UpdateUserOptions(userSpec dboperatorv1alpha1.UserSpec) (bool, error) {
+	if userSpec.Host != "" || userSpec.TlsRequires != nil || userSpec.MaxQueriesPerHour != nil || userSpec.MaxUserConnections != nil {
+		return false, fmt.Errorf("host, tls_requires, max_queries_per_hour and max_user_connections are only supported for MySQL")
+	}
+	conn, err := p.GetDbConnection(nil, nil)
+	if err != nil {
+		return false, err
+	}
+	return UpdateRoleOptions(conn, userSpec.UserName, userSpec.ConnectionLimit, userSpec.ValidUntil, userSpec.RoleSettings)
 }
 
 func (p *PostgresConnection) GetUsers() (map[string]shared.DbSideUser, error) {
@@ -166,9 +235,207 @@ func (p *PostgresConnection) Execute(qry string, user *string) error {
 	return err
 }
 
-func (p *PostgresConnection) CreateDb(dbName string) error {
-	quotedDbName := pq.QuoteIdentifier(dbName)
-	return p.Execute(fmt.Sprintf("CREATE DATABASE %s;", quotedDbName), nil)
+func (p *PostgresConnection) CreateDb(dbSpec dboperatorv1alpha1.DbSpec) error {
+	query, err := BuildCreateDb(dbSpec, p.Flavor)
+	if err != nil {
+		return err
+	}
+	return p.Execute(query, nil)
+}
+
+func (p *PostgresConnection) UpdateDbOptions(dbSpec dboperatorv1alpha1.DbSpec) (bool, []string, error) {
+	err := ValidateDbOptions(dbSpec, p.Flavor)
+	if err != nil {
+		return false, nil, err
+	}
+	conn, err := p.GetDbConnection(nil, nil)
+	if err != nil {
+		return false, nil, err
+	}
+	if p.Flavor == "cockroachdb" {
+		return UpdateCockroachDbOptions(conn, dbSpec)
+	}
+	return UpdateDbOptions(conn, dbSpec)
+}
+
+func (p *PostgresConnection) GetAppliedMigrations(historyTable string, userName *string) (map[string]string, error) {
+	conn, err := p.GetDbConnection(userName, nil)
+	if err != nil {
+		return nil, err
+	}
+	return GetAppliedMigrations(conn, historyTable)
+}
+
+func (p *PostgresConnection) ApplyMigration(historyTable string, migration shared.Migration, userName *string) error {
+	conn, err := p.GetDbConnection(userName, nil)
+	if err != nil {
+		return err
+	}
+	return ApplyMigration(conn, historyTable, migration, p.Flavor)
+}
+
+func (p *PostgresConnection) getExtensionConnection() (*sql.DB, error) {
+	if p.Flavor == "cockroachdb" {
+		return nil, fmt.Errorf("extensions are only supported for Postgres")
+	}
+	return p.GetDbConnection(nil, nil)
+}
+
+func (p *PostgresConnection) GetExtensions() (map[string]shared.DbSideExtension, error) {
+	conn, err := p.getExtensionConnection()
+	if err != nil {
+		return nil, err
+	}
+	return GetExtensions(conn)
+}
+
+func (p *PostgresConnection) CreateExtension(extensionSpec dboperatorv1alpha1.ExtensionSpec) error {
+	conn, err := p.getExtensionConnection()
+	if err != nil {
+		return err
+	}
+	_, err = conn.Exec(BuildCreateExtension(extensionSpec))
+	return err
+}
+
+func (p *PostgresConnection) UpdateExtension(extensionSpec dboperatorv1alpha1.ExtensionSpec) (bool, error) {
+	conn, err := p.getExtensionConnection()
+	if err != nil {
+		return false, err
+	}
+	return UpdateExtension(conn, extensionSpec)
+}
+
+func (p *PostgresConnection) DropExtension(name string, cascade bool) error {
+	conn, err := p.getExtensionConnection()
+	if err != nil {
+		return err
+	}
+	return DropExtension(conn, name, cascade)
+}
+
+func (p *PostgresConnection) getReplicationConnection() (*sql.DB, error) {
+	if p.Flavor == "cockroachdb" {
+		return nil, fmt.Errorf("logical replication is only supported for Postgres")
+	}
+	return p.GetDbConnection(nil, nil)
+}
+
+func (p *PostgresConnection) GetPublication(name string) (*shared.DbSidePublication, error) {
+	conn, err := p.getReplicationConnection()
+	if err != nil {
+		return nil, err
+	}
+	return GetPublication(conn, name)
+}
+
+func (p *PostgresConnection) CreatePublication(publicationSpec dboperatorv1alpha1.PublicationSpec) error {
+	conn, err := p.getReplicationConnection()
+	if err != nil {
+		return err
+	}
+	query, err := BuildCreatePublication(publicationSpec)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Exec(query)
+	return err
+}
+
+func (p *PostgresConnection) UpdatePublication(publicationSpec dboperatorv1alpha1.PublicationSpec) (bool, error) {
+	conn, err := p.getReplicationConnection()
+	if err != nil {
+		return false, err
+	}
+	return UpdatePublication(conn, publicationSpec)
+}
+
+func (p *PostgresConnection) DropPublication(name string) error {
+	conn, err := p.getReplicationConnection()
+	if err != nil {
+		return err
+	}
+	return DropPublication(conn, name)
+}
+
+func (p *PostgresConnection) GetSubscription(name string) (*shared.DbSideSubscription, error) {
+	conn, err := p.getReplicationConnection()
+	if err != nil {
+		return nil, err
+	}
+	return GetSubscription(conn, name)
+}
+
+func (p *PostgresConnection) CreateSubscription(subscriptionSpec dboperatorv1alpha1.SubscriptionSpec, connInfo string) error {
+	conn, err := p.getReplicationConnection()
+	if err != nil {
+		return err
+	}
+	_, err = conn.Exec(BuildCreateSubscription(subscriptionSpec, connInfo))
+	return err
+}
+
+func (p *PostgresConnection) UpdateSubscription(subscriptionSpec dboperatorv1alpha1.SubscriptionSpec, connInfo string, publishedTables []string) (bool, error) {
+	conn, err := p.getReplicationConnection()
+	if err != nil {
+		return false, err
+	}
+	return UpdateSubscription(conn, subscriptionSpec, connInfo, publishedTables)
+}
+
+func (p *PostgresConnection) DropSubscription(name string) error {
+	conn, err := p.getReplicationConnection()
+	if err != nil {
+		return err
+	}
+	return DropSubscription(conn, name)
+}
+
+func (p *PostgresConnection) getForeignServerConnection(userName *string) (*sql.DB, error) {
+	if p.Flavor == "cockroachdb" {
+		return nil, fmt.Errorf("foreign servers are only supported for Postgres")
+	}
+	return p.GetDbConnection(userName, nil)
+}
+
+func (p *PostgresConnection) GetForeignServer(name string) (*shared.DbSideForeignServer, error) {
+	conn, err := p.getForeignServerConnection(nil)
+	if err != nil {
+		return nil, err
+	}
+	return GetForeignServer(conn, name)
+}
+
+func (p *PostgresConnection) CreateForeignServer(name string, options map[string]string) error {
+	conn, err := p.getForeignServerConnection(nil)
+	if err != nil {
+		return err
+	}
+	return CreateForeignServer(conn, name, options)
+}
+
+func (p *PostgresConnection) UpdateForeignServer(name string, options map[string]string, userMappings map[string]shared.Credentials, createdMappings map[string]string) (bool, map[string]string, error) {
+	conn, err := p.getForeignServerConnection(nil)
+	if err != nil {
+		return false, nil, err
+	}
+	return UpdateForeignServer(conn, name, options, userMappings, createdMappings)
+}
+
+func (p *PostgresConnection) ImportForeignSchema(name string, importSpec dboperatorv1alpha1.ForeignSchemaImport, userName *string) ([]string, error) {
+	conn, err := p.getForeignServerConnection(userName)
+	if err != nil {
+		return nil, err
+	}
+	return ImportForeignSchema(conn, name, importSpec)
+}
+
+func (p *PostgresConnection) DropForeignServer(name string, userMappings []string, cascade bool) error {
+	conn, err := p.getForeignServerConnection(nil)
+	if err != nil {
+		return err
+	}
+	return DropForeignServer(conn, name, userMappings, cascade)
 }
 
 func (p *PostgresConnection) CreateSchema(schemaName string, creator *string) error {
@@ -188,6 +455,10 @@ func (p *PostgresConnection) DropDb(dbName string, cascade bool) error {
 		}
 		cascadeStr = "CASCADE"
 	}
+	err = p.CloseDatabaseConnections(dbName)
+	if err != nil {
+		return err
+	}
 	quotedDbName := pq.QuoteIdentifier(dbName)
 	_, err = conn.Exec(fmt.Sprintf("DROP DATABASE %s %s;", quotedDbName, cascadeStr))
 	return err
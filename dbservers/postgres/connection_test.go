@@ -0,0 +1,49 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestUpdateDbOwner(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	connGetter := func(userName, databaseName *string) (*sql.DB, error) {
+		if databaseName == nil || *databaseName != "app" {
+			t.Errorf("expected the grants to run on database app")
+		}
+		return db, nil
+	}
+
+	mock.ExpectQuery("SELECT pg_catalog.pg_get_userbyid(d.datdba) FROM pg_catalog.pg_database d WHERE d.datname = $1").WithArgs("app").WillReturnRows(sqlmock.NewRows([]string{"pg_get_userbyid"}).AddRow("postgres"))
+	mock.ExpectExec(`ALTER DATABASE "app" OWNER TO "app-owner";`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(fmt.Sprintf(PG_GRANT_SCRIPT, `"app-owner"`)).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	changed, err := UpdateDbOwner(db, "app", "app-owner", connGetter)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if !changed {
+		t.Errorf("expected the owner to change")
+	}
+
+	mock.ExpectQuery("SELECT pg_catalog.pg_get_userbyid(d.datdba) FROM pg_catalog.pg_database d WHERE d.datname = $1").WithArgs("app").WillReturnRows(sqlmock.NewRows([]string{"pg_get_userbyid"}).AddRow("app-owner"))
+	changed, err = UpdateDbOwner(db, "app", "app-owner", connGetter)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if changed {
+		t.Errorf("expected no changes")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
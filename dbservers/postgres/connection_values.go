@@ -0,0 +1,54 @@
+package postgres
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+
+	"github.com/obeleh/db-operator/shared"
+)
+
+// Values for the connection secret of a user, the database defaults to postgres like it does for the operator
+func BuildConnectionValues(connectInfo *shared.DbServerConnectInfo) shared.ConnectionValues {
+	database := connectInfo.Database
+	if database == "" {
+		database = "postgres"
+	}
+	sslMode, found := connectInfo.Options["sslmode"]
+	if !found {
+		sslMode = shared.SSL_MODE_REQUIRE
+	}
+	password := ""
+	if connectInfo.Password != nil {
+		password = *connectInfo.Password
+	}
+	hostPort := net.JoinHostPort(connectInfo.Host, strconv.Itoa(connectInfo.Port))
+	query := url.Values{"sslmode": []string{sslMode}}.Encode()
+
+	uri := url.URL{
+		Scheme:   "postgresql",
+		Host:     hostPort,
+		Path:     "/" + database,
+		RawQuery: query,
+	}
+	if connectInfo.Password != nil {
+		uri.User = url.UserPassword(connectInfo.UserName, password)
+	} else {
+		uri.User = url.User(connectInfo.UserName)
+	}
+
+	libpqInfo := *connectInfo
+	libpqInfo.Database = database
+	return shared.ConnectionValues{
+		Host:     connectInfo.Host,
+		Port:     connectInfo.Port,
+		Database: database,
+		UserName: connectInfo.UserName,
+		Password: password,
+		SslMode:  sslMode,
+		Uri:      uri.String(),
+		JdbcUrl:  fmt.Sprintf("jdbc:postgresql://%s/%s?%s", hostPort, url.PathEscape(database), query),
+		Libpq:    BuildConnInfo(&libpqInfo),
+	}
+}
@@ -1,15 +1,21 @@
 package postgres
 
 import (
-	"crypto/sha256"
+	"crypto/tls"
 	"database/sql"
+	"encoding/binary"
 	"fmt"
-	"os"
-	"path/filepath"
+	"io"
+	"net"
+	"time"
 
+	"github.com/lib/pq"
 	"github.com/obeleh/db-operator/shared"
 )
 
+// Deadline for the SSLRequest and the TLS handshake when the driver doesn't set a connect timeout
+const TLS_HANDSHAKE_TIMEOUT = 30 * time.Second
+
 type PostgresConnector struct {
 }
 
@@ -25,100 +31,130 @@ func (c *PostgresConnector) Connect(connectInfo *shared.DbServerConnectInfo, cre
 	// https://www.postgresql.org/docs/current/libpq-ssl.html#LIBPQ-SSL-PROTECTION
 	sslMode, found := connectInfo.Options["sslmode"]
 	if !found {
-		sslMode = "require"
+		sslMode = shared.SSL_MODE_REQUIRE
 	}
 
-	var connStr string
-	var err error
+	var userName string
+	var password, caCert, tlsCrt, tlsKey *string
 	if credentials == nil {
-		connStr, err = getConnectionString(
-			connectInfo.Host,
-			connectInfo.UserName,
-			dbName,
-			sslMode,
-			connectInfo.Port,
-			connectInfo.Password,
-			connectInfo.CaCert,
-			connectInfo.TlsCrt,
-			connectInfo.TlsKey,
-		)
+		userName = connectInfo.UserName
+		password = connectInfo.Password
+		caCert = connectInfo.CaCert
+		tlsCrt = connectInfo.TlsCrt
+		tlsKey = connectInfo.TlsKey
 	} else {
-		var caCert *string
+		userName = credentials.UserName
+		password = credentials.Password
 		if credentials.CaCert != nil {
 			caCert = credentials.CaCert
 		} else if connectInfo.CaCert != nil {
 			caCert = connectInfo.CaCert
 		}
-		connStr, err = getConnectionString(
-			connectInfo.Host,
-			credentials.UserName,
-			dbName,
-			sslMode,
-			connectInfo.Port,
-			credentials.Password,
-			caCert,
-			credentials.TlsCrt,
-			credentials.TlsKey,
-		)
+		tlsCrt = credentials.TlsCrt
+		tlsKey = credentials.TlsKey
+	}
+
+	tlsConfig, err := getTlsConfig(connectInfo.Host, sslMode, caCert, tlsCrt, tlsKey)
+	if err != nil {
+		return nil, err
 	}
 
+	// TLS is set up by the dialer, so the driver itself doesn't need to
+	connStr := getConnectionString(connectInfo.Host, userName, dbName, shared.SSL_MODE_DISABLE, connectInfo.Port, password)
+	connector, err := pq.NewConnector(connStr)
 	if err != nil {
 		return nil, err
 	}
+	if tlsConfig != nil {
+		connector.Dialer(&tlsDialer{tlsConfig: tlsConfig})
+	}
+	return sql.OpenDB(connector), nil
+}
 
-	return sql.Open("postgres", connStr)
+func getTlsConfig(host string, sslMode string, caCert, tlsCrt, tlsKey *string) (*tls.Config, error) {
+	if sslMode == shared.SSL_MODE_DISABLE {
+		return nil, nil
+	}
+	// For backwards compatibility libpq treats require as verify-ca when there's a root certificate
+	if sslMode == shared.SSL_MODE_REQUIRE && caCert != nil {
+		sslMode = shared.SSL_MODE_VERIFY_CA
+	}
+	return shared.BuildTlsConfig(sslMode, host, caCert, tlsCrt, tlsKey)
 }
 
-func getConnectionString(host, userName, dbName, sslMode string, port int, password, caCert, tlsCrt, tlsKey *string) (string, error) {
+func getConnectionString(host, userName, dbName, sslMode string, port int, password *string) string {
 	connStr := fmt.Sprintf("host=%s port=%d user=%s dbname=%s sslmode=%s",
 		host, port, userName, dbName, sslMode)
 
 	if password != nil {
 		connStr += fmt.Sprintf(" password=%s", *password)
 	}
+	return connStr
+}
 
-	// For some reason it's not possible yet to load Tls Certs from memory so we write to file
-	// Open PR: https://github.com/lib/pq/pull/1066/files
-	if caCert != nil || tlsCrt != nil || tlsKey != nil {
-		tempCertsDir := filepath.Join(".", "tempCertsDir")
-		_ = os.MkdirAll(tempCertsDir, os.ModePerm)
-
-		if caCert != nil {
-			filePath, err := writeToTempFile(*caCert)
-			if err != nil {
-				return "", err
-			}
-			connStr += fmt.Sprintf(" sslrootcert=%s", filePath)
-		}
+// lib/pq can only load certificates from files, so instead of letting the driver negotiate TLS
+// the dialer does it with a tls.Config that is kept in memory
+// https://www.postgresql.org/docs/current/protocol-flow.html#PROTOCOL-FLOW-SSL
+type tlsDialer struct {
+	tlsConfig *tls.Config
+}
 
-		if tlsKey != nil {
-			filePath, err := writeToTempFile(*tlsKey)
-			if err != nil {
-				return "", err
-			}
-			connStr += fmt.Sprintf(" sslkey=%s", filePath)
-		}
+func (d *tlsDialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialTimeout(network, address, 0)
+}
 
-		if tlsCrt != nil {
-			filePath, err := writeToTempFile(*tlsCrt)
-			if err != nil {
-				return "", err
-			}
-			connStr += fmt.Sprintf(" sslcert=%s", filePath)
-		}
+func (d *tlsDialer) DialTimeout(network, address string, timeout time.Duration) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: timeout, KeepAlive: 5 * time.Minute}
+	conn, err := dialer.Dial(network, address)
+	if err != nil {
+		return nil, err
 	}
-	return connStr, nil
+	if timeout <= 0 {
+		timeout = TLS_HANDSHAKE_TIMEOUT
+	}
+	tlsConn, err := startTls(conn, d.tlsConfig, timeout)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
 }
 
-func writeToTempFile(contents string) (string, error) {
-	byteContent := []byte(contents)
-	sha256Sum := sha256.Sum256(byteContent)
-	fileName := fmt.Sprintf("%x", sha256Sum)
+// A server that accepts the connection but never answers would block the reconcile, so the
+// SSLRequest and the handshake have to finish within timeout
+func startTls(conn net.Conn, tlsConfig *tls.Config, timeout time.Duration) (net.Conn, error) {
+	err := conn.SetDeadline(time.Now().Add(timeout))
+	if err != nil {
+		return nil, err
+	}
+
+	// SSLRequest message: length 8 and the SSL request code
+	sslRequest := make([]byte, 8)
+	binary.BigEndian.PutUint32(sslRequest[0:4], 8)
+	binary.BigEndian.PutUint32(sslRequest[4:8], 80877103)
+	_, err = conn.Write(sslRequest)
+	if err != nil {
+		return nil, err
+	}
 
-	filePath := filepath.Join(".", "tempCertsDir", fileName)
-	_, err := os.Stat(filePath)
-	if err == nil {
-		return filePath, nil // file existed
+	response := make([]byte, 1)
+	_, err = io.ReadFull(conn, response)
+	if err != nil {
+		return nil, err
+	}
+	if response[0] != 'S' {
+		return nil, fmt.Errorf("SSL is not enabled on the server")
+	}
+
+	tlsConn := tls.Client(conn, tlsConfig)
+	err = tlsConn.Handshake()
+	if err != nil {
+		return nil, err
+	}
+	// The driver sets its own deadlines for queries
+	err = conn.SetDeadline(time.Time{})
+	if err != nil {
+		return nil, err
 	}
-	return filePath, os.WriteFile(filePath, byteContent, 0600)
+	return tlsConn, nil
 }
@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestGetTlsConfig(t *testing.T) {
+	tlsConfig, err := getTlsConfig("localhost", "disable", nil, nil, nil)
+	if err != nil || tlsConfig != nil {
+		t.Errorf("expected no tls config for sslmode disable")
+	}
+
+	tlsConfig, err = getTlsConfig("localhost", "require", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if !tlsConfig.InsecureSkipVerify {
+		t.Errorf("expected sslmode require to skip verification without a CA certificate")
+	}
+
+	_, err = getTlsConfig("localhost", "verify-ca", nil, nil, nil)
+	if err == nil {
+		t.Errorf("expected error for verify-ca without CA certificate")
+	}
+
+	_, err = getTlsConfig("localhost", "sometimes", nil, nil, nil)
+	if err == nil {
+		t.Errorf("expected error for invalid sslmode")
+	}
+}
+
+func TestStartTlsNotSupported(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		defer server.Close()
+		request := make([]byte, 8)
+		_, err := io.ReadFull(server, request)
+		if err == nil && binary.BigEndian.Uint32(request[4:8]) == 80877103 {
+			server.Write([]byte{'N'})
+		}
+	}()
+
+	_, err := startTls(client, &tls.Config{}, time.Second)
+	if err == nil {
+		t.Errorf("expected error when the server doesn't support SSL")
+	}
+}
+
+func TestStartTlsTimeout(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	go func() {
+		// Accepts the SSLRequest and answers S, but never takes part in the handshake
+		request := make([]byte, 8)
+		_, err := io.ReadFull(server, request)
+		if err == nil {
+			server.Write([]byte{'S'})
+		}
+	}()
+
+	start := time.Now()
+	_, err := startTls(client, &tls.Config{InsecureSkipVerify: true}, 100*time.Millisecond)
+	if err == nil {
+		t.Errorf("expected error when the server doesn't answer the handshake")
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("expected the handshake to time out, it took %s", time.Since(start))
+	}
+}
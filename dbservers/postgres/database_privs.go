@@ -9,6 +9,7 @@ import (
 	"github.com/lib/pq"
 	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
 	"github.com/obeleh/db-operator/dbservers/query_utils"
+	"github.com/obeleh/db-operator/shared"
 )
 
 func NewDatabasePrivsReconciler(privs dboperatorv1alpha1.DbPriv, conn *sql.DB, userName string, dbName string, normalizedPrivSet []string, serverVersion *PostgresVersion) (*PrivsReconciler, error) {
@@ -31,7 +32,7 @@ func NewDatabasePrivsReconciler(privs dboperatorv1alpha1.DbPriv, conn *sql.DB, u
 	}, nil
 }
 
-func grantDatabasePrivileges(conn *sql.DB, user string, db string, privs []string) error {
+func grantDatabasePrivileges(conn shared.Executor, user string, db string, privs []string) error {
 	privsStr := strings.Join(privs, ", ")
 	escapedDb := pq.QuoteIdentifier(db)
 	escapedUser := pq.QuoteIdentifier(user)
@@ -47,7 +48,7 @@ func grantDatabasePrivileges(conn *sql.DB, user string, db string, privs []strin
 	}
 }
 
-func revokeDatabasePrivileges(conn *sql.DB, user string, db string, privs []string) error {
+func revokeDatabasePrivileges(conn shared.Executor, user string, db string, privs []string) error {
 	privsStr := strings.Join(privs, ", ")
 	escapedDb := pq.QuoteIdentifier(db)
 	escapedUser := pq.QuoteIdentifier(user)
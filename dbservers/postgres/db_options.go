@@ -0,0 +1,241 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/dbservers/query_utils"
+	funk "github.com/thoas/go-funk"
+)
+
+var nonAlphaNumeric = regexp.MustCompile("[^a-z0-9]")
+
+// Postgres ignores case and punctuation in encoding names, UTF-8 and utf8 are the same encoding
+func normalizeEncoding(encoding string) string {
+	return nonAlphaNumeric.ReplaceAllString(strings.ToLower(encoding), "")
+}
+
+func ValidateDbOptions(dbSpec dboperatorv1alpha1.DbSpec, flavor string) error {
+	if dbSpec.CharacterSet != "" || dbSpec.Collate != "" {
+		return fmt.Errorf("character_set and collate are only supported for MySQL")
+	}
+	if flavor == "cockroachdb" {
+		if dbSpec.LcCollate != "" || dbSpec.LcCtype != "" || dbSpec.Template != "" || dbSpec.ConnectionLimit != nil || dbSpec.Tablespace != "" {
+			return fmt.Errorf("lc_collate, lc_ctype, template, connection_limit and tablespace are only supported for Postgres")
+		}
+		if dbSpec.PrimaryRegion == "" && (len(dbSpec.Regions) > 0 || dbSpec.Survive != "") {
+			return fmt.Errorf("regions and survive need a primary_region")
+		}
+		return nil
+	}
+	if dbSpec.PrimaryRegion != "" || len(dbSpec.Regions) > 0 || dbSpec.Survive != "" {
+		return fmt.Errorf("primary_region, regions and survive are only supported for CockroachDB")
+	}
+	return nil
+}
+
+func surviveClause(survive string) string {
+	if survive == dboperatorv1alpha1.SURVIVE_REGION {
+		return "SURVIVE REGION FAILURE"
+	}
+	return "SURVIVE ZONE FAILURE"
+}
+
+func quoteIdentifiers(identifiers []string) string {
+	return strings.Join(funk.Map(identifiers, pq.QuoteIdentifier).([]string), ", ")
+}
+
+func BuildCreateDb(dbSpec dboperatorv1alpha1.DbSpec, flavor string) (string, error) {
+	err := ValidateDbOptions(dbSpec, flavor)
+	if err != nil {
+		return "", err
+	}
+	options := []string{}
+	if dbSpec.Template != "" {
+		options = append(options, "TEMPLATE "+pq.QuoteIdentifier(dbSpec.Template))
+	}
+	if dbSpec.Encoding != "" {
+		options = append(options, "ENCODING = "+pq.QuoteLiteral(dbSpec.Encoding))
+	}
+	if dbSpec.LcCollate != "" {
+		options = append(options, "LC_COLLATE "+pq.QuoteLiteral(dbSpec.LcCollate))
+	}
+	if dbSpec.LcCtype != "" {
+		options = append(options, "LC_CTYPE "+pq.QuoteLiteral(dbSpec.LcCtype))
+	}
+	if dbSpec.Tablespace != "" {
+		options = append(options, "TABLESPACE "+pq.QuoteIdentifier(dbSpec.Tablespace))
+	}
+	if dbSpec.ConnectionLimit != nil {
+		options = append(options, fmt.Sprintf("CONNECTION LIMIT %d", *dbSpec.ConnectionLimit))
+	}
+	if dbSpec.PrimaryRegion != "" {
+		options = append(options, "PRIMARY REGION "+pq.QuoteIdentifier(dbSpec.PrimaryRegion))
+	}
+	if len(dbSpec.Regions) > 0 {
+		options = append(options, "REGIONS "+quoteIdentifiers(dbSpec.Regions))
+	}
+	if dbSpec.Survive != "" {
+		options = append(options, surviveClause(dbSpec.Survive))
+	}
+
+	query := "CREATE DATABASE " + pq.QuoteIdentifier(dbSpec.DbName)
+	if len(options) > 0 {
+		query += " " + strings.Join(options, " ")
+	}
+	return query + ";", nil
+}
+
+func getEncodingDrift(conn *sql.DB, dbName string, encoding string) ([]string, error) {
+	if encoding == "" {
+		return nil, nil
+	}
+	curEncoding, err := query_utils.SelectFirstValueString(conn, "SELECT pg_encoding_to_char(encoding) FROM pg_database WHERE datname = $1", dbName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read encoding of db %s %s", dbName, err)
+	}
+	if normalizeEncoding(curEncoding) != normalizeEncoding(encoding) {
+		return []string{fmt.Sprintf("encoding is %s instead of %s", curEncoding, encoding)}, nil
+	}
+	return nil, nil
+}
+
+// Reconciles CONNECTION LIMIT and TABLESPACE of a database
+// Returns the options that can't be changed after creation and differ from the spec
+func UpdateDbOptions(conn *sql.DB, dbSpec dboperatorv1alpha1.DbSpec) (bool, []string, error) {
+	drift, err := getEncodingDrift(conn, dbSpec.DbName, dbSpec.Encoding)
+	if err != nil {
+		return false, nil, err
+	}
+
+	if dbSpec.LcCollate != "" || dbSpec.LcCtype != "" {
+		rows, err := conn.Query("SELECT datcollate, datctype FROM pg_database WHERE datname = $1", dbSpec.DbName)
+		if err != nil {
+			return false, nil, fmt.Errorf("unable to read locale of db %s %s", dbSpec.DbName, err)
+		}
+		defer rows.Close()
+		if !rows.Next() {
+			return false, nil, fmt.Errorf("db %s not found in pg_database", dbSpec.DbName)
+		}
+		var curCollate, curCtype string
+		err = rows.Scan(&curCollate, &curCtype)
+		if err != nil {
+			return false, nil, fmt.Errorf("unable to load locale of db %s %s", dbSpec.DbName, err)
+		}
+		if dbSpec.LcCollate != "" && curCollate != dbSpec.LcCollate {
+			drift = append(drift, fmt.Sprintf("lc_collate is %s instead of %s", curCollate, dbSpec.LcCollate))
+		}
+		if dbSpec.LcCtype != "" && curCtype != dbSpec.LcCtype {
+			drift = append(drift, fmt.Sprintf("lc_ctype is %s instead of %s", curCtype, dbSpec.LcCtype))
+		}
+	}
+
+	quotedDbName := pq.QuoteIdentifier(dbSpec.DbName)
+	queries := []string{}
+	if dbSpec.ConnectionLimit != nil {
+		curConnectionLimit, err := query_utils.SelectFirstValueInt(conn, "SELECT datconnlimit FROM pg_database WHERE datname = $1", dbSpec.DbName)
+		if err != nil {
+			return false, drift, fmt.Errorf("unable to read connection limit of db %s %s", dbSpec.DbName, err)
+		}
+		if curConnectionLimit != *dbSpec.ConnectionLimit {
+			queries = append(queries, fmt.Sprintf("ALTER DATABASE %s WITH CONNECTION LIMIT %d;", quotedDbName, *dbSpec.ConnectionLimit))
+		}
+	}
+	if dbSpec.Tablespace != "" {
+		curTablespace, err := query_utils.SelectFirstValueString(conn, "SELECT t.spcname FROM pg_database d JOIN pg_tablespace t ON t.oid = d.dattablespace WHERE d.datname = $1", dbSpec.DbName)
+		if err != nil {
+			return false, drift, fmt.Errorf("unable to read tablespace of db %s %s", dbSpec.DbName, err)
+		}
+		if curTablespace != dbSpec.Tablespace {
+			// Moving fails while anyone is connected to the database, it's retried on the next reconcile
+			queries = append(queries, fmt.Sprintf("ALTER DATABASE %s SET TABLESPACE %s;", quotedDbName, pq.QuoteIdentifier(dbSpec.Tablespace)))
+		}
+	}
+
+	for _, query := range queries {
+		_, err = conn.Exec(query)
+		if err != nil {
+			return false, drift, err
+		}
+	}
+	return len(queries) > 0, drift, nil
+}
+
+func getRegions(conn *sql.DB, dbName string) ([]string, string, error) {
+	rows, err := conn.Query(fmt.Sprintf(`SELECT region, "primary" FROM [SHOW REGIONS FROM DATABASE %s]`, pq.QuoteIdentifier(dbName)))
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to read regions of db %s %s", dbName, err)
+	}
+	defer rows.Close()
+
+	regions := []string{}
+	primaryRegion := ""
+	for rows.Next() {
+		var region string
+		var primary bool
+		err = rows.Scan(&region, &primary)
+		if err != nil {
+			return nil, "", fmt.Errorf("unable to load regions of db %s %s", dbName, err)
+		}
+		if primary {
+			primaryRegion = region
+		}
+		regions = append(regions, region)
+	}
+	return regions, primaryRegion, nil
+}
+
+// Reconciles PRIMARY REGION, REGIONS and SURVIVE of a CockroachDB database
+// Regions are only dropped when regions is set in the spec
+func UpdateCockroachDbOptions(conn *sql.DB, dbSpec dboperatorv1alpha1.DbSpec) (bool, []string, error) {
+	drift, err := getEncodingDrift(conn, dbSpec.DbName, dbSpec.Encoding)
+	if err != nil {
+		return false, nil, err
+	}
+	if dbSpec.PrimaryRegion == "" {
+		return false, drift, nil
+	}
+
+	quotedDbName := pq.QuoteIdentifier(dbSpec.DbName)
+	curRegions, curPrimaryRegion, err := getRegions(conn, dbSpec.DbName)
+	if err != nil {
+		return false, drift, err
+	}
+	queries := []string{}
+	if curPrimaryRegion != dbSpec.PrimaryRegion {
+		queries = append(queries, fmt.Sprintf("ALTER DATABASE %s SET PRIMARY REGION %s;", quotedDbName, pq.QuoteIdentifier(dbSpec.PrimaryRegion)))
+	}
+	for _, region := range dbSpec.Regions {
+		if region != dbSpec.PrimaryRegion && !funk.ContainsString(curRegions, region) {
+			queries = append(queries, fmt.Sprintf("ALTER DATABASE %s ADD REGION %s;", quotedDbName, pq.QuoteIdentifier(region)))
+		}
+	}
+	if dbSpec.Regions != nil {
+		for _, region := range curRegions {
+			if region != dbSpec.PrimaryRegion && !funk.ContainsString(dbSpec.Regions, region) {
+				queries = append(queries, fmt.Sprintf("ALTER DATABASE %s DROP REGION %s;", quotedDbName, pq.QuoteIdentifier(region)))
+			}
+		}
+	}
+	if dbSpec.Survive != "" {
+		curSurvive, err := query_utils.SelectFirstValueString(conn, fmt.Sprintf("SELECT survival_goal FROM [SHOW SURVIVAL GOAL FROM DATABASE %s]", quotedDbName))
+		if err != nil {
+			return false, drift, fmt.Errorf("unable to read survival goal of db %s %s", dbSpec.DbName, err)
+		}
+		if curSurvive != dbSpec.Survive {
+			queries = append(queries, fmt.Sprintf("ALTER DATABASE %s %s;", quotedDbName, surviveClause(dbSpec.Survive)))
+		}
+	}
+
+	for _, query := range queries {
+		_, err = conn.Exec(query)
+		if err != nil {
+			return false, drift, err
+		}
+	}
+	return len(queries) > 0, drift, nil
+}
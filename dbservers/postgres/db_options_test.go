@@ -0,0 +1,124 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+)
+
+func TestBuildCreateDb(t *testing.T) {
+	connectionLimit := 10
+	query, err := BuildCreateDb(dboperatorv1alpha1.DbSpec{
+		DbName:          "app",
+		Template:        "template0",
+		Encoding:        "UTF8",
+		LcCollate:       "en_US.UTF-8",
+		ConnectionLimit: &connectionLimit,
+	}, "postgres")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	expected := `CREATE DATABASE "app" TEMPLATE "template0" ENCODING = 'UTF8' LC_COLLATE 'en_US.UTF-8' CONNECTION LIMIT 10;`
+	if query != expected {
+		t.Errorf("expected %s got %s", expected, query)
+	}
+
+	query, err = BuildCreateDb(dboperatorv1alpha1.DbSpec{
+		DbName:        "app",
+		PrimaryRegion: "us-east1",
+		Regions:       []string{"us-west1", "europe-west1"},
+		Survive:       dboperatorv1alpha1.SURVIVE_REGION,
+	}, "cockroachdb")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	expected = `CREATE DATABASE "app" PRIMARY REGION "us-east1" REGIONS "us-west1", "europe-west1" SURVIVE REGION FAILURE;`
+	if query != expected {
+		t.Errorf("expected %s got %s", expected, query)
+	}
+
+	_, err = BuildCreateDb(dboperatorv1alpha1.DbSpec{DbName: "app", PrimaryRegion: "us-east1"}, "postgres")
+	if err == nil {
+		t.Errorf("expected regions to be refused on postgres")
+	}
+	_, err = BuildCreateDb(dboperatorv1alpha1.DbSpec{DbName: "app", Tablespace: "fast"}, "cockroachdb")
+	if err == nil {
+		t.Errorf("expected a tablespace to be refused on cockroachdb")
+	}
+}
+
+func TestUpdateDbOptions(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	connectionLimit := 20
+	dbSpec := dboperatorv1alpha1.DbSpec{
+		DbName:          "app",
+		Encoding:        "utf-8",
+		LcCollate:       "C",
+		ConnectionLimit: &connectionLimit,
+		Tablespace:      "fast",
+	}
+
+	mock.ExpectQuery("SELECT pg_encoding_to_char(encoding) FROM pg_database WHERE datname = $1").WithArgs("app").WillReturnRows(sqlmock.NewRows([]string{"pg_encoding_to_char"}).AddRow("UTF8"))
+	mock.ExpectQuery("SELECT datcollate, datctype FROM pg_database WHERE datname = $1").WithArgs("app").WillReturnRows(sqlmock.NewRows([]string{"datcollate", "datctype"}).AddRow("en_US.UTF-8", "en_US.UTF-8"))
+	mock.ExpectQuery("SELECT datconnlimit FROM pg_database WHERE datname = $1").WithArgs("app").WillReturnRows(sqlmock.NewRows([]string{"datconnlimit"}).AddRow(-1))
+	mock.ExpectQuery("SELECT t.spcname FROM pg_database d JOIN pg_tablespace t ON t.oid = d.dattablespace WHERE d.datname = $1").WithArgs("app").WillReturnRows(sqlmock.NewRows([]string{"spcname"}).AddRow("fast"))
+	mock.ExpectExec(`ALTER DATABASE "app" WITH CONNECTION LIMIT 20;`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	changed, drift, err := UpdateDbOptions(db, dbSpec)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if !changed {
+		t.Errorf("expected the connection limit to change")
+	}
+	if len(drift) != 1 || drift[0] != "lc_collate is en_US.UTF-8 instead of C" {
+		t.Errorf("unexpected drift %v", drift)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUpdateCockroachDbOptions(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	dbSpec := dboperatorv1alpha1.DbSpec{
+		DbName:        "app",
+		PrimaryRegion: "us-east1",
+		Regions:       []string{"us-west1"},
+		Survive:       dboperatorv1alpha1.SURVIVE_ZONE,
+	}
+
+	mock.ExpectQuery(`SELECT region, "primary" FROM [SHOW REGIONS FROM DATABASE "app"]`).WillReturnRows(
+		sqlmock.NewRows([]string{"region", "primary"}).AddRow("us-east1", true).AddRow("europe-west1", false),
+	)
+	mock.ExpectQuery(`SELECT survival_goal FROM [SHOW SURVIVAL GOAL FROM DATABASE "app"]`).WillReturnRows(sqlmock.NewRows([]string{"survival_goal"}).AddRow("zone"))
+	mock.ExpectExec(`ALTER DATABASE "app" ADD REGION "us-west1";`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`ALTER DATABASE "app" DROP REGION "europe-west1";`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	changed, drift, err := UpdateCockroachDbOptions(db, dbSpec)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if !changed {
+		t.Errorf("expected the regions to change")
+	}
+	if len(drift) != 0 {
+		t.Errorf("unexpected drift %v", drift)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
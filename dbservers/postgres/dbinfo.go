@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"strings"
 
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
 	"github.com/obeleh/db-operator/shared"
 	v1 "k8s.io/api/core/v1"
 )
@@ -16,17 +17,22 @@ type PostgresActions struct {
 }
 
 func (i *PostgresActions) BuildContainer(scriptName string) v1.Container {
+	userName, secretName, passwordKey := i.GetJobCredentials()
+	return i.buildContainer(scriptName, userName, secretName, passwordKey)
+}
+
+func (i *PostgresActions) buildContainer(scriptName string, userName string, secretName string, passwordKey string) v1.Container {
 	dbServer := i.DbServer
 	envVars := []v1.EnvVar{
 		{Name: "PGHOST", Value: dbServer.Spec.Address},
-		{Name: "PGUSER", Value: dbServer.Spec.UserName},
+		{Name: "PGUSER", Value: userName},
 		{Name: "PGPORT", Value: strconv.Itoa(dbServer.Spec.Port)},
 		{Name: "PGPASSWORD", ValueFrom: &v1.EnvVarSource{
 			SecretKeyRef: &v1.SecretKeySelector{
 				LocalObjectReference: v1.LocalObjectReference{
-					Name: dbServer.Spec.SecretName,
+					Name: secretName,
 				},
-				Key: shared.Nvl(dbServer.Spec.PasswordKey, "password"),
+				Key: shared.Nvl(passwordKey, "password"),
 			},
 		}},
 		{Name: "DATABASE", Value: i.Db.Spec.DbName},
@@ -50,3 +56,14 @@ func (i *PostgresActions) BuildBackupContainer() v1.Container {
 func (i *PostgresActions) BuildRestoreContainer() v1.Container {
 	return i.BuildContainer(shared.RESTORE_POSTGRES)
 }
+
+func (i *PostgresActions) BuildRunSqlContainer(sql string, user *dboperatorv1alpha1.User) v1.Container {
+	var container v1.Container
+	if user == nil {
+		container = i.BuildContainer(shared.RUN_SQL_POSTGRES)
+	} else {
+		container = i.buildContainer(shared.RUN_SQL_POSTGRES, user.Spec.UserName, user.Spec.SecretName, user.Spec.PasswordKey)
+	}
+	container.Env = append(container.Env, v1.EnvVar{Name: "SQL", Value: sql})
+	return container
+}
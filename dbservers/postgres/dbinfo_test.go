@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"testing"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/shared"
+	v1 "k8s.io/api/core/v1"
+)
+
+func getEnvVar(container v1.Container, name string) *v1.EnvVar {
+	for i, envVar := range container.Env {
+		if envVar.Name == name {
+			return &container.Env[i]
+		}
+	}
+	return nil
+}
+
+func TestBuildRunSqlContainerAsUser(t *testing.T) {
+	actions := PostgresActions{DbActionsBase: shared.DbActionsBase{
+		Db:       &dboperatorv1alpha1.Db{Spec: dboperatorv1alpha1.DbSpec{DbName: "app"}},
+		DbServer: &dboperatorv1alpha1.DbServer{Spec: dboperatorv1alpha1.DbServerSpec{Address: "pg", Port: 5432, UserName: "postgres", SecretName: "pg-admin"}},
+	}}
+	user := &dboperatorv1alpha1.User{Spec: dboperatorv1alpha1.UserSpec{UserName: "maintenance", SecretName: "maintenance-secret", PasswordKey: "pw"}}
+
+	container := actions.BuildRunSqlContainer("VACUUM ANALYZE;", user)
+	if getEnvVar(container, "PGUSER").Value != "maintenance" {
+		t.Errorf("expected the statements to run as the user")
+	}
+	secretRef := getEnvVar(container, "PGPASSWORD").ValueFrom.SecretKeyRef
+	if secretRef.Name != "maintenance-secret" || secretRef.Key != "pw" {
+		t.Errorf("expected the password from the secret of the user, got %s/%s", secretRef.Name, secretRef.Key)
+	}
+	if getEnvVar(container, "SQL").Value != "VACUUM ANALYZE;" {
+		t.Errorf("expected the statements in the SQL env var")
+	}
+}
+
+func TestBuildContainerAsJobUser(t *testing.T) {
+	actions := PostgresActions{DbActionsBase: shared.DbActionsBase{
+		Db:       &dboperatorv1alpha1.Db{Spec: dboperatorv1alpha1.DbSpec{DbName: "app"}},
+		DbServer: &dboperatorv1alpha1.DbServer{Spec: dboperatorv1alpha1.DbServerSpec{Address: "pg", Port: 5432, UserName: "postgres", SecretName: "pg-admin"}},
+	}}
+	container := actions.BuildBackupContainer()
+	if getEnvVar(container, "PGUSER").Value != "postgres" || getEnvVar(container, "PGPASSWORD").ValueFrom.SecretKeyRef.Name != "pg-admin" {
+		t.Errorf("expected the backup to connect as the DbServer user")
+	}
+
+	actions.JobUser = &dboperatorv1alpha1.User{Spec: dboperatorv1alpha1.UserSpec{UserName: "app_owner", SecretName: "app-owner-secret"}}
+	container = actions.BuildBackupContainer()
+	if getEnvVar(container, "PGUSER").Value != "app_owner" {
+		t.Errorf("expected the backup to connect as the job user")
+	}
+	secretRef := getEnvVar(container, "PGPASSWORD").ValueFrom.SecretKeyRef
+	if secretRef.Name != "app-owner-secret" || secretRef.Key != "password" {
+		t.Errorf("expected the password from the secret of the job user, got %s/%s", secretRef.Name, secretRef.Key)
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/lib/pq"
 	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/shared"
 )
 
 func NewDefaultTablePrivsReconciler(privs dboperatorv1alpha1.DbPriv, conn *sql.DB, userName string, tableName string, normalizedPrivSet []string, serverVersion *PostgresVersion) (*PrivsReconciler, error) {
@@ -29,7 +30,7 @@ func NewDefaultTablePrivsReconciler(privs dboperatorv1alpha1.DbPriv, conn *sql.D
 	}, nil
 }
 
-func revokeDefaultTablePrivileges(conn *sql.DB, user string, role string, privs []string) error {
+func revokeDefaultTablePrivileges(conn shared.Executor, user string, role string, privs []string) error {
 	privsStr := strings.Join(privs, ", ")
 	escapedUser := pq.QuoteIdentifier(user)
 	escapedRole := pq.QuoteIdentifier(role)
@@ -39,7 +40,7 @@ func revokeDefaultTablePrivileges(conn *sql.DB, user string, role string, privs
 	return err
 }
 
-func grantDefaultTablePrivileges(conn *sql.DB, user string, role string, privs []string) error {
+func grantDefaultTablePrivileges(conn shared.Executor, user string, role string, privs []string) error {
 	privsStr := strings.Join(privs, ", ")
 	escapedUser := pq.QuoteIdentifier(user)
 	escapedRole := pq.QuoteIdentifier(role)
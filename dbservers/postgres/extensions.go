@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/shared"
+)
+
+func GetExtensions(conn *sql.DB) (map[string]shared.DbSideExtension, error) {
+	rows, err := conn.Query("SELECT e.extname, e.extversion, n.nspname FROM pg_extension e JOIN pg_namespace n ON n.oid = e.extnamespace")
+	if err != nil {
+		return nil, fmt.Errorf("unable to read extensions from server %s", err)
+	}
+	defer rows.Close()
+
+	extensions := make(map[string]shared.DbSideExtension)
+	for rows.Next() {
+		var extension shared.DbSideExtension
+		err := rows.Scan(&extension.Name, &extension.Version, &extension.Schema)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load extension %s", err)
+		}
+		extensions[extension.Name] = extension
+	}
+	return extensions, nil
+}
+
+func BuildCreateExtension(extensionSpec dboperatorv1alpha1.ExtensionSpec) string {
+	parts := []string{"CREATE EXTENSION IF NOT EXISTS " + pq.QuoteIdentifier(extensionSpec.Name)}
+	if extensionSpec.Schema != "" {
+		parts = append(parts, "SCHEMA "+pq.QuoteIdentifier(extensionSpec.Schema))
+	}
+	if extensionSpec.Version != "" {
+		parts = append(parts, "VERSION "+pq.QuoteLiteral(extensionSpec.Version))
+	}
+	if extensionSpec.Cascade {
+		parts = append(parts, "CASCADE")
+	}
+	return strings.Join(parts, " ") + ";"
+}
+
+// Updates the extension to the version in the spec and moves it to the schema in the spec
+// Fields that are not set are left as they are on the server
+func UpdateExtension(conn *sql.DB, extensionSpec dboperatorv1alpha1.ExtensionSpec) (bool, error) {
+	extensions, err := GetExtensions(conn)
+	if err != nil {
+		return false, err
+	}
+	current, exists := extensions[extensionSpec.Name]
+	if !exists {
+		return false, fmt.Errorf("extension %s is not installed", extensionSpec.Name)
+	}
+
+	quotedName := pq.QuoteIdentifier(extensionSpec.Name)
+	queries := []string{}
+	if extensionSpec.Version != "" && current.Version != extensionSpec.Version {
+		queries = append(queries, fmt.Sprintf("ALTER EXTENSION %s UPDATE TO %s;", quotedName, pq.QuoteLiteral(extensionSpec.Version)))
+	}
+	if extensionSpec.Schema != "" && current.Schema != extensionSpec.Schema {
+		queries = append(queries, fmt.Sprintf("ALTER EXTENSION %s SET SCHEMA %s;", quotedName, pq.QuoteIdentifier(extensionSpec.Schema)))
+	}
+	for _, query := range queries {
+		_, err = conn.Exec(query)
+		if err != nil {
+			return false, err
+		}
+	}
+	return len(queries) > 0, nil
+}
+
+func DropExtension(conn *sql.DB, name string, cascade bool) error {
+	query := "DROP EXTENSION IF EXISTS " + pq.QuoteIdentifier(name)
+	if cascade {
+		query += " CASCADE"
+	}
+	_, err := conn.Exec(query + ";")
+	return err
+}
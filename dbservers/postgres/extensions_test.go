@@ -0,0 +1,53 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+)
+
+func TestBuildCreateExtension(t *testing.T) {
+	query := BuildCreateExtension(dboperatorv1alpha1.ExtensionSpec{Name: "postgis_topology", Schema: "gis", Version: "3.4.0", Cascade: true})
+	expected := `CREATE EXTENSION IF NOT EXISTS "postgis_topology" SCHEMA "gis" VERSION '3.4.0' CASCADE;`
+	if query != expected {
+		t.Errorf("expected %s got %s", expected, query)
+	}
+}
+
+func TestUpdateExtension(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	extensionsQuery := "SELECT e.extname, e.extversion, n.nspname FROM pg_extension e JOIN pg_namespace n ON n.oid = e.extnamespace"
+	extensionSpec := dboperatorv1alpha1.ExtensionSpec{Name: "pg_trgm", Version: "1.6", Schema: "extensions"}
+
+	mock.ExpectQuery(extensionsQuery).WillReturnRows(
+		sqlmock.NewRows([]string{"extname", "extversion", "nspname"}).AddRow("plpgsql", "1.0", "pg_catalog").AddRow("pg_trgm", "1.5", "public"),
+	)
+	mock.ExpectExec(`ALTER EXTENSION "pg_trgm" UPDATE TO '1.6';`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`ALTER EXTENSION "pg_trgm" SET SCHEMA "extensions";`).WillReturnResult(sqlmock.NewResult(0, 0))
+	changed, err := UpdateExtension(db, extensionSpec)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if !changed {
+		t.Errorf("expected the extension to change")
+	}
+
+	mock.ExpectQuery(extensionsQuery).WillReturnRows(sqlmock.NewRows([]string{"extname", "extversion", "nspname"}).AddRow("pg_trgm", "1.6", "extensions"))
+	changed, err = UpdateExtension(db, extensionSpec)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if changed {
+		t.Errorf("expected no changes")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
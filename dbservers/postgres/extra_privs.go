@@ -0,0 +1,225 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/shared"
+)
+
+// The grants of a user as rows of database, schema, table and privilege
+const (
+	DATABASE_GRANTS_QUERY = `SELECT d.datname, '', '', acl.privilege_type
+	FROM pg_database d
+	CROSS JOIN LATERAL aclexplode(d.datacl) acl
+	WHERE acl.grantee = (SELECT oid FROM pg_roles WHERE rolname = $1)
+	AND acl.grantee <> d.datdba
+	AND NOT d.datistemplate
+	ORDER BY d.datname, acl.privilege_type;`
+	SCHEMA_GRANTS_QUERY = `SELECT current_database(), n.nspname, '', acl.privilege_type
+	FROM pg_namespace n
+	CROSS JOIN LATERAL aclexplode(n.nspacl) acl
+	WHERE acl.grantee = (SELECT oid FROM pg_roles WHERE rolname = $1)
+	AND acl.grantee <> n.nspowner
+	AND n.nspname NOT IN ('information_schema', 'pg_catalog', 'pg_toast')
+	ORDER BY n.nspname, acl.privilege_type;`
+	TABLE_GRANTS_QUERY = `SELECT table_catalog, table_schema, table_name, privilege_type
+	FROM information_schema.role_table_grants
+	WHERE grantee = $1
+	AND grantor IS DISTINCT FROM grantee
+	AND table_schema NOT IN ('crdb_internal', 'information_schema', 'pg_catalog', 'pg_extension')
+	ORDER BY table_schema, table_name, privilege_type;`
+)
+
+// A grant of the user on a database, schema or table that no db_privs entry covers
+type extraGrant struct {
+	dbName string
+	schema string
+	table  string
+	privs  []string
+
+	conn *sql.DB
+}
+
+func (g *extraGrant) scope() string {
+	if g.table != "" {
+		return fmt.Sprintf("%s.%s.%s", g.dbName, g.schema, g.table)
+	}
+	if g.schema != "" {
+		return fmt.Sprintf("%s.%s", g.dbName, g.schema)
+	}
+	return g.dbName
+}
+
+func (g *extraGrant) revoke(exec shared.Executor, userName string) error {
+	if g.table != "" {
+		return revokeQualifiedTablePrivileges(exec, userName, g.schema+"."+g.table, g.privs)
+	}
+	if g.schema != "" {
+		return revokeSchemaPrivileges(exec, userName, g.schema, g.privs)
+	}
+	return revokeDatabasePrivileges(exec, userName, g.dbName, g.privs)
+}
+
+func revokeQualifiedTablePrivileges(conn shared.Executor, user string, table string, privs []string) error {
+	privSet := strings.Join(privs, ", ")
+	quotedUserName := pq.QuoteIdentifier(user)
+	_, err := conn.Exec(fmt.Sprintf("REVOKE %s ON TABLE %s FROM %s", privSet, quoteTableName(table), quotedUserName)) // nosemgrep, sql query is constructed from sanitized strings
+	return err
+}
+
+// The databases, schemas and tables the db_privs of a user manage, a table scope ending in * covers every table of the schema or database
+type privsCoverage struct {
+	databases map[string]bool
+	schemas   map[string]bool
+	tables    map[string]bool
+}
+
+func getPrivsCoverage(dbPrivs []dboperatorv1alpha1.DbPriv, serverVersion *PostgresVersion) (*privsCoverage, error) {
+	coverage := &privsCoverage{
+		databases: map[string]bool{},
+		schemas:   map[string]bool{},
+		tables:    map[string]bool{},
+	}
+	for _, dbPriv := range dbPrivs {
+		dbName := GetDbNameFromScopeName(dbPriv.Scope)
+		privType, scopedName, _, err := parseDbPriv(dbPriv, serverVersion)
+		if err != nil {
+			return nil, err
+		}
+		switch privType {
+		case "database":
+			coverage.databases[dbName] = true
+		case "schema":
+			coverage.schemas[dbName+"."+scopedName] = true
+		case "table":
+			if dbPriv.PrivType != "" {
+				scopedName = strings.TrimPrefix(scopedName, dbName+".")
+			}
+			if strings.HasSuffix(scopedName, ".ALL") {
+				coverage.tables[dbName+"."+strings.TrimSuffix(scopedName, ".ALL")+".*"] = true
+			} else {
+				schema, table := splitTableName(scopedName)
+				coverage.tables[fmt.Sprintf("%s.%s.%s", dbName, schema, table)] = true
+			}
+		case "defaultTable":
+			// The default privileges end up on every table the grantor creates in the database
+			coverage.tables[dbName+".*.*"] = true
+		}
+	}
+	return coverage, nil
+}
+
+func (c *privsCoverage) covers(grant *extraGrant) bool {
+	if grant.table != "" {
+		return c.tables[grant.scope()] || c.tables[fmt.Sprintf("%s.%s.*", grant.dbName, grant.schema)] || c.tables[grant.dbName+".*.*"]
+	}
+	if grant.schema != "" {
+		return c.schemas[grant.scope()]
+	}
+	return c.databases[grant.dbName]
+}
+
+// Lists the grants of the user on databases, schemas and tables that are not in db_privs.
+// Grants a user has as the owner of an object are left out. CockroachDB has no ACLs to read, there only table grants are listed
+func getExtraGrants(conn *sql.DB, userName string, dbPrivs []dboperatorv1alpha1.DbPriv, serverVersion *PostgresVersion, connectionGetter ConnectionGetter) ([]*extraGrant, error) {
+	coverage, err := getPrivsCoverage(dbPrivs, serverVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	grants := []*extraGrant{}
+	if serverVersion.ProductName != CockroachDB {
+		databaseGrants, err := selectGrants(conn, DATABASE_GRANTS_QUERY, userName)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read database grants %s", err)
+		}
+		grants = append(grants, databaseGrants...)
+	}
+
+	databases, err := GetDatabasesUserHasAccessTo(conn, userName)
+	if err != nil {
+		return nil, err
+	}
+	for _, dbName := range databases {
+		dbName := dbName
+		dbConn, err := connectionGetter(nil, &dbName)
+		if err != nil {
+			return nil, err
+		}
+		if serverVersion.ProductName != CockroachDB {
+			schemaGrants, err := selectGrants(dbConn, SCHEMA_GRANTS_QUERY, userName)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read schema grants %s", err)
+			}
+			grants = append(grants, schemaGrants...)
+		}
+		tableGrants, err := selectGrants(dbConn, TABLE_GRANTS_QUERY, userName)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read tablePrivs %s", err)
+		}
+		grants = append(grants, tableGrants...)
+	}
+
+	extraGrants := []*extraGrant{}
+	for _, grant := range grants {
+		if !coverage.covers(grant) {
+			extraGrants = append(extraGrants, grant)
+		}
+	}
+	return extraGrants, nil
+}
+
+// Reads rows of database, schema, table and privilege and groups the privileges per scope, the grants are revoked through conn
+func selectGrants(conn *sql.DB, query string, userName string) ([]*extraGrant, error) {
+	rows, err := conn.Query(query, userName) // nosemgrep, the queries are constants
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	grants := []*extraGrant{}
+	grantsByScope := map[string]*extraGrant{}
+	for rows.Next() {
+		var dbName, schema, table, privilege string
+		err := rows.Scan(&dbName, &schema, &table, &privilege)
+		if err != nil {
+			return nil, err
+		}
+		grant := &extraGrant{dbName: dbName, schema: schema, table: table, conn: conn}
+		existing, found := grantsByScope[grant.scope()]
+		if !found {
+			grantsByScope[grant.scope()] = grant
+			grants = append(grants, grant)
+			existing = grant
+		}
+		existing.privs = append(existing.privs, privilege)
+	}
+	for _, grant := range grants {
+		sort.Strings(grant.privs)
+	}
+	return grants, rows.Err()
+}
+
+// Revokes the grants of the user on databases, schemas and tables that are not in db_privs
+func RevokeExtraUserPrivs(conn *sql.DB, userName string, dbPrivs []dboperatorv1alpha1.DbPriv, connectionGetter ConnectionGetter) (bool, error) {
+	serverVersion, err := getServerVersion(conn)
+	if err != nil {
+		return false, err
+	}
+	extraGrants, err := getExtraGrants(conn, userName, dbPrivs, serverVersion, connectionGetter)
+	if err != nil {
+		return false, err
+	}
+	for _, grant := range extraGrants {
+		err = grant.revoke(grant.conn, userName)
+		if err != nil {
+			return false, err
+		}
+	}
+	return len(extraGrants) > 0, nil
+}
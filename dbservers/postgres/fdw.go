@@ -0,0 +1,255 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/shared"
+	funk "github.com/thoas/go-funk"
+)
+
+// Options of a postgres_fdw server that connects to dbName on the DbServer at host and port
+// https://www.postgresql.org/docs/current/postgres-fdw.html#POSTGRES-FDW-OPTIONS-CONNECTION
+func BuildForeignServerOptions(host string, port int, dbName string, serverOptions map[string]string) map[string]string {
+	sslMode, found := serverOptions["sslmode"]
+	if !found {
+		sslMode = shared.SSL_MODE_REQUIRE
+	}
+	return map[string]string{
+		"host":    host,
+		"port":    strconv.Itoa(port),
+		"dbname":  dbName,
+		"sslmode": sslMode,
+	}
+}
+
+func sortedKeys(options map[string]string) []string {
+	keys := make([]string, 0, len(options))
+	for key := range options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func buildOptions(options map[string]string) string {
+	parts := []string{}
+	for _, key := range sortedKeys(options) {
+		parts = append(parts, pq.QuoteIdentifier(key)+" "+pq.QuoteLiteral(options[key]))
+	}
+	return "OPTIONS (" + strings.Join(parts, ", ") + ")"
+}
+
+// Builds the OPTIONS clause of an ALTER statement, empty when nothing changes
+// When the current options aren't known all options are SET, the caller has to know they exist
+func buildAlterOptions(current map[string]string, desired map[string]string, known bool) string {
+	parts := []string{}
+	for _, key := range sortedKeys(desired) {
+		value, found := current[key]
+		if !known || found && value != desired[key] {
+			parts = append(parts, "SET "+pq.QuoteIdentifier(key)+" "+pq.QuoteLiteral(desired[key]))
+		} else if !found {
+			parts = append(parts, "ADD "+pq.QuoteIdentifier(key)+" "+pq.QuoteLiteral(desired[key]))
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "OPTIONS (" + strings.Join(parts, ", ") + ")"
+}
+
+func BuildCreateForeignServer(name string, options map[string]string) string {
+	return fmt.Sprintf("CREATE SERVER %s FOREIGN DATA WRAPPER postgres_fdw %s;", pq.QuoteIdentifier(name), buildOptions(options))
+}
+
+func CreateForeignServer(conn *sql.DB, name string, options map[string]string) error {
+	_, err := conn.Exec("CREATE EXTENSION IF NOT EXISTS postgres_fdw;")
+	if err != nil {
+		return err
+	}
+	_, err = conn.Exec(BuildCreateForeignServer(name, options))
+	return err
+}
+
+func GetForeignServer(conn *sql.DB, name string) (*shared.DbSideForeignServer, error) {
+	rows, err := conn.Query("SELECT COALESCE(o.option_name, ''), COALESCE(o.option_value, '') FROM pg_foreign_server s LEFT JOIN LATERAL pg_options_to_table(s.srvoptions) o ON true WHERE s.srvname = $1", name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read foreign server %s %s", name, err)
+	}
+	defer rows.Close()
+
+	var server *shared.DbSideForeignServer
+	for rows.Next() {
+		if server == nil {
+			server = &shared.DbSideForeignServer{Name: name, Options: map[string]string{}, UserMappings: map[string]map[string]string{}}
+		}
+		var option, value string
+		err = rows.Scan(&option, &value)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load foreign server %s %s", name, err)
+		}
+		if option != "" {
+			server.Options[option] = value
+		}
+	}
+	rows.Close()
+	if server == nil {
+		return nil, nil
+	}
+
+	// The options of a user mapping are only visible to superusers and the mapped user
+	mappingRows, err := conn.Query("SELECT um.usename, COALESCE(o.option_name, ''), COALESCE(o.option_value, '') FROM pg_user_mappings um LEFT JOIN LATERAL pg_options_to_table(um.umoptions) o ON true WHERE um.srvname = $1", name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read user mappings of foreign server %s %s", name, err)
+	}
+	defer mappingRows.Close()
+	for mappingRows.Next() {
+		var userName, option, value string
+		err = mappingRows.Scan(&userName, &option, &value)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load user mappings of foreign server %s %s", name, err)
+		}
+		if _, found := server.UserMappings[userName]; !found {
+			server.UserMappings[userName] = map[string]string{}
+		}
+		if option != "" {
+			server.UserMappings[userName][option] = value
+		}
+	}
+	return server, nil
+}
+
+func getMappingOptions(credentials shared.Credentials) map[string]string {
+	options := map[string]string{"user": credentials.UserName}
+	if credentials.Password != nil {
+		options["password"] = *credentials.Password
+	}
+	return options
+}
+
+// Sets the connection options of the server and reconciles the user mappings
+// Options that were added by hand are left alone, so are the mappings that weren't created by the ForeignServer.
+// createdMappings are the mappings the ForeignServer created before with the hash of the options it set on them.
+// Returns the same for the mappings it created now
+func UpdateForeignServer(conn *sql.DB, name string, options map[string]string, userMappings map[string]shared.Credentials, createdMappings map[string]string) (bool, map[string]string, error) {
+	current, err := GetForeignServer(conn, name)
+	if err != nil {
+		return false, nil, err
+	}
+	if current == nil {
+		return false, nil, fmt.Errorf("foreign server %s does not exist", name)
+	}
+
+	quotedName := pq.QuoteIdentifier(name)
+	queries := []string{}
+	alterOptions := buildAlterOptions(current.Options, options, true)
+	if alterOptions != "" {
+		queries = append(queries, fmt.Sprintf("ALTER SERVER %s %s;", quotedName, alterOptions))
+	}
+
+	created := map[string]string{}
+	userNames := funk.Keys(userMappings).([]string)
+	sort.Strings(userNames)
+	for _, userName := range userNames {
+		quotedUserName := pq.QuoteIdentifier(userName)
+		mappingOptions := getMappingOptions(userMappings[userName])
+		optionsHash := shared.HashSecretValue(buildOptions(mappingOptions))
+		createMapping := fmt.Sprintf("CREATE USER MAPPING FOR %s SERVER %s %s;", quotedUserName, quotedName, buildOptions(mappingOptions))
+		previousHash, wasCreated := createdMappings[userName]
+		curOptions, found := current.UserMappings[userName]
+		switch {
+		case !found:
+			queries = append(queries, createMapping)
+			wasCreated = true
+		case len(curOptions) > 0:
+			alterOptions := buildAlterOptions(curOptions, mappingOptions, true)
+			if alterOptions != "" {
+				queries = append(queries, fmt.Sprintf("ALTER USER MAPPING FOR %s SERVER %s %s;", quotedUserName, quotedName, alterOptions))
+			}
+		case wasCreated:
+			// The options aren't visible without a superuser, a mapping that was created here has all of them
+			if previousHash != optionsHash {
+				queries = append(queries, fmt.Sprintf("ALTER USER MAPPING FOR %s SERVER %s %s;", quotedUserName, quotedName, buildAlterOptions(nil, mappingOptions, false)))
+			}
+		default:
+			// Which options another mapping has isn't known, so it's replaced by one with the options of the spec
+			queries = append(queries, fmt.Sprintf("DROP USER MAPPING FOR %s SERVER %s;", quotedUserName, quotedName), createMapping)
+			wasCreated = true
+		}
+		if wasCreated {
+			created[userName] = optionsHash
+		}
+	}
+	for userName := range createdMappings {
+		_, desired := userMappings[userName]
+		_, exists := current.UserMappings[userName]
+		if !desired && exists {
+			queries = append(queries, fmt.Sprintf("DROP USER MAPPING IF EXISTS FOR %s SERVER %s;", pq.QuoteIdentifier(userName), quotedName))
+		}
+	}
+
+	for _, query := range queries {
+		_, err = conn.Exec(query)
+		if err != nil {
+			return false, nil, err
+		}
+	}
+	return len(queries) > 0, created, nil
+}
+
+func GetForeignTables(conn *sql.DB, name string, schema string) ([]string, error) {
+	return getTables(conn, "SELECT c.relname FROM pg_foreign_table ft JOIN pg_class c ON c.oid = ft.ftrelid JOIN pg_namespace n ON n.oid = c.relnamespace JOIN pg_foreign_server s ON s.oid = ft.ftserver WHERE s.srvname = $1 AND n.nspname = $2 ORDER BY 1", name, schema)
+}
+
+// Imports the tables of importSpec that aren't foreign tables yet, without tables in the spec
+// every remote table that isn't imported yet is, so tables added to the remote schema are picked up
+func ImportForeignSchema(conn *sql.DB, name string, importSpec dboperatorv1alpha1.ForeignSchemaImport) ([]string, error) {
+	tables, err := GetForeignTables(conn, name, importSpec.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read foreign tables of server %s %s", name, err)
+	}
+
+	query := fmt.Sprintf("IMPORT FOREIGN SCHEMA %s", pq.QuoteIdentifier(importSpec.RemoteSchema))
+	if len(importSpec.Tables) > 0 {
+		missing := []string{}
+		for _, table := range importSpec.Tables {
+			if !funk.ContainsString(tables, table) {
+				missing = append(missing, table)
+			}
+		}
+		if len(missing) == 0 {
+			return tables, nil
+		}
+		query += fmt.Sprintf(" LIMIT TO (%s)", quoteIdentifiers(missing))
+	} else if len(tables) > 0 {
+		query += fmt.Sprintf(" EXCEPT (%s)", quoteIdentifiers(tables))
+	}
+	query += fmt.Sprintf(" FROM SERVER %s INTO %s;", pq.QuoteIdentifier(name), pq.QuoteIdentifier(importSpec.Schema))
+	_, err = conn.Exec(query)
+	if err != nil {
+		return nil, err
+	}
+	return GetForeignTables(conn, name, importSpec.Schema)
+}
+
+// Drops the user mappings in userMappings first, without cascade the drop fails while the server has other mappings or foreign tables
+func DropForeignServer(conn *sql.DB, name string, userMappings []string, cascade bool) error {
+	quotedName := pq.QuoteIdentifier(name)
+	for _, userName := range userMappings {
+		_, err := conn.Exec(fmt.Sprintf("DROP USER MAPPING IF EXISTS FOR %s SERVER %s;", pq.QuoteIdentifier(userName), quotedName))
+		if err != nil {
+			return err
+		}
+	}
+	query := "DROP SERVER IF EXISTS " + quotedName
+	if cascade {
+		query += " CASCADE"
+	}
+	_, err := conn.Exec(query + ";")
+	return err
+}
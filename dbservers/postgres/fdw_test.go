@@ -0,0 +1,146 @@
+package postgres
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/shared"
+)
+
+func TestBuildCreateForeignServer(t *testing.T) {
+	options := BuildForeignServerOptions("prod.db.svc", 5432, "shop", nil)
+	query := BuildCreateForeignServer("prod", options)
+	expected := `CREATE SERVER "prod" FOREIGN DATA WRAPPER postgres_fdw OPTIONS ("dbname" 'shop', "host" 'prod.db.svc', "port" '5432', "sslmode" 'require');`
+	if query != expected {
+		t.Errorf("expected %s got %s", expected, query)
+	}
+}
+
+func TestUpdateForeignServer(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	serverQuery := "SELECT COALESCE(o.option_name, ''), COALESCE(o.option_value, '') FROM pg_foreign_server s LEFT JOIN LATERAL pg_options_to_table(s.srvoptions) o ON true WHERE s.srvname = $1"
+	mappingsQuery := "SELECT um.usename, COALESCE(o.option_name, ''), COALESCE(o.option_value, '') FROM pg_user_mappings um LEFT JOIN LATERAL pg_options_to_table(um.umoptions) o ON true WHERE um.srvname = $1"
+	mock.ExpectQuery(serverQuery).WithArgs("prod").WillReturnRows(
+		sqlmock.NewRows([]string{"option_name", "option_value"}).AddRow("host", "old.db.svc").AddRow("port", "5432").AddRow("dbname", "shop").AddRow("fetch_size", "1000"),
+	)
+	mock.ExpectQuery(mappingsQuery).WithArgs("prod").WillReturnRows(
+		sqlmock.NewRows([]string{"usename", "option_name", "option_value"}).
+			AddRow("reporting", "user", "reader").AddRow("reporting", "password", "old").
+			AddRow("analyst", "", "").
+			AddRow("legacy", "", "").
+			AddRow("viewer", "", "").
+			AddRow("former", "user", "reader").
+			AddRow("dba", "user", "admin").
+			AddRow("public", "user", "guest"),
+	)
+	mock.ExpectExec(`ALTER SERVER "prod" OPTIONS (SET "host" 'prod.db.svc', ADD "sslmode" 'require');`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`ALTER USER MAPPING FOR "analyst" SERVER "prod" OPTIONS (SET "password" 'secret', SET "user" 'reader');`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE USER MAPPING FOR "etl" SERVER "prod" OPTIONS ("password" 'etl-secret', "user" 'etl');`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DROP USER MAPPING FOR "legacy" SERVER "prod";`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE USER MAPPING FOR "legacy" SERVER "prod" OPTIONS ("password" 'secret', "user" 'reader');`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`ALTER USER MAPPING FOR "reporting" SERVER "prod" OPTIONS (SET "password" 'secret');`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DROP USER MAPPING IF EXISTS FOR "former" SERVER "prod";`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	password := "secret"
+	etlPassword := "etl-secret"
+	userMappings := map[string]shared.Credentials{
+		"reporting": {UserName: "reader", Password: &password},
+		"analyst":   {UserName: "reader", Password: &password},
+		"etl":       {UserName: "etl", Password: &etlPassword},
+		"legacy":    {UserName: "reader", Password: &password},
+		"viewer":    {UserName: "viewer", Password: &password},
+	}
+	// The options of analyst, legacy and viewer aren't visible, only analyst and viewer were created by the ForeignServer
+	// The options of viewer didn't change since they were set, the mapping of dba wasn't created by the ForeignServer so it's kept
+	createdMappings := map[string]string{"analyst": "", "former": "", "reporting": "", "viewer": getMappingOptionsHash("viewer", password)}
+	changed, created, err := UpdateForeignServer(db, "prod", BuildForeignServerOptions("prod.db.svc", 5432, "shop", nil), userMappings, createdMappings)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if !changed {
+		t.Errorf("expected the foreign server to change")
+	}
+	expectedCreated := map[string]string{
+		"analyst":   getMappingOptionsHash("reader", password),
+		"etl":       getMappingOptionsHash("etl", etlPassword),
+		"legacy":    getMappingOptionsHash("reader", password),
+		"reporting": getMappingOptionsHash("reader", password),
+		"viewer":    getMappingOptionsHash("viewer", password),
+	}
+	if !reflect.DeepEqual(created, expectedCreated) {
+		t.Errorf("expected created mappings %v got %v", expectedCreated, created)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestImportForeignSchema(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	tablesQuery := "SELECT c.relname FROM pg_foreign_table ft JOIN pg_class c ON c.oid = ft.ftrelid JOIN pg_namespace n ON n.oid = c.relnamespace JOIN pg_foreign_server s ON s.oid = ft.ftserver WHERE s.srvname = $1 AND n.nspname = $2 ORDER BY 1"
+	importSpec := dboperatorv1alpha1.ForeignSchemaImport{RemoteSchema: "public", Schema: "prod"}
+
+	mock.ExpectQuery(tablesQuery).WithArgs("prod", "prod").WillReturnRows(sqlmock.NewRows([]string{"relname"}).AddRow("orders"))
+	mock.ExpectExec(`IMPORT FOREIGN SCHEMA "public" EXCEPT ("orders") FROM SERVER "prod" INTO "prod";`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(tablesQuery).WithArgs("prod", "prod").WillReturnRows(sqlmock.NewRows([]string{"relname"}).AddRow("customers").AddRow("orders"))
+	tables, err := ImportForeignSchema(db, "prod", importSpec)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if len(tables) != 2 {
+		t.Errorf("expected 2 foreign tables got %v", tables)
+	}
+
+	importSpec.Tables = []string{"orders", "customers"}
+	mock.ExpectQuery(tablesQuery).WithArgs("prod", "prod").WillReturnRows(sqlmock.NewRows([]string{"relname"}).AddRow("customers").AddRow("orders"))
+	_, err = ImportForeignSchema(db, "prod", importSpec)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func getMappingOptionsHash(userName string, password string) string {
+	return shared.HashSecretValue(buildOptions(getMappingOptions(shared.Credentials{UserName: userName, Password: &password})))
+}
+
+func TestDropForeignServer(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`DROP USER MAPPING IF EXISTS FOR "reporting" SERVER "prod";`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DROP SERVER IF EXISTS "prod";`).WillReturnResult(sqlmock.NewResult(0, 0))
+	err = DropForeignServer(db, "prod", []string{"reporting"}, false)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	mock.ExpectExec(`DROP SERVER IF EXISTS "prod" CASCADE;`).WillReturnResult(sqlmock.NewResult(0, 0))
+	err = DropForeignServer(db, "prod", nil, true)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,143 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/obeleh/db-operator/dbservers/query_utils"
+	"github.com/obeleh/db-operator/shared"
+)
+
+// CockroachDB can't comment on roles, so it keeps the markers in a table
+const MANAGED_OBJECTS_TABLE = "db_operator.managed_objects"
+
+type commentTarget struct {
+	query   string
+	objType string
+}
+
+var COMMENT_TARGETS = map[string]commentTarget{
+	shared.MANAGED_DATABASE: {"SELECT pg_catalog.shobj_description(oid, 'pg_database') FROM pg_catalog.pg_database WHERE datname = $1", "DATABASE"},
+	shared.MANAGED_USER:     {"SELECT pg_catalog.shobj_description(oid, 'pg_authid') FROM pg_catalog.pg_roles WHERE rolname = $1", "ROLE"},
+	shared.MANAGED_SCHEMA:   {"SELECT pg_catalog.obj_description(oid, 'pg_namespace') FROM pg_catalog.pg_namespace WHERE nspname = $1", "SCHEMA"},
+}
+
+func (p *PostgresConnection) GetManagedBy(objectType string, name string) (string, error) {
+	conn, err := p.GetDbConnection(nil, nil)
+	if err != nil {
+		return "", err
+	}
+	if p.Flavor == "cockroachdb" {
+		return GetTrackedManagedBy(conn, objectType, p.trackedName(objectType, name))
+	}
+	return GetCommentManagedBy(conn, objectType, name)
+}
+
+func (p *PostgresConnection) SetManagedBy(objectType string, name string, managedBy string) error {
+	conn, err := p.GetDbConnection(nil, nil)
+	if err != nil {
+		return err
+	}
+	if p.Flavor == "cockroachdb" {
+		return SetTrackedManagedBy(conn, objectType, p.trackedName(objectType, name), managedBy)
+	}
+	return SetCommentManagedBy(conn, objectType, name, managedBy)
+}
+
+func (p *PostgresConnection) ForgetManagedBy(objectType string, name string) error {
+	if p.Flavor != "cockroachdb" {
+		// The comment is dropped together with the object
+		return nil
+	}
+	conn, err := p.GetDbConnection(nil, nil)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Exec(fmt.Sprintf("DELETE FROM %s WHERE object_type = $1 AND object_name = $2", MANAGED_OBJECTS_TABLE), objectType, p.trackedName(objectType, name))
+	return err
+}
+
+// Schemas are tracked as database.schema, the connection points at the database of the schema
+func (p *PostgresConnection) trackedName(objectType string, name string) string {
+	if objectType == shared.MANAGED_SCHEMA {
+		return fmt.Sprintf("%s.%s", p.ServerConnInfo.Database, name)
+	}
+	return name
+}
+
+// The marker is a line of the comment, the other lines are left to the DBA
+func GetCommentManagedBy(conn *sql.DB, objectType string, name string) (string, error) {
+	target, found := COMMENT_TARGETS[objectType]
+	if !found {
+		return "", fmt.Errorf("unknown object type %s", objectType)
+	}
+	comment, err := query_utils.SelectFirstValueStringNullToEmpty(conn, target.query, name)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(comment, "\n") {
+		if strings.HasPrefix(line, shared.MANAGED_BY_PREFIX) {
+			return line, nil
+		}
+	}
+	return "", nil
+}
+
+// Replaces the marker line of the comment and keeps the rest of it
+func SetCommentManagedBy(conn *sql.DB, objectType string, name string, managedBy string) error {
+	target, found := COMMENT_TARGETS[objectType]
+	if !found {
+		return fmt.Errorf("unknown object type %s", objectType)
+	}
+	comment, err := query_utils.SelectFirstValueStringNullToEmpty(conn, target.query, name)
+	if err != nil {
+		return err
+	}
+	lines := []string{}
+	for _, line := range strings.Split(comment, "\n") {
+		if line != "" && !strings.HasPrefix(line, shared.MANAGED_BY_PREFIX) {
+			lines = append(lines, line)
+		}
+	}
+	lines = append(lines, managedBy)
+	_, err = conn.Exec(fmt.Sprintf("COMMENT ON %s %s IS %s", target.objType, pq.QuoteIdentifier(name), pq.QuoteLiteral(strings.Join(lines, "\n"))))
+	return err
+}
+
+func ensureManagedObjectsTable(conn *sql.DB) error {
+	_, err := conn.Exec("CREATE DATABASE IF NOT EXISTS db_operator")
+	if err != nil {
+		return err
+	}
+	_, err = conn.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		object_type STRING NOT NULL,
+		object_name STRING NOT NULL,
+		managed_by STRING NOT NULL,
+		PRIMARY KEY (object_type, object_name)
+	)`, MANAGED_OBJECTS_TABLE))
+	return err
+}
+
+func GetTrackedManagedBy(conn *sql.DB, objectType string, name string) (string, error) {
+	err := ensureManagedObjectsTable(conn)
+	if err != nil {
+		return "", err
+	}
+	managedBy := ""
+	err = conn.QueryRow(fmt.Sprintf("SELECT managed_by FROM %s WHERE object_type = $1 AND object_name = $2", MANAGED_OBJECTS_TABLE), objectType, name).Scan(&managedBy)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return managedBy, err
+}
+
+func SetTrackedManagedBy(conn *sql.DB, objectType string, name string, managedBy string) error {
+	err := ensureManagedObjectsTable(conn)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Exec(fmt.Sprintf("UPSERT INTO %s (object_type, object_name, managed_by) VALUES ($1, $2, $3)", MANAGED_OBJECTS_TABLE), objectType, name, managedBy)
+	return err
+}
@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/obeleh/db-operator/shared"
+)
+
+func TestCommentManagedBy(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	query := "SELECT pg_catalog.shobj_description(oid, 'pg_authid') FROM pg_catalog.pg_roles WHERE rolname = $1"
+	mock.ExpectQuery(query).WithArgs("legacy").WillReturnRows(sqlmock.NewRows([]string{"shobj_description"}).AddRow(nil))
+	mock.ExpectQuery(query).WithArgs("legacy").WillReturnRows(sqlmock.NewRows([]string{"shobj_description"}).AddRow(nil))
+	mock.ExpectExec(`COMMENT ON ROLE "legacy" IS 'managed by db-operator User default/legacy'`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	managedBy, err := GetCommentManagedBy(db, shared.MANAGED_USER, "legacy")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if managedBy != "" {
+		t.Errorf("expected no marker, got %s", managedBy)
+	}
+	err = SetCommentManagedBy(db, shared.MANAGED_USER, "legacy", "managed by db-operator User default/legacy")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCommentManagedByKeepsComment(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	query := "SELECT pg_catalog.shobj_description(oid, 'pg_database') FROM pg_catalog.pg_database WHERE datname = $1"
+	mock.ExpectQuery(query).WithArgs("shop").WillReturnRows(sqlmock.NewRows([]string{"shobj_description"}).AddRow("owned by the shop team"))
+	mock.ExpectQuery(query).WithArgs("shop").WillReturnRows(sqlmock.NewRows([]string{"shobj_description"}).AddRow("owned by the shop team\nmanaged by db-operator Db default/shop"))
+	mock.ExpectQuery(query).WithArgs("shop").WillReturnRows(sqlmock.NewRows([]string{"shobj_description"}).AddRow("owned by the shop team\nmanaged by db-operator Db default/shop"))
+	mock.ExpectExec("COMMENT ON DATABASE \"shop\" IS 'owned by the shop team\nmanaged by db-operator Db default/shop (adopted)'").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// A comment of the DBA is not a marker
+	managedBy, err := GetCommentManagedBy(db, shared.MANAGED_DATABASE, "shop")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if managedBy != "" {
+		t.Errorf("expected no marker, got %s", managedBy)
+	}
+	managedBy, err = GetCommentManagedBy(db, shared.MANAGED_DATABASE, "shop")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if managedBy != "managed by db-operator Db default/shop" {
+		t.Errorf("unexpected marker %s", managedBy)
+	}
+	err = SetCommentManagedBy(db, shared.MANAGED_DATABASE, "shop", "managed by db-operator Db default/shop (adopted)")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestTrackedManagedBy(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherRegexp))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec("CREATE DATABASE IF NOT EXISTS db_operator").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS db_operator.managed_objects").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT managed_by FROM db_operator.managed_objects").WithArgs(shared.MANAGED_SCHEMA, "app.reporting").WillReturnRows(sqlmock.NewRows([]string{"managed_by"}).AddRow("managed by db-operator Schema default/reporting"))
+
+	managedBy, err := GetTrackedManagedBy(db, shared.MANAGED_SCHEMA, "app.reporting")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if managedBy != "managed by db-operator Schema default/reporting" {
+		t.Errorf("unexpected marker %s", managedBy)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
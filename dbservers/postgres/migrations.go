@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/obeleh/db-operator/shared"
+)
+
+func GetAppliedMigrations(conn *sql.DB, historyTable string) (map[string]string, error) {
+	quotedTable := pq.QuoteIdentifier(historyTable)
+	_, err := conn.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		version TEXT PRIMARY KEY,
+		checksum TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`, quotedTable))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create migrations history table %s %s", historyTable, err)
+	}
+
+	rows, err := conn.Query(fmt.Sprintf("SELECT version, checksum FROM %s", quotedTable))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read migrations history %s", err)
+	}
+	defer rows.Close()
+
+	applied := map[string]string{}
+	for rows.Next() {
+		var version, checksum string
+		err = rows.Scan(&version, &checksum)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load migration %s", err)
+		}
+		applied[version] = checksum
+	}
+	return applied, nil
+}
+
+// Without arguments the statements of the migration are sent as one simple query, so a file can hold several of them.
+// On Postgres a transaction level advisory lock keeps two DbMigrations from applying the same migration, CockroachDB
+// has no advisory locks but runs the transaction serializable, so the second insert into the history table fails
+func ApplyMigration(conn *sql.DB, historyTable string, migration shared.Migration, flavor string) error {
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	quotedTable := pq.QuoteIdentifier(historyTable)
+	if flavor != "cockroachdb" {
+		_, err = tx.Exec("SELECT pg_advisory_xact_lock(hashtext($1))", fmt.Sprintf("db-operator %s", historyTable))
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("unable to lock the migrations history %s", err)
+		}
+	}
+	checksum := ""
+	err = tx.QueryRow(fmt.Sprintf("SELECT checksum FROM %s WHERE version = $1", quotedTable), migration.Version).Scan(&checksum)
+	if err == nil {
+		tx.Rollback()
+		return migration.CheckAlreadyApplied(checksum)
+	}
+	if err != sql.ErrNoRows {
+		tx.Rollback()
+		return fmt.Errorf("unable to read migrations history %s", err)
+	}
+
+	_, err = tx.Exec(migration.SQL)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("migration %s failed: %s", migration.Version, err)
+	}
+	_, err = tx.Exec(fmt.Sprintf("INSERT INTO %s (version, checksum) VALUES ($1, $2)", quotedTable), migration.Version, migration.Checksum)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("unable to record migration %s %s", migration.Version, err)
+	}
+	return tx.Commit()
+}
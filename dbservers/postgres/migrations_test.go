@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/obeleh/db-operator/shared"
+)
+
+func TestApplyMigration(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	migration := shared.NewMigration("0001_users.sql", "CREATE TABLE users (id INT PRIMARY KEY);")
+	lockQuery := "SELECT pg_advisory_xact_lock(hashtext($1))"
+	historyQuery := `SELECT checksum FROM "db_operator_migrations" WHERE version = $1`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(lockQuery).WithArgs("db-operator db_operator_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(historyQuery).WithArgs("0001_users.sql").WillReturnRows(sqlmock.NewRows([]string{"checksum"}))
+	mock.ExpectExec(migration.SQL).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO "db_operator_migrations" (version, checksum) VALUES ($1, $2)`).WithArgs("0001_users.sql", migration.Checksum).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	err = ApplyMigration(db, "db_operator_migrations", migration, "postgres")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(lockQuery).WithArgs("db-operator db_operator_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(historyQuery).WithArgs("0001_users.sql").WillReturnRows(sqlmock.NewRows([]string{"checksum"}))
+	mock.ExpectExec(migration.SQL).WillReturnError(fmt.Errorf("relation \"users\" already exists"))
+	mock.ExpectRollback()
+	err = ApplyMigration(db, "db_operator_migrations", migration, "postgres")
+	if err == nil {
+		t.Errorf("expected the failed migration to return an error")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestApplyMigrationAppliedWhileWaiting(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	migration := shared.NewMigration("0001_users.sql", "CREATE TABLE users (id INT PRIMARY KEY);")
+	historyQuery := `SELECT checksum FROM "db_operator_migrations" WHERE version = $1`
+
+	// CockroachDB has no advisory locks
+	mock.ExpectBegin()
+	mock.ExpectQuery(historyQuery).WithArgs("0001_users.sql").WillReturnRows(sqlmock.NewRows([]string{"checksum"}).AddRow(migration.Checksum))
+	mock.ExpectRollback()
+	err = ApplyMigration(db, "db_operator_migrations", migration, "cockroachdb")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(historyQuery).WithArgs("0001_users.sql").WillReturnRows(sqlmock.NewRows([]string{"checksum"}).AddRow("changed"))
+	mock.ExpectRollback()
+	err = ApplyMigration(db, "db_operator_migrations", migration, "cockroachdb")
+	if err == nil {
+		t.Errorf("expected an error for a migration that was applied with another checksum")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
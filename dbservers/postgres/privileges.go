@@ -25,7 +25,7 @@ type PostgresVersion struct {
 }
 
 type ConnectionGetter func(*string, *string) (*sql.DB, error)
-type privsAdjuster func(*sql.DB, string, string, []string) error
+type privsAdjuster func(shared.Executor, string, string, []string) error
 
 const PostgreSQL = "PostgreSQL"
 const CockroachDB = "CockroachDB"
@@ -36,12 +36,14 @@ func (p *PostgresVersion) GetValidPrivs(privType string) []string {
 			"database": {"CREATE", "CONNECT", "TEMPORARY", "TEMP", "ALL"},
 			"table":    {"SELECT", "INSERT", "UPDATE", "DELETE", "TRUNCATE", "REFERENCES", "TRIGGER", "ALL"},
 			"schema":   {"CREATE", "USAGE"}, // accepted by has_schema_privilege
+			"column":   {"SELECT", "INSERT", "UPDATE", "REFERENCES", "ALL"},
 		}[privType]
 	} else if p.ProductName == CockroachDB {
 		return map[string][]string{
 			"database": {"CREATE", "CONNECT", "BACKUP", "RESTORE", "ALL"},
 			"table":    {"SELECT", "INSERT", "UPDATE", "DELETE", "TRUNCATE", "REFERENCES", "TRIGGER", "BACKUP", "ALL"},
 			"schema":   {"CREATE", "USAGE"}, // accepted by has_schema_privilege
+			"column":   {"SELECT", "INSERT", "UPDATE", "REFERENCES", "ALL"},
 		}[privType]
 	}
 	log.Fatalf("Unknown product name %s", p.ProductName)
@@ -249,42 +251,42 @@ func RevokeAllDbPrivs(conn *sql.DB, user string, dbPrivs []dboperatorv1alpha1.Db
 }
 
 func UpdateUserPrivs(conn *sql.DB, userName string, serverPrivs string, dbPrivs []dboperatorv1alpha1.DbPriv, connectionGetter ConnectionGetter) (bool, error) {
-	// Server Privs
-	maps, err := shared.SelectToArrayMap(conn, "SELECT * FROM pg_roles WHERE rolname = $1", userName)
+	return updateUserPrivs(conn, userName, serverPrivs, dbPrivs, connectionGetter, nil)
+}
+
+// Returns the statements UpdateUserPrivs would run
+func PlanUserPrivs(conn *sql.DB, userName string, serverPrivs string, dbPrivs []dboperatorv1alpha1.DbPriv, connectionGetter ConnectionGetter) ([]dboperatorv1alpha1.PlannedStatement, error) {
+	plan := []dboperatorv1alpha1.PlannedStatement{}
+	_, err := updateUserPrivs(conn, userName, serverPrivs, dbPrivs, connectionGetter, &plan)
+	return plan, err
+}
+
+// When plan is set the statements are recorded in it instead of being run
+func updateUserPrivs(conn *sql.DB, userName string, serverPrivs string, dbPrivs []dboperatorv1alpha1.DbPriv, connectionGetter ConnectionGetter, plan *[]dboperatorv1alpha1.PlannedStatement) (bool, error) {
+	var exec shared.Executor = conn
+	if plan != nil {
+		exec = shared.StatementRecorder{Statements: plan}
+	}
+
+	changed := false
+	roleAttrFlags, changingFlags, err := getRoleAttrChanges(conn, userName, serverPrivs)
 	if err != nil {
 		return false, err
 	}
-	changed := false
-	currentRoleAttrs := maps[0]
-	var serverPrivsChanging bool = false
 	// TODO Scan server version and add to parser
-
 	serverVersion, err := getServerVersion(conn)
 	if err != nil {
 		return false, err
 	}
 
-	roleAttrFlags, err := ParseRoleAttrs(serverPrivs, 0)
-	if err != nil {
-		return false, err
-	}
-	if len(roleAttrFlags) > 0 {
-		for _, flag := range roleAttrFlags {
-			roleAttrValue := !strings.HasPrefix(flag, "NO")
-			if currentRoleAttrs[PRIV_TO_AUTHID_COLUMN[flag]] != roleAttrValue {
-				serverPrivsChanging = true
-			}
-		}
-	}
-
-	if serverPrivsChanging {
+	if len(changingFlags) > 0 {
 		escapedUser := pq.QuoteIdentifier(userName)
 		alter := []string{fmt.Sprintf("ALTER USER %s", escapedUser)}
 		if len(roleAttrFlags) > 0 {
 			alter = append(alter, fmt.Sprintf("WITH %s", strings.Join(roleAttrFlags, " ")))
 		}
 
-		_, err = conn.Exec(strings.Join(alter, " "))
+		_, err = exec.Exec(strings.Join(alter, " "))
 		if err != nil {
 			return false, err
 		}
@@ -296,6 +298,13 @@ func UpdateUserPrivs(conn *sql.DB, userName string, serverPrivs string, dbPrivs
 		if err != nil {
 			return changed, err
 		}
+		if plan != nil {
+			err = privReconciler.PlanPrivs(plan)
+			if err != nil {
+				return changed, err
+			}
+			continue
+		}
 		curChange, err := privReconciler.ReconcilePrivs()
 		if err != nil {
 			return changed, err
@@ -305,6 +314,68 @@ func UpdateUserPrivs(conn *sql.DB, userName string, serverPrivs string, dbPrivs
 	return changed, nil
 }
 
+// Returns the role attributes in server_privs and the ones among them the role doesn't have yet
+func getRoleAttrChanges(conn *sql.DB, userName string, serverPrivs string) ([]string, []string, error) {
+	maps, err := shared.SelectToArrayMap(conn, "SELECT * FROM pg_roles WHERE rolname = $1", userName)
+	if err != nil {
+		return nil, nil, err
+	}
+	currentRoleAttrs := maps[0]
+
+	roleAttrFlags, err := ParseRoleAttrs(serverPrivs, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	changingFlags := []string{}
+	for _, flag := range roleAttrFlags {
+		roleAttrValue := !strings.HasPrefix(flag, "NO")
+		if currentRoleAttrs[PRIV_TO_AUTHID_COLUMN[flag]] != roleAttrValue {
+			changingFlags = append(changingFlags, flag)
+		}
+	}
+	return roleAttrFlags, changingFlags, nil
+}
+
+// Role attributes show up as missing on the "server" scope, role attributes that are not in server_privs are not managed.
+// Grants on databases, schemas and tables without a db_privs entry show up as extra on their own scope
+func GetUserPrivsDrift(conn *sql.DB, userName string, serverPrivs string, dbPrivs []dboperatorv1alpha1.DbPriv, connectionGetter ConnectionGetter) ([]shared.PrivsDrift, error) {
+	drifts := []shared.PrivsDrift{}
+	_, changingFlags, err := getRoleAttrChanges(conn, userName, serverPrivs)
+	if err != nil {
+		return nil, err
+	}
+	if len(changingFlags) > 0 {
+		drifts = append(drifts, shared.PrivsDrift{Scope: "server", Missing: changingFlags})
+	}
+
+	serverVersion, err := getServerVersion(conn)
+	if err != nil {
+		return nil, err
+	}
+	for _, dbPriv := range dbPrivs {
+		privReconciler, err := GetPrivsReconciler(userName, dbPriv, serverVersion, connectionGetter)
+		if err != nil {
+			return nil, err
+		}
+		drift, err := privReconciler.GetDrift()
+		if err != nil {
+			return nil, err
+		}
+		if len(drift.Extra) > 0 || len(drift.Missing) > 0 {
+			drifts = append(drifts, drift)
+		}
+	}
+
+	extraGrants, err := getExtraGrants(conn, userName, dbPrivs, serverVersion, connectionGetter)
+	if err != nil {
+		return nil, err
+	}
+	for _, grant := range extraGrants {
+		drifts = append(drifts, shared.PrivsDrift{Scope: grant.scope(), Extra: grant.privs})
+	}
+	return drifts, nil
+}
+
 func revokeDefaultedPrivs(conn *sql.DB, objType string, userName string, schemaName string) error {
 	quotedUserName := pq.QuoteIdentifier(userName)
 	quotedSchemaName := pq.QuoteIdentifier(schemaName)
@@ -335,8 +406,7 @@ func GetSchemas(conn *sql.DB) (map[string]shared.DbSideSchema, error) {
 	return schemas, nil
 }
 
-func GetDatabasesUserHasAccessTo(conn *sql.DB, userName string) ([]string, error) {
-	rows, err := conn.Query(`
+const DATABASES_USER_HAS_ACCESS_TO_QUERY = `
 		SELECT
 			d.datname AS database_name
 		FROM
@@ -347,7 +417,10 @@ func GetDatabasesUserHasAccessTo(conn *sql.DB, userName string) ([]string, error
 			d.datname NOT IN ('template0', 'template1', 'postgres')
 			AND r.rolname = $1
 			AND has_database_privilege(r.rolname, d.datname, 'CONNECT');
-	`, userName)
+	`
+
+func GetDatabasesUserHasAccessTo(conn *sql.DB, userName string) ([]string, error) {
+	rows, err := conn.Query(DATABASES_USER_HAS_ACCESS_TO_QUERY, userName)
 	if err != nil {
 		return nil, err
 	}
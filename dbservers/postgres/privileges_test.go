@@ -9,6 +9,7 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 	_ "github.com/lib/pq"
 	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/shared"
 	"github.com/thoas/go-funk"
 )
 
@@ -313,3 +314,318 @@ func TestGetDefaultPrivileges(t *testing.T) {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
 }
+
+func TestParseColumnPrivs(t *testing.T) {
+	privs, err := parseColumnPrivs("select(email, phone), UPDATE(\"Phone\")")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	expected := []string{"SELECT(email)", "SELECT(phone)", "UPDATE(Phone)"}
+	if !funk.Equal(privs, expected) {
+		t.Errorf("got %s expected %s", privs, expected)
+	}
+}
+
+func TestParseColumnPrivsWithoutColumns(t *testing.T) {
+	_, err := parseColumnPrivs("SELECT")
+	if err == nil {
+		t.Error("expected error parsing column privileges without columns")
+	}
+}
+
+func TestNormalizeColumnPrivileges(t *testing.T) {
+	privs, err := NormalizeColumnPrivileges([]string{"ALL(email)", "SELECT(email)"}, &PostgresVersion{ProductName: PostgreSQL})
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	expected := []string{"SELECT(email)", "INSERT(email)", "UPDATE(email)", "REFERENCES(email)"}
+	missing, unExpected := funk.Difference(expected, privs)
+	if len(missing.([]string)) > 0 {
+		t.Errorf("expected %s privs", missing)
+	}
+	if len(unExpected.([]string)) > 0 {
+		t.Errorf("got unexpected %s privs", unExpected)
+	}
+
+	_, err = NormalizeColumnPrivileges([]string{"DELETE(email)"}, &PostgresVersion{ProductName: PostgreSQL})
+	if err == nil {
+		t.Error("expected error for DELETE on a column")
+	}
+}
+
+func TestReconcileColumnPrivs(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	connGetter := func(userName, databaseName *string) (*sql.DB, error) {
+		return db, nil
+	}
+	dbPriv := dboperatorv1alpha1.DbPriv{
+		Scope:    "testdb.public.customers",
+		Privs:    "SELECT(name,email)",
+		PrivType: "column",
+	}
+	reconciler, err := GetPrivsReconciler("testuser", dbPriv, &PostgresVersion{ProductName: PostgreSQL}, connGetter)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	currentPrivs := sqlmock.NewRows([]string{"privilege_type", "column_name"})
+	currentPrivs.AddRow("SELECT", "name")
+	currentPrivs.AddRow("SELECT", "phone")
+	mock.ExpectQuery(`SELECT acl.privilege_type, att.attname
+	FROM pg_attribute att
+	JOIN pg_class c ON c.oid = att.attrelid
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+	CROSS JOIN LATERAL aclexplode(att.attacl) acl
+	WHERE acl.grantee = (SELECT oid FROM pg_roles WHERE rolname = $1)
+	AND n.nspname = $2
+	AND c.relname = $3
+	AND att.attnum > 0
+	AND NOT att.attisdropped;`).WithArgs("testuser", "public", "customers").WillReturnRows(currentPrivs)
+	mock.ExpectExec(`REVOKE SELECT ("phone") ON TABLE "public"."customers" FROM "testuser"`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`GRANT SELECT ("email") ON TABLE "public"."customers" TO "testuser"`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	changed, err := reconciler.ReconcilePrivs()
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if !changed {
+		t.Error("expected column privileges to change")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPlanColumnPrivs(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	connGetter := func(userName, databaseName *string) (*sql.DB, error) {
+		return db, nil
+	}
+	grantor := "migration-user"
+	dbPriv := dboperatorv1alpha1.DbPriv{
+		Scope:    "testdb.public.customers",
+		Privs:    "SELECT(name,email)",
+		PrivType: "column",
+		Grantor:  &grantor,
+	}
+	reconciler, err := GetPrivsReconciler("testuser", dbPriv, &PostgresVersion{ProductName: PostgreSQL}, connGetter)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	currentPrivs := sqlmock.NewRows([]string{"privilege_type", "column_name"})
+	currentPrivs.AddRow("SELECT", "name")
+	currentPrivs.AddRow("SELECT", "phone")
+	mock.ExpectQuery(`SELECT acl.privilege_type, att.attname
+	FROM pg_attribute att
+	JOIN pg_class c ON c.oid = att.attrelid
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+	CROSS JOIN LATERAL aclexplode(att.attacl) acl
+	WHERE acl.grantee = (SELECT oid FROM pg_roles WHERE rolname = $1)
+	AND n.nspname = $2
+	AND c.relname = $3
+	AND att.attnum > 0
+	AND NOT att.attisdropped;`).WithArgs("testuser", "public", "customers").WillReturnRows(currentPrivs)
+
+	plan := []dboperatorv1alpha1.PlannedStatement{}
+	err = reconciler.PlanPrivs(&plan)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	expected := []dboperatorv1alpha1.PlannedStatement{
+		{Database: "testdb", RunAs: "migration-user", Statement: `REVOKE SELECT ("phone") ON TABLE "public"."customers" FROM "testuser"`},
+		{Database: "testdb", RunAs: "migration-user", Statement: `GRANT SELECT ("email") ON TABLE "public"."customers" TO "testuser"`},
+	}
+	if !reflect.DeepEqual(plan, expected) {
+		t.Errorf("expected plan %v, got %v", expected, plan)
+	}
+
+	// Planning doesn't run anything
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetColumnPrivsDrift(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	connGetter := func(userName, databaseName *string) (*sql.DB, error) {
+		return db, nil
+	}
+	dbPriv := dboperatorv1alpha1.DbPriv{
+		Scope:    "testdb.public.customers",
+		Privs:    "SELECT(name,email)",
+		PrivType: "column",
+	}
+	reconciler, err := GetPrivsReconciler("testuser", dbPriv, &PostgresVersion{ProductName: PostgreSQL}, connGetter)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	currentPrivs := sqlmock.NewRows([]string{"privilege_type", "column_name"})
+	currentPrivs.AddRow("SELECT", "name")
+	currentPrivs.AddRow("UPDATE", "phone")
+	currentPrivs.AddRow("SELECT", "phone")
+	mock.ExpectQuery(`SELECT acl.privilege_type, att.attname
+	FROM pg_attribute att
+	JOIN pg_class c ON c.oid = att.attrelid
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+	CROSS JOIN LATERAL aclexplode(att.attacl) acl
+	WHERE acl.grantee = (SELECT oid FROM pg_roles WHERE rolname = $1)
+	AND n.nspname = $2
+	AND c.relname = $3
+	AND att.attnum > 0
+	AND NOT att.attisdropped;`).WithArgs("testuser", "public", "customers").WillReturnRows(currentPrivs)
+
+	drift, err := reconciler.GetDrift()
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	expected := shared.PrivsDrift{
+		Scope:   "testdb.public.customers",
+		Extra:   []string{"SELECT(phone)", "UPDATE(phone)"},
+		Missing: []string{"SELECT(email)"},
+	}
+	if !reflect.DeepEqual(drift, expected) {
+		t.Errorf("expected drift %v, got %v", expected, drift)
+	}
+	if !reflect.DeepEqual(reconciler.FoundPrivSet, []string{"SELECT(name)", "UPDATE(phone)", "SELECT(phone)"}) {
+		t.Errorf("unexpected FoundPrivSet %v", reconciler.FoundPrivSet)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+// testdb CONNECT, the schema app1 and all its tables are in the spec, the other grants are not
+func expectExtraGrants(mock sqlmock.Sqlmock) {
+	databaseGrants := sqlmock.NewRows([]string{"datname", "nspname", "relname", "privilege_type"})
+	databaseGrants.AddRow("otherdb", "", "", "CONNECT")
+	databaseGrants.AddRow("testdb", "", "", "CONNECT")
+	mock.ExpectQuery(DATABASE_GRANTS_QUERY).WithArgs("testuser").WillReturnRows(databaseGrants)
+
+	databases := sqlmock.NewRows([]string{"database_name"})
+	databases.AddRow("testdb")
+	mock.ExpectQuery(DATABASES_USER_HAS_ACCESS_TO_QUERY).WithArgs("testuser").WillReturnRows(databases)
+
+	schemaGrants := sqlmock.NewRows([]string{"current_database", "nspname", "relname", "privilege_type"})
+	schemaGrants.AddRow("testdb", "app1", "", "USAGE")
+	schemaGrants.AddRow("testdb", "public", "", "CREATE")
+	mock.ExpectQuery(SCHEMA_GRANTS_QUERY).WithArgs("testuser").WillReturnRows(schemaGrants)
+
+	tableGrants := sqlmock.NewRows([]string{"table_catalog", "table_schema", "table_name", "privilege_type"})
+	tableGrants.AddRow("testdb", "app1", "orders", "SELECT")
+	tableGrants.AddRow("testdb", "public", "customers", "UPDATE")
+	tableGrants.AddRow("testdb", "public", "customers", "SELECT")
+	mock.ExpectQuery(TABLE_GRANTS_QUERY).WithArgs("testuser").WillReturnRows(tableGrants)
+}
+
+var extraGrantsDbPrivs = []dboperatorv1alpha1.DbPriv{
+	{Scope: "testdb", Privs: "CONNECT", PrivType: "database"},
+	{Scope: "testdb.app1", Privs: "USAGE", PrivType: "schema"},
+	{Scope: "testdb.app1.ALL", Privs: "SELECT", PrivType: "table"},
+}
+
+func TestGetExtraGrants(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	connGetter := func(userName, databaseName *string) (*sql.DB, error) {
+		return db, nil
+	}
+	expectExtraGrants(mock)
+	grants, err := getExtraGrants(db, "testuser", extraGrantsDbPrivs, &PostgresVersion{ProductName: PostgreSQL}, connGetter)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	drifts := []shared.PrivsDrift{}
+	for _, grant := range grants {
+		drifts = append(drifts, shared.PrivsDrift{Scope: grant.scope(), Extra: grant.privs})
+	}
+	expected := []shared.PrivsDrift{
+		{Scope: "otherdb", Extra: []string{"CONNECT"}},
+		{Scope: "testdb.public", Extra: []string{"CREATE"}},
+		{Scope: "testdb.public.customers", Extra: []string{"SELECT", "UPDATE"}},
+	}
+	if !reflect.DeepEqual(drifts, expected) {
+		t.Errorf("expected extra grants %v, got %v", expected, drifts)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetExtraGrantsDefaultPrivsCoverTables(t *testing.T) {
+	grantor := "migration-user"
+	coverage, err := getPrivsCoverage([]dboperatorv1alpha1.DbPriv{
+		{Scope: "testdb.TABLES", Privs: "SELECT", PrivType: "defaultTable", Grantor: &grantor},
+		{Scope: "otherdb", Privs: "customers:SELECT"},
+	}, &PostgresVersion{ProductName: PostgreSQL})
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if !coverage.covers(&extraGrant{dbName: "testdb", schema: "app1", table: "orders"}) {
+		t.Errorf("expected the default privileges to cover the tables of testdb")
+	}
+	if !coverage.covers(&extraGrant{dbName: "otherdb", schema: "public", table: "customers"}) {
+		t.Errorf("expected otherdb.public.customers to be covered")
+	}
+	if coverage.covers(&extraGrant{dbName: "otherdb", schema: "public", table: "orders"}) {
+		t.Errorf("expected otherdb.public.orders not to be covered")
+	}
+	if coverage.covers(&extraGrant{dbName: "testdb", schema: "app1"}) {
+		t.Errorf("expected the schema app1 not to be covered")
+	}
+}
+
+func TestRevokeExtraUserPrivs(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	connGetter := func(userName, databaseName *string) (*sql.DB, error) {
+		return db, nil
+	}
+	addVersionQueryToMock(mock, FullPostgresVersionString)
+	expectExtraGrants(mock)
+	mock.ExpectExec(`REVOKE CONNECT ON DATABASE "otherdb" FROM "testuser"`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`REVOKE CREATE on SCHEMA "public" FROM "testuser";`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`REVOKE SELECT, UPDATE ON TABLE "public"."customers" FROM "testuser"`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	changed, err := RevokeExtraUserPrivs(db, "testuser", extraGrantsDbPrivs, connGetter)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if !changed {
+		t.Errorf("expected the extra grants to be revoked")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
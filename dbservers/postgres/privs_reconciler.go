@@ -3,9 +3,11 @@ package postgres
 import (
 	"database/sql"
 	"fmt"
+	"sort"
 	"strings"
 
 	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/shared"
 	funk "github.com/thoas/go-funk"
 )
 
@@ -17,6 +19,7 @@ var PRIVS_RECONCILER_CONSTRUCTORS = map[string]privsReconcilerConstructor{
 	"defaultTable": NewDefaultTablePrivsReconciler,
 	"database":     NewDatabasePrivsReconciler,
 	"schema":       NewSchemaPrivsReconciler,
+	"column":       NewColumnPrivsReconciler,
 }
 
 func diffPrivSet(curPrivs []string, privs []string) ([]string, []string, []string) {
@@ -46,16 +49,47 @@ func (r *PrivsReconciler) GetConn() *sql.DB {
 }
 
 func (r *PrivsReconciler) ReconcilePrivs() (bool, error) {
+	return r.reconcilePrivs(r.conn)
+}
+
+// Records the statements ReconcilePrivs would run on the database of the scope, as the grantor when there is one
+func (r *PrivsReconciler) PlanPrivs(plan *[]dboperatorv1alpha1.PlannedStatement) error {
+	recorder := shared.StatementRecorder{
+		Database:   GetDbNameFromScopeName(r.Scope),
+		Statements: plan,
+	}
+	if r.Grantor != nil {
+		recorder.RunAs = *r.Grantor
+	}
+	_, err := r.reconcilePrivs(recorder)
+	return err
+}
+
+// Loads FoundPrivSet from the server and compares it with the DesiredPrivSet
+func (r *PrivsReconciler) GetDrift() (shared.PrivsDrift, error) {
 	curPrivs, err := r.privsGetFun(r.conn, r.UserName, r.scopedName)
 	if err != nil {
-		return false, err
+		return shared.PrivsDrift{}, err
 	}
 	r.FoundPrivSet = curPrivs
-	_, toRevoke, toGrant := diffPrivSet(curPrivs, r.DesiredPrivSet)
+	_, extra, missing := diffPrivSet(r.FoundPrivSet, r.DesiredPrivSet)
+	// Sorted so a plan of the statements always comes out the same
+	sort.Strings(extra)
+	sort.Strings(missing)
+	return shared.PrivsDrift{Scope: r.Scope, Extra: extra, Missing: missing}, nil
+}
+
+func (r *PrivsReconciler) reconcilePrivs(exec shared.Executor) (bool, error) {
+	drift, err := r.GetDrift()
+	if err != nil {
+		return false, err
+	}
+	toRevoke := drift.Extra
+	toGrant := drift.Missing
 
 	changed := false
 	if len(toRevoke) > 0 {
-		err = r.revokeFun(r.conn, r.UserName, r.scopedName, toRevoke)
+		err = r.revokeFun(exec, r.UserName, r.scopedName, toRevoke)
 		if err != nil {
 			return changed, err
 		}
@@ -63,7 +97,7 @@ func (r *PrivsReconciler) ReconcilePrivs() (bool, error) {
 	}
 
 	if len(toGrant) > 0 {
-		err = r.grantFun(r.conn, r.UserName, r.scopedName, toGrant)
+		err = r.grantFun(exec, r.UserName, r.scopedName, toGrant)
 		if err != nil {
 			return changed, err
 		}
@@ -100,6 +134,18 @@ func normalizeDbPriv(dbPriv dboperatorv1alpha1.DbPriv, dbName string) (string, s
 			if err != nil {
 				return "", "", nil, err
 			}
+		} else if privType == "column" {
+			// scope is db.schema.table or db.table
+			parts := strings.SplitN(dbPriv.Scope, ".", 2)
+			if len(parts) != 2 {
+				return "", "", nil, fmt.Errorf("expected table in scope '%s' for column privileges", dbPriv.Scope)
+			}
+			scopedName = parts[1]
+			var err error
+			privSet, err = parseColumnPrivs(dbPriv.Privs)
+			if err != nil {
+				return "", "", nil, err
+			}
 		}
 	} else {
 		if dbPriv.Privs != "" {
@@ -151,30 +197,48 @@ func GetPrivsReconciler(userName string, dbPriv dboperatorv1alpha1.DbPriv, serve
 		return nil, err
 	}
 
+	privType, scopedName, privSet, err := parseDbPriv(dbPriv, serverVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	constructor := PRIVS_RECONCILER_CONSTRUCTORS[privType]
+	return constructor(dbPriv, conn, userName, scopedName, privSet, serverVersion)
+}
+
+// Normalizes the DbPriv and checks the privileges against the server version, doesn't need a connection
+func parseDbPriv(dbPriv dboperatorv1alpha1.DbPriv, serverVersion *PostgresVersion) (string, string, []string, error) {
+	dbName := GetDbNameFromScopeName(dbPriv.Scope)
 	if dbPriv.Privs != "" && dbPriv.DefaultPrivs != "" {
-		return nil, fmt.Errorf("both privs and default privs specified")
+		return "", "", nil, fmt.Errorf("both privs and default privs specified")
 	}
 
 	if strings.Contains(dbPriv.Privs, "/") {
-		return nil, fmt.Errorf("privs cannot contain '/' this is deprecated")
+		return "", "", nil, fmt.Errorf("privs cannot contain '/' this is deprecated")
 	}
 
 	privType, scopedName, privSet, err := normalizeDbPriv(dbPriv, dbName)
 	if err != nil {
-		return nil, err
+		return "", "", nil, err
 	}
 
-	strippedPrivType := strings.ToLower(strings.TrimPrefix(privType, "default"))
-	if !funk.Subset(privSet, serverVersion.GetValidPrivs(strippedPrivType)) {
-		invalidPrivs := strings.Join(funk.Subtract(privSet, serverVersion.GetValidPrivs(strippedPrivType)).([]string), " ")
-		return nil, fmt.Errorf("invalid privs specified for %s: %s", strippedPrivType, invalidPrivs)
-	}
-	privSet = NormalizePrivileges(privSet, privType, serverVersion)
-
-	constructor, ok := PRIVS_RECONCILER_CONSTRUCTORS[privType]
+	_, ok := PRIVS_RECONCILER_CONSTRUCTORS[privType]
 	if !ok {
-		return nil, fmt.Errorf("invalid privType: %s", privType)
+		return "", "", nil, fmt.Errorf("invalid privType: %s", privType)
 	}
 
-	return constructor(dbPriv, conn, userName, scopedName, privSet, serverVersion)
+	if privType == "column" {
+		privSet, err = NormalizeColumnPrivileges(privSet, serverVersion)
+		if err != nil {
+			return "", "", nil, err
+		}
+	} else {
+		strippedPrivType := strings.ToLower(strings.TrimPrefix(privType, "default"))
+		if !funk.Subset(privSet, serverVersion.GetValidPrivs(strippedPrivType)) {
+			invalidPrivs := strings.Join(funk.Subtract(privSet, serverVersion.GetValidPrivs(strippedPrivType)).([]string), " ")
+			return "", "", nil, fmt.Errorf("invalid privs specified for %s: %s", strippedPrivType, invalidPrivs)
+		}
+		privSet = NormalizePrivileges(privSet, privType, serverVersion)
+	}
+	return privType, scopedName, privSet, nil
 }
@@ -0,0 +1,333 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/shared"
+	funk "github.com/thoas/go-funk"
+)
+
+// Operations a publication can publish, in the order of pg_publication
+var PUBLISH_OPERATIONS = []string{"insert", "update", "delete", "truncate"}
+
+// Tables without a schema are in public, like they are in CREATE PUBLICATION
+func qualifyTableName(table string) string {
+	if strings.Contains(table, ".") {
+		return table
+	}
+	return "public." + table
+}
+
+func quoteTableNames(tables []string) string {
+	quoted := []string{}
+	for _, table := range tables {
+		parts := strings.SplitN(qualifyTableName(table), ".", 2)
+		quoted = append(quoted, pq.QuoteIdentifier(parts[0])+"."+pq.QuoteIdentifier(parts[1]))
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func sameStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sortedA := append([]string(nil), a...)
+	sortedB := append([]string(nil), b...)
+	sort.Strings(sortedA)
+	sort.Strings(sortedB)
+	for i := range sortedA {
+		if sortedA[i] != sortedB[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Operations of the spec in the order of PUBLISH_OPERATIONS, all of them when the spec has none
+func getPublishOperations(publicationSpec dboperatorv1alpha1.PublicationSpec) []string {
+	if len(publicationSpec.Operations) == 0 {
+		return PUBLISH_OPERATIONS
+	}
+	operations := []string{}
+	for _, operation := range PUBLISH_OPERATIONS {
+		if funk.ContainsString(publicationSpec.Operations, operation) {
+			operations = append(operations, operation)
+		}
+	}
+	return operations
+}
+
+func ValidatePublication(publicationSpec dboperatorv1alpha1.PublicationSpec) error {
+	if publicationSpec.AllTables && len(publicationSpec.Tables) > 0 {
+		return fmt.Errorf("all_tables can't be combined with tables")
+	}
+	for _, operation := range publicationSpec.Operations {
+		if !funk.ContainsString(PUBLISH_OPERATIONS, operation) {
+			return fmt.Errorf("invalid operation %s, expected one of %s", operation, strings.Join(PUBLISH_OPERATIONS, ", "))
+		}
+	}
+	return nil
+}
+
+func BuildCreatePublication(publicationSpec dboperatorv1alpha1.PublicationSpec) (string, error) {
+	err := ValidatePublication(publicationSpec)
+	if err != nil {
+		return "", err
+	}
+	query := "CREATE PUBLICATION " + pq.QuoteIdentifier(publicationSpec.Name)
+	if publicationSpec.AllTables {
+		query += " FOR ALL TABLES"
+	} else if len(publicationSpec.Tables) > 0 {
+		query += " FOR TABLE " + quoteTableNames(publicationSpec.Tables)
+	}
+	if len(publicationSpec.Operations) > 0 {
+		query += fmt.Sprintf(" WITH (publish = %s)", pq.QuoteLiteral(strings.Join(getPublishOperations(publicationSpec), ", ")))
+	}
+	return query + ";", nil
+}
+
+func GetPublication(conn *sql.DB, name string) (*shared.DbSidePublication, error) {
+	rows, err := conn.Query("SELECT puballtables, pubinsert, pubupdate, pubdelete, pubtruncate FROM pg_publication WHERE pubname = $1", name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read publication %s %s", name, err)
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, nil
+	}
+	publication := shared.DbSidePublication{Name: name}
+	var pubInsert, pubUpdate, pubDelete, pubTruncate bool
+	err = rows.Scan(&publication.AllTables, &pubInsert, &pubUpdate, &pubDelete, &pubTruncate)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load publication %s %s", name, err)
+	}
+	rows.Close()
+	for i, published := range []bool{pubInsert, pubUpdate, pubDelete, pubTruncate} {
+		if published {
+			publication.Operations = append(publication.Operations, PUBLISH_OPERATIONS[i])
+		}
+	}
+
+	publication.Tables, err = getTables(conn, "SELECT schemaname || '.' || tablename FROM pg_publication_tables WHERE pubname = $1 ORDER BY 1", name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read tables of publication %s %s", name, err)
+	}
+	return &publication, nil
+}
+
+func getTables(conn *sql.DB, query string, args ...interface{}) ([]string, error) {
+	rows, err := conn.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tables := []string{}
+	for rows.Next() {
+		var table string
+		err = rows.Scan(&table)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+// Adds and drops tables until the publication has the tables of the spec and sets the published operations
+// A publication can't be switched to or from FOR ALL TABLES
+func UpdatePublication(conn *sql.DB, publicationSpec dboperatorv1alpha1.PublicationSpec) (bool, error) {
+	err := ValidatePublication(publicationSpec)
+	if err != nil {
+		return false, err
+	}
+	current, err := GetPublication(conn, publicationSpec.Name)
+	if err != nil {
+		return false, err
+	}
+	if current == nil {
+		return false, fmt.Errorf("publication %s does not exist", publicationSpec.Name)
+	}
+	if current.AllTables != publicationSpec.AllTables {
+		return false, fmt.Errorf("all_tables of publication %s can't be changed, recreate the publication instead", publicationSpec.Name)
+	}
+
+	quotedName := pq.QuoteIdentifier(publicationSpec.Name)
+	queries := []string{}
+	if !publicationSpec.AllTables {
+		tables := funk.Map(publicationSpec.Tables, qualifyTableName).([]string)
+		toAdd := []string{}
+		for _, table := range tables {
+			if !funk.ContainsString(current.Tables, table) {
+				toAdd = append(toAdd, table)
+			}
+		}
+		toDrop := []string{}
+		for _, table := range current.Tables {
+			if !funk.ContainsString(tables, table) {
+				toDrop = append(toDrop, table)
+			}
+		}
+		if len(toAdd) > 0 {
+			queries = append(queries, fmt.Sprintf("ALTER PUBLICATION %s ADD TABLE %s;", quotedName, quoteTableNames(toAdd)))
+		}
+		if len(toDrop) > 0 {
+			queries = append(queries, fmt.Sprintf("ALTER PUBLICATION %s DROP TABLE %s;", quotedName, quoteTableNames(toDrop)))
+		}
+	}
+	operations := getPublishOperations(publicationSpec)
+	if !sameStrings(current.Operations, operations) {
+		queries = append(queries, fmt.Sprintf("ALTER PUBLICATION %s SET (publish = %s);", quotedName, pq.QuoteLiteral(strings.Join(operations, ", "))))
+	}
+
+	for _, query := range queries {
+		_, err = conn.Exec(query)
+		if err != nil {
+			return false, err
+		}
+	}
+	return len(queries) > 0, nil
+}
+
+func DropPublication(conn *sql.DB, name string) error {
+	_, err := conn.Exec(fmt.Sprintf("DROP PUBLICATION IF EXISTS %s;", pq.QuoteIdentifier(name)))
+	return err
+}
+
+func quoteConnInfoValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	return "'" + strings.ReplaceAll(value, "'", `\'`) + "'"
+}
+
+// Builds the libpq connection string a subscription uses to connect to the source database
+// Certificates can't be passed in a connection string, with verify-ca and verify-full
+// the subscriber uses the root certificate in the home directory of the Postgres server
+// https://www.postgresql.org/docs/current/libpq-connect.html#LIBPQ-CONNSTRING
+func BuildConnInfo(connectInfo *shared.DbServerConnectInfo) string {
+	sslMode, found := connectInfo.Options["sslmode"]
+	if !found {
+		sslMode = shared.SSL_MODE_REQUIRE
+	}
+	parts := []string{
+		"host=" + quoteConnInfoValue(connectInfo.Host),
+		fmt.Sprintf("port=%d", connectInfo.Port),
+		"dbname=" + quoteConnInfoValue(connectInfo.Database),
+		"user=" + quoteConnInfoValue(connectInfo.UserName),
+	}
+	if connectInfo.Password != nil {
+		parts = append(parts, "password="+quoteConnInfoValue(*connectInfo.Password))
+	}
+	parts = append(parts, "sslmode="+quoteConnInfoValue(sslMode))
+	return strings.Join(parts, " ")
+}
+
+func BuildCreateSubscription(subscriptionSpec dboperatorv1alpha1.SubscriptionSpec, connInfo string) string {
+	query := fmt.Sprintf("CREATE SUBSCRIPTION %s CONNECTION %s PUBLICATION %s",
+		pq.QuoteIdentifier(subscriptionSpec.Name), pq.QuoteLiteral(connInfo), quoteIdentifiers(subscriptionSpec.Publications))
+	options := []string{}
+	if subscriptionSpec.SlotName != "" {
+		options = append(options, "slot_name = "+pq.QuoteLiteral(subscriptionSpec.SlotName))
+	}
+	if subscriptionSpec.CreateSlot != nil {
+		options = append(options, fmt.Sprintf("create_slot = %t", *subscriptionSpec.CreateSlot))
+	}
+	if subscriptionSpec.CopyData != nil {
+		options = append(options, fmt.Sprintf("copy_data = %t", *subscriptionSpec.CopyData))
+	}
+	if len(options) > 0 {
+		query += " WITH (" + strings.Join(options, ", ") + ")"
+	}
+	return query + ";"
+}
+
+func GetSubscription(conn *sql.DB, name string) (*shared.DbSideSubscription, error) {
+	rows, err := conn.Query("SELECT s.oid, array_to_string(s.subpublications, ',') FROM pg_subscription s JOIN pg_database d ON d.oid = s.subdbid WHERE d.datname = current_database() AND s.subname = $1", name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read subscription %s %s", name, err)
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, nil
+	}
+	subscription := shared.DbSideSubscription{Name: name}
+	var oid int64
+	var publications string
+	err = rows.Scan(&oid, &publications)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load subscription %s %s", name, err)
+	}
+	rows.Close()
+	subscription.Publications = strings.Split(publications, ",")
+
+	subscription.Tables, err = getTables(conn, "SELECT n.nspname || '.' || c.relname FROM pg_subscription_rel r JOIN pg_class c ON c.oid = r.srrelid JOIN pg_namespace n ON n.oid = c.relnamespace WHERE r.srsubid = $1 ORDER BY 1", oid)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read tables of subscription %s %s", name, err)
+	}
+
+	// Only the apply worker has no relid, the others are copying the data of a table
+	statRows, err := conn.Query("SELECT COALESCE(received_lsn::text, ''), COALESCE(latest_end_lsn::text, ''), EXTRACT(EPOCH FROM now() - latest_end_time) FROM pg_stat_subscription WHERE subid = $1 AND relid IS NULL", oid)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read stats of subscription %s %s", name, err)
+	}
+	defer statRows.Close()
+	if statRows.Next() {
+		var lagSeconds sql.NullFloat64
+		err = statRows.Scan(&subscription.ReceivedLsn, &subscription.LatestEndLsn, &lagSeconds)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load stats of subscription %s %s", name, err)
+		}
+		if lagSeconds.Valid {
+			lag := time.Duration(lagSeconds.Float64 * float64(time.Second))
+			subscription.Lag = &lag
+		}
+	}
+	return &subscription, nil
+}
+
+// Sets the connection and the publications of the subscription to the spec
+// Only superusers can read the connection of a subscription, it's only set when connInfo is not empty
+// When the publications are the same but the source publishes other tables the subscription is refreshed
+// Setting the publications refreshes the subscription as well
+func UpdateSubscription(conn *sql.DB, subscriptionSpec dboperatorv1alpha1.SubscriptionSpec, connInfo string, publishedTables []string) (bool, error) {
+	current, err := GetSubscription(conn, subscriptionSpec.Name)
+	if err != nil {
+		return false, err
+	}
+	if current == nil {
+		return false, fmt.Errorf("subscription %s does not exist", subscriptionSpec.Name)
+	}
+
+	refreshOptions := ""
+	if subscriptionSpec.CopyData != nil {
+		refreshOptions = fmt.Sprintf(" WITH (copy_data = %t)", *subscriptionSpec.CopyData)
+	}
+	quotedName := pq.QuoteIdentifier(subscriptionSpec.Name)
+	queries := []string{}
+	if connInfo != "" {
+		queries = append(queries, fmt.Sprintf("ALTER SUBSCRIPTION %s CONNECTION %s;", quotedName, pq.QuoteLiteral(connInfo)))
+	}
+	if !sameStrings(current.Publications, subscriptionSpec.Publications) {
+		queries = append(queries, fmt.Sprintf("ALTER SUBSCRIPTION %s SET PUBLICATION %s%s;", quotedName, quoteIdentifiers(subscriptionSpec.Publications), refreshOptions))
+	} else if publishedTables != nil && !sameStrings(current.Tables, publishedTables) {
+		queries = append(queries, fmt.Sprintf("ALTER SUBSCRIPTION %s REFRESH PUBLICATION%s;", quotedName, refreshOptions))
+	}
+
+	for _, query := range queries {
+		_, err = conn.Exec(query)
+		if err != nil {
+			return false, err
+		}
+	}
+	return len(queries) > 0, nil
+}
+
+// Also drops the replication slot on the source when the subscription has one
+func DropSubscription(conn *sql.DB, name string) error {
+	_, err := conn.Exec(fmt.Sprintf("DROP SUBSCRIPTION IF EXISTS %s;", pq.QuoteIdentifier(name)))
+	return err
+}
@@ -0,0 +1,156 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/shared"
+)
+
+const publicationQuery = "SELECT puballtables, pubinsert, pubupdate, pubdelete, pubtruncate FROM pg_publication WHERE pubname = $1"
+const publicationTablesQuery = "SELECT schemaname || '.' || tablename FROM pg_publication_tables WHERE pubname = $1 ORDER BY 1"
+
+func TestBuildCreatePublication(t *testing.T) {
+	query, err := BuildCreatePublication(dboperatorv1alpha1.PublicationSpec{Name: "orders", Tables: []string{"orders", "sales.Lines"}, Operations: []string{"update", "insert"}})
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	expected := `CREATE PUBLICATION "orders" FOR TABLE "public"."orders", "sales"."Lines" WITH (publish = 'insert, update');`
+	if query != expected {
+		t.Errorf("expected %s got %s", expected, query)
+	}
+
+	query, err = BuildCreatePublication(dboperatorv1alpha1.PublicationSpec{Name: "everything", AllTables: true})
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	expected = `CREATE PUBLICATION "everything" FOR ALL TABLES;`
+	if query != expected {
+		t.Errorf("expected %s got %s", expected, query)
+	}
+
+	_, err = BuildCreatePublication(dboperatorv1alpha1.PublicationSpec{Name: "both", AllTables: true, Tables: []string{"orders"}})
+	if err == nil {
+		t.Errorf("expected all_tables combined with tables to be refused")
+	}
+}
+
+func TestUpdatePublication(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	publicationSpec := dboperatorv1alpha1.PublicationSpec{Name: "orders", Tables: []string{"orders", "sales.lines"}}
+	mock.ExpectQuery(publicationQuery).WithArgs("orders").WillReturnRows(
+		sqlmock.NewRows([]string{"puballtables", "pubinsert", "pubupdate", "pubdelete", "pubtruncate"}).AddRow(false, true, true, false, false),
+	)
+	mock.ExpectQuery(publicationTablesQuery).WithArgs("orders").WillReturnRows(
+		sqlmock.NewRows([]string{"table"}).AddRow("public.customers").AddRow("public.orders"),
+	)
+	mock.ExpectExec(`ALTER PUBLICATION "orders" ADD TABLE "sales"."lines";`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`ALTER PUBLICATION "orders" DROP TABLE "public"."customers";`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`ALTER PUBLICATION "orders" SET (publish = 'insert, update, delete, truncate');`).WillReturnResult(sqlmock.NewResult(0, 0))
+	changed, err := UpdatePublication(db, publicationSpec)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if !changed {
+		t.Errorf("expected the publication to change")
+	}
+
+	mock.ExpectQuery(publicationQuery).WithArgs("orders").WillReturnRows(
+		sqlmock.NewRows([]string{"puballtables", "pubinsert", "pubupdate", "pubdelete", "pubtruncate"}).AddRow(true, true, true, true, true),
+	)
+	mock.ExpectQuery(publicationTablesQuery).WithArgs("orders").WillReturnRows(sqlmock.NewRows([]string{"table"}))
+	_, err = UpdatePublication(db, publicationSpec)
+	if err == nil {
+		t.Errorf("expected switching from all_tables to be refused")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestBuildConnInfo(t *testing.T) {
+	password := `it's a \secret`
+	connectInfo := &shared.DbServerConnectInfo{
+		Host:        "source.db.svc",
+		Port:        5432,
+		Credentials: shared.Credentials{UserName: "postgres", Password: &password},
+		Database:    "shop",
+		Options:     map[string]string{"sslmode": "verify-full"},
+	}
+	connInfo := BuildConnInfo(connectInfo)
+	expected := `host='source.db.svc' port=5432 dbname='shop' user='postgres' password='it\'s a \\secret' sslmode='verify-full'`
+	if connInfo != expected {
+		t.Errorf("expected %s got %s", expected, connInfo)
+	}
+}
+
+func TestBuildCreateSubscription(t *testing.T) {
+	createSlot := false
+	query := BuildCreateSubscription(dboperatorv1alpha1.SubscriptionSpec{Name: "shop", Publications: []string{"orders", "customers"}, SlotName: "shop_slot", CreateSlot: &createSlot}, "host='source' port=5432")
+	expected := `CREATE SUBSCRIPTION "shop" CONNECTION 'host=''source'' port=5432' PUBLICATION "orders", "customers" WITH (slot_name = 'shop_slot', create_slot = false);`
+	if query != expected {
+		t.Errorf("expected %s got %s", expected, query)
+	}
+}
+
+func TestUpdateSubscription(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	subscriptionQuery := "SELECT s.oid, array_to_string(s.subpublications, ',') FROM pg_subscription s JOIN pg_database d ON d.oid = s.subdbid WHERE d.datname = current_database() AND s.subname = $1"
+	tablesQuery := "SELECT n.nspname || '.' || c.relname FROM pg_subscription_rel r JOIN pg_class c ON c.oid = r.srrelid JOIN pg_namespace n ON n.oid = c.relnamespace WHERE r.srsubid = $1 ORDER BY 1"
+	statsQuery := "SELECT COALESCE(received_lsn::text, ''), COALESCE(latest_end_lsn::text, ''), EXTRACT(EPOCH FROM now() - latest_end_time) FROM pg_stat_subscription WHERE subid = $1 AND relid IS NULL"
+	expectSubscription := func() {
+		mock.ExpectQuery(subscriptionQuery).WithArgs("shop").WillReturnRows(
+			sqlmock.NewRows([]string{"oid", "subpublications"}).AddRow(16400, "orders"),
+		)
+		mock.ExpectQuery(tablesQuery).WithArgs(16400).WillReturnRows(sqlmock.NewRows([]string{"table"}).AddRow("public.orders"))
+		mock.ExpectQuery(statsQuery).WithArgs(16400).WillReturnRows(
+			sqlmock.NewRows([]string{"received_lsn", "latest_end_lsn", "lag"}).AddRow("0/1A2B3C4", "0/1A2B3C4", 2.5),
+		)
+	}
+
+	expectSubscription()
+	subscription, err := GetSubscription(db, "shop")
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if subscription.ReceivedLsn != "0/1A2B3C4" || subscription.Lag == nil || *subscription.Lag != 2500*time.Millisecond {
+		t.Errorf("unexpected stats %+v", subscription)
+	}
+
+	subscriptionSpec := dboperatorv1alpha1.SubscriptionSpec{Name: "shop", Publications: []string{"orders"}}
+	expectSubscription()
+	mock.ExpectExec(`ALTER SUBSCRIPTION "shop" CONNECTION 'host=''source''';`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`ALTER SUBSCRIPTION "shop" REFRESH PUBLICATION;`).WillReturnResult(sqlmock.NewResult(0, 0))
+	changed, err := UpdateSubscription(db, subscriptionSpec, "host='source'", []string{"public.lines", "public.orders"})
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if !changed {
+		t.Errorf("expected the subscription to change")
+	}
+
+	subscriptionSpec.Publications = []string{"orders", "customers"}
+	expectSubscription()
+	mock.ExpectExec(`ALTER SUBSCRIPTION "shop" SET PUBLICATION "orders", "customers";`).WillReturnResult(sqlmock.NewResult(0, 0))
+	_, err = UpdateSubscription(db, subscriptionSpec, "", []string{"public.orders"})
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,132 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/obeleh/db-operator/dbservers/query_utils"
+	funk "github.com/thoas/go-funk"
+)
+
+// Settings that take a list of values, these are SET as a list of literals instead of one literal
+var LIST_SETTINGS = []string{"search_path", "temp_tablespaces", "local_preload_libraries", "session_preload_libraries"}
+
+func splitRoleSettingValue(name string, value string) []string {
+	if !funk.ContainsString(LIST_SETTINGS, name) {
+		return []string{value}
+	}
+	return funk.FilterString(funk.Map(strings.Split(value, ","), strings.TrimSpace).([]string), StringNotEmpty)
+}
+
+// Normalizes settings to the name=value format used in pg_db_role_setting.setconfig
+func normalizeRoleSettings(roleSettings map[string]string) []string {
+	normalized := []string{}
+	for name, value := range roleSettings {
+		name = strings.ToLower(strings.TrimSpace(name))
+		normalized = append(normalized, fmt.Sprintf("%s=%s", name, strings.Join(splitRoleSettingValue(name, value), ", ")))
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+func splitRoleSetting(roleSetting string) (string, string) {
+	parts := strings.SplitN(roleSetting, "=", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+func getRoleSettings(conn *sql.DB, userName string) ([]string, error) {
+	// setdatabase = 0 holds the settings that apply to the role in every database
+	return query_utils.SelectFirstValueStringSlice(
+		conn,
+		"SELECT unnest(s.setconfig) FROM pg_db_role_setting s JOIN pg_roles r ON r.oid = s.setrole WHERE r.rolname = $1 AND s.setdatabase = 0",
+		userName,
+	)
+}
+
+func reconcileRoleSettings(conn *sql.DB, userName string, roleSettings map[string]string) (bool, error) {
+	curSettings, err := getRoleSettings(conn, userName)
+	if err != nil {
+		return false, fmt.Errorf("unable to read role settings %s", err)
+	}
+	desiredSettings := normalizeRoleSettings(roleSettings)
+	_, otherCurrent, toSet := diffPrivSet(curSettings, desiredSettings)
+	desiredNames := funk.Map(desiredSettings, func(setting string) string {
+		name, _ := splitRoleSetting(setting)
+		return name
+	}).([]string)
+
+	quotedUserName := pq.QuoteIdentifier(userName)
+	changed := false
+	for _, setting := range otherCurrent {
+		name, _ := splitRoleSetting(setting)
+		if funk.ContainsString(desiredNames, name) {
+			// Changed value, the SET below overwrites it
+			continue
+		}
+		_, err = conn.Exec(fmt.Sprintf("ALTER ROLE %s RESET %s", quotedUserName, pq.QuoteIdentifier(name)))
+		if err != nil {
+			return changed, err
+		}
+		changed = true
+	}
+	for _, setting := range toSet {
+		name, value := splitRoleSetting(setting)
+		values := funk.Map(splitRoleSettingValue(name, value), pq.QuoteLiteral).([]string)
+		_, err = conn.Exec(fmt.Sprintf("ALTER ROLE %s SET %s = %s", quotedUserName, pq.QuoteIdentifier(name), strings.Join(values, ", ")))
+		if err != nil {
+			return changed, err
+		}
+		changed = true
+	}
+	return changed, nil
+}
+
+// Reconciles CONNECTION LIMIT, VALID UNTIL and the per role settings
+// Options that are not set are left as they are on the server
+func UpdateRoleOptions(conn *sql.DB, userName string, connectionLimit *int, validUntil string, roleSettings map[string]string) (bool, error) {
+	alter := []string{}
+	if connectionLimit != nil {
+		curConnectionLimit, err := query_utils.SelectFirstValueInt(conn, "SELECT rolconnlimit FROM pg_roles WHERE rolname = $1", userName)
+		if err != nil {
+			return false, fmt.Errorf("unable to read connection limit %s", err)
+		}
+		if curConnectionLimit != *connectionLimit {
+			alter = append(alter, fmt.Sprintf("CONNECTION LIMIT %d", *connectionLimit))
+		}
+	}
+
+	if validUntil != "" {
+		// Let the server parse the timestamp so we don't have to deal with formats and time zones
+		validUntilEqual, err := query_utils.SelectFirstValueBool(conn, "SELECT rolvaliduntil IS NOT DISTINCT FROM $2::timestamptz FROM pg_roles WHERE rolname = $1", userName, validUntil)
+		if err != nil {
+			return false, fmt.Errorf("unable to compare valid until %s", err)
+		}
+		if !validUntilEqual {
+			alter = append(alter, fmt.Sprintf("VALID UNTIL %s", pq.QuoteLiteral(validUntil)))
+		}
+	}
+
+	changed := false
+	if len(alter) > 0 {
+		_, err := conn.Exec(fmt.Sprintf("ALTER ROLE %s WITH %s", pq.QuoteIdentifier(userName), strings.Join(alter, " ")))
+		if err != nil {
+			return false, err
+		}
+		changed = true
+	}
+
+	if roleSettings != nil {
+		settingsChanged, err := reconcileRoleSettings(conn, userName, roleSettings)
+		if err != nil {
+			return changed, err
+		}
+		changed = changed || settingsChanged
+	}
+	return changed, nil
+}
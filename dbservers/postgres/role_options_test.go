@@ -0,0 +1,55 @@
+package postgres
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestNormalizeRoleSettings(t *testing.T) {
+	normalized := normalizeRoleSettings(map[string]string{
+		"Statement_Timeout": "30s",
+		"search_path":       "\"$user\",public, app",
+	})
+	expected := []string{"search_path=\"$user\", public, app", "statement_timeout=30s"}
+	if !reflect.DeepEqual(normalized, expected) {
+		t.Errorf("expected %v, got %v", expected, normalized)
+	}
+}
+
+func TestUpdateRoleOptions(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT rolconnlimit FROM pg_roles WHERE rolname = $1").WithArgs("testuser").WillReturnRows(sqlmock.NewRows([]string{"rolconnlimit"}).AddRow(-1))
+	mock.ExpectQuery("SELECT rolvaliduntil IS NOT DISTINCT FROM $2::timestamptz FROM pg_roles WHERE rolname = $1").WithArgs("testuser", "2030-01-01T00:00:00Z").WillReturnRows(sqlmock.NewRows([]string{"?column?"}).AddRow(true))
+	mock.ExpectExec(`ALTER ROLE "testuser" WITH CONNECTION LIMIT 5`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	currentSettings := sqlmock.NewRows([]string{"unnest"})
+	currentSettings.AddRow("statement_timeout=10s")
+	currentSettings.AddRow("work_mem=64MB")
+	mock.ExpectQuery("SELECT unnest(s.setconfig) FROM pg_db_role_setting s JOIN pg_roles r ON r.oid = s.setrole WHERE r.rolname = $1 AND s.setdatabase = 0").WithArgs("testuser").WillReturnRows(currentSettings)
+	mock.ExpectExec(`ALTER ROLE "testuser" RESET "work_mem"`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`ALTER ROLE "testuser" SET "search_path" = 'app', 'public'`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`ALTER ROLE "testuser" SET "statement_timeout" = '30s'`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	connectionLimit := 5
+	changed, err := UpdateRoleOptions(db, "testuser", &connectionLimit, "2030-01-01T00:00:00Z", map[string]string{
+		"statement_timeout": "30s",
+		"search_path":       "app, public",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if !changed {
+		t.Errorf("expected changes")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
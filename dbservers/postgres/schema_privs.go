@@ -8,6 +8,7 @@ import (
 	"github.com/lib/pq"
 	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
 	"github.com/obeleh/db-operator/dbservers/query_utils"
+	"github.com/obeleh/db-operator/shared"
 )
 
 func NewSchemaPrivsReconciler(privs dboperatorv1alpha1.DbPriv, conn *sql.DB, userName string, schemaName string, normalizedPrivSet []string, serverVersion *PostgresVersion) (*PrivsReconciler, error) {
@@ -49,7 +50,7 @@ func getSchemaPrivileges(conn *sql.DB, user string, schema string) ([]string, er
 	return schemaPrivs, nil
 }
 
-func grantSchemaPrivileges(conn *sql.DB, user string, schemaName string, privs []string) error {
+func grantSchemaPrivileges(conn shared.Executor, user string, schemaName string, privs []string) error {
 	privsStr := strings.Join(privs, ", ")
 	escapedSchema := pq.QuoteIdentifier(schemaName)
 	escapedUser := pq.QuoteIdentifier(user)
@@ -59,7 +60,7 @@ func grantSchemaPrivileges(conn *sql.DB, user string, schemaName string, privs [
 	return err
 }
 
-func revokeSchemaPrivileges(conn *sql.DB, user string, schemaName string, privs []string) error {
+func revokeSchemaPrivileges(conn shared.Executor, user string, schemaName string, privs []string) error {
 	privsStr := strings.Join(privs, ", ")
 	escapedSchema := pq.QuoteIdentifier(schemaName)
 	escapedUser := pq.QuoteIdentifier(user)
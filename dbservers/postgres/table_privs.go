@@ -7,6 +7,7 @@ import (
 
 	"github.com/lib/pq"
 	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/shared"
 )
 
 func NewTablePrivsReconciler(privs dboperatorv1alpha1.DbPriv, conn *sql.DB, userName string, scopedName string, normalizedPrivSet []string, serverVersion *PostgresVersion) (*PrivsReconciler, error) {
@@ -72,7 +73,7 @@ func getTablePrivileges(conn *sql.DB, user string, table string) ([]string, erro
 	return tablePrivs, nil
 }
 
-func grantTablePrivileges(conn *sql.DB, user string, table string, privs []string) error {
+func grantTablePrivileges(conn shared.Executor, user string, table string, privs []string) error {
 	privSet := strings.Join(privs, ", ")
 	quotedTableName := pq.QuoteIdentifier(table)
 	quotedUserName := pq.QuoteIdentifier(user)
@@ -80,7 +81,7 @@ func grantTablePrivileges(conn *sql.DB, user string, table string, privs []strin
 	return err
 }
 
-func revokeTablePrivileges(conn *sql.DB, user string, table string, privs []string) error {
+func revokeTablePrivileges(conn shared.Executor, user string, table string, privs []string) error {
 	privSet := strings.Join(privs, ", ")
 	quotedTableName := pq.QuoteIdentifier(table)
 	quotedUserName := pq.QuoteIdentifier(user)
@@ -111,7 +112,7 @@ func getTablePrivilegesForAllTables(conn *sql.DB, user string, schema string, pr
 	return privsFound, nil
 }
 
-func grantPrivilegesOnAllTables(conn *sql.DB, user string, schema string, privs []string) error {
+func grantPrivilegesOnAllTables(conn shared.Executor, user string, schema string, privs []string) error {
 	privSet := strings.Join(privs, ", ")
 	quotedSchemaName := pq.QuoteIdentifier(schema)
 	quotedUserName := pq.QuoteIdentifier(user)
@@ -119,7 +120,7 @@ func grantPrivilegesOnAllTables(conn *sql.DB, user string, schema string, privs
 	return err
 }
 
-func revokePrivilegesOnAllTables(conn *sql.DB, user string, schema string, privs []string) error {
+func revokePrivilegesOnAllTables(conn shared.Executor, user string, schema string, privs []string) error {
 	privSet := strings.Join(privs, ", ")
 	quotedSchemaName := pq.QuoteIdentifier(schema)
 	quotedUserName := pq.QuoteIdentifier(user)
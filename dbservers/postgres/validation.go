@@ -0,0 +1,25 @@
+package postgres
+
+import (
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+)
+
+// Validation without a connection, the server version is unknown so the product is all we go on
+
+func getProductVersion(flavor string) *PostgresVersion {
+	if flavor == "cockroachdb" {
+		return &PostgresVersion{ProductName: CockroachDB}
+	}
+	return &PostgresVersion{ProductName: PostgreSQL}
+}
+
+func ValidateDbPriv(dbPriv dboperatorv1alpha1.DbPriv, flavor string) error {
+	_, _, _, err := parseDbPriv(dbPriv, getProductVersion(flavor))
+	return err
+}
+
+func ValidateServerPrivs(serverPrivs string) error {
+	// Same version as UpdateUserPrivs uses
+	_, err := ParseRoleAttrs(serverPrivs, 0)
+	return err
+}
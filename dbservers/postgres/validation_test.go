@@ -0,0 +1,54 @@
+package postgres
+
+import (
+	"testing"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+)
+
+func TestValidateDbPriv(t *testing.T) {
+	validPrivs := []dboperatorv1alpha1.DbPriv{
+		{Scope: "mydb", Privs: "CONNECT,TEMP"},
+		{Scope: "mydb.public", Privs: "USAGE", PrivType: "schema"},
+		{Scope: "mydb", Privs: "public.table1:SELECT,DELETE"},
+		{Scope: "mydb.table1", Privs: "SELECT(col1, col2)", PrivType: "column"},
+	}
+	for _, dbPriv := range validPrivs {
+		err := ValidateDbPriv(dbPriv, "postgres")
+		if err != nil {
+			t.Errorf("unexpected error validating %s %s: %s", dbPriv.Scope, dbPriv.Privs, err)
+		}
+	}
+
+	invalidPrivs := []dboperatorv1alpha1.DbPriv{
+		{Scope: "mydb", Privs: "/table1:selct"},
+		{Scope: "mydb", Privs: "table1:SELCT"},
+		{Scope: "mydb", Privs: "CONNECT", DefaultPrivs: "SELECT"},
+		{Scope: "mydb", Privs: "CONNECT", PrivType: "sequence"},
+		{Scope: "mydb.table1", Privs: "DELETE(col1)", PrivType: "column"},
+	}
+	for _, dbPriv := range invalidPrivs {
+		err := ValidateDbPriv(dbPriv, "postgres")
+		if err == nil {
+			t.Errorf("expected error validating %s %s", dbPriv.Scope, dbPriv.Privs)
+		}
+	}
+
+	// BACKUP only exists on CockroachDB
+	backupPriv := dboperatorv1alpha1.DbPriv{Scope: "mydb", Privs: "BACKUP"}
+	if ValidateDbPriv(backupPriv, "cockroachdb") != nil {
+		t.Errorf("expected BACKUP to be valid on CockroachDB")
+	}
+	if ValidateDbPriv(backupPriv, "postgres") == nil {
+		t.Errorf("expected BACKUP to be invalid on Postgres")
+	}
+}
+
+func TestValidateServerPrivs(t *testing.T) {
+	if err := ValidateServerPrivs("LOGIN,CREATEDB"); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+	if ValidateServerPrivs("LOGIN,SUPERUSR") == nil {
+		t.Errorf("expected error for invalid role attribute")
+	}
+}
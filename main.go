@@ -38,7 +38,10 @@ import (
 	ctrlZap "sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	dboperatorv1beta1 "github.com/obeleh/db-operator/api/v1beta1"
 	"github.com/obeleh/db-operator/controllers"
+	"github.com/obeleh/db-operator/shared"
+	"github.com/obeleh/db-operator/webhooks"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	//+kubebuilder:scaffold:imports
 )
@@ -52,6 +55,7 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(dboperatorv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(dboperatorv1beta1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
@@ -107,6 +111,11 @@ func main() {
 
 	controllers.InitializeDbServerChannel()
 
+	if err = mgr.Add(shared.SharedConnectionPool); err != nil {
+		setupLog.Error(err, "unable to add connection pool")
+		os.Exit(1)
+	}
+
 	if err = (&controllers.DbCopyJobReconciler{
 		Client: mgr.GetClient(),
 		Log:    logger.With(zap.Namespace("DbCopyJobReconciler")),
@@ -140,9 +149,10 @@ func main() {
 		os.Exit(1)
 	}
 	if err = (&controllers.DbReconciler{
-		Client: mgr.GetClient(),
-		Log:    logger.With(zap.Namespace("DbReconciler")),
-		Scheme: mgr.GetScheme(),
+		Client:   mgr.GetClient(),
+		Log:      logger.With(zap.Namespace("DbReconciler")),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("db-operator"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Db")
 		os.Exit(1)
@@ -155,6 +165,14 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "DbCopyCronJob")
 		os.Exit(1)
 	}
+	if err = (&controllers.ClusterDbServerReconciler{
+		Client: mgr.GetClient(),
+		Log:    logger.With(zap.Namespace("ClusterDbServerReconciler")),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ClusterDbServer")
+		os.Exit(1)
+	}
 	if err = (&controllers.DbServerReconciler{
 		Client: mgr.GetClient(),
 		Log:    logger.With(zap.Namespace("DbServerReconciler")),
@@ -163,6 +181,14 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "DbServer")
 		os.Exit(1)
 	}
+	if err = (&controllers.DbServerInventoryReconciler{
+		Client: mgr.GetClient(),
+		Log:    logger.With(zap.Namespace("DbServerInventoryReconciler")),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "DbServerInventory")
+		os.Exit(1)
+	}
 	if err = (&controllers.RestoreCronJobReconciler{
 		Client: mgr.GetClient(),
 		Log:    logger.With(zap.Namespace("RestoreCronJobReconciler")),
@@ -196,9 +222,10 @@ func main() {
 		os.Exit(1)
 	}
 	if err = (&controllers.UserReconciler{
-		Client: mgr.GetClient(),
-		Log:    logger.With(zap.Namespace("UserReconciler")),
-		Scheme: mgr.GetScheme(),
+		Client:   mgr.GetClient(),
+		Log:      logger.With(zap.Namespace("UserReconciler")),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("db-operator"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "User")
 		os.Exit(1)
@@ -212,11 +239,65 @@ func main() {
 		os.Exit(1)
 	}
 	if err = (&controllers.SchemaReconciler{
+		Client:   mgr.GetClient(),
+		Log:      logger.With(zap.Namespace("SchemaReconciler")),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("db-operator"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Schema")
+		os.Exit(1)
+	}
+	if err = (&controllers.ExtensionReconciler{
+		Client:   mgr.GetClient(),
+		Log:      logger.With(zap.Namespace("ExtensionReconciler")),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("db-operator"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Extension")
+		os.Exit(1)
+	}
+	if err = (&controllers.DbMigrationReconciler{
+		Client:   mgr.GetClient(),
+		Log:      logger.With(zap.Namespace("DbMigrationReconciler")),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("db-operator"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "DbMigration")
+		os.Exit(1)
+	}
+	if err = (&controllers.SqlCronJobReconciler{
 		Client: mgr.GetClient(),
-		Log:    logger.With(zap.Namespace("SchemaReconciler")),
+		Log:    logger.With(zap.Namespace("SqlCronJobReconciler")),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "Schema")
+		setupLog.Error(err, "unable to create controller", "controller", "SqlCronJob")
+		os.Exit(1)
+	}
+	if err = (&controllers.PublicationReconciler{
+		Client:   mgr.GetClient(),
+		Log:      logger.With(zap.Namespace("PublicationReconciler")),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("db-operator"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Publication")
+		os.Exit(1)
+	}
+	if err = (&controllers.SubscriptionReconciler{
+		Client:   mgr.GetClient(),
+		Log:      logger.With(zap.Namespace("SubscriptionReconciler")),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("db-operator"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Subscription")
+		os.Exit(1)
+	}
+	if err = (&controllers.ForeignServerReconciler{
+		Client:   mgr.GetClient(),
+		Log:      logger.With(zap.Namespace("ForeignServerReconciler")),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("db-operator"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ForeignServer")
 		os.Exit(1)
 	}
 	if err = (&controllers.CockroachDBBackupCronJobReconciler{
@@ -227,6 +308,13 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "CockroachDBBackupCronJob")
 		os.Exit(1)
 	}
+	// Webhooks need serving certificates, config/default sets ENABLE_WEBHOOKS when they're enabled there
+	if os.Getenv("ENABLE_WEBHOOKS") == "true" {
+		if err = webhooks.SetupWebhooksWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhooks")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -0,0 +1,267 @@
+package shared
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	DEFAULT_POOL_IDLE_TIMEOUT          = 10 * time.Minute
+	DEFAULT_POOL_HEALTH_CHECK_INTERVAL = 30 * time.Second
+	POOL_HEALTH_CHECK_TIMEOUT          = 5 * time.Second
+)
+
+type PoolKey struct {
+	ServerKey string
+	ConnectionKey
+}
+
+type pooledConnection struct {
+	conn        *sql.DB
+	configHash  string
+	lastUsed    time.Time
+	lastChecked time.Time
+	// Number of stores holding the handle, an evicted handle is only closed once this drops to 0
+	refs    int
+	evicted bool
+}
+
+// Keeps sql.DB handles open across reconciles so every reconcile doesn't have to set up new connections
+// Handles are keyed by DbServer, user and database. The connect info and credentials are hashed, when
+// they change (for example because the Secret changed) the old handle is evicted and a new one is opened.
+// Handles are reference counted, every Get has to be paired with a Release. An evicted handle stays open
+// until the reconciles that still hold it release it, so they don't fail with "sql: database is closed"
+type ConnectionPool struct {
+	IdleTimeout         time.Duration
+	HealthCheckInterval time.Duration
+
+	mu          sync.Mutex
+	connections map[PoolKey]*pooledConnection
+	// Every handle that's handed out and not closed yet, including evicted ones
+	handles map[*sql.DB]*pooledConnection
+}
+
+func NewConnectionPool() *ConnectionPool {
+	return &ConnectionPool{
+		IdleTimeout:         DEFAULT_POOL_IDLE_TIMEOUT,
+		HealthCheckInterval: DEFAULT_POOL_HEALTH_CHECK_INTERVAL,
+		connections:         map[PoolKey]*pooledConnection{},
+		handles:             map[*sql.DB]*pooledConnection{},
+	}
+}
+
+// Shared by all controllers of the manager
+var SharedConnectionPool = NewConnectionPool()
+
+func HashConnectionConfig(connectInfo *DbServerConnectInfo, credentials *Credentials, databaseName *string) string {
+	hash := sha256.New()
+	write := func(value string) {
+		hash.Write([]byte(value))
+		hash.Write([]byte{0})
+	}
+	writePtr := func(value *string) {
+		if value == nil {
+			write("<nil>")
+		} else {
+			write(*value)
+		}
+	}
+
+	write(connectInfo.Host)
+	write(fmt.Sprint(connectInfo.Port))
+	write(fmt.Sprint(connectInfo.MaxOpenConnections))
+	write(connectInfo.Database)
+	optionKeys := []string{}
+	for key := range connectInfo.Options {
+		optionKeys = append(optionKeys, key)
+	}
+	sort.Strings(optionKeys)
+	for _, key := range optionKeys {
+		write(key)
+		write(connectInfo.Options[key])
+	}
+	// User connections fall back on the CA of the DbServer
+	writePtr(connectInfo.CaCert)
+	write(credentials.UserName)
+	writePtr(credentials.Password)
+	writePtr(credentials.CaCert)
+	writePtr(credentials.TlsCrt)
+	writePtr(credentials.TlsKey)
+	writePtr(databaseName)
+	return fmt.Sprintf("%x", hash.Sum(nil))
+}
+
+func closeHandles(handles []*sql.DB) {
+	for _, conn := range handles {
+		conn.Close()
+	}
+}
+
+// Takes the handle out of the pool, it's returned for closing when no store holds it, p.mu must be held
+func (p *ConnectionPool) evict(key PoolKey, pooled *pooledConnection) []*sql.DB {
+	if p.connections[key] == pooled {
+		delete(p.connections, key)
+	}
+	pooled.evicted = true
+	return p.closeIfUnused(pooled)
+}
+
+func (p *ConnectionPool) closeIfUnused(pooled *pooledConnection) []*sql.DB {
+	if !pooled.evicted || pooled.refs > 0 {
+		return nil
+	}
+	delete(p.handles, pooled.conn)
+	return []*sql.DB{pooled.conn}
+}
+
+// Returns a handle for key, the caller has to Release it when it's done
+func (p *ConnectionPool) Get(key PoolKey, configHash string, maxOpenConnections int, open func() (*sql.DB, error)) (*sql.DB, error) {
+	p.mu.Lock()
+	toClose := []*sql.DB{}
+	defer func() {
+		p.mu.Unlock()
+		closeHandles(toClose)
+	}()
+
+	pooled, found := p.connections[key]
+	if found && pooled.configHash != configHash {
+		// Connect info or credentials changed, the old handle is of no use anymore
+		toClose = append(toClose, p.evict(key, pooled)...)
+		found = false
+	}
+	if found {
+		pooled.lastUsed = time.Now()
+		pooled.refs++
+		needsHealthCheck := time.Since(pooled.lastChecked) > p.HealthCheckInterval
+		if !needsHealthCheck {
+			return pooled.conn, nil
+		}
+		pooled.lastChecked = time.Now()
+		p.mu.Unlock()
+		healthy := p.isHealthy(pooled.conn)
+		p.mu.Lock()
+		if healthy {
+			return pooled.conn, nil
+		}
+		pooled.refs--
+		toClose = append(toClose, p.evict(key, pooled)...)
+	}
+
+	// Another reconcile could have opened it while we were health checking
+	pooled, found = p.connections[key]
+	if found && pooled.configHash == configHash {
+		pooled.lastUsed = time.Now()
+		pooled.refs++
+		return pooled.conn, nil
+	}
+
+	conn, err := open()
+	if err != nil {
+		return nil, err
+	}
+	if maxOpenConnections > 0 {
+		conn.SetMaxOpenConns(maxOpenConnections)
+	}
+	conn.SetConnMaxIdleTime(p.IdleTimeout)
+	now := time.Now()
+	pooled = &pooledConnection{conn: conn, configHash: configHash, lastUsed: now, lastChecked: now, refs: 1}
+	p.connections[key] = pooled
+	p.handles[conn] = pooled
+	return conn, nil
+}
+
+// Hands back a handle from Get, an evicted handle is closed when the last holder releases it
+func (p *ConnectionPool) Release(conn *sql.DB) {
+	p.mu.Lock()
+	toClose := []*sql.DB{}
+	pooled, found := p.handles[conn]
+	if found && pooled.refs > 0 {
+		pooled.refs--
+		toClose = p.closeIfUnused(pooled)
+	}
+	p.mu.Unlock()
+	closeHandles(toClose)
+}
+
+func (p *ConnectionPool) isHealthy(conn *sql.DB) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), POOL_HEALTH_CHECK_TIMEOUT)
+	defer cancel()
+	return conn.PingContext(ctx) == nil
+}
+
+func (p *ConnectionPool) evictWhere(condition func(key PoolKey, pooled *pooledConnection) bool) {
+	p.mu.Lock()
+	toClose := []*sql.DB{}
+	for key, pooled := range p.connections {
+		if condition(key, pooled) {
+			toClose = append(toClose, p.evict(key, pooled)...)
+		}
+	}
+	p.mu.Unlock()
+	closeHandles(toClose)
+}
+
+// Evicts all handles of a DbServer, for example when it's deleted
+func (p *ConnectionPool) InvalidateServer(serverKey string) {
+	p.evictWhere(func(key PoolKey, _ *pooledConnection) bool {
+		return key.ServerKey == serverKey
+	})
+}
+
+// Evicts all handles connected to a database, a database can't be dropped while there are connections to it
+// Handles that are still held by other reconciles are closed when they release them
+func (p *ConnectionPool) InvalidateDatabase(serverKey string, databaseName string) {
+	p.evictWhere(func(key PoolKey, _ *pooledConnection) bool {
+		return key.ServerKey == serverKey && key.DbName == databaseName
+	})
+}
+
+func (p *ConnectionPool) EvictIdle() {
+	p.evictWhere(func(_ PoolKey, pooled *pooledConnection) bool {
+		return pooled.refs == 0 && time.Since(pooled.lastUsed) > p.IdleTimeout
+	})
+}
+
+func (p *ConnectionPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.connections)
+}
+
+// Start implements manager.Runnable, it evicts idle handles until the manager stops
+func (p *ConnectionPool) Start(ctx context.Context) error {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.EvictIdle()
+		case <-ctx.Done():
+			p.closeAll()
+			return nil
+		}
+	}
+}
+
+// Closes every handle, also the ones that are still held, when the manager stops
+func (p *ConnectionPool) closeAll() {
+	p.mu.Lock()
+	toClose := []*sql.DB{}
+	for conn := range p.handles {
+		toClose = append(toClose, conn)
+	}
+	p.connections = map[PoolKey]*pooledConnection{}
+	p.handles = map[*sql.DB]*pooledConnection{}
+	p.mu.Unlock()
+	closeHandles(toClose)
+}
+
+// Every replica keeps its own connections, also the ones that aren't the leader
+func (p *ConnectionPool) NeedLeaderElection() bool {
+	return false
+}
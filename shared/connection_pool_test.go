@@ -0,0 +1,166 @@
+package shared
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func openMock() func() (*sql.DB, error) {
+	return func() (*sql.DB, error) {
+		db, _, err := sqlmock.New()
+		return db, err
+	}
+}
+
+// A closed sql.DB refuses to ping before it reaches the driver, the sqlmock driver accepts pings otherwise
+func isClosed(conn *sql.DB) bool {
+	return conn.Ping() != nil
+}
+
+func TestConnectionPoolConfigHashChange(t *testing.T) {
+	pool := NewConnectionPool()
+	key := PoolKey{ServerKey: "default/postgres", ConnectionKey: ConnectionKey{DbName: "shop"}}
+
+	first, err := pool.Get(key, "hash-1", 0, openMock())
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	again, err := pool.Get(key, "hash-1", 0, openMock())
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if again != first {
+		t.Errorf("expected the pooled handle to be reused")
+	}
+	pool.Release(again)
+
+	second, err := pool.Get(key, "hash-2", 0, openMock())
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if second == first {
+		t.Errorf("expected a new handle after the config changed")
+	}
+	if isClosed(first) {
+		t.Errorf("expected the old handle to stay open while it's held")
+	}
+	pool.Release(first)
+	if !isClosed(first) {
+		t.Errorf("expected the old handle to be closed once it's released")
+	}
+	if pool.Len() != 1 {
+		t.Errorf("expected 1 pooled handle got %d", pool.Len())
+	}
+
+	pool.Release(second)
+	if isClosed(second) {
+		t.Errorf("expected a released handle to stay pooled")
+	}
+}
+
+func TestConnectionPoolHealthCheckEviction(t *testing.T) {
+	pool := NewConnectionPool()
+	pool.HealthCheckInterval = 0
+	key := PoolKey{ServerKey: "default/postgres", ConnectionKey: ConnectionKey{DbName: "shop"}}
+
+	unhealthy, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	held, err := pool.Get(key, "hash", 0, func() (*sql.DB, error) { return unhealthy, nil })
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	mock.ExpectPing().WillReturnError(fmt.Errorf("connection reset"))
+	replacement, err := pool.Get(key, "hash", 0, openMock())
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if replacement == unhealthy {
+		t.Errorf("expected the unhealthy handle to be replaced")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+
+	mock.ExpectClose()
+	pool.Release(held)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("expected the evicted handle to be closed once released: %s", err)
+	}
+	pool.Release(replacement)
+}
+
+func TestConnectionPoolEvictIdleKeepsHeldHandles(t *testing.T) {
+	pool := NewConnectionPool()
+	pool.IdleTimeout = 0
+	key := PoolKey{ServerKey: "default/postgres", ConnectionKey: ConnectionKey{DbName: "shop"}}
+
+	conn, err := pool.Get(key, "hash", 0, openMock())
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	pool.EvictIdle()
+	if pool.Len() != 1 || isClosed(conn) {
+		t.Errorf("expected a held handle not to be evicted as idle")
+	}
+	pool.Release(conn)
+	pool.EvictIdle()
+	if pool.Len() != 0 || !isClosed(conn) {
+		t.Errorf("expected an idle handle to be evicted and closed")
+	}
+}
+
+// Run with -race, handles that are held must never be closed underneath a reconcile
+func TestConnectionPoolConcurrentGetAndInvalidate(t *testing.T) {
+	pool := NewConnectionPool()
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			key := PoolKey{ServerKey: "default/postgres", ConnectionKey: ConnectionKey{DbName: "shop", UserName: fmt.Sprint(worker % 2)}}
+			for j := 0; j < 50; j++ {
+				conn, err := pool.Get(key, fmt.Sprint(j%3), 0, openMock())
+				if err != nil {
+					errs <- err
+					return
+				}
+				if isClosed(conn) {
+					errs <- fmt.Errorf("got a closed handle")
+				}
+				pool.InvalidateDatabase("default/postgres", "shop")
+				if isClosed(conn) {
+					errs <- fmt.Errorf("held handle was closed")
+				}
+				pool.Release(conn)
+			}
+		}(i)
+	}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				pool.InvalidateServer("default/postgres")
+				pool.EvictIdle()
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	pool.closeAll()
+	if pool.Len() != 0 {
+		t.Errorf("expected an empty pool after closing it")
+	}
+}
@@ -55,7 +55,7 @@ func (c *ConnectionsStore) GetDbConnection(userName, databaseName *string) (*sql
 			return nil, fmt.Errorf("Credentials for '%s' not found while constructing database connection", *userName)
 		}
 	}
-	conn, err := c.Connect(c.ServerConnInfo, creds, databaseName)
+	conn, err := c.connect(connectionKey, creds, databaseName)
 	if err != nil {
 		return nil, err
 	}
@@ -67,8 +67,56 @@ func (c *ConnectionsStore) GetDbConnection(userName, databaseName *string) (*sql
 	return conn, nil
 }
 
+// Connections of a known DbServer come from the shared pool, others are opened for this store only
+func (c *ConnectionsStore) connect(connectionKey ConnectionKey, creds *Credentials, databaseName *string) (*sql.DB, error) {
+	if !c.isPooled() {
+		return c.Connect(c.ServerConnInfo, creds, databaseName)
+	}
+	// Without an explicit database the connection goes to the database of the connect info
+	if databaseName == nil {
+		connectionKey.DbName = c.ServerConnInfo.Database
+	}
+	poolKey := PoolKey{ServerKey: c.ServerConnInfo.ServerKey, ConnectionKey: connectionKey}
+	configHash := HashConnectionConfig(c.ServerConnInfo, creds, databaseName)
+	return SharedConnectionPool.Get(poolKey, configHash, c.ServerConnInfo.MaxOpenConnections, func() (*sql.DB, error) {
+		return c.Connect(c.ServerConnInfo, creds, databaseName)
+	})
+}
+
+func (c *ConnectionsStore) isPooled() bool {
+	return c.ServerConnInfo.ServerKey != ""
+}
+
+// Closes every connection of this store to the database and evicts the pooled ones,
+// pooled handles that other reconciles still hold are closed when they release them
+func (c *ConnectionsStore) CloseDatabaseConnections(databaseName string) error {
+	for connectionKey, conn := range c.connections {
+		if connectionKey.DbName == databaseName {
+			delete(c.connections, connectionKey)
+			if c.isPooled() {
+				SharedConnectionPool.Release(conn)
+			} else {
+				err := conn.Close()
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if c.isPooled() {
+		SharedConnectionPool.InvalidateDatabase(c.ServerConnInfo.ServerKey, databaseName)
+	}
+	return nil
+}
+
+// Pooled connections are released and stay open for the next reconcile
 func (c *ConnectionsStore) Close() error {
-	for _, conn := range c.connections {
+	for connectionKey, conn := range c.connections {
+		delete(c.connections, connectionKey)
+		if c.isPooled() {
+			SharedConnectionPool.Release(conn)
+			continue
+		}
 		err := conn.Close()
 		if err != nil {
 			return err
@@ -1,6 +1,11 @@
 package shared
 
-import "k8s.io/apimachinery/pkg/types"
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/types"
+)
 
 type Credentials struct {
 	UserName     string
@@ -10,3 +15,8 @@ type Credentials struct {
 	TlsKey       *string
 	SourceSecret *types.NamespacedName
 }
+
+// Hash of a value with credentials in it, a status keeps the hash to notice changes without keeping the credentials
+func HashSecretValue(value string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(value)))
+}
@@ -0,0 +1,95 @@
+package shared
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type cronField struct {
+	name  string
+	min   int
+	max   int
+	names []string // names for min, min+1, ...
+}
+
+// The standard 5 field cron format as accepted by Kubernetes CronJobs and CockroachDB backup schedules
+var CRON_FIELDS = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}},
+	{name: "day of week", min: 0, max: 7, names: []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}},
+}
+
+var CRON_MACROS = []string{"@yearly", "@annually", "@monthly", "@weekly", "@daily", "@midnight", "@hourly"}
+
+func ValidateCronSchedule(schedule string) error {
+	schedule = strings.TrimSpace(schedule)
+	if strings.HasPrefix(schedule, "@") {
+		for _, macro := range CRON_MACROS {
+			if schedule == macro {
+				return nil
+			}
+		}
+		return fmt.Errorf("unknown cron macro %s", schedule)
+	}
+
+	fields := strings.Fields(schedule)
+	if len(fields) != len(CRON_FIELDS) {
+		return fmt.Errorf("expected %d fields in cron schedule '%s', got %d", len(CRON_FIELDS), schedule, len(fields))
+	}
+	for i, field := range fields {
+		err := CRON_FIELDS[i].validate(field)
+		if err != nil {
+			return fmt.Errorf("invalid %s in cron schedule '%s': %s", CRON_FIELDS[i].name, schedule, err)
+		}
+	}
+	return nil
+}
+
+func (f cronField) validate(field string) error {
+	for _, item := range strings.Split(field, ",") {
+		rangePart, step, hasStep := strings.Cut(item, "/")
+		if hasStep {
+			stepValue, err := strconv.Atoi(step)
+			if err != nil || stepValue < 1 {
+				return fmt.Errorf("invalid step '%s'", step)
+			}
+		}
+		if rangePart == "*" || (rangePart == "?" && (f.name == "day of month" || f.name == "day of week")) {
+			continue
+		}
+		start, end, isRange := strings.Cut(rangePart, "-")
+		startValue, err := f.parseValue(start)
+		if err != nil {
+			return err
+		}
+		if isRange {
+			endValue, err := f.parseValue(end)
+			if err != nil {
+				return err
+			}
+			if endValue < startValue {
+				return fmt.Errorf("range '%s' ends before it starts", rangePart)
+			}
+		}
+	}
+	return nil
+}
+
+func (f cronField) parseValue(value string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(value, name) {
+			return f.min + i, nil
+		}
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value '%s'", value)
+	}
+	if number < f.min || number > f.max {
+		return 0, fmt.Errorf("value %d out of range %d-%d", number, f.min, f.max)
+	}
+	return number, nil
+}
@@ -0,0 +1,47 @@
+package shared
+
+import "testing"
+
+func TestValidateCronSchedule(t *testing.T) {
+	testCases := []struct {
+		schedule string
+		valid    bool
+	}{
+		{"* * * * *", true},
+		{"0 3 * * *", true},
+		{" 0 3 * * * ", true},
+		{"*/15 * * * *", true},
+		{"0 0-6/2 * * *", true},
+		{"0,30 8-18 * * MON-FRI", true},
+		{"0 0 1 jan,jul *", true},
+		{"0 0 ? * SUN", true},
+		{"0 0 * * 7", true},
+		{"@daily", true},
+		{"@midnight", true},
+		{"", false},
+		{"* * * *", false},
+		{"* * * * * *", false},
+		{"60 * * * *", false},
+		{"* 24 * * *", false},
+		{"* * 0 * *", false},
+		{"* * * 13 *", false},
+		{"* * * * 8", false},
+		{"? * * * *", false},
+		{"*/0 * * * *", false},
+		{"*/x * * * *", false},
+		{"0 18-8 * * *", false},
+		{"0 0 * FOO *", false},
+		{"0 0 * * MON-", false},
+		{"@every 5m", false},
+		{"@reboot", false},
+	}
+	for _, tc := range testCases {
+		err := ValidateCronSchedule(tc.schedule)
+		if tc.valid && err != nil {
+			t.Errorf("expected '%s' to be valid, unexpected error %s", tc.schedule, err)
+		}
+		if !tc.valid && err == nil {
+			t.Errorf("expected an error for '%s'", tc.schedule)
+		}
+	}
+}
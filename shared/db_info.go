@@ -15,10 +15,22 @@ type DbActions interface {
 	BuildContainer(scriptName string) v1.Container
 	BuildBackupContainer() v1.Container
 	BuildRestoreContainer() v1.Container
+	// Runs the SQL in the database, as the user when it's not nil
+	BuildRunSqlContainer(sql string, user *dboperatorv1alpha1.User) v1.Container
 }
 
 type DbActionsBase struct {
 	Db       *dboperatorv1alpha1.Db
 	DbServer *dboperatorv1alpha1.DbServer
 	Options  map[string]string
+	// User the Jobs connect as instead of the DbServer user, needed when the Secret of the DbServer isn't in the namespace of the Job
+	JobUser *dboperatorv1alpha1.User
+}
+
+// Returns the user name and the Secret with the password the Jobs connect with
+func (b *DbActionsBase) GetJobCredentials() (string, string, string) {
+	if b.JobUser != nil {
+		return b.JobUser.Spec.UserName, b.JobUser.Spec.SecretName, b.JobUser.Spec.PasswordKey
+	}
+	return b.DbServer.Spec.UserName, b.DbServer.Spec.SecretName, b.DbServer.Spec.PasswordKey
 }
@@ -1,6 +1,8 @@
 package shared
 
 import (
+	"time"
+
 	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
 )
 
@@ -17,25 +19,132 @@ type DbSideSchema struct {
 	SchemaName string
 }
 
+type DbSideExtension struct {
+	Name    string
+	Version string
+	Schema  string
+}
+
+type DbSidePublication struct {
+	Name      string
+	AllTables bool
+	// Tables as schema.table
+	Tables     []string
+	Operations []string
+}
+
+type DbSideSubscription struct {
+	Name         string
+	Publications []string
+	// Tables as schema.table
+	Tables       []string
+	ReceivedLsn  string
+	LatestEndLsn string
+	// nil when the subscription worker isn't running
+	Lag *time.Duration
+}
+
+type DbSideForeignServer struct {
+	Name    string
+	Options map[string]string
+	// Options of the user mappings by user name, empty when the options aren't visible to the connection
+	UserMappings map[string]map[string]string
+}
+
+// Privileges a user has on the server that are not in the spec (Extra) and the other way around (Missing)
+type PrivsDrift struct {
+	Scope   string
+	Extra   []string
+	Missing []string
+}
+
+// Kinds of objects that carry a managed-by marker
+const (
+	MANAGED_DATABASE = "database"
+	MANAGED_USER     = "user"
+	MANAGED_SCHEMA   = "schema"
+)
+
+// Every managed-by marker starts with this, on Postgres it tells the marker apart from the rest of the comment
+const MANAGED_BY_PREFIX = "managed by db-operator "
+
 type DbServerConnectInfo struct {
 	Host string
 	Port int
 	Credentials
 	Database string
 	Options  map[string]string
+	// namespace/name of the DbServer, connections are pooled when it's set
+	ServerKey          string
+	MaxOpenConnections int
 }
 
 type DbServerConnectionInterface interface {
-	CreateUser(userName string, password string) error
+	CreateUser(userSpec dboperatorv1alpha1.UserSpec, password string) error
 	DropUser(userSpec dboperatorv1alpha1.UserSpec) error
 	GetUsers() (map[string]DbSideUser, error)
-	CreateDb(dbName string) error
+	CreateDb(dbSpec dboperatorv1alpha1.DbSpec) error
 	CreateSchema(schemaName string, creator *string) error
 	DropDb(dbName string, cascade bool) error
 	DropSchema(schemaName string, userName *string, cascade bool) error
 	GetDbs() (map[string]DbSideDb, error)
 	GetSchemas(userName *string) (map[string]DbSideSchema, error)
-	UpdateUserPrivs(string, string, []dboperatorv1alpha1.DbPriv) (bool, error)
+	UpdateUserPrivs(userSpec dboperatorv1alpha1.UserSpec) (bool, error)
+	// Returns the statements UpdateUserPrivs would run without running them
+	PlanUserPrivs(userSpec dboperatorv1alpha1.UserSpec) ([]dboperatorv1alpha1.PlannedStatement, error)
+	// Compares the privileges on the server with the spec without changing anything
+	GetUserPrivsDrift(userSpec dboperatorv1alpha1.UserSpec) ([]PrivsDrift, error)
+	// Revokes the grants of the user on scopes that are not in db_privs
+	RevokeExtraUserPrivs(userSpec dboperatorv1alpha1.UserSpec) (bool, error)
+	UpdateUserOptions(userSpec dboperatorv1alpha1.UserSpec) (bool, error)
+	// Reconciles the options of a database that can be changed after creation
+	// Returns descriptions of the options that can't be changed and differ from the spec
+	UpdateDbOptions(dbSpec dboperatorv1alpha1.DbSpec) (bool, []string, error)
+	// Makes the user the owner of the database, on MySQL by granting ALL on it
+	UpdateDbOwner(dbName string, owner dboperatorv1alpha1.UserSpec) (bool, error)
+	// Extensions in the database of the connection, only supported on Postgres
+	GetExtensions() (map[string]DbSideExtension, error)
+	CreateExtension(extensionSpec dboperatorv1alpha1.ExtensionSpec) error
+	// Updates the version and schema of an installed extension to the spec
+	UpdateExtension(extensionSpec dboperatorv1alpha1.ExtensionSpec) (bool, error)
+	DropExtension(name string, cascade bool) error
+	// Logical replication in the database of the connection, only supported on Postgres
+	// Get returns nil when the publication or subscription doesn't exist
+	GetPublication(name string) (*DbSidePublication, error)
+	CreatePublication(publicationSpec dboperatorv1alpha1.PublicationSpec) error
+	UpdatePublication(publicationSpec dboperatorv1alpha1.PublicationSpec) (bool, error)
+	DropPublication(name string) error
+	GetSubscription(name string) (*DbSideSubscription, error)
+	CreateSubscription(subscriptionSpec dboperatorv1alpha1.SubscriptionSpec, connInfo string) error
+	// Updates the connection and publications of the subscription
+	// and refreshes it when the published tables differ from the subscribed tables
+	// The connection can't be read back, it's only set when connInfo is not empty
+	UpdateSubscription(subscriptionSpec dboperatorv1alpha1.SubscriptionSpec, connInfo string, publishedTables []string) (bool, error)
+	DropSubscription(name string) error
+	// Foreign servers of postgres_fdw in the database of the connection, only supported on Postgres
+	// Get returns nil when the server doesn't exist
+	GetForeignServer(name string) (*DbSideForeignServer, error)
+	// Creates the postgres_fdw extension when it isn't installed yet and the server
+	CreateForeignServer(name string, options map[string]string) error
+	// Sets the options of the server and creates and updates user mappings until there's one for each user name
+	// in userMappings with its remote credentials, only the createdMappings of users that aren't in userMappings are dropped
+	// createdMappings hold the hash of the options the caller set on them, a mapping whose options can't be read is only altered when it changes
+	// Returns the mappings that were created by the caller with the hash of their options
+	UpdateForeignServer(name string, options map[string]string, userMappings map[string]Credentials, createdMappings map[string]string) (bool, map[string]string, error)
+	// Imports the tables of the remote schema that aren't foreign tables in the local schema yet
+	// Returns the foreign tables of the server in the local schema
+	ImportForeignSchema(name string, importSpec dboperatorv1alpha1.ForeignSchemaImport, userName *string) ([]string, error)
+	// Drops userMappings and the server, cascade also drops the other user mappings and the foreign tables of the server
+	DropForeignServer(name string, userMappings []string, cascade bool) error
+	// Creates the history table when it doesn't exist, returns the checksums of the applied migrations by version
+	GetAppliedMigrations(historyTable string, userName *string) (map[string]string, error)
+	// Runs the migration and records it in the history table in one transaction
+	ApplyMigration(historyTable string, migration Migration, userName *string) error
+	// Returns the managed-by marker of an object, empty when it has none
+	GetManagedBy(objectType string, name string) (string, error)
+	SetManagedBy(objectType string, name string, managedBy string) error
+	// Cleans up the marker of an object that was dropped
+	ForgetManagedBy(objectType string, name string) error
 	Close() error
 	Execute(query string, userName *string) error
 }
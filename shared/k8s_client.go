@@ -5,6 +5,7 @@ import (
 
 	"go.uber.org/zap"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -13,4 +14,6 @@ type K8sClient struct {
 	Ctx    context.Context
 	NsNm   types.NamespacedName
 	Log    *zap.Logger
+	// Optional, events are only emitted when it's set
+	Recorder record.EventRecorder
 }
@@ -0,0 +1,29 @@
+package shared
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+const DEFAULT_MIGRATIONS_HISTORY_TABLE = "db_operator_migrations"
+
+// A versioned SQL file, the checksum detects changes to migrations that were already applied
+type Migration struct {
+	Version  string
+	Checksum string
+	SQL      string
+}
+
+func NewMigration(version string, sql string) Migration {
+	sum := sha256.Sum256([]byte(sql))
+	return Migration{Version: version, Checksum: hex.EncodeToString(sum[:]), SQL: sql}
+}
+
+// For a migration that another DbMigration applied while this one waited for the migrations lock
+func (m Migration) CheckAlreadyApplied(checksum string) error {
+	if checksum != m.Checksum {
+		return fmt.Errorf("migration %s was already applied with another checksum", m.Version)
+	}
+	return nil
+}
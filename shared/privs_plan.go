@@ -0,0 +1,55 @@
+package shared
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+)
+
+// Runs the statements that change privileges, *sql.DB runs them and a StatementRecorder only records them
+type Executor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// Records statements instead of running them, used to plan privilege changes
+type StatementRecorder struct {
+	Database   string
+	RunAs      string
+	Statements *[]dboperatorv1alpha1.PlannedStatement
+}
+
+func (r StatementRecorder) Exec(query string, args ...interface{}) (sql.Result, error) {
+	*r.Statements = append(*r.Statements, dboperatorv1alpha1.PlannedStatement{
+		Database:  r.Database,
+		RunAs:     r.RunAs,
+		Statement: RenderStatement(query, args),
+	})
+	return driver.RowsAffected(0), nil
+}
+
+// Fills in the ? placeholders with quoted literals so the statement can be shown in a plan
+func RenderStatement(query string, args []interface{}) string {
+	for _, arg := range args {
+		literal := "'" + strings.ReplaceAll(fmt.Sprint(arg), "'", "''") + "'"
+		query = strings.Replace(query, "?", literal, 1)
+	}
+	return query
+}
+
+// The hash the approve-privs-plan annotation needs to match before a plan is applied
+func HashPrivsPlan(statements []dboperatorv1alpha1.PlannedStatement) string {
+	hash := sha256.New()
+	for _, statement := range statements {
+		hash.Write([]byte(statement.Database))
+		hash.Write([]byte{0})
+		hash.Write([]byte(statement.RunAs))
+		hash.Write([]byte{0})
+		hash.Write([]byte(statement.Statement))
+		hash.Write([]byte{0})
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil))[:16]
+}
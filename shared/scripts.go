@@ -59,6 +59,23 @@ mysql -u $MYSQL_USER -h $MYSQL_HOST $MYSQL_DATABASE < $LATEST_BACKUP
 echo "mysql restore done"
 `
 
+// psql runs every statement in its own transaction so VACUUM is allowed
+const RUN_SQL_POSTGRES_SCRIPT string = `#!/bin/bash -e
+psql \
+	--set=ON_ERROR_STOP=1 \
+	--host=$PGHOST \
+	--username=$PGUSER \
+	--port=$PGPORT \
+	--dbname=$DATABASE \
+	<<< "$SQL"
+echo "sql done"
+`
+
+const RUN_SQL_MYSQL_SCRIPT string = `#!/bin/bash -e
+mysql -u $MYSQL_USER -h $MYSQL_HOST $MYSQL_DATABASE <<< "$SQL"
+echo "sql done"
+`
+
 const DOWNLOAD_AZ_BLOBS_SCRIPT string = `#!/bin/bash -e
 # Only supports up/downloading with service principal
 # But should be fairly simple to expand with other methods.
@@ -101,6 +118,8 @@ const BACKUP_POSTGRES string = "backup_postgres.sh"
 const RESTORE_POSTGRES string = "restore_postgres.sh"
 const BACKUP_MYSQL string = "backup_mysql.sh"
 const RESTORE_MYSQL string = "restore_mysql.sh"
+const RUN_SQL_POSTGRES string = "run_sql_postgres.sh"
+const RUN_SQL_MYSQL string = "run_sql_mysql.sh"
 const UPLOAD_AZ_BLOBS string = "upload_az_blobs.sh"
 const DOWNLOAD_AZ_BLOBS string = "download_az_blobs.sh"
 const UPLOAD_S3 string = "upload_s3.sh"
@@ -111,6 +130,8 @@ var SCRIPTS_MAP map[string]string = map[string]string{
 	RESTORE_POSTGRES:  RESTORE_POSTGRES_SCRIPT,
 	BACKUP_MYSQL:      BACKUP_MYSQL_SCRIPT,
 	RESTORE_MYSQL:     RESTORE_MYSQL_SCRIPT,
+	RUN_SQL_POSTGRES:  RUN_SQL_POSTGRES_SCRIPT,
+	RUN_SQL_MYSQL:     RUN_SQL_MYSQL_SCRIPT,
 	UPLOAD_AZ_BLOBS:   UPLOAD_AZ_BLOBS_SCRIPT,
 	DOWNLOAD_AZ_BLOBS: DOWNLOAD_AZ_BLOBS_SCRIPT,
 	UPLOAD_S3:         UPLOAD_S3_SCRIPT,
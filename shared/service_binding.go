@@ -0,0 +1,44 @@
+package shared
+
+import (
+	"strconv"
+	"strings"
+)
+
+const SERVICE_BINDING_PROVIDER = "db-operator"
+
+// Type entry of a binding, CockroachDB speaks the Postgres protocol so its clients bind as postgresql
+// https://github.com/servicebinding/spec#well-known-secret-entries
+func ServiceBindingType(serverType string) string {
+	flavor := strings.ToLower(serverType)
+	if flavor == "postgres" || flavor == "cockroachdb" {
+		return "postgresql"
+	}
+	return flavor
+}
+
+// Kubernetes type of the binding Secret, servicebinding.io/<type> like the spec recommends
+func ServiceBindingSecretType(serverType string) string {
+	return "servicebinding.io/" + ServiceBindingType(serverType)
+}
+
+// Data of a binding Secret laid out per the Service Binding spec, database and ca.crt are left out when they're empty
+func BuildServiceBindingData(serverType string, values ConnectionValues, caCert *string) map[string][]byte {
+	data := map[string][]byte{
+		"type":     []byte(ServiceBindingType(serverType)),
+		"provider": []byte(SERVICE_BINDING_PROVIDER),
+		"host":     []byte(values.Host),
+		"port":     []byte(strconv.Itoa(values.Port)),
+		"uri":      []byte(values.Uri),
+		"username": []byte(values.UserName),
+		"password": []byte(values.Password),
+		"sslmode":  []byte(values.SslMode),
+	}
+	if values.Database != "" {
+		data["database"] = []byte(values.Database)
+	}
+	if caCert != nil && *caCert != "" {
+		data["ca.crt"] = []byte(*caCert)
+	}
+	return data
+}
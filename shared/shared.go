@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"os"
 	path "path/filepath"
 	"reflect"
 	"regexp"
@@ -71,6 +72,15 @@ func GetTypeName(myvar interface{}) string {
 
 const SCRIPTS_CONFIGMAP string = "db-operator-scripts"
 
+// The Secrets of cluster scoped resources are read from the namespace the operator runs in
+func GetOperatorNamespace() (string, error) {
+	namespace := os.Getenv("OPERATOR_NAMESPACE")
+	if namespace == "" {
+		return "", fmt.Errorf("OPERATOR_NAMESPACE is not set, it's needed for cluster scoped resources")
+	}
+	return namespace, nil
+}
+
 func Nvl(val1 string, val2 string) string {
 	if len(val1) == 0 {
 		return val2
@@ -0,0 +1,80 @@
+package shared
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+)
+
+// sslmode values as described in https://www.postgresql.org/docs/current/libpq-ssl.html#LIBPQ-SSL-PROTECTION
+const (
+	SSL_MODE_DISABLE     = "disable"
+	SSL_MODE_REQUIRE     = "require"
+	SSL_MODE_VERIFY_CA   = "verify-ca"
+	SSL_MODE_VERIFY_FULL = "verify-full"
+)
+
+// Builds a tls.Config from PEM encoded certificates kept in memory
+func BuildTlsConfig(sslMode string, serverName string, caCert, tlsCrt, tlsKey *string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{ServerName: serverName}
+
+	if (tlsCrt == nil) != (tlsKey == nil) {
+		return nil, fmt.Errorf("both a client certificate and key are needed for certificate authentication")
+	}
+	if tlsCrt != nil {
+		cert, err := tls.X509KeyPair([]byte(*tlsCrt), []byte(*tlsKey))
+		if err != nil {
+			return nil, fmt.Errorf("unable to load client certificate %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	var rootCAs *x509.CertPool
+	if caCert != nil {
+		rootCAs = x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM([]byte(*caCert)) {
+			return nil, fmt.Errorf("unable to parse CA certificate")
+		}
+	}
+
+	switch sslMode {
+	case SSL_MODE_REQUIRE:
+		tlsConfig.InsecureSkipVerify = true
+	case SSL_MODE_VERIFY_CA:
+		if rootCAs == nil {
+			return nil, fmt.Errorf("sslmode %s needs a CA certificate", sslMode)
+		}
+		// Verify the chain but not the host name
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return verifyCertificateChain(rawCerts, rootCAs)
+		}
+	case SSL_MODE_VERIFY_FULL:
+		// A nil RootCAs means the system roots are used
+		tlsConfig.RootCAs = rootCAs
+	default:
+		return nil, fmt.Errorf("invalid sslmode %s", sslMode)
+	}
+	return tlsConfig, nil
+}
+
+func verifyCertificateChain(rawCerts [][]byte, rootCAs *x509.CertPool) error {
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for i, rawCert := range rawCerts {
+		cert, err := x509.ParseCertificate(rawCert)
+		if err != nil {
+			return err
+		}
+		certs[i] = cert
+	}
+	if len(certs) == 0 {
+		return fmt.Errorf("server did not present a certificate")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{Roots: rootCAs, Intermediates: intermediates})
+	return err
+}
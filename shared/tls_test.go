@@ -0,0 +1,95 @@
+package shared
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func generateCertificate(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate key %s", err)
+	}
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "db.example.com"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("unable to create certificate %s", err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("unable to marshal key %s", err)
+	}
+	cert := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	keyPem := string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}))
+	return cert, keyPem
+}
+
+func TestBuildTlsConfig(t *testing.T) {
+	cert, key := generateCertificate(t)
+
+	tlsConfig, err := BuildTlsConfig(SSL_MODE_REQUIRE, "db.example.com", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if !tlsConfig.InsecureSkipVerify || tlsConfig.ServerName != "db.example.com" {
+		t.Errorf("expected sslmode require to skip verification")
+	}
+
+	tlsConfig, err = BuildTlsConfig(SSL_MODE_VERIFY_CA, "db.example.com", &cert, &cert, &key)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if !tlsConfig.InsecureSkipVerify || tlsConfig.VerifyPeerCertificate == nil {
+		t.Errorf("expected sslmode verify-ca to verify the chain only")
+	}
+	if len(tlsConfig.Certificates) != 1 {
+		t.Errorf("expected the client certificate to be loaded")
+	}
+	block, _ := pem.Decode([]byte(cert))
+	if err = tlsConfig.VerifyPeerCertificate([][]byte{block.Bytes}, nil); err != nil {
+		t.Errorf("expected the certificate to verify against its own CA %s", err)
+	}
+
+	tlsConfig, err = BuildTlsConfig(SSL_MODE_VERIFY_FULL, "db.example.com", &cert, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if tlsConfig.InsecureSkipVerify || tlsConfig.RootCAs == nil {
+		t.Errorf("expected sslmode verify-full to verify with the CA certificate")
+	}
+
+	invalid := []struct {
+		sslMode string
+		caCert  *string
+		tlsCrt  *string
+		tlsKey  *string
+	}{
+		{SSL_MODE_VERIFY_CA, nil, nil, nil},
+		{"sometimes", nil, nil, nil},
+		{SSL_MODE_REQUIRE, nil, &cert, nil},
+		{SSL_MODE_REQUIRE, nil, &cert, &cert},
+		{SSL_MODE_VERIFY_FULL, &key, nil, nil},
+	}
+	for _, tc := range invalid {
+		_, err = BuildTlsConfig(tc.sslMode, "db.example.com", tc.caCert, tc.tlsCrt, tc.tlsKey)
+		if err == nil {
+			t.Errorf("expected an error for sslmode %s", tc.sslMode)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package webhooks
+
+import (
+	"context"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/shared"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+//+kubebuilder:webhook:path=/validate-db-operator-kubemaster-com-v1alpha1-backupcronjob,mutating=false,failurePolicy=fail,sideEffects=None,groups=db-operator.kubemaster.com,resources=backupcronjobs,verbs=create;update,versions=v1alpha1,name=vbackupcronjob.db-operator.kubemaster.com,admissionReviewVersions=v1
+//+kubebuilder:webhook:path=/validate-db-operator-kubemaster-com-v1alpha1-restorecronjob,mutating=false,failurePolicy=fail,sideEffects=None,groups=db-operator.kubemaster.com,resources=restorecronjobs,verbs=create;update,versions=v1alpha1,name=vrestorecronjob.db-operator.kubemaster.com,admissionReviewVersions=v1
+//+kubebuilder:webhook:path=/validate-db-operator-kubemaster-com-v1alpha1-dbcopycronjob,mutating=false,failurePolicy=fail,sideEffects=None,groups=db-operator.kubemaster.com,resources=dbcopycronjobs,verbs=create;update,versions=v1alpha1,name=vdbcopycronjob.db-operator.kubemaster.com,admissionReviewVersions=v1
+//+kubebuilder:webhook:path=/validate-db-operator-kubemaster-com-v1alpha1-cockroachdbbackupcronjob,mutating=false,failurePolicy=fail,sideEffects=None,groups=db-operator.kubemaster.com,resources=cockroachdbbackupcronjobs,verbs=create;update,versions=v1alpha1,name=vcockroachdbbackupcronjob.db-operator.kubemaster.com,admissionReviewVersions=v1
+//+kubebuilder:webhook:path=/validate-db-operator-kubemaster-com-v1alpha1-sqlcronjob,mutating=false,failurePolicy=fail,sideEffects=None,groups=db-operator.kubemaster.com,resources=sqlcronjobs,verbs=create;update,versions=v1alpha1,name=vsqlcronjob.db-operator.kubemaster.com,admissionReviewVersions=v1
+
+func validateInterval(ctx context.Context, obj client.Object) (admission.Warnings, field.ErrorList) {
+	var interval string
+	switch cronJob := obj.(type) {
+	case *dboperatorv1alpha1.BackupCronJob:
+		interval = cronJob.Spec.Interval
+	case *dboperatorv1alpha1.RestoreCronJob:
+		interval = cronJob.Spec.Interval
+	case *dboperatorv1alpha1.DbCopyCronJob:
+		interval = cronJob.Spec.Interval
+	case *dboperatorv1alpha1.CockroachDBBackupCronJob:
+		interval = cronJob.Spec.Interval
+	case *dboperatorv1alpha1.SqlCronJob:
+		interval = cronJob.Spec.Interval
+	}
+
+	err := shared.ValidateCronSchedule(interval)
+	if err != nil {
+		return nil, field.ErrorList{field.Invalid(field.NewPath("spec", "interval"), interval, err.Error())}
+	}
+	return nil, nil
+}
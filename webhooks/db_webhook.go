@@ -0,0 +1,43 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/controllers"
+	"github.com/obeleh/db-operator/dbservers"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+//+kubebuilder:webhook:path=/validate-db-operator-kubemaster-com-v1alpha1-db,mutating=false,failurePolicy=fail,sideEffects=None,groups=db-operator.kubemaster.com,resources=dbs,verbs=create;update,versions=v1alpha1,name=vdb.db-operator.kubemaster.com,admissionReviewVersions=v1
+
+// Creation options are validated against the flavor of the DbServer the Db points to
+type DbValidator struct {
+	Client client.Client
+}
+
+func (v *DbValidator) Validate(ctx context.Context, obj client.Object) (admission.Warnings, field.ErrorList) {
+	db := obj.(*dboperatorv1alpha1.Db)
+	errs := field.ErrorList{}
+	warnings := admission.Warnings{}
+	if db.Spec.Server != "" && db.Spec.ServerRef != nil {
+		errs = append(errs, field.Invalid(field.NewPath("spec", "server"), db.Spec.Server, "can't be combined with server_ref"))
+	}
+	if db.Spec.ServiceBinding && db.Spec.Owner == "" {
+		errs = append(errs, field.Invalid(field.NewPath("spec", "service_binding"), db.Spec.ServiceBinding, "needs an owner, the binding has the credentials of the owner"))
+	}
+
+	dbServer, err := controllers.GetDbServer(db.Spec.GetServerRef(), v.Client, db.Namespace)
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("options were not validated, unable to get DbServer %s: %s", db.Spec.GetServerRef().Name, err))
+		return warnings, errs
+	}
+	err = dbservers.ValidateDbOptions(dbServer.Spec.ServerType, db.Spec)
+	if err != nil {
+		errs = append(errs, field.Invalid(field.NewPath("spec"), db.Spec.DbName, err.Error()))
+	}
+	return warnings, errs
+}
@@ -0,0 +1,31 @@
+package webhooks
+
+import (
+	"context"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/dbservers"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+//+kubebuilder:webhook:path=/validate-db-operator-kubemaster-com-v1alpha1-dbserver,mutating=false,failurePolicy=fail,sideEffects=None,groups=db-operator.kubemaster.com,resources=dbservers,verbs=create;update,versions=v1alpha1,name=vdbserver.db-operator.kubemaster.com,admissionReviewVersions=v1
+//+kubebuilder:webhook:path=/validate-db-operator-kubemaster-com-v1alpha1-clusterdbserver,mutating=false,failurePolicy=fail,sideEffects=None,groups=db-operator.kubemaster.com,resources=clusterdbservers,verbs=create;update,versions=v1alpha1,name=vclusterdbserver.db-operator.kubemaster.com,admissionReviewVersions=v1
+
+func validateDbServer(ctx context.Context, obj client.Object) (admission.Warnings, field.ErrorList) {
+	var spec dboperatorv1alpha1.DbServerSpec
+	switch dbServer := obj.(type) {
+	case *dboperatorv1alpha1.DbServer:
+		spec = dbServer.Spec
+	case *dboperatorv1alpha1.ClusterDbServer:
+		spec = dbServer.Spec
+	}
+
+	errs := field.ErrorList{}
+	err := dbservers.ValidateServerType(spec.ServerType)
+	if err != nil {
+		errs = append(errs, field.NotSupported(field.NewPath("spec", "server_type"), spec.ServerType, dbservers.SERVER_TYPES))
+	}
+	return nil, errs
+}
@@ -0,0 +1,34 @@
+package webhooks
+
+import (
+	"context"
+	"strings"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+//+kubebuilder:webhook:path=/validate-db-operator-kubemaster-com-v1alpha1-backuptarget,mutating=false,failurePolicy=fail,sideEffects=None,groups=db-operator.kubemaster.com,resources=backuptargets,verbs=create;update,versions=v1alpha1,name=vbackuptarget.db-operator.kubemaster.com,admissionReviewVersions=v1
+//+kubebuilder:webhook:path=/validate-db-operator-kubemaster-com-v1alpha1-restoretarget,mutating=false,failurePolicy=fail,sideEffects=None,groups=db-operator.kubemaster.com,resources=restoretargets,verbs=create;update,versions=v1alpha1,name=vrestoretarget.db-operator.kubemaster.com,admissionReviewVersions=v1
+
+// Storage types GetStorageActions knows how to handle
+var STORAGE_TYPES = []string{"s3"}
+
+func validateStorageType(ctx context.Context, obj client.Object) (admission.Warnings, field.ErrorList) {
+	var storageType string
+	switch target := obj.(type) {
+	case *dboperatorv1alpha1.BackupTarget:
+		storageType = target.Spec.StorageType
+	case *dboperatorv1alpha1.RestoreTarget:
+		storageType = target.Spec.StorageType
+	}
+
+	for _, validType := range STORAGE_TYPES {
+		if strings.ToLower(storageType) == validType {
+			return nil, nil
+		}
+	}
+	return nil, field.ErrorList{field.NotSupported(field.NewPath("spec", "storage_type"), storageType, STORAGE_TYPES)}
+}
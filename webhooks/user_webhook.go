@@ -0,0 +1,71 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/controllers"
+	"github.com/obeleh/db-operator/dbservers"
+	"github.com/obeleh/db-operator/shared"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+//+kubebuilder:webhook:path=/validate-db-operator-kubemaster-com-v1alpha1-user,mutating=false,failurePolicy=fail,sideEffects=None,groups=db-operator.kubemaster.com,resources=users,verbs=create;update,versions=v1alpha1,name=vuser.db-operator.kubemaster.com,admissionReviewVersions=v1
+
+// Privileges are validated against the flavor of the DbServer the User points to
+type UserValidator struct {
+	Client client.Client
+}
+
+func (v *UserValidator) Validate(ctx context.Context, obj client.Object) (admission.Warnings, field.ErrorList) {
+	user := obj.(*dboperatorv1alpha1.User)
+	specPath := field.NewPath("spec")
+	errs := field.ErrorList{}
+	warnings := admission.Warnings{}
+
+	if user.Spec.DbServerName != "" && user.Spec.DbServerRef != nil {
+		errs = append(errs, field.Invalid(specPath.Child("db_server_name"), user.Spec.DbServerName, "can't be combined with db_server_ref"))
+	}
+	dropUserOptions := user.Spec.DropUserOptions
+	if dropUserOptions != nil && dropUserOptions.DropOwned && dropUserOptions.ReassingOwnedTo != "" {
+		errs = append(errs, field.Invalid(specPath.Child("drop_user_options", "reassign_owned_to"), dropUserOptions.ReassingOwnedTo, "can't be combined with drop_owned"))
+	}
+	connectionSecret := user.Spec.ConnectionSecret
+	if connectionSecret != nil {
+		if connectionSecret.SecretName == user.Spec.SecretName {
+			errs = append(errs, field.Invalid(specPath.Child("connection_secret", "secret_name"), connectionSecret.SecretName, "can't be the secret with the password of the user"))
+		}
+		err := shared.ValidateConnectionSecretTemplates(connectionSecret.Templates)
+		if err != nil {
+			errs = append(errs, field.Invalid(specPath.Child("connection_secret", "templates"), connectionSecret.Templates, err.Error()))
+		}
+	}
+	if user.Spec.ServiceBinding {
+		bindingSecretName := controllers.ServiceBindingSecretName("User", user.Name)
+		if user.Spec.SecretName == bindingSecretName || connectionSecret != nil && connectionSecret.SecretName == bindingSecretName {
+			errs = append(errs, field.Invalid(specPath.Child("service_binding"), user.Spec.ServiceBinding, fmt.Sprintf("the binding secret %s is already used by the user", bindingSecretName)))
+		}
+	}
+
+	dbServer, err := controllers.GetDbServer(user.Spec.GetDbServerRef(), v.Client, user.Namespace)
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("privileges were not validated, unable to get DbServer %s: %s", user.Spec.GetDbServerRef().Name, err))
+		return warnings, errs
+	}
+	serverType := dbServer.Spec.ServerType
+
+	err = dbservers.ValidateServerPrivs(serverType, user.Spec.ServerPrivs)
+	if err != nil {
+		errs = append(errs, field.Invalid(specPath.Child("server_privs"), user.Spec.ServerPrivs, err.Error()))
+	}
+	for i, dbPriv := range user.Spec.DbPrivs {
+		err = dbservers.ValidateDbPriv(serverType, dbPriv)
+		if err != nil {
+			errs = append(errs, field.Invalid(specPath.Child("db_privs").Index(i), dbPriv.Privs, err.Error()))
+		}
+	}
+	return warnings, errs
+}
@@ -0,0 +1,121 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// Validating webhooks run the checks the reconcilers would otherwise only do at reconcile time,
+// so invalid resources are rejected when they're applied instead of ending up in a backoff loop
+
+type validateFunc func(ctx context.Context, obj client.Object) (admission.Warnings, field.ErrorList)
+
+// Validates on create and on updates of the spec, deleting is always allowed
+type objectValidator struct {
+	validate validateFunc
+}
+
+func (v *objectValidator) run(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	clientObj, ok := obj.(client.Object)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object %T", obj)
+	}
+	warnings, errs := v.validate(ctx, clientObj)
+	if len(errs) == 0 {
+		return warnings, nil
+	}
+	gvk := clientObj.GetObjectKind().GroupVersionKind()
+	groupKind := gvk.GroupKind()
+	if groupKind.Kind == "" {
+		groupKind.Group = dboperatorv1alpha1.GroupVersion.Group
+		groupKind.Kind = reflect.TypeOf(obj).Elem().Name()
+	}
+	return warnings, apierrors.NewInvalid(groupKind, clientObj.GetName(), errs)
+}
+
+func (v *objectValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return v.run(ctx, obj)
+}
+
+func (v *objectValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	// Resources from before a rule was added have to stay deletable, removing the finalizer is an update too
+	clientObj, ok := newObj.(client.Object)
+	if ok && clientObj.GetDeletionTimestamp() != nil {
+		return nil, nil
+	}
+	if specUnchanged(oldObj, newObj) {
+		return nil, nil
+	}
+	return v.run(ctx, newObj)
+}
+
+// True when only the metadata or the status changed
+func specUnchanged(oldObj, newObj runtime.Object) bool {
+	oldValue := reflect.Indirect(reflect.ValueOf(oldObj))
+	newValue := reflect.Indirect(reflect.ValueOf(newObj))
+	if oldValue.Kind() != reflect.Struct || oldValue.Type() != newValue.Type() {
+		return false
+	}
+	oldSpec := oldValue.FieldByName("Spec")
+	if !oldSpec.IsValid() {
+		return false
+	}
+	return reflect.DeepEqual(oldSpec.Interface(), newValue.FieldByName("Spec").Interface())
+}
+
+func (v *objectValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func register(mgr ctrl.Manager, obj runtime.Object, validate validateFunc) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(obj).
+		WithValidator(&objectValidator{validate: validate}).
+		Complete()
+}
+
+func SetupWebhooksWithManager(mgr ctrl.Manager) error {
+	userValidator := &UserValidator{Client: mgr.GetClient()}
+	dbValidator := &DbValidator{Client: mgr.GetClient()}
+	validators := []struct {
+		obj      runtime.Object
+		validate validateFunc
+	}{
+		{&dboperatorv1alpha1.User{}, userValidator.Validate},
+		{&dboperatorv1alpha1.Db{}, dbValidator.Validate},
+		{&dboperatorv1alpha1.DbServer{}, validateDbServer},
+		{&dboperatorv1alpha1.ClusterDbServer{}, validateDbServer},
+		{&dboperatorv1alpha1.BackupTarget{}, validateStorageType},
+		{&dboperatorv1alpha1.RestoreTarget{}, validateStorageType},
+		{&dboperatorv1alpha1.BackupCronJob{}, validateInterval},
+		{&dboperatorv1alpha1.RestoreCronJob{}, validateInterval},
+		{&dboperatorv1alpha1.DbCopyCronJob{}, validateInterval},
+		{&dboperatorv1alpha1.CockroachDBBackupCronJob{}, validateInterval},
+		{&dboperatorv1alpha1.SqlCronJob{}, validateInterval},
+	}
+	for _, validator := range validators {
+		err := register(mgr, validator.obj, validator.validate)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The builder serves /convert for every kind that has a hub and convertible spokes in the scheme,
+	// User, Db and DbServer already got it through their validators
+	for _, obj := range []runtime.Object{&dboperatorv1alpha1.Schema{}} {
+		err := ctrl.NewWebhookManagedBy(mgr).For(obj).Complete()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
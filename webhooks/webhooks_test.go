@@ -0,0 +1,259 @@
+package webhooks
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	dboperatorv1alpha1 "github.com/obeleh/db-operator/api/v1alpha1"
+	"github.com/obeleh/db-operator/controllers"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type validatorTestCase struct {
+	name     string
+	validate validateFunc
+	oldObj   client.Object
+	obj      client.Object
+	valid    bool
+	warnings bool
+}
+
+func newFakeClient(t *testing.T, objs ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if err := dboperatorv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	return fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objs...).
+		WithIndex(&dboperatorv1alpha1.DbServer{}, controllers.DB_SERVER_NAME_INDEX, func(obj client.Object) []string {
+			return []string{obj.GetName()}
+		}).
+		Build()
+}
+
+func runValidatorTestCases(t *testing.T, testCases []validatorTestCase) {
+	ctx := context.Background()
+	for _, tc := range testCases {
+		validator := &objectValidator{validate: tc.validate}
+		oldObj := tc.oldObj
+		if oldObj == nil {
+			// An object of the same kind with an empty spec, an unchanged spec isn't validated
+			oldObj = reflect.New(reflect.TypeOf(tc.obj).Elem()).Interface().(client.Object)
+		}
+		createWarnings, createErr := validator.ValidateCreate(ctx, tc.obj)
+		updateWarnings, updateErr := validator.ValidateUpdate(ctx, oldObj, tc.obj)
+		for action, err := range map[string]error{"create": createErr, "update": updateErr} {
+			if tc.valid && err != nil {
+				t.Errorf("%s: unexpected error on %s %s", tc.name, action, err)
+			}
+			if !tc.valid && !apierrors.IsInvalid(err) {
+				t.Errorf("%s: expected an Invalid error on %s got %v", tc.name, action, err)
+			}
+		}
+		if tc.warnings != (len(createWarnings) > 0) || tc.warnings != (len(updateWarnings) > 0) {
+			t.Errorf("%s: expected warnings %t got %v and %v", tc.name, tc.warnings, createWarnings, updateWarnings)
+		}
+		if _, err := validator.ValidateDelete(ctx, tc.obj); err != nil {
+			t.Errorf("%s: expected deleting to be allowed got %s", tc.name, err)
+		}
+	}
+}
+
+func dbServer(name string, serverType string) *dboperatorv1alpha1.DbServer {
+	return &dboperatorv1alpha1.DbServer{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop"},
+		Spec:       dboperatorv1alpha1.DbServerSpec{ServerType: serverType},
+	}
+}
+
+func TestUserValidator(t *testing.T) {
+	validator := &UserValidator{Client: newFakeClient(t, dbServer("postgres", "postgres"))}
+	user := func(mutate func(spec *dboperatorv1alpha1.UserSpec)) *dboperatorv1alpha1.User {
+		user := &dboperatorv1alpha1.User{
+			ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "shop"},
+			Spec: dboperatorv1alpha1.UserSpec{
+				UserName:     "shop",
+				SecretName:   "shop-credentials",
+				DbServerName: "postgres",
+				ServerPrivs:  "LOGIN",
+				DbPrivs:      []dboperatorv1alpha1.DbPriv{{Scope: "shop", Privs: "CONNECT"}},
+			},
+		}
+		mutate(&user.Spec)
+		return user
+	}
+
+	runValidatorTestCases(t, []validatorTestCase{
+		{name: "valid user", validate: validator.Validate, obj: user(func(spec *dboperatorv1alpha1.UserSpec) {}), valid: true},
+		{name: "server privs changed", validate: validator.Validate, oldObj: user(func(spec *dboperatorv1alpha1.UserSpec) {}), obj: user(func(spec *dboperatorv1alpha1.UserSpec) {
+			spec.ServerPrivs = "LOGIN,CREATEDB"
+		}), valid: true},
+		{name: "unknown DbServer", validate: validator.Validate, obj: user(func(spec *dboperatorv1alpha1.UserSpec) {
+			spec.DbServerName = "mysql"
+		}), valid: true, warnings: true},
+		{name: "db_server_name and db_server_ref", validate: validator.Validate, obj: user(func(spec *dboperatorv1alpha1.UserSpec) {
+			spec.DbServerRef = &dboperatorv1alpha1.DbServerRef{Name: "postgres", Namespace: "shop"}
+		})},
+		{name: "drop_owned and reassign_owned_to", validate: validator.Validate, obj: user(func(spec *dboperatorv1alpha1.UserSpec) {
+			spec.DropUserOptions = &dboperatorv1alpha1.DropUserOptions{DropOwned: true, ReassingOwnedTo: "postgres"}
+		})},
+		{name: "connection secret is the password secret", validate: validator.Validate, obj: user(func(spec *dboperatorv1alpha1.UserSpec) {
+			spec.ConnectionSecret = &dboperatorv1alpha1.ConnectionSecret{SecretName: "shop-credentials"}
+		})},
+		{name: "service binding", validate: validator.Validate, obj: user(func(spec *dboperatorv1alpha1.UserSpec) {
+			spec.ServiceBinding = true
+			spec.ConnectionSecret = &dboperatorv1alpha1.ConnectionSecret{SecretName: "shop-binding"}
+		}), valid: true},
+		{name: "connection secret is the binding secret", validate: validator.Validate, obj: user(func(spec *dboperatorv1alpha1.UserSpec) {
+			spec.ServiceBinding = true
+			spec.ConnectionSecret = &dboperatorv1alpha1.ConnectionSecret{SecretName: "shop-user-binding"}
+		})},
+		{name: "connection secret template", validate: validator.Validate, obj: user(func(spec *dboperatorv1alpha1.UserSpec) {
+			spec.ConnectionSecret = &dboperatorv1alpha1.ConnectionSecret{SecretName: "shop-connection", Templates: map[string]string{"url": "{{ .Uri"}}
+		})},
+		{name: "invalid server privs", validate: validator.Validate, obj: user(func(spec *dboperatorv1alpha1.UserSpec) {
+			spec.ServerPrivs = "LOGIN,SUPERUSR"
+		})},
+		{name: "invalid db privs", validate: validator.Validate, obj: user(func(spec *dboperatorv1alpha1.UserSpec) {
+			spec.DbPrivs = []dboperatorv1alpha1.DbPriv{{Scope: "shop", Privs: "table1:SELCT"}}
+		})},
+	})
+}
+
+func TestDbValidator(t *testing.T) {
+	validator := &DbValidator{Client: newFakeClient(t, dbServer("postgres", "postgres"), dbServer("mysql", "mysql"))}
+	db := func(mutate func(spec *dboperatorv1alpha1.DbSpec)) *dboperatorv1alpha1.Db {
+		db := &dboperatorv1alpha1.Db{
+			ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "shop"},
+			Spec:       dboperatorv1alpha1.DbSpec{Server: "postgres", DbName: "shop"},
+		}
+		mutate(&db.Spec)
+		return db
+	}
+
+	runValidatorTestCases(t, []validatorTestCase{
+		{name: "valid db", validate: validator.Validate, obj: db(func(spec *dboperatorv1alpha1.DbSpec) {}), valid: true},
+		{name: "postgres options", validate: validator.Validate, obj: db(func(spec *dboperatorv1alpha1.DbSpec) {
+			spec.Encoding = "UTF8"
+		}), valid: true},
+		{name: "owner added", validate: validator.Validate, oldObj: db(func(spec *dboperatorv1alpha1.DbSpec) {}), obj: db(func(spec *dboperatorv1alpha1.DbSpec) {
+			spec.Owner = "shop"
+			spec.ServiceBinding = true
+		}), valid: true},
+		{name: "unknown DbServer", validate: validator.Validate, obj: db(func(spec *dboperatorv1alpha1.DbSpec) {
+			spec.Server = "cockroachdb"
+		}), valid: true, warnings: true},
+		{name: "server and server_ref", validate: validator.Validate, obj: db(func(spec *dboperatorv1alpha1.DbSpec) {
+			spec.ServerRef = &dboperatorv1alpha1.DbServerRef{Name: "postgres", Namespace: "shop"}
+		})},
+		{name: "service binding without owner", validate: validator.Validate, obj: db(func(spec *dboperatorv1alpha1.DbSpec) {
+			spec.ServiceBinding = true
+		})},
+		{name: "postgres options on mysql", validate: validator.Validate, obj: db(func(spec *dboperatorv1alpha1.DbSpec) {
+			spec.Server = "mysql"
+			spec.Encoding = "UTF8"
+		})},
+	})
+}
+
+func TestDbServerValidator(t *testing.T) {
+	clusterDbServer := func(serverType string) *dboperatorv1alpha1.ClusterDbServer {
+		return &dboperatorv1alpha1.ClusterDbServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "shared"},
+			Spec:       dboperatorv1alpha1.DbServerSpec{ServerType: serverType},
+		}
+	}
+
+	runValidatorTestCases(t, []validatorTestCase{
+		{name: "postgres", validate: validateDbServer, obj: dbServer("postgres", "postgres"), valid: true},
+		{name: "cockroachdb", validate: validateDbServer, obj: dbServer("cockroachdb", "cockroachdb"), valid: true},
+		{name: "server type changed", validate: validateDbServer, oldObj: dbServer("db", "postgres"), obj: dbServer("db", "mysql"), valid: true},
+		{name: "unknown server type", validate: validateDbServer, obj: dbServer("oracle", "oracle")},
+		{name: "cluster mysql", validate: validateDbServer, obj: clusterDbServer("mysql"), valid: true},
+		{name: "cluster unknown server type", validate: validateDbServer, oldObj: clusterDbServer("mysql"), obj: clusterDbServer("")},
+	})
+}
+
+func TestStorageTypeValidator(t *testing.T) {
+	backupTarget := func(storageType string) *dboperatorv1alpha1.BackupTarget {
+		return &dboperatorv1alpha1.BackupTarget{
+			ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "shop"},
+			Spec:       dboperatorv1alpha1.BackupTargetSpec{DbName: "shop", StorageLocation: "backups", StorageType: storageType},
+		}
+	}
+	restoreTarget := func(storageType string) *dboperatorv1alpha1.RestoreTarget {
+		return &dboperatorv1alpha1.RestoreTarget{
+			ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "shop"},
+			Spec:       dboperatorv1alpha1.RestoreTargetSpec{DbName: "shop", StorageLocation: "backups", StorageType: storageType},
+		}
+	}
+
+	runValidatorTestCases(t, []validatorTestCase{
+		{name: "backup to s3", validate: validateStorageType, obj: backupTarget("s3"), valid: true},
+		{name: "backup to S3", validate: validateStorageType, obj: backupTarget("S3"), valid: true},
+		{name: "backup to gcs", validate: validateStorageType, obj: backupTarget("gcs")},
+		{name: "restore from s3", validate: validateStorageType, obj: restoreTarget("s3"), valid: true},
+		{name: "restore without storage type", validate: validateStorageType, obj: restoreTarget("")},
+	})
+}
+
+func TestIntervalValidator(t *testing.T) {
+	testCases := []validatorTestCase{}
+	for interval, valid := range map[string]bool{"0 3 * * *": true, "@daily": true, "0 3 * *": false, "61 * * * *": false} {
+		objs := []client.Object{
+			&dboperatorv1alpha1.BackupCronJob{Spec: dboperatorv1alpha1.BackupCronJobSpec{Interval: interval}},
+			&dboperatorv1alpha1.RestoreCronJob{Spec: dboperatorv1alpha1.RestoreCronJobSpec{Interval: interval}},
+			&dboperatorv1alpha1.DbCopyCronJob{Spec: dboperatorv1alpha1.DbCopyCronJobSpec{Interval: interval}},
+			&dboperatorv1alpha1.CockroachDBBackupCronJob{Spec: dboperatorv1alpha1.CockroachDBBackupCronJobSpec{Interval: interval}},
+			&dboperatorv1alpha1.SqlCronJob{Spec: dboperatorv1alpha1.SqlCronJobSpec{Interval: interval}},
+		}
+		for _, obj := range objs {
+			obj.SetName("nightly")
+			obj.SetNamespace("shop")
+			testCases = append(testCases, validatorTestCase{name: interval, validate: validateInterval, obj: obj, valid: valid})
+		}
+	}
+	runValidatorTestCases(t, testCases)
+}
+
+func TestValidateUpdateOfInvalidObject(t *testing.T) {
+	ctx := context.Background()
+	validator := &objectValidator{validate: validateDbServer}
+	// Created before the server type was validated
+	oldObj := dbServer("oracle", "oracle")
+	oldObj.Finalizers = []string{controllers.DB_OPERATOR_FINALIZER}
+
+	labeled := oldObj.DeepCopy()
+	labeled.Labels = map[string]string{"team": "shop"}
+	_, err := validator.ValidateUpdate(ctx, oldObj, labeled)
+	if err != nil {
+		t.Errorf("expected a metadata change to be allowed got %s", err)
+	}
+
+	deleting := oldObj.DeepCopy()
+	now := metav1.Now()
+	deleting.DeletionTimestamp = &now
+	withoutFinalizer := deleting.DeepCopy()
+	withoutFinalizer.Finalizers = nil
+	_, err = validator.ValidateUpdate(ctx, deleting, withoutFinalizer)
+	if err != nil {
+		t.Errorf("expected removing the finalizer to be allowed got %s", err)
+	}
+
+	changed := oldObj.DeepCopy()
+	changed.Spec.Port = 1521
+	_, err = validator.ValidateUpdate(ctx, oldObj, changed)
+	if !apierrors.IsInvalid(err) {
+		t.Errorf("expected a spec change to be validated got %v", err)
+	}
+}